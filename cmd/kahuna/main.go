@@ -16,9 +16,9 @@ import (
 
 func main() {
 	// parsing commands
-	cfgFile, debug, migrate := kahuna.ParseCommands()
+	cmd := kahuna.ParseCommands()
 
-	cfg, err := kahuna.ParseConfig(cfgFile)
+	cfg, err := kahuna.ParseConfig(cmd.ConfigFile)
 	if err != nil {
 		fmt.Printf("config: unable to unmarshal config (%s)\n", err)
 		os.Exit(1)
@@ -26,7 +26,7 @@ func main() {
 
 	// init our logger
 	logLevel := cfg.Global.LogLevel
-	if debug {
+	if cmd.Debug {
 		logLevel = "DEBUG"
 	}
 	klog.Init("kahuna", []klog.LoggerConfiguration{
@@ -45,15 +45,25 @@ func main() {
 		os.Exit(1)
 	}
 
-	if migrate {
-		err = ae.MigrateDatabase(cfg)
-		if err != nil {
-			klog.Error(err)
-			os.Exit(1)
-		}
-	} else {
+	switch {
+	case cmd.Name == kahuna.KahunaCommandBackup:
+		err = ae.BackupDatabase(cfg, cmd.Archive, cmd.PassphraseFile)
+	case cmd.Name == kahuna.KahunaCommandRestore:
+		err = ae.RestoreDatabase(cfg, cmd.Archive, cmd.PassphraseFile, cmd.Force)
+	case cmd.Name == kahuna.KahunaCommandCheck:
+		err = ae.CheckDatabase(cfg, cmd.Repair)
+	case cmd.Rollback != "":
+		err = ae.RollbackDatabase(cfg, cmd.Rollback, cmd.DryRun)
+	case cmd.Migrate:
+		err = ae.MigrateDatabase(cfg, cmd.DryRun)
+	default:
 		ae.Run(cfg)
 	}
 
+	if err != nil {
+		klog.Error(err)
+		os.Exit(1)
+	}
+
 	os.Exit(0)
 }
@@ -12,6 +12,7 @@ import (
 	"math/big"
 	"slices"
 	"sync"
+	"time"
 
 	"github.com/kowabunga-cloud/common"
 	"github.com/kowabunga-cloud/common/agents"
@@ -27,6 +28,12 @@ type KowabungaAgent struct {
 	Interrupted chan bool
 	Version     string
 	Methods     []string
+	ConnectedAt time.Time
+}
+
+func (agent *KowabungaAgent) Call(method string, args, reply any) error {
+	methodName := fmt.Sprintf("%s.%s", agent.Type, method)
+	return agent.Client.Call(methodName, args, reply)
 }
 
 func (agent *KowabungaAgent) WatchKeepalive() {
@@ -51,7 +58,16 @@ func GetAgents() map[string]*KowabungaAgent {
 	return kAgents
 }
 
+// DisconnectAgent closes agent's connection, whichever Kahuna replica it is connected to
 func DisconnectAgent(agentId string) {
+	if GetAgent(agentId) == nil {
+		forwardDisconnect(agentId)
+		return
+	}
+	disconnectLocalAgent(agentId)
+}
+
+func disconnectLocalAgent(agentId string) {
 	ag := GetAgent(agentId)
 	if ag == nil {
 		return
@@ -114,6 +130,8 @@ func RegisterAgent(agentType, agentId string, client *wsrpc.WsRpcClient) error {
 			}
 		}
 		ag.Client = client
+		ag.ConnectedAt = time.Now()
+		NewAgentPresence(ag).Save()
 		return nil
 	}
 
@@ -123,13 +141,13 @@ func RegisterAgent(agentType, agentId string, client *wsrpc.WsRpcClient) error {
 		Type:        agentType,
 		IsConnected: true,
 		Interrupted: make(chan bool, 1),
+		ConnectedAt: time.Now(),
 	}
 
 	// discover agent's RPC capabilities
 	args := agents.CapabilitiesArgs{}
 	var reply agents.CapabilitiesReply
-	method := fmt.Sprintf("%s.Capabilities", ag.Type)
-	err := ag.Client.Call(method, args, &reply)
+	err := ag.Call("Capabilities", args, &reply)
 	if err != nil {
 		klog.Errorf("Unable to call remote Capabilities() RPC. Is it a legit agent ??")
 		return err
@@ -141,6 +159,7 @@ func RegisterAgent(agentType, agentId string, client *wsrpc.WsRpcClient) error {
 	agentsLock.Lock()
 	GetAgents()[agentId] = ag
 	agentsLock.Unlock()
+	NewAgentPresence(ag).Save()
 	go ag.WatchKeepalive()
 
 	switch ag.Type {
@@ -186,6 +205,7 @@ func UnregisterAgent(agentId string) {
 	agentsLock.Lock()
 	delete(GetAgents(), agentId)
 	agentsLock.Unlock()
+	RemoveAgentPresence(agentId)
 }
 
 func VerifyAgents(candidates []string, kind string) []string {
@@ -211,9 +231,9 @@ func VerifyAgents(candidates []string, kind string) []string {
 func RPC(candidateAgents []string, method string, args, reply any) error {
 	ag := GetEligibleAgent(candidateAgents, method)
 	if ag == nil {
-		return fmt.Errorf("RPC: can't find any eligible agent to perform such request")
+		// agent may be connected to another Kahuna replica
+		return forwardRPC(candidateAgents, method, args, reply)
 	}
 
-	methodName := fmt.Sprintf("%s.%s", ag.Type, method)
-	return ag.Client.Call(methodName, args, reply)
+	return ag.Call(method, args, reply)
 }
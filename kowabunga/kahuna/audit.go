@@ -0,0 +1,296 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * Kowabunga audit trail keeps a persistent record of every mutating API call
+ * (who did what, on which resource and with which outcome).
+ */
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/kowabunga-cloud/common/klog"
+)
+
+const (
+	MongoCollectionAuditSchemaVersion = 1
+	MongoCollectionAuditName          = "audit"
+
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+
+	AuditListDefaultLimit = 100
+	AuditListMaxLimit     = 1000
+
+	auditResponseMaxCapture = 65536
+)
+
+var auditedMethods = []string{
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+type AuditEntry struct {
+	ID            bson.ObjectID `bson:"_id"`
+	Timestamp     time.Time     `bson:"timestamp"`
+	SchemaVersion int           `bson:"schema_version"`
+
+	// caller
+	UserID     string `bson:"user_id"`
+	AuthMethod string `bson:"auth_method"`
+	RemoteAddr string `bson:"remote_addr"`
+
+	// request
+	Route         string `bson:"route"`
+	Method        string `bson:"method"`
+	URI           string `bson:"uri"`
+	ProjectID     string `bson:"project_id"`
+	ResourceID    string `bson:"resource_id"`
+	PayloadDigest string `bson:"payload_digest"`
+
+	// response
+	Status     int    `bson:"status"`
+	Outcome    string `bson:"outcome"`
+	DurationMs int64  `bson:"duration_ms"`
+}
+
+type AuditEntryModel struct {
+	Id            string `json:"id"`
+	Timestamp     string `json:"timestamp"`
+	UserId        string `json:"user_id,omitempty"`
+	AuthMethod    string `json:"auth_method,omitempty"`
+	RemoteAddr    string `json:"remote_addr,omitempty"`
+	Route         string `json:"route"`
+	Method        string `json:"method"`
+	Uri           string `json:"uri"`
+	ProjectId     string `json:"project_id,omitempty"`
+	ResourceId    string `json:"resource_id,omitempty"`
+	PayloadDigest string `json:"payload_digest,omitempty"`
+	Status        int    `json:"status"`
+	Outcome       string `json:"outcome"`
+	DurationMs    int64  `json:"duration_ms"`
+}
+
+type AuditFilter struct {
+	UserID     string
+	ProjectID  string
+	ResourceID string
+	From       time.Time
+	To         time.Time
+	Limit      int64
+}
+
+func NewAuditEntry(r *http.Request, route string) *AuditEntry {
+	ctx := r.Context()
+	now := time.Now()
+	return &AuditEntry{
+		ID:            bson.NewObjectIDFromTimestamp(now),
+		Timestamp:     now,
+		SchemaVersion: MongoCollectionAuditSchemaVersion,
+		UserID:        ctxGetUserId(ctx),
+		AuthMethod:    ctxGetAuthMethod(ctx),
+		RemoteAddr:    r.RemoteAddr,
+		Route:         route,
+		Method:        r.Method,
+		URI:           r.RequestURI,
+		ProjectID:     auditTargetProject(r),
+	}
+}
+
+func (a *AuditEntry) String() string {
+	return a.ID.Hex()
+}
+
+func (a *AuditEntry) Save() {
+	_, err := GetDB().Insert(MongoCollectionAuditName, a)
+	if err != nil {
+		klog.Errorf("Unable to save audit entry for %s %s: %v", a.Method, a.URI, err)
+	}
+}
+
+func (a *AuditEntry) Model() AuditEntryModel {
+	return AuditEntryModel{
+		Id:            a.String(),
+		Timestamp:     a.Timestamp.UTC().Format(time.RFC3339),
+		UserId:        a.UserID,
+		AuthMethod:    a.AuthMethod,
+		RemoteAddr:    a.RemoteAddr,
+		Route:         a.Route,
+		Method:        a.Method,
+		Uri:           a.URI,
+		ProjectId:     a.ProjectID,
+		ResourceId:    a.ResourceID,
+		PayloadDigest: a.PayloadDigest,
+		Status:        a.Status,
+		Outcome:       a.Outcome,
+		DurationMs:    a.DurationMs,
+	}
+}
+
+// auditEntriesFilter converts an audit filter into a database query filter
+func auditEntriesFilter(f AuditFilter) bson.D {
+	filter := bson.D{}
+	if f.UserID != "" {
+		filter = append(filter, bson.E{Key: "user_id", Value: f.UserID})
+	}
+	if f.ProjectID != "" {
+		filter = append(filter, bson.E{Key: "project_id", Value: f.ProjectID})
+	}
+	if f.ResourceID != "" {
+		filter = append(filter, bson.E{Key: "resource_id", Value: f.ResourceID})
+	}
+
+	period := bson.D{}
+	if !f.From.IsZero() {
+		period = append(period, bson.E{Key: "$gte", Value: f.From})
+	}
+	if !f.To.IsZero() {
+		period = append(period, bson.E{Key: "$lte", Value: f.To})
+	}
+	if len(period) > 0 {
+		filter = append(filter, bson.E{Key: "timestamp", Value: period})
+	}
+
+	return filter
+}
+
+func auditEntriesLimit(f AuditFilter) int64 {
+	limit := f.Limit
+	if limit <= 0 {
+		limit = AuditListDefaultLimit
+	}
+	return min(limit, AuditListMaxLimit)
+}
+
+func FindAuditEntries(f AuditFilter) ([]AuditEntry, error) {
+	// most recent entries first
+	sort := bson.D{bson.E{Key: "timestamp", Value: -1}}
+
+	res := []AuditEntry{}
+	err := GetDB().FindAllByFilter(MongoCollectionAuditName, auditEntriesFilter(f), sort, auditEntriesLimit(f), &res)
+	return res, err
+}
+
+// auditResponseWriter catches response status code and (partial) body for audit purpose
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	room := auditResponseMaxCapture - w.body.Len()
+	if room > 0 {
+		w.body.Write(b[:min(room, len(b))])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func auditPayloadDigest(r *http.Request) string {
+	if r.Body == nil {
+		return ""
+	}
+
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		klog.Errorf("Unable to read request body for audit: %v", err)
+	}
+	_ = r.Body.Close()
+
+	// restore body for next handlers
+	r.Body = io.NopCloser(bytes.NewReader(payload))
+
+	if len(payload) == 0 {
+		return ""
+	}
+
+	// keyed digest, so that sensitive payloads (e.g. passwords) can't be brute-forced out of the audit trail
+	mac := hmac.New(sha256.New, []byte(GetCfg().Global.JWT.Signature))
+	_, _ = mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// auditTargetProject finds out the project owning the resources targeted by the request, whatever
+// the route (e.g. {instanceId} as well as {projectId}). It must be resolved before request is
+// processed, as deleted resources can't be resolved anymore.
+func auditTargetProject(r *http.Request) string {
+	target := rbacResolveTarget(r)
+	if target.Ambiguous {
+		return ""
+	}
+	return target.ProjectID
+}
+
+// auditTargetResource finds out the resource targeted by the request,
+// i.e. the last path variable of the route template (e.g. {instanceId})
+// or the newly created resource ID for creation requests.
+func auditTargetResource(r *http.Request, w *auditResponseWriter) string {
+	if r.Method == http.MethodPost && (w.status == http.StatusOK || w.status == http.StatusCreated) {
+		var created struct {
+			Id string `json:"id"`
+		}
+		err := json.Unmarshal(w.body.Bytes(), &created)
+		if err == nil && created.Id != "" {
+			return created.Id
+		}
+	}
+
+	_, id := routeTargetVar(r)
+	return id
+}
+
+func auditMiddleware(next http.Handler, name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !slices.Contains(auditedMethods, r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		entry := NewAuditEntry(r, name)
+		entry.PayloadDigest = auditPayloadDigest(r)
+
+		aw := &auditResponseWriter{
+			ResponseWriter: w,
+		}
+		next.ServeHTTP(aw, r)
+
+		entry.DurationMs = time.Since(start).Milliseconds()
+		entry.Status = aw.status
+		if entry.Status == 0 {
+			entry.Status = http.StatusOK
+		}
+		entry.Outcome = AuditOutcomeSuccess
+		if entry.Status >= http.StatusBadRequest {
+			entry.Outcome = AuditOutcomeFailure
+		}
+		entry.ResourceID = auditTargetResource(r, aw)
+
+		entry.Save()
+	})
+}
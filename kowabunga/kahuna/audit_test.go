@@ -0,0 +1,116 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestAuditEntry(t *testing.T) {
+	f := newTestRbacFixture(t)
+	acme := f.organization("acme")
+	webapp := f.project("webapp", acme)
+	backend := f.project("backend", acme)
+	u := f.user("john", UserRoleStandard, acme)
+	instanceId := f.resource(MongoCollectionInstanceName, webapp)
+	volumeId := f.resource(MongoCollectionVolumeName, backend)
+
+	r := httptest.NewRequest(http.MethodDelete, "/api/v1/instance/"+instanceId, nil)
+	r = mux.SetURLVars(r, map[string]string{"instanceId": instanceId})
+	r = r.WithContext(ctxSetUserId(context.Background(), u.String()))
+
+	entry := NewAuditEntry(r, "DeleteInstance")
+	if entry.UserID != u.String() || entry.Route != "DeleteInstance" || entry.Method != http.MethodDelete {
+		t.Fatalf("unexpected audit entry: %+v", entry)
+	}
+	if entry.URI != "/api/v1/instance/"+instanceId || entry.RemoteAddr != r.RemoteAddr {
+		t.Fatalf("unexpected audit entry request: %+v", entry)
+	}
+	if entry.SchemaVersion != MongoCollectionAuditSchemaVersion || entry.ID.IsZero() {
+		t.Fatalf("unexpected audit entry identity: %+v", entry)
+	}
+
+	// project is resolved from any targeted resource, not only from project routes
+	for _, tc := range []struct {
+		vars    map[string]string
+		project string
+	}{
+		{map[string]string{"instanceId": instanceId}, webapp.String()},
+		{map[string]string{"projectId": backend.String()}, backend.String()},
+		{map[string]string{"projectId": webapp.String(), "instanceId": instanceId}, webapp.String()},
+		{map[string]string{"instanceId": instanceId, "volumeId": volumeId}, ""},
+		{map[string]string{"organizationId": acme.String()}, ""},
+		{map[string]string{}, ""},
+	} {
+		r := mux.SetURLVars(httptest.NewRequest(http.MethodPut, "/", nil), tc.vars)
+		entry := NewAuditEntry(r, "Update")
+		if entry.ProjectID != tc.project {
+			t.Errorf("%v: unexpected audit entry project %s, expected %s", tc.vars, entry.ProjectID, tc.project)
+		}
+	}
+}
+
+func TestAuditTargetResource(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/project", nil)
+	aw := &auditResponseWriter{
+		ResponseWriter: httptest.NewRecorder(),
+	}
+	aw.WriteHeader(http.StatusCreated)
+	_, _ = aw.Write([]byte(`{"id":"0123456789abcdef01234567","name":"acme"}`))
+
+	if auditTargetResource(r, aw) != "0123456789abcdef01234567" {
+		t.Fatalf("created resource has not been audited")
+	}
+
+	// failed creations have no resource
+	aw = &auditResponseWriter{
+		ResponseWriter: httptest.NewRecorder(),
+	}
+	aw.WriteHeader(http.StatusConflict)
+	_, _ = aw.Write([]byte(`"conflict"`))
+	if auditTargetResource(r, aw) != "" {
+		t.Fatalf("failed creation has been audited with a resource")
+	}
+}
+
+func TestAuditEntriesFilter(t *testing.T) {
+	filter := auditEntriesFilter(AuditFilter{})
+	if len(filter) != 0 {
+		t.Fatalf("unexpected empty audit filter: %v", filter)
+	}
+
+	filter = auditEntriesFilter(AuditFilter{ProjectID: "webapp"})
+	expected := bson.D{bson.E{Key: "project_id", Value: "webapp"}}
+	if filter.String() != expected.String() {
+		t.Fatalf("unexpected project audit filter: %v", filter)
+	}
+
+	from := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	filter = auditEntriesFilter(AuditFilter{UserID: "john", ProjectID: "webapp", From: from})
+	expected = bson.D{
+		bson.E{Key: "user_id", Value: "john"},
+		bson.E{Key: "project_id", Value: "webapp"},
+		bson.E{Key: "timestamp", Value: bson.D{bson.E{Key: "$gte", Value: from}}},
+	}
+	if filter.String() != expected.String() {
+		t.Fatalf("unexpected audit filter: %v", filter)
+	}
+
+	if auditEntriesLimit(AuditFilter{}) != AuditListDefaultLimit {
+		t.Fatalf("unexpected default audit limit")
+	}
+	if auditEntriesLimit(AuditFilter{Limit: 10 * AuditListMaxLimit}) != AuditListMaxLimit {
+		t.Fatalf("unexpected maximum audit limit")
+	}
+}
@@ -0,0 +1,712 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * Database backup and restore: all collections are exported, from a single
+ * database snapshot, into a gzip-compressed tar archive, one MongoDB Extended
+ * JSON document per line, alongside with a descriptor recording archive
+ * format and collections schema versions. Secrets may optionally be encrypted
+ * with a passphrase-derived key.
+ */
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/kowabunga-cloud/common/klog"
+)
+
+const (
+	BackupFormatVersion      = 1
+	BackupDescriptorFile     = "backup.json"
+	BackupCollectionsDir     = "collections"
+	BackupCollectionFileExt  = ".jsonl"
+	BackupInsertBatchSize    = 500
+	BackupKdfIterations      = 600000
+	BackupCipher             = "aes-256-gcm"
+	BackupKdf                = "pbkdf2-sha256"
+	BackupEncryptedPrefix    = "encrypted:"
+	BackupSchemaVersionField = "schema_version"
+	BackupVerifierPlaintext  = "kowabunga"
+
+	ErrBackupNoDescriptor       = "backup archive has no descriptor"
+	ErrBackupFormatVersion      = "unsupported backup archive format version"
+	ErrBackupSchemaVersion      = "backup archive was created by a more recent Kahuna, collection schema is unsupported"
+	ErrBackupPassphraseRequired = "backup archive secrets are encrypted, a passphrase is required"
+	ErrBackupInvalidPassphrase  = "unable to decrypt backup archive secrets, invalid passphrase"
+	ErrBackupDatabaseNotEmpty   = "database is not empty, use --force to overwrite existing collections"
+	ErrBackupUnknownCollection  = "backup archive contains a collection it does not describe"
+	ErrBackupDocumentsCount     = "backup archive collection documents count does not match its description"
+)
+
+// current schema version of collections, as supported by this Kahuna
+var backupSchemaVersions = map[string]int{
+	MongoCollectionAdapterName:         MongoCollectionAdapterSchemaVersion,
+	MongoCollectionAgentName:           MongoCollectionAgentSchemaVersion,
+	MongoCollectionAuditName:           MongoCollectionAuditSchemaVersion,
+	MongoCollectionDnsRecordName:       MongoCollectionDnsRecordSchemaVersion,
+	MongoCollectionHarName:             MongoCollectionHarSchemaVersion,
+	MongoCollectionInstanceName:        MongoCollectionInstanceSchemaVersion,
+	MongoCollectionIPsecName:           MongoCollectionKawaiiIPsecSchemaVersion,
+	MongoCollectionKaktusName:          MongoCollectionKaktusSchemaVersion,
+	MongoCollectionKawaiiName:          MongoCollectionKawaiiSchemaVersion,
+	MongoCollectionKiwiName:            MongoCollectionKiwiSchemaVersion,
+	MongoCollectionKomputeName:         MongoCollectionKomputeSchemaVersion,
+	MongoCollectionKonveyName:          MongoCollectionKonveySchemaVersion,
+	MongoCollectionKyloName:            MongoCollectionKyloSchemaVersion,
+	MongoCollectionMigrationName:       MongoCollectionMigrationSchemaVersion,
+	MongoCollectionMzrName:             MongoCollectionMzrSchemaVersion,
+	MongoCollectionNfsName:             MongoCollectionNfsSchemaVersion,
+	MongoCollectionOrganizationName:    MongoCollectionOrganizationSchemaVersion,
+	MongoCollectionProjectName:         MongoCollectionProjectSchemaVersion,
+	MongoCollectionRegionName:          MongoCollectionRegionSchemaVersion,
+	MongoCollectionStoragePoolName:     MongoCollectionStoragePoolSchemaVersion,
+	MongoCollectionSubnetName:          MongoCollectionSubnetSchemaVersion,
+	MongoCollectionTaskName:            MongoCollectionTaskSchemaVersion,
+	MongoCollectionTeamName:            MongoCollectionTeamSchemaVersion,
+	MongoCollectionTemplateName:        MongoCollectionTemplateSchemaVersion,
+	MongoCollectionTokenName:           MongoCollectionTokenSchemaVersion,
+	MongoCollectionUserName:            MongoCollectionUserSchemaVersion,
+	MongoCollectionVNetName:            MongoCollectionVNetSchemaVersion,
+	MongoCollectionVolumeName:          MongoCollectionVolumeSchemaVersion,
+	MongoCollectionWebhookName:         MongoCollectionWebhookSchemaVersion,
+	MongoCollectionWebhookDeliveryName: MongoCollectionWebhookDeliverySchemaVersion,
+	MongoCollectionZoneName:            MongoCollectionZoneSchemaVersion,
+}
+
+// secret documents fields, (optionally) encrypted in backup archives. Array sub-documents fields are
+// transformed in all array elements.
+var backupSecretFields = map[string][]string{
+	MongoCollectionInstanceName:     {"initial_root_password"},
+	MongoCollectionIPsecName:        {"pre_shared_key"},
+	MongoCollectionOrganizationName: {"auth.oidc.client_secret"},
+	MongoCollectionProjectName:      {"default_root_password"},
+	MongoCollectionTokenName:        {"api_key_hash"},
+	MongoCollectionUserName:         {"password_hash", "password_renewal_token", "registration_token", "notification_channels.url"},
+	MongoCollectionWebhookName:      {"secret", "url"},
+}
+
+// runtime-only collections, which are meaningless once restored
+var backupSkippedCollections = []string{
+	MongoCollectionAgentPresenceName,
+	MongoCollectionIdempotencyName,
+	MongoCollectionMigrationLockName,
+	MongoCollectionOidcLoginName,
+}
+
+type BackupEncryption struct {
+	Cipher     string `json:"cipher"`
+	Kdf        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Verifier   string `json:"verifier"` // encrypted known plaintext, for passphrase to be checked upfront
+}
+
+type BackupCollection struct {
+	Name           string `json:"name"`
+	Documents      int64  `json:"documents"`
+	SchemaVersions []int  `json:"schema_versions"`
+}
+
+type BackupDescriptor struct {
+	FormatVersion int                `json:"format_version"`
+	KahunaVersion string             `json:"kahuna_version"`
+	Database      string             `json:"database"`
+	CreatedAt     time.Time          `json:"created_at"`
+	Encryption    *BackupEncryption  `json:"encryption,omitempty"`
+	Collections   []BackupCollection `json:"collections"`
+}
+
+func ReadBackupPassphrase(passphraseFile string) (string, error) {
+	if passphraseFile == "" {
+		return "", nil
+	}
+
+	content, err := os.ReadFile(passphraseFile)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+func backupCipher(passphrase string, enc *BackupEncryption) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, enc.Salt, enc.Iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func backupEncrypt(aead cipher.AEAD) func(string) (string, error) {
+	return func(value string) (string, error) {
+		nonce := make([]byte, aead.NonceSize())
+		_, err := rand.Read(nonce)
+		if err != nil {
+			return "", err
+		}
+		sealed := aead.Seal(nonce, nonce, []byte(value), nil)
+		return BackupEncryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+	}
+}
+
+func backupDecrypt(aead cipher.AEAD) func(string) (string, error) {
+	return func(value string) (string, error) {
+		if !strings.HasPrefix(value, BackupEncryptedPrefix) {
+			return value, nil
+		}
+		sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, BackupEncryptedPrefix))
+		if err != nil || len(sealed) < aead.NonceSize() {
+			return "", errors.New(ErrBackupInvalidPassphrase)
+		}
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		plain, err := aead.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			return "", errors.New(ErrBackupInvalidPassphrase)
+		}
+		return string(plain), nil
+	}
+}
+
+// backupTransformField applies fn to document's (dot-separated path) string field, if any
+func backupTransformField(doc bson.D, fieldPath []string, fn func(string) (string, error)) error {
+	for i, e := range doc {
+		if e.Key != fieldPath[0] {
+			continue
+		}
+
+		if len(fieldPath) > 1 {
+			switch sub := e.Value.(type) {
+			case bson.D:
+				return backupTransformField(sub, fieldPath[1:], fn)
+			case bson.A:
+				for _, item := range sub {
+					itemDoc, ok := item.(bson.D)
+					if !ok {
+						continue
+					}
+					err := backupTransformField(itemDoc, fieldPath[1:], fn)
+					if err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}
+
+		value, ok := e.Value.(string)
+		if !ok || value == "" {
+			return nil
+		}
+		value, err := fn(value)
+		if err != nil {
+			return err
+		}
+		doc[i].Value = value
+		return nil
+	}
+
+	return nil
+}
+
+func backupTransformSecrets(collection string, doc bson.D, fn func(string) (string, error)) error {
+	for _, field := range backupSecretFields[collection] {
+		err := backupTransformField(doc, strings.Split(field, "."), fn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func backupSchemaVersion(doc bson.D) int {
+	for _, e := range doc {
+		if e.Key != BackupSchemaVersionField {
+			continue
+		}
+		switch v := e.Value.(type) {
+		case int32:
+			return int(v)
+		case int64:
+			return int(v)
+		}
+	}
+	return 0
+}
+
+func backupCollectionFile(collection string) string {
+	return path.Join(BackupCollectionsDir, collection+BackupCollectionFileExt)
+}
+
+func backupWriteFile(tw *tar.Writer, name string, f *os.File) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	err = tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    fi.Size(),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// backupCollection dumps collection documents into archive, returning its description
+func backupCollection(ctx context.Context, tw *tar.Writer, collection string, encrypt func(string) (string, error)) (*BackupCollection, error) {
+	tmp, err := os.CreateTemp("", "kahuna-backup-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	bc := BackupCollection{
+		Name:           collection,
+		SchemaVersions: []int{},
+	}
+
+	w := bufio.NewWriter(tmp)
+	err = GetDB().Dump(ctx, collection, func(doc bson.D) error {
+		if encrypt != nil {
+			err := backupTransformSecrets(collection, doc, encrypt)
+			if err != nil {
+				return err
+			}
+		}
+
+		line, err := bson.MarshalExtJSON(doc, true, false)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(line, '\n'))
+		if err != nil {
+			return err
+		}
+
+		bc.Documents++
+		sv := backupSchemaVersion(doc)
+		if !slices.Contains(bc.SchemaVersions, sv) {
+			bc.SchemaVersions = append(bc.SchemaVersions, sv)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(bc.SchemaVersions)
+	return &bc, backupWriteFile(tw, backupCollectionFile(collection), tmp)
+}
+
+// BackupDatabase exports all collections into archive file
+func BackupDatabase(archive, passphrase string) error {
+	collections, err := GetDB().ListCollections()
+	if err != nil {
+		return err
+	}
+	slices.Sort(collections)
+
+	desc := BackupDescriptor{
+		FormatVersion: BackupFormatVersion,
+		KahunaVersion: version,
+		Database:      GetDB().DB.Name(),
+		CreatedAt:     time.Now().UTC(),
+		Collections:   []BackupCollection{},
+	}
+
+	var encrypt func(string) (string, error)
+	if passphrase != "" {
+		desc.Encryption = &BackupEncryption{
+			Cipher:     BackupCipher,
+			Kdf:        BackupKdf,
+			Iterations: BackupKdfIterations,
+			Salt:       make([]byte, 16),
+		}
+		_, err = rand.Read(desc.Encryption.Salt)
+		if err != nil {
+			return err
+		}
+		aead, err := backupCipher(passphrase, desc.Encryption)
+		if err != nil {
+			return err
+		}
+		encrypt = backupEncrypt(aead)
+		desc.Encryption.Verifier, err = encrypt(BackupVerifierPlaintext)
+		if err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(archive, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	// all collections are read from the very same point in time
+	err = GetDB().Snapshot(func(ctx context.Context) error {
+		for _, collection := range collections {
+			if strings.HasPrefix(collection, "system.") || slices.Contains(backupSkippedCollections, collection) {
+				continue
+			}
+
+			klog.Infof("Backing up collection %s ...", collection)
+			bc, err := backupCollection(ctx, tw, collection, encrypt)
+			if err != nil {
+				return fmt.Errorf("%s: %w", collection, err)
+			}
+			desc.Collections = append(desc.Collections, *bc)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// descriptor comes last, once collections have been described
+	content, err := json.MarshalIndent(desc, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:    BackupDescriptorFile,
+		Mode:    0600,
+		Size:    int64(len(content)),
+		ModTime: desc.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(content)
+	if err != nil {
+		return err
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return err
+	}
+	err = gw.Close()
+	if err != nil {
+		return err
+	}
+
+	klog.Infof("Backed up %d collections into %s", len(desc.Collections), archive)
+	return nil
+}
+
+// backupWalk calls fn on each archive file
+func backupWalk(archive string, fn func(name string, r io.Reader) error) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = gr.Close()
+	}()
+
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = fn(hdr.Name, tr)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func ReadBackupDescriptor(archive string) (*BackupDescriptor, error) {
+	var desc *BackupDescriptor
+	err := backupWalk(archive, func(name string, r io.Reader) error {
+		if name != BackupDescriptorFile {
+			return nil
+		}
+		desc = &BackupDescriptor{}
+		return json.NewDecoder(r).Decode(desc)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if desc == nil {
+		return nil, errors.New(ErrBackupNoDescriptor)
+	}
+
+	return desc, nil
+}
+
+// Validate ensures archive can be restored, telling whether schema migration is required afterwards
+func (desc *BackupDescriptor) Validate() (bool, error) {
+	if desc.FormatVersion != BackupFormatVersion {
+		return false, fmt.Errorf("%s: %d", ErrBackupFormatVersion, desc.FormatVersion)
+	}
+
+	migrate := false
+	for _, bc := range desc.Collections {
+		current, ok := backupSchemaVersions[bc.Name]
+		if !ok || len(bc.SchemaVersions) == 0 {
+			continue
+		}
+		if slices.Max(bc.SchemaVersions) > current {
+			return false, fmt.Errorf("%s: %s v%d (Kahuna %s)", ErrBackupSchemaVersion, bc.Name, slices.Max(bc.SchemaVersions), desc.KahunaVersion)
+		}
+		if slices.Min(bc.SchemaVersions) < current {
+			migrate = true
+		}
+	}
+
+	return migrate, nil
+}
+
+// Cipher checks passphrase against archive's verifier, returning secrets decryption function
+func (desc *BackupDescriptor) Cipher(passphrase string) (func(string) (string, error), error) {
+	if desc.Encryption == nil {
+		return nil, nil
+	}
+	if passphrase == "" {
+		return nil, errors.New(ErrBackupPassphraseRequired)
+	}
+
+	aead, err := backupCipher(passphrase, desc.Encryption)
+	if err != nil {
+		return nil, err
+	}
+	decrypt := backupDecrypt(aead)
+
+	if desc.Encryption.Verifier != "" {
+		plain, err := decrypt(desc.Encryption.Verifier)
+		if err != nil || plain != BackupVerifierPlaintext {
+			return nil, errors.New(ErrBackupInvalidPassphrase)
+		}
+	}
+
+	return decrypt, nil
+}
+
+// collection returns archive file's collection description
+func (desc *BackupDescriptor) collection(name string) (*BackupCollection, error) {
+	collection := strings.TrimSuffix(path.Base(name), BackupCollectionFileExt)
+	for i, bc := range desc.Collections {
+		if bc.Name == collection && backupCollectionFile(collection) == name {
+			return &desc.Collections[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%s: %s", ErrBackupUnknownCollection, name)
+}
+
+// restoreCollection reads collection documents, inserting them unless insert is nil (dry-run)
+func restoreCollection(collection string, r io.Reader, decrypt func(string) (string, error), insert func([]any) error) (int64, error) {
+	var count int64
+	batch := []any{}
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		var err error
+		if insert != nil {
+			err = insert(batch)
+		}
+		count += int64(len(batch))
+		batch = []any{}
+		return err
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var doc bson.D
+		err := bson.UnmarshalExtJSON(scanner.Bytes(), true, &doc)
+		if err != nil {
+			return count, err
+		}
+
+		if decrypt != nil {
+			err = backupTransformSecrets(collection, doc, decrypt)
+			if err != nil {
+				return count, err
+			}
+		}
+
+		batch = append(batch, doc)
+		if len(batch) >= BackupInsertBatchSize {
+			err = flush()
+			if err != nil {
+				return count, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return count, err
+	}
+
+	return count, flush()
+}
+
+// restoreWalk calls fn on each archive collection, which must be described
+func restoreWalk(archive string, desc *BackupDescriptor, fn func(bc *BackupCollection, r io.Reader) error) error {
+	return backupWalk(archive, func(name string, r io.Reader) error {
+		if name == BackupDescriptorFile {
+			return nil
+		}
+		bc, err := desc.collection(name)
+		if err != nil {
+			return err
+		}
+		return fn(bc, r)
+	})
+}
+
+// ValidateArchive reads the whole archive, ensuring all collections are described and all secrets can be decrypted
+func (desc *BackupDescriptor) ValidateArchive(archive string, decrypt func(string) (string, error)) error {
+	return restoreWalk(archive, desc, func(bc *BackupCollection, r io.Reader) error {
+		count, err := restoreCollection(bc.Name, r, decrypt, nil)
+		if err != nil {
+			return fmt.Errorf("%s: %w", bc.Name, err)
+		}
+		if count != bc.Documents {
+			return fmt.Errorf("%s: %s (%d/%d)", ErrBackupDocumentsCount, bc.Name, count, bc.Documents)
+		}
+		return nil
+	})
+}
+
+// RestoreDatabase imports all archive collections, optionally replacing existing ones
+func RestoreDatabase(archive, passphrase string, force bool) error {
+	desc, err := ReadBackupDescriptor(archive)
+	if err != nil {
+		return err
+	}
+	klog.Infof("Restoring backup of %s database, created on %s by Kahuna %s", desc.Database, desc.CreatedAt.Format(time.RFC3339), desc.KahunaVersion)
+
+	migrate, err := desc.Validate()
+	if err != nil {
+		return err
+	}
+
+	decrypt, err := desc.Cipher(passphrase)
+	if err != nil {
+		return err
+	}
+
+	// nothing gets dropped unless the whole archive can be restored
+	klog.Infof("Validating backup archive ...")
+	err = desc.ValidateArchive(archive, decrypt)
+	if err != nil {
+		return err
+	}
+
+	// never silently merge into existing data
+	if !force {
+		for _, bc := range desc.Collections {
+			count, err := GetDB().CountAll(bc.Name)
+			if err != nil {
+				return err
+			}
+			if count != 0 {
+				return fmt.Errorf("%s (%s)", ErrBackupDatabaseNotEmpty, bc.Name)
+			}
+		}
+	}
+	for _, bc := range desc.Collections {
+		count, err := GetDB().CountAll(bc.Name)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			continue
+		}
+		klog.Warningf("Dropping existing collection %s ...", bc.Name)
+		err = GetDB().DropCollection(bc.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = restoreWalk(archive, desc, func(bc *BackupCollection, r io.Reader) error {
+		klog.Infof("Restoring collection %s ...", bc.Name)
+		count, err := restoreCollection(bc.Name, r, decrypt, func(batch []any) error {
+			return GetDB().InsertMany(bc.Name, batch)
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", bc.Name, err)
+		}
+		klog.Infof("Restored %d documents into collection %s", count, bc.Name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if migrate {
+		klog.Infof("Backup archive has outdated collections schema")
+		return MigrateDatabaseSchema(false)
+	}
+
+	return nil
+}
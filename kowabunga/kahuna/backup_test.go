@@ -0,0 +1,254 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestBackupSecretsEncryption(t *testing.T) {
+	enc := &BackupEncryption{
+		Cipher:     BackupCipher,
+		Kdf:        BackupKdf,
+		Iterations: 1000,
+		Salt:       []byte("0123456789abcdef"),
+	}
+	aead, err := backupCipher("s3cr3t", enc)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	doc := bson.D{
+		bson.E{Key: "name", Value: "acme"},
+		bson.E{Key: "schema_version", Value: int32(1)},
+		bson.E{Key: "auth", Value: bson.D{
+			bson.E{Key: "oidc", Value: bson.D{
+				bson.E{Key: "issuer", Value: "https://sso.acme.com"},
+				bson.E{Key: "client_secret", Value: "oidc-secret"},
+			}},
+		}},
+	}
+	err = backupTransformSecrets(MongoCollectionOrganizationName, doc, backupEncrypt(aead))
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	line, err := bson.MarshalExtJSON(doc, true, false)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if strings.Contains(string(line), "oidc-secret") {
+		t.Fatalf("secret was not encrypted: %s", line)
+	}
+
+	var restored bson.D
+	err = bson.UnmarshalExtJSON(line, true, &restored)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if backupSchemaVersion(restored) != 1 {
+		t.Fatalf("unexpected schema version: %d", backupSchemaVersion(restored))
+	}
+
+	// wrong passphrase
+	wrong, err := backupCipher("guess", enc)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	err = backupTransformSecrets(MongoCollectionOrganizationName, restored, backupDecrypt(wrong))
+	if err == nil {
+		t.Fatalf("secret was decrypted with an invalid passphrase")
+	}
+
+	err = backupTransformSecrets(MongoCollectionOrganizationName, restored, backupDecrypt(aead))
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	line, err = bson.MarshalExtJSON(restored, true, false)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if !strings.Contains(string(line), `"client_secret":"oidc-secret"`) {
+		t.Fatalf("secret was not decrypted: %s", line)
+	}
+}
+
+func TestBackupDescriptorValidate(t *testing.T) {
+	desc := BackupDescriptor{
+		FormatVersion: BackupFormatVersion,
+		Collections: []BackupCollection{
+			{Name: MongoCollectionProjectName, SchemaVersions: []int{1, MongoCollectionProjectSchemaVersion}},
+		},
+	}
+	migrate, err := desc.Validate()
+	if err != nil || !migrate {
+		t.Fatalf("outdated schema should require migration (%v)", err)
+	}
+
+	desc.Collections[0].SchemaVersions = []int{MongoCollectionProjectSchemaVersion + 1}
+	_, err = desc.Validate()
+	if err == nil {
+		t.Fatalf("newer schema was accepted")
+	}
+}
+
+func TestBackupSecretsArrays(t *testing.T) {
+	enc := &BackupEncryption{
+		Iterations: 1000,
+		Salt:       []byte("0123456789abcdef"),
+	}
+	aead, err := backupCipher("s3cr3t", enc)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	doc := bson.D{
+		bson.E{Key: "name", Value: "john"},
+		bson.E{Key: "notification_channels", Value: bson.A{
+			bson.D{bson.E{Key: "type", Value: NotificationChannelEmail}},
+			bson.D{
+				bson.E{Key: "type", Value: NotificationChannelChat},
+				bson.E{Key: "url", Value: "https://hooks.slack.com/services/T0/B0/X"},
+			},
+		}},
+	}
+	err = backupTransformSecrets(MongoCollectionUserName, doc, backupEncrypt(aead))
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	line, err := bson.MarshalExtJSON(doc, true, false)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if strings.Contains(string(line), "hooks.slack.com") {
+		t.Fatalf("notification channel URL was not encrypted: %s", line)
+	}
+}
+
+// writeTestBackup creates a backup archive with given collections files contents
+func writeTestBackup(t *testing.T, desc *BackupDescriptor, files map[string]string) string {
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	content, err := json.Marshal(desc)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	files[BackupDescriptorFile] = string(content)
+	for name, content := range files {
+		err = tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content))})
+		if err != nil {
+			t.Fatalf("%s", err.Error())
+		}
+		_, err = tw.Write([]byte(content))
+		if err != nil {
+			t.Fatalf("%s", err.Error())
+		}
+	}
+	if tw.Close() != nil || gw.Close() != nil {
+		t.Fatalf("unable to write archive")
+	}
+
+	return archive
+}
+
+func TestBackupValidateArchive(t *testing.T) {
+	enc := &BackupEncryption{
+		Cipher:     BackupCipher,
+		Kdf:        BackupKdf,
+		Iterations: 1000,
+		Salt:       []byte("0123456789abcdef"),
+	}
+	aead, err := backupCipher("s3cr3t", enc)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	encrypt := backupEncrypt(aead)
+	enc.Verifier, err = encrypt(BackupVerifierPlaintext)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	doc := bson.D{
+		bson.E{Key: "name", Value: "acme"},
+		bson.E{Key: "secret", Value: "hmac-secret"},
+	}
+	err = backupTransformSecrets(MongoCollectionWebhookName, doc, encrypt)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	line, err := bson.MarshalExtJSON(doc, true, false)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	desc := &BackupDescriptor{
+		FormatVersion: BackupFormatVersion,
+		Encryption:    enc,
+		Collections: []BackupCollection{
+			{Name: MongoCollectionWebhookName, Documents: 1},
+		},
+	}
+
+	// passphrase is checked upfront
+	_, err = desc.Cipher("guess")
+	if err == nil || err.Error() != ErrBackupInvalidPassphrase {
+		t.Fatalf("invalid passphrase has been accepted (%v)", err)
+	}
+	_, err = desc.Cipher("")
+	if err == nil || err.Error() != ErrBackupPassphraseRequired {
+		t.Fatalf("missing passphrase has been accepted (%v)", err)
+	}
+	decrypt, err := desc.Cipher("s3cr3t")
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	archive := writeTestBackup(t, desc, map[string]string{
+		backupCollectionFile(MongoCollectionWebhookName): string(line) + "\n",
+	})
+	err = desc.ValidateArchive(archive, decrypt)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	// undescribed collection
+	archive = writeTestBackup(t, desc, map[string]string{
+		backupCollectionFile(MongoCollectionWebhookName): string(line) + "\n",
+		backupCollectionFile(MongoCollectionUserName):    "",
+	})
+	err = desc.ValidateArchive(archive, decrypt)
+	if err == nil || !strings.Contains(err.Error(), ErrBackupUnknownCollection) {
+		t.Fatalf("undescribed collection has been accepted (%v)", err)
+	}
+
+	// truncated collection
+	archive = writeTestBackup(t, desc, map[string]string{
+		backupCollectionFile(MongoCollectionWebhookName): "",
+	})
+	err = desc.ValidateArchive(archive, decrypt)
+	if err == nil || !strings.Contains(err.Error(), ErrBackupDocumentsCount) {
+		t.Fatalf("truncated collection has been accepted (%v)", err)
+	}
+}
@@ -24,10 +24,10 @@ import (
 
 const (
 	CacheTypeInMemory = "memory"
+	CacheTypeRedis    = "redis"
 
-	CacheErrDisabled = "in-memory cache is disabled"
-
-	CacheNsUserResources = "userResources"
+	CacheErrDisabled        = "cache is disabled"
+	CacheErrUnsupportedType = "unsupported cache type"
 )
 
 type KowabungaCache struct {
@@ -50,24 +50,41 @@ func GetCache() *KowabungaCache {
 	return kCache
 }
 
-func (kc *KowabungaCache) Init(enabled bool, tp string, size, ttl int) {
+// Init sets cache up. Unreachable shared cache is an error rather than silently
+// falling back to no cache, replicas being expected to share the same one.
+func (kc *KowabungaCache) Init(enabled bool, cfg KowabungaCacheConfig) error {
 	kc.enabled = enabled
 
 	if !enabled {
-		return
+		return nil
 	}
 
-	switch tp {
+	expire := time.Duration(cfg.TTL) * time.Minute
+	switch cfg.Type {
 	case CacheTypeInMemory:
 		klog.Debugf("Initializing Kowabunga in-memory cache ...")
-		sizeMB := size * common.MiB
-		expire := time.Duration(ttl) * time.Minute
+		sizeMB := cfg.Size * common.MiB
 		fcs := freecache_store.NewFreecache(freecache.NewCache(sizeMB), store.WithExpiration(expire))
 		kc.ms = marshaler.New(cache.New[any](fcs))
+	case CacheTypeRedis:
+		klog.Debugf("Initializing Kowabunga Redis cache ...")
+		rs, err := NewRedisStore(cfg.Redis.URI, store.WithExpiration(expire))
+		if err != nil {
+			kc.enabled = false
+			return fmt.Errorf("unable to connect to Redis cache: %w", err)
+		}
+		kc.ms = marshaler.New(cache.New[any](rs))
 	default:
-		// unsupported cache type, disabling
 		kc.enabled = false
+		return fmt.Errorf("%s: %s", CacheErrUnsupportedType, cfg.Type)
 	}
+
+	return nil
+}
+
+// Disable turns cache off, e.g. for offline database maintenance
+func (kc *KowabungaCache) Disable() {
+	kc.enabled = false
 }
 
 func (kc *KowabungaCache) key(ns, key string) string {
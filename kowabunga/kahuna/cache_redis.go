@@ -0,0 +1,155 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	store "github.com/eko/gocache/lib/v4/store"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	RedisStoreType       = "redis"
+	RedisStoreKeyPrefix  = "kahuna:"
+	RedisStoreTagPattern = RedisStoreKeyPrefix + "tag:%s"
+
+	RedisStoreErrKeyType = "key type not supported by Redis store"
+)
+
+// RedisStore is a gocache store, shared amongst Kahuna replicas
+type RedisStore struct {
+	client  *redis.Client
+	options *store.Options
+}
+
+func NewRedisStore(uri string, options ...store.Option) (*RedisStore, error) {
+	opts, err := redis.ParseURL(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	rs := RedisStore{
+		client:  redis.NewClient(opts),
+		options: store.ApplyOptions(options...),
+	}
+
+	err = rs.client.Ping(context.TODO()).Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return &rs, nil
+}
+
+func (rs *RedisStore) key(key any) (string, error) {
+	k, ok := key.(string)
+	if !ok {
+		return "", errors.New(RedisStoreErrKeyType)
+	}
+	return RedisStoreKeyPrefix + k, nil
+}
+
+func (rs *RedisStore) Get(ctx context.Context, key any) (any, error) {
+	k, err := rs.key(key)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := rs.client.Get(ctx, k).Result()
+	if err == redis.Nil {
+		return nil, store.NotFoundWithCause(err)
+	}
+	return value, err
+}
+
+func (rs *RedisStore) GetWithTTL(ctx context.Context, key any) (any, time.Duration, error) {
+	k, err := rs.key(key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	value, err := rs.client.Get(ctx, k).Result()
+	if err == redis.Nil {
+		return nil, 0, store.NotFoundWithCause(err)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ttl, err := rs.client.TTL(ctx, k).Result()
+	return value, ttl, err
+}
+
+func (rs *RedisStore) Set(ctx context.Context, key any, value any, options ...store.Option) error {
+	k, err := rs.key(key)
+	if err != nil {
+		return err
+	}
+
+	opts := store.ApplyOptionsWithDefault(rs.options, options...)
+	err = rs.client.Set(ctx, k, value, opts.Expiration).Err()
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range opts.Tags {
+		tagKey := fmt.Sprintf(RedisStoreTagPattern, tag)
+		err = rs.client.SAdd(ctx, tagKey, k).Err()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (rs *RedisStore) Delete(ctx context.Context, key any) error {
+	k, err := rs.key(key)
+	if err != nil {
+		return err
+	}
+	return rs.client.Del(ctx, k).Err()
+}
+
+func (rs *RedisStore) Invalidate(ctx context.Context, options ...store.InvalidateOption) error {
+	opts := store.ApplyInvalidateOptions(options...)
+
+	for _, tag := range opts.Tags {
+		tagKey := fmt.Sprintf(RedisStoreTagPattern, tag)
+		keys, err := rs.client.SMembers(ctx, tagKey).Result()
+		if err != nil {
+			return err
+		}
+		keys = append(keys, tagKey)
+		err = rs.client.Del(ctx, keys...).Err()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Clear only removes Kahuna's keys, Redis server may be shared with other applications
+func (rs *RedisStore) Clear(ctx context.Context) error {
+	iter := rs.client.Scan(ctx, 0, RedisStoreKeyPrefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		err := rs.client.Del(ctx, iter.Val()).Err()
+		if err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
+
+func (rs *RedisStore) GetType() string {
+	return RedisStoreType
+}
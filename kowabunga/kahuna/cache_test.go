@@ -0,0 +1,38 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"testing"
+)
+
+func TestCacheInit(t *testing.T) {
+	kc := &KowabungaCache{}
+
+	err := kc.Init(true, KowabungaCacheConfig{
+		Type:  CacheTypeRedis,
+		Redis: KowabungaRedisConfig{URI: "redis://127.0.0.1:1/0"},
+	})
+	if err == nil || kc.enabled {
+		t.Fatalf("unreachable Redis cache has been silently accepted")
+	}
+
+	err = kc.Init(true, KowabungaCacheConfig{Type: "memcached"})
+	if err == nil || kc.enabled {
+		t.Fatalf("unsupported cache type has been accepted")
+	}
+
+	err = kc.Init(true, KowabungaCacheConfig{Type: CacheTypeInMemory, Size: 1, TTL: 1})
+	if err != nil || !kc.enabled {
+		t.Fatalf("unable to initialize in-memory cache: %v", err)
+	}
+
+	kc.Disable()
+	if kc.Get("test", "key", nil) == nil {
+		t.Fatalf("disabled cache has been queried")
+	}
+}
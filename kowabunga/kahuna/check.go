@@ -0,0 +1,537 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/kowabunga-cloud/common/klog"
+)
+
+const (
+	ConsistencyIssueDanglingReference = "dangling_reference"
+	ConsistencyIssueOrphan            = "orphan"
+	ConsistencyIssueUnlinked          = "unlinked"
+	ConsistencyIssueUsageMismatch     = "usage_mismatch"
+	ConsistencyIssueUnknownDomain     = "unknown_domain"
+	ConsistencyIssueMissingDomain     = "missing_domain"
+	ConsistencyIssueUnknownVolume     = "unknown_volume"
+	ConsistencyIssueMissingVolume     = "missing_volume"
+	ConsistencyIssueAgentFailure      = "agent_failure"
+
+	RpcKaktusListInstances = "ListInstances"
+	RpcKaktusListVolumes   = "ListVolumes"
+
+	ErrConsistencyIssuesRemaining = "database consistency issues remain"
+)
+
+// Kaktus agent state listing RPCs, wire-compatible with the agent's own definitions
+
+type KaktusListInstancesArgs struct{}
+type KaktusListInstancesReply struct {
+	Instances []string
+}
+
+type KaktusListVolumesArgs struct {
+	Pool string
+}
+type KaktusListVolumesReply struct {
+	Volumes []string
+}
+
+// consistencyLink describes a denormalized parent/child relationship
+type consistencyLink struct {
+	Parent    string // parent collection
+	Children  string // parent's field referencing its children, if any
+	Child     string // child collection
+	ParentRef string // child's field referencing its parent, if any
+}
+
+var consistencyLinks = []consistencyLink{
+	{MongoCollectionOrganizationName, "project_ids", MongoCollectionProjectName, "organization_id"},
+	{MongoCollectionOrganizationName, "team_ids", MongoCollectionTeamName, "organization_id"},
+	{MongoCollectionOrganizationName, "user_ids", MongoCollectionUserName, "organization_id"},
+	{MongoCollectionOrganizationName, "admin_ids", MongoCollectionUserName, ""},
+	{MongoCollectionTeamName, "user_ids", MongoCollectionUserName, ""},
+	{MongoCollectionUserName, "team_ids", MongoCollectionTeamName, ""},
+	{MongoCollectionRegionName, "zone_ids", MongoCollectionZoneName, "region_id"},
+	{MongoCollectionRegionName, "kiwi_ids", MongoCollectionKiwiName, "region_id"},
+	{MongoCollectionRegionName, "vnet_ids", MongoCollectionVNetName, "region_id"},
+	{MongoCollectionRegionName, "storage_pool_ids", MongoCollectionStoragePoolName, "region_id"},
+	{MongoCollectionRegionName, "nfs_ids", MongoCollectionNfsName, "region_id"},
+	{MongoCollectionRegionName, "record_ids", MongoCollectionDnsRecordName, "region_id"},
+	{MongoCollectionZoneName, "kaktus_ids", MongoCollectionKaktusName, "zone_id"},
+	{MongoCollectionKaktusName, "instance_ids", MongoCollectionInstanceName, "kaktus_id"},
+	{MongoCollectionKaktusName, "agent_ids", MongoCollectionAgentName, ""},
+	{MongoCollectionKiwiName, "agent_ids", MongoCollectionAgentName, ""},
+	{MongoCollectionStoragePoolName, "template_ids", MongoCollectionTemplateName, "storage_pool_id"},
+	{MongoCollectionStoragePoolName, "volume_ids", MongoCollectionVolumeName, "storage_pool_id"},
+	{MongoCollectionStoragePoolName, "agent_ids", MongoCollectionAgentName, ""},
+	{MongoCollectionNfsName, "kylo_ids", MongoCollectionKyloName, "nfs_id"},
+	{MongoCollectionVNetName, "subnet_ids", MongoCollectionSubnetName, "vnet_id"},
+	{MongoCollectionSubnetName, "adapter_ids", MongoCollectionAdapterName, "subnet_id"},
+	{MongoCollectionProjectName, "team_ids", MongoCollectionTeamName, ""},
+	{MongoCollectionProjectName, "region_ids", MongoCollectionRegionName, ""},
+	{MongoCollectionProjectName, "instance_ids", MongoCollectionInstanceName, "project_id"},
+	{MongoCollectionProjectName, "volume_ids", MongoCollectionVolumeName, "project_id"},
+	{MongoCollectionProjectName, "kompute_ids", MongoCollectionKomputeName, "project_id"},
+	{MongoCollectionProjectName, "kawaii_ids", MongoCollectionKawaiiName, "project_id"},
+	{MongoCollectionProjectName, "konvey_ids", MongoCollectionKonveyName, "project_id"},
+	{MongoCollectionProjectName, "kylo_ids", MongoCollectionKyloName, "project_id"},
+	{MongoCollectionProjectName, "record_ids", MongoCollectionDnsRecordName, "project_id"},
+	{MongoCollectionKawaiiName, "ipsec_ids", MongoCollectionIPsecName, "kawaii_id"},
+}
+
+type ConsistencyIssue struct {
+	Kind       string `json:"kind"`
+	Collection string `json:"collection"`
+	ID         string `json:"id"`
+	Name       string `json:"name,omitempty"`
+	Field      string `json:"field,omitempty"`
+	Reference  string `json:"reference,omitempty"`
+	Message    string `json:"message"`
+	Repairable bool   `json:"repairable"`
+	Repaired   bool   `json:"repaired"`
+	Error      string `json:"error,omitempty"`
+
+	repair func() error
+}
+
+type ConsistencyReport struct {
+	Repair        bool                `json:"repair"`
+	AgentsChecked bool                `json:"agents_checked"` // agent-side state is only available to running Kahuna
+	Documents     int                 `json:"documents"`
+	Repaired      int                 `json:"repaired"`
+	Issues        []*ConsistencyIssue `json:"issues"`
+}
+
+// Unrepaired returns the count of issues which remain
+func (r *ConsistencyReport) Unrepaired() int {
+	return len(r.Issues) - r.Repaired
+}
+
+type consistencyChecker struct {
+	docs   map[string]map[string]bson.M // collection's documents, by ID
+	report *ConsistencyReport
+}
+
+func newConsistencyChecker(repair, agents bool) *consistencyChecker {
+	return &consistencyChecker{
+		docs: map[string]map[string]bson.M{},
+		report: &ConsistencyReport{
+			Repair:        repair,
+			AgentsChecked: agents,
+			Issues:        []*ConsistencyIssue{},
+		},
+	}
+}
+
+func consistencyCollections() []string {
+	collections := []string{}
+	for _, l := range consistencyLinks {
+		collections = append(collections, l.Parent, l.Child)
+	}
+	slices.Sort(collections)
+	return slices.Compact(collections)
+}
+
+// load reads all linked collections at the very same point in time
+func (c *consistencyChecker) load() error {
+	return GetDB().Snapshot(func(ctx context.Context) error {
+		for _, collection := range consistencyCollections() {
+			docs := map[string]bson.M{}
+			err := GetDB().Dump(ctx, collection, func(doc bson.D) error {
+				m := consistencyDocument(doc)
+				id, _ := m["_id"].(bson.ObjectID)
+				docs[id.Hex()] = m
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			c.docs[collection] = docs
+			c.report.Documents += len(docs)
+		}
+		return nil
+	})
+}
+
+func consistencyDocument(doc bson.D) bson.M {
+	m := bson.M{}
+	for _, e := range doc {
+		m[e.Key] = e.Value
+	}
+	return m
+}
+
+func consistencyString(doc bson.M, key string) string {
+	s, _ := doc[key].(string)
+	return s
+}
+
+func consistencyStrings(doc bson.M, key string) []string {
+	res := []string{}
+	a, _ := doc[key].(bson.A)
+	for _, v := range a {
+		s, ok := v.(string)
+		if ok {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func consistencyInt(doc bson.M, key string) int64 {
+	switch v := doc[key].(type) {
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+func consistencySubDocument(doc bson.M, key string) bson.M {
+	d, _ := doc[key].(bson.D)
+	return consistencyDocument(d)
+}
+
+func consistencyPatch(collection, id string, update bson.D) func() error {
+	return func() error {
+		oid, err := bson.ObjectIDFromHex(id)
+		if err != nil {
+			return err
+		}
+		return GetDB().Patch(collection, oid, update)
+	}
+}
+
+func (c *consistencyChecker) sorted(collection string) []string {
+	return slices.Sorted(maps.Keys(c.docs[collection]))
+}
+
+func (c *consistencyChecker) name(collection, id string) string {
+	return consistencyString(c.docs[collection][id], "name")
+}
+
+func (c *consistencyChecker) add(issue *ConsistencyIssue) {
+	issue.Repairable = issue.repair != nil
+	c.report.Issues = append(c.report.Issues, issue)
+}
+
+// checkLinks looks for parents referencing non-existing children and children unknown to their parents
+func (c *consistencyChecker) checkLinks() {
+	for _, l := range consistencyLinks {
+		if l.Children != "" {
+			for _, id := range c.sorted(l.Parent) {
+				for _, ref := range consistencyStrings(c.docs[l.Parent][id], l.Children) {
+					_, ok := c.docs[l.Child][ref]
+					if ok {
+						continue
+					}
+					c.add(&ConsistencyIssue{
+						Kind:       ConsistencyIssueDanglingReference,
+						Collection: l.Parent,
+						ID:         id,
+						Name:       c.name(l.Parent, id),
+						Field:      l.Children,
+						Reference:  ref,
+						Message:    fmt.Sprintf("%s references non-existing %s %s", l.Parent, l.Child, ref),
+						repair:     consistencyPatch(l.Parent, id, bson.D{bson.E{Key: "$pull", Value: bson.D{bson.E{Key: l.Children, Value: ref}}}}),
+					})
+				}
+			}
+		}
+
+		if l.ParentRef == "" {
+			continue
+		}
+
+		for _, id := range c.sorted(l.Child) {
+			parentId := consistencyString(c.docs[l.Child][id], l.ParentRef)
+			if parentId == "" {
+				continue
+			}
+
+			parent, ok := c.docs[l.Parent][parentId]
+			if !ok {
+				// child resources may still exist agent-side, let administrators handle it
+				c.add(&ConsistencyIssue{
+					Kind:       ConsistencyIssueOrphan,
+					Collection: l.Child,
+					ID:         id,
+					Name:       c.name(l.Child, id),
+					Field:      l.ParentRef,
+					Reference:  parentId,
+					Message:    fmt.Sprintf("%s belongs to non-existing %s %s", l.Child, l.Parent, parentId),
+				})
+				continue
+			}
+
+			if l.Children == "" || slices.Contains(consistencyStrings(parent, l.Children), id) {
+				continue
+			}
+			c.add(&ConsistencyIssue{
+				Kind:       ConsistencyIssueUnlinked,
+				Collection: l.Parent,
+				ID:         parentId,
+				Name:       c.name(l.Parent, parentId),
+				Field:      l.Children,
+				Reference:  id,
+				Message:    fmt.Sprintf("%s does not reference its %s %s", l.Parent, l.Child, id),
+				repair:     consistencyPatch(l.Parent, parentId, bson.D{bson.E{Key: "$addToSet", Value: bson.D{bson.E{Key: l.Children, Value: id}}}}),
+			})
+		}
+	}
+}
+
+// checkUsage compares projects and Kaktus usage counters with their instances and volumes actual sums
+func (c *consistencyChecker) checkUsage() {
+	projects := map[string]*ProjectResources{}
+	kaktuses := map[string]*KaktusResources{}
+	for _, id := range c.sorted(MongoCollectionProjectName) {
+		projects[id] = &ProjectResources{}
+	}
+	for _, id := range c.sorted(MongoCollectionKaktusName) {
+		kaktuses[id] = &KaktusResources{}
+	}
+
+	for _, id := range c.sorted(MongoCollectionInstanceName) {
+		i := c.docs[MongoCollectionInstanceName][id]
+		cpu := uint16(consistencyInt(i, "vcpus"))
+		mem := uint64(consistencyInt(i, "memory"))
+
+		p, ok := projects[consistencyString(i, "project_id")]
+		if ok {
+			p.InstancesCount += 1
+			p.VCPUs += cpu
+			p.MemorySize += mem
+		}
+
+		k, ok := kaktuses[consistencyString(i, "kaktus_id")]
+		if ok {
+			k.InstancesCount += 1
+			k.VCPUs += cpu
+			k.MemorySize += mem
+		}
+	}
+
+	for _, id := range c.sorted(MongoCollectionVolumeName) {
+		v := c.docs[MongoCollectionVolumeName][id]
+		if consistencyString(v, "type") == VolumeTypeTemplate {
+			continue
+		}
+		p, ok := projects[consistencyString(v, "project_id")]
+		if ok {
+			p.StorageSize += uint64(consistencyInt(v, "size"))
+		}
+	}
+
+	for _, id := range c.sorted(MongoCollectionProjectName) {
+		u := projects[id]
+		c.checkUsageCounters(MongoCollectionProjectName, id, bson.D{
+			bson.E{Key: "vcpus", Value: int64(u.VCPUs)},
+			bson.E{Key: "memory", Value: int64(u.MemorySize)},
+			bson.E{Key: "storage", Value: int64(u.StorageSize)},
+			bson.E{Key: "instances", Value: int64(u.InstancesCount)},
+		})
+	}
+
+	for _, id := range c.sorted(MongoCollectionKaktusName) {
+		u := kaktuses[id]
+		c.checkUsageCounters(MongoCollectionKaktusName, id, bson.D{
+			bson.E{Key: "vcpus", Value: int64(u.VCPUs)},
+			bson.E{Key: "memory", Value: int64(u.MemorySize)},
+			bson.E{Key: "instances", Value: int64(u.InstancesCount)},
+		})
+	}
+}
+
+func (c *consistencyChecker) checkUsageCounters(collection, id string, expected bson.D) {
+	usage := consistencySubDocument(c.docs[collection][id], "usage")
+
+	mismatches := []string{}
+	set := bson.D{}
+	for _, e := range expected {
+		value := consistencyInt(usage, e.Key)
+		if value == e.Value.(int64) {
+			continue
+		}
+		mismatches = append(mismatches, fmt.Sprintf("%s is %d instead of %d", e.Key, value, e.Value))
+		set = append(set, bson.E{Key: "usage." + e.Key, Value: e.Value})
+	}
+
+	if len(mismatches) == 0 {
+		return
+	}
+	c.add(&ConsistencyIssue{
+		Kind:       ConsistencyIssueUsageMismatch,
+		Collection: collection,
+		ID:         id,
+		Name:       c.name(collection, id),
+		Field:      "usage",
+		Message:    fmt.Sprintf("%s usage %s", collection, strings.Join(mismatches, ", ")),
+		repair:     consistencyPatch(collection, id, bson.D{bson.E{Key: "$set", Value: set}}),
+	})
+}
+
+func (c *consistencyChecker) agentFailure(collection, id string, err error) {
+	c.add(&ConsistencyIssue{
+		Kind:       ConsistencyIssueAgentFailure,
+		Collection: collection,
+		ID:         id,
+		Name:       c.name(collection, id),
+		Message:    fmt.Sprintf("unable to retrieve %s agent-side state: %v", collection, err),
+	})
+}
+
+// childrenByName returns child collection documents IDs, indexed by name, which belong to parent
+func (c *consistencyChecker) childrenByName(collection, parentRef, parentId string) map[string]string {
+	children := map[string]string{}
+	for _, id := range c.sorted(collection) {
+		doc := c.docs[collection][id]
+		if consistencyString(doc, parentRef) == parentId {
+			children[consistencyString(doc, "name")] = id
+		}
+	}
+	return children
+}
+
+// checkAgents compares libvirt domains and RBD images with known instances and volumes
+func (c *consistencyChecker) checkAgents() {
+	for _, id := range c.sorted(MongoCollectionKaktusName) {
+		k := c.docs[MongoCollectionKaktusName][id]
+
+		var reply KaktusListInstancesReply
+		err := RPC(consistencyStrings(k, "agent_ids"), RpcKaktusListInstances, KaktusListInstancesArgs{}, &reply)
+		if err != nil {
+			c.agentFailure(MongoCollectionKaktusName, id, err)
+			continue
+		}
+
+		instances := c.childrenByName(MongoCollectionInstanceName, "kaktus_id", id)
+		for _, domain := range reply.Instances {
+			_, ok := instances[domain]
+			if ok {
+				continue
+			}
+			c.add(&ConsistencyIssue{
+				Kind:       ConsistencyIssueUnknownDomain,
+				Collection: MongoCollectionKaktusName,
+				ID:         id,
+				Name:       c.name(MongoCollectionKaktusName, id),
+				Reference:  domain,
+				Message:    fmt.Sprintf("libvirt domain %s is unknown to Kahuna", domain),
+			})
+		}
+		for _, name := range slices.Sorted(maps.Keys(instances)) {
+			if slices.Contains(reply.Instances, name) {
+				continue
+			}
+			c.add(&ConsistencyIssue{
+				Kind:       ConsistencyIssueMissingDomain,
+				Collection: MongoCollectionInstanceName,
+				ID:         instances[name],
+				Name:       name,
+				Reference:  id,
+				Message:    fmt.Sprintf("instance has no libvirt domain on Kaktus %s", c.name(MongoCollectionKaktusName, id)),
+			})
+		}
+	}
+
+	for _, id := range c.sorted(MongoCollectionStoragePoolName) {
+		p := c.docs[MongoCollectionStoragePoolName][id]
+
+		var reply KaktusListVolumesReply
+		args := KaktusListVolumesArgs{
+			Pool: consistencyString(p, "libvirt_pool"),
+		}
+		err := RPC(consistencyStrings(p, "agent_ids"), RpcKaktusListVolumes, args, &reply)
+		if err != nil {
+			c.agentFailure(MongoCollectionStoragePoolName, id, err)
+			continue
+		}
+
+		volumes := c.childrenByName(MongoCollectionVolumeName, "storage_pool_id", id)
+		for _, image := range reply.Volumes {
+			_, ok := volumes[image]
+			if ok {
+				continue
+			}
+			c.add(&ConsistencyIssue{
+				Kind:       ConsistencyIssueUnknownVolume,
+				Collection: MongoCollectionStoragePoolName,
+				ID:         id,
+				Name:       c.name(MongoCollectionStoragePoolName, id),
+				Reference:  image,
+				Message:    fmt.Sprintf("RBD image %s is unknown to Kahuna", image),
+			})
+		}
+		for _, name := range slices.Sorted(maps.Keys(volumes)) {
+			if slices.Contains(reply.Volumes, name) {
+				continue
+			}
+			c.add(&ConsistencyIssue{
+				Kind:       ConsistencyIssueMissingVolume,
+				Collection: MongoCollectionVolumeName,
+				ID:         volumes[name],
+				Name:       name,
+				Reference:  id,
+				Message:    fmt.Sprintf("volume has no RBD image in storage pool %s", c.name(MongoCollectionStoragePoolName, id)),
+			})
+		}
+	}
+}
+
+// repair fixes all repairable issues, in order
+func (c *consistencyChecker) repair() {
+	for _, issue := range c.report.Issues {
+		if issue.repair == nil {
+			continue
+		}
+		err := issue.repair()
+		if err != nil {
+			klog.Errorf("Unable to repair %s %s: %v", issue.Collection, issue.ID, err)
+			issue.Error = err.Error()
+			continue
+		}
+		issue.Repaired = true
+		c.report.Repaired += 1
+	}
+}
+
+// CheckConsistency looks for dangling references, orphaned documents, erroneous usage counters
+// and, if requested so, agent-side resources unknown to Kahuna. Repairable issues are fixed if
+// requested so, others are only reported.
+func CheckConsistency(repair, agents bool) (*ConsistencyReport, error) {
+	c := newConsistencyChecker(repair, agents)
+
+	err := c.load()
+	if err != nil {
+		return nil, err
+	}
+
+	c.checkLinks()
+	c.checkUsage()
+	if agents {
+		c.checkAgents()
+	}
+
+	if repair {
+		c.repair()
+	}
+
+	return c.report, nil
+}
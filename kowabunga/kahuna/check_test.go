@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	testCheckProjectId  = "64b7f0a1e4b0c1a2b3c4d5e6"
+	testCheckInstanceId = "64b7f0a1e4b0c1a2b3c4d5e7"
+	testCheckVolumeId   = "64b7f0a1e4b0c1a2b3c4d5e8"
+	testCheckMissingId  = "64b7f0a1e4b0c1a2b3c4d5e9"
+	testCheckOrphanId   = "64b7f0a1e4b0c1a2b3c4d5ea"
+)
+
+func testConsistencyChecker() *consistencyChecker {
+	c := newConsistencyChecker(false, false)
+	c.docs[MongoCollectionProjectName] = map[string]bson.M{
+		testCheckProjectId: {
+			"name":         "acme",
+			"instance_ids": bson.A{testCheckInstanceId, testCheckMissingId},
+			"volume_ids":   bson.A{},
+			"usage": bson.D{
+				bson.E{Key: "vcpus", Value: int32(2)},
+				bson.E{Key: "memory", Value: int64(1024)},
+				bson.E{Key: "storage", Value: int64(0)},
+				bson.E{Key: "instances", Value: int32(1)},
+			},
+		},
+	}
+	c.docs[MongoCollectionInstanceName] = map[string]bson.M{
+		testCheckInstanceId: {
+			"name":       "web",
+			"project_id": testCheckProjectId,
+			"vcpus":      int64(2),
+			"memory":     int64(1024),
+		},
+		testCheckOrphanId: {
+			"name":       "ghost",
+			"project_id": testCheckMissingId,
+		},
+	}
+	c.docs[MongoCollectionVolumeName] = map[string]bson.M{
+		testCheckVolumeId: {
+			"name":       "data",
+			"project_id": testCheckProjectId,
+			"type":       VolumeTypeRaw,
+			"size":       int64(4096),
+		},
+	}
+	return c
+}
+
+func testConsistencyIssue(c *consistencyChecker, kind string) *ConsistencyIssue {
+	for _, issue := range c.report.Issues {
+		if issue.Kind == kind {
+			return issue
+		}
+	}
+	return nil
+}
+
+func TestConsistencyCheckLinks(t *testing.T) {
+	c := testConsistencyChecker()
+	c.checkLinks()
+
+	dangling := testConsistencyIssue(c, ConsistencyIssueDanglingReference)
+	if dangling == nil || dangling.ID != testCheckProjectId || dangling.Field != "instance_ids" || dangling.Reference != testCheckMissingId || !dangling.Repairable {
+		t.Fatalf("dangling instance reference was not reported: %+v", dangling)
+	}
+
+	orphan := testConsistencyIssue(c, ConsistencyIssueOrphan)
+	if orphan == nil || orphan.ID != testCheckOrphanId || orphan.Repairable {
+		t.Fatalf("orphaned instance was not reported: %+v", orphan)
+	}
+
+	unlinked := testConsistencyIssue(c, ConsistencyIssueUnlinked)
+	if unlinked == nil || unlinked.Field != "volume_ids" || unlinked.Reference != testCheckVolumeId || !unlinked.Repairable {
+		t.Fatalf("unlinked volume was not reported: %+v", unlinked)
+	}
+
+	if len(c.report.Issues) != 3 {
+		t.Fatalf("unexpected issues: %d", len(c.report.Issues))
+	}
+}
+
+func TestConsistencyCheckUsage(t *testing.T) {
+	c := testConsistencyChecker()
+	c.checkUsage()
+
+	if len(c.report.Issues) != 1 {
+		t.Fatalf("unexpected issues: %d", len(c.report.Issues))
+	}
+
+	issue := c.report.Issues[0]
+	if issue.Kind != ConsistencyIssueUsageMismatch || issue.Message != "project usage storage is 0 instead of 4096" {
+		t.Fatalf("unexpected usage issue: %+v", issue)
+	}
+}
+
+func TestConsistencyUnrepaired(t *testing.T) {
+	c := testConsistencyChecker()
+	c.checkLinks()
+	if len(c.report.Issues) == 0 || c.report.Unrepaired() != len(c.report.Issues) {
+		t.Fatalf("unexpected unrepaired issues count %d", c.report.Unrepaired())
+	}
+	if c.report.AgentsChecked {
+		t.Fatalf("agents should not have been checked")
+	}
+
+	c.report.Issues[0].Repaired = true
+	c.report.Repaired += 1
+	if c.report.Unrepaired() != len(c.report.Issues)-1 {
+		t.Fatalf("unexpected unrepaired issues count %d", c.report.Unrepaired())
+	}
+}
@@ -0,0 +1,440 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * Agents keep a WebSocket connection to a single Kahuna replica. Their presence
+ * is recorded in database, so that any replica can forward RPC requests to the
+ * one an agent is connected to, through server-to-server API calls. These are
+ * only sent over HTTPS and signed with a secret shared amongst replicas.
+ */
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/kowabunga-cloud/common/klog"
+)
+
+const (
+	MongoCollectionAgentPresenceSchemaVersion = 1
+	MongoCollectionAgentPresenceName          = "agent_presence"
+
+	ClusterHeartbeatIntervalSeconds  = 30
+	ClusterPresenceExpirationSeconds = 3 * ClusterHeartbeatIntervalSeconds
+
+	ClusterRpcRoute        = "/cluster/rpc"
+	ClusterDisconnectRoute = "/cluster/disconnect"
+
+	// forwarded RPCs give up before originating API request times out, for caller to be answered
+	ClusterForwardTimeoutSeconds  = HttpWriteTimeoutSeconds - 5*60
+	ClusterSignatureMaxAgeSeconds = 60
+
+	HttpHeaderClusterTimestamp = "X-Kowabunga-Replica-Timestamp"
+	HttpHeaderClusterSignature = "X-Kowabunga-Replica-Signature"
+
+	ErrClusterNoEligibleAgent = "RPC: can't find any eligible agent to perform such request"
+	ErrClusterForwardFailed   = "unable to forward request to Kahuna replica"
+	ErrClusterMisconfigured   = "cluster advertise URL must be an HTTPS one and secret must be set, requests won't be forwarded to this replica"
+)
+
+type AgentPresence struct {
+	ID            string    `bson:"_id"` // agent ID
+	SchemaVersion int       `bson:"schema_version"`
+	Type          string    `bson:"type"`
+	Version       string    `bson:"version"`
+	Methods       []string  `bson:"methods"`
+	ReplicaID     string    `bson:"replica_id"`
+	ReplicaURL    string    `bson:"replica_url"`
+	ConnectedAt   time.Time `bson:"connected_at"`
+	HeartbeatAt   time.Time `bson:"heartbeat_at"`
+}
+
+type ClusterRpcRequest struct {
+	AgentID string          `json:"agent"`
+	Method  string          `json:"method"`
+	Args    json.RawMessage `json:"args"`
+}
+
+type ClusterRpcResponse struct {
+	Reply json.RawMessage `json:"reply,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+type ClusterDisconnectRequest struct {
+	AgentID string `json:"agent"`
+}
+
+// replica identifier singleton
+var replicaIdOnce sync.Once
+var replicaId string
+
+// ReplicaID uniquely identifies this Kahuna process amongst replicas
+func ReplicaID() string {
+	replicaIdOnce.Do(func() {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "kahuna"
+		}
+		b := make([]byte, 4)
+		_, _ = rand.Read(b)
+		replicaId = fmt.Sprintf("%s-%s", hostname, hex.EncodeToString(b))
+	})
+	return replicaId
+}
+
+// isReplicaURL tells whether URL can be used for server-to-server calls
+func isReplicaURL(uri string) bool {
+	u, err := url.Parse(uri)
+	return err == nil && u.Scheme == "https" && u.Host != ""
+}
+
+// replicaURL returns the URL other replicas reach this one at, if it can be forwarded requests
+func replicaURL() string {
+	cfg := GetCfg().Global.Cluster
+	if cfg.Secret == "" || !isReplicaURL(cfg.AdvertiseURL) {
+		return ""
+	}
+	return strings.TrimSuffix(cfg.AdvertiseURL, "/")
+}
+
+func NewAgentPresence(ag *KowabungaAgent) *AgentPresence {
+	return &AgentPresence{
+		ID:            ag.ID,
+		SchemaVersion: MongoCollectionAgentPresenceSchemaVersion,
+		Type:          ag.Type,
+		Version:       ag.Version,
+		Methods:       ag.Methods,
+		ReplicaID:     ReplicaID(),
+		ReplicaURL:    replicaURL(),
+		ConnectedAt:   ag.ConnectedAt,
+		HeartbeatAt:   time.Now(),
+	}
+}
+
+func (p *AgentPresence) Save() {
+	err := GetDB().UpsertByKey(MongoCollectionAgentPresenceName, "_id", p.ID, p)
+	if err != nil {
+		klog.Error(err)
+	}
+}
+
+// RemoveAgentPresence forgets about agent, unless it has already reconnected to another replica
+func RemoveAgentPresence(agentId string) {
+	filter := bson.D{
+		bson.E{Key: "_id", Value: agentId},
+		bson.E{Key: "replica_id", Value: ReplicaID()},
+	}
+	err := GetDB().DeleteAllByFilter(MongoCollectionAgentPresenceName, filter)
+	if err != nil {
+		klog.Error(err)
+	}
+}
+
+// RemoveReplicaAgentPresences forgets about all agents connected to this replica
+func RemoveReplicaAgentPresences() {
+	filter := bson.D{bson.E{Key: "replica_id", Value: ReplicaID()}}
+	err := GetDB().DeleteAllByFilter(MongoCollectionAgentPresenceName, filter)
+	if err != nil {
+		klog.Error(err)
+	}
+}
+
+// FindRemoteAgentPresences returns live candidate agents, capable of method, connected to other replicas
+func FindRemoteAgentPresences(candidateAgents []string, method string) ([]AgentPresence, error) {
+	presences := []AgentPresence{}
+	filter := bson.D{
+		bson.E{Key: "_id", Value: bson.D{bson.E{Key: "$in", Value: candidateAgents}}},
+		bson.E{Key: "methods", Value: method},
+		bson.E{Key: "replica_id", Value: bson.D{bson.E{Key: "$ne", Value: ReplicaID()}}},
+		bson.E{Key: "replica_url", Value: bson.D{bson.E{Key: "$ne", Value: ""}}},
+		bson.E{Key: "heartbeat_at", Value: bson.D{bson.E{Key: "$gt", Value: time.Now().Add(-ClusterPresenceExpirationSeconds * time.Second)}}},
+	}
+	err := GetDB().FindAllByFilter(MongoCollectionAgentPresenceName, filter, nil, 0, &presences)
+	return presences, err
+}
+
+// ClusterHeartbeat periodically refreshes presence of agents connected to this replica
+// and removes the ones from replicas which vanished, until context is cancelled.
+func ClusterHeartbeat(ctx context.Context) {
+	if GetCfg().Global.Cluster.AdvertiseURL != "" && replicaURL() == "" {
+		klog.Warningf("%s", ErrClusterMisconfigured)
+	}
+
+	ticker := time.NewTicker(ClusterHeartbeatIntervalSeconds * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			agents := GetAgents()
+			agentsLock.Lock()
+			presences := []*AgentPresence{}
+			for _, ag := range agents {
+				presences = append(presences, NewAgentPresence(ag))
+			}
+			agentsLock.Unlock()
+
+			for _, p := range presences {
+				p.Save()
+			}
+
+			filter := bson.D{bson.E{Key: "heartbeat_at", Value: bson.D{bson.E{Key: "$lt", Value: time.Now().Add(-ClusterPresenceExpirationSeconds * time.Second)}}}}
+			err := GetDB().DeleteAllByFilter(MongoCollectionAgentPresenceName, filter)
+			if err != nil {
+				klog.Error(err)
+			}
+		}
+	}
+}
+
+// clusterSignature authenticates request path and body, at a given time
+func clusterSignature(secret, timestamp, path string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(timestamp + "\n" + path + "\n"))
+	_, _ = mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// clusterClient sends server-to-server calls
+var clusterClient = &http.Client{
+	Timeout: ClusterForwardTimeoutSeconds * time.Second,
+}
+
+// ClusterForwardError reports a request the remote replica did not process
+type ClusterForwardError struct {
+	URL    string
+	Status string
+}
+
+func (e *ClusterForwardError) Error() string {
+	return fmt.Sprintf("%s: %s (%s)", ErrClusterForwardFailed, e.URL, e.Status)
+}
+
+// isClusterRetryable tells whether forwarding can safely be tried through another replica, request having not been processed
+func isClusterRetryable(err error) bool {
+	var fwdErr *ClusterForwardError
+	if errors.As(err, &fwdErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func clusterPost(uri string, payload, result any) error {
+	if !isReplicaURL(uri) {
+		return &ClusterForwardError{URL: uri, Status: "not an HTTPS URL"}
+	}
+	secret := GetCfg().Global.Cluster.Secret
+	if secret == "" {
+		return errors.New(ErrClusterMisconfigured)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, uri, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HttpHeaderClusterTimestamp, timestamp)
+	req.Header.Set(HttpHeaderClusterSignature, clusterSignature(secret, timestamp, req.URL.Path, body))
+
+	resp, err := clusterClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return &ClusterForwardError{URL: uri, Status: resp.Status}
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// forwardRPC performs RPC through the replica one of the candidate agents is connected to
+func forwardRPC(candidateAgents []string, method string, args, reply any) error {
+	presences, err := FindRemoteAgentPresences(candidateAgents, method)
+	if err != nil || len(presences) == 0 {
+		return errors.New(ErrClusterNoEligibleAgent)
+	}
+
+	return forwardRPCTo(presences, method, args, reply)
+}
+
+// forwardRPCTo addresses agents in random order, trying the next one as long as request has not been processed
+func forwardRPCTo(presences []AgentPresence, method string, args, reply any) error {
+	rpcArgs, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	presences = slices.Clone(presences)
+	err = errors.New(ErrClusterNoEligibleAgent)
+	for len(presences) > 0 {
+		n, randErr := rand.Int(rand.Reader, big.NewInt(int64(len(presences))))
+		if randErr != nil {
+			return randErr
+		}
+		p := presences[n.Int64()]
+		presences = append(presences[:n.Int64()], presences[n.Int64()+1:]...)
+
+		klog.Debugf("Forwarding %s RPC to agent %s through Kahuna replica %s", method, p.ID, p.ReplicaID)
+		var resp ClusterRpcResponse
+		err = clusterPost(p.ReplicaURL+ClusterRpcRoute, ClusterRpcRequest{
+			AgentID: p.ID,
+			Method:  method,
+			Args:    rpcArgs,
+		}, &resp)
+		if err == nil && resp.Error == ErrClusterNoEligibleAgent {
+			// agent has left replica in-between
+			err = &ClusterForwardError{URL: p.ReplicaURL, Status: resp.Error}
+		}
+		if isClusterRetryable(err) {
+			klog.Warningf("Unable to forward %s RPC through Kahuna replica %s, trying next one: %v", method, p.ReplicaID, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if resp.Error != "" {
+			return errors.New(resp.Error)
+		}
+
+		return json.Unmarshal(resp.Reply, reply)
+	}
+
+	return err
+}
+
+// forwardDisconnect requests the replica agent is connected to to close its connection
+func forwardDisconnect(agentId string) {
+	var p AgentPresence
+	err := GetDB().Find(MongoCollectionAgentPresenceName, "_id", agentId, &p)
+	if err != nil || p.ReplicaID == ReplicaID() || p.ReplicaURL == "" {
+		return
+	}
+
+	err = clusterPost(p.ReplicaURL+ClusterDisconnectRoute, ClusterDisconnectRequest{AgentID: agentId}, nil)
+	if err != nil {
+		klog.Error(err)
+	}
+}
+
+// reqFromReplica returns the body of a request issued by another Kahuna replica, failing if it isn't one
+func reqFromReplica(r *http.Request) ([]byte, error) {
+	secret := GetCfg().Global.Cluster.Secret
+	if secret == "" || r.Method != http.MethodPost {
+		return nil, errors.New(ErrClusterMisconfigured)
+	}
+
+	timestamp, err := strconv.ParseInt(r.Header.Get(HttpHeaderClusterTimestamp), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	age := time.Since(time.Unix(timestamp, 0))
+	if age > ClusterSignatureMaxAgeSeconds*time.Second || age < -ClusterSignatureMaxAgeSeconds*time.Second {
+		return nil, fmt.Errorf("outdated replica request signature")
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	signature := clusterSignature(secret, r.Header.Get(HttpHeaderClusterTimestamp), r.URL.Path, body)
+	if !hmac.Equal([]byte(r.Header.Get(HttpHeaderClusterSignature)), []byte(signature)) {
+		return nil, fmt.Errorf("invalid replica request signature")
+	}
+
+	return body, nil
+}
+
+func clusterRpcHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := reqFromReplica(r)
+	if err != nil {
+		HttpMiddlewareError(w, r, HttpForbidden, err)
+		return
+	}
+
+	var req ClusterRpcRequest
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		HttpMiddlewareError(w, r, HttpBadParams, err)
+		return
+	}
+
+	// only address locally connected agents, never forward again
+	resp := ClusterRpcResponse{}
+	ag := GetEligibleAgent([]string{req.AgentID}, req.Method)
+	if ag == nil {
+		resp.Error = ErrClusterNoEligibleAgent
+	} else {
+		var reply json.RawMessage
+		err = ag.Call(req.Method, req.Args, &reply)
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		resp.Reply = reply
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		klog.Error(err)
+	}
+}
+
+func clusterDisconnectHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := reqFromReplica(r)
+	if err != nil {
+		HttpMiddlewareError(w, r, HttpForbidden, err)
+		return
+	}
+
+	var req ClusterDisconnectRequest
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		HttpMiddlewareError(w, r, HttpBadParams, err)
+		return
+	}
+
+	disconnectLocalAgent(req.AgentID)
+}
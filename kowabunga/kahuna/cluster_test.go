@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func setClusterTestCfg(t *testing.T, advertiseURL string) {
+	SetCfg(&KowabungaConfig{
+		Global: KowabungaGlobalConfig{
+			Cluster: KowabungaClusterConfig{
+				AdvertiseURL: advertiseURL,
+				Secret:       "s3cr3t",
+			},
+		},
+	})
+	t.Cleanup(func() {
+		SetCfg(nil)
+	})
+}
+
+func TestClusterReplicaURL(t *testing.T) {
+	setClusterTestCfg(t, "http://kahuna-1.acme.com")
+	if replicaURL() != "" {
+		t.Fatalf("plain HTTP advertise URL has been accepted")
+	}
+
+	setClusterTestCfg(t, "https://kahuna-1.acme.com/")
+	if replicaURL() != "https://kahuna-1.acme.com" {
+		t.Fatalf("unexpected replica URL %s", replicaURL())
+	}
+
+	GetCfg().Global.Cluster.Secret = ""
+	if replicaURL() != "" {
+		t.Fatalf("replica URL has been advertised without any secret")
+	}
+}
+
+func TestClusterRequestSignature(t *testing.T) {
+	setClusterTestCfg(t, "https://kahuna-1.acme.com")
+
+	body := []byte(`{"agent":"abc"}`)
+	newRequest := func(secret string, ts time.Time) *http.Request {
+		timestamp := strconv.FormatInt(ts.Unix(), 10)
+		r := httptest.NewRequest(http.MethodPost, ClusterDisconnectRoute, bytes.NewReader(body))
+		r.Header.Set(HttpHeaderClusterTimestamp, timestamp)
+		r.Header.Set(HttpHeaderClusterSignature, clusterSignature(secret, timestamp, ClusterDisconnectRoute, body))
+		return r
+	}
+
+	b, err := reqFromReplica(newRequest("s3cr3t", time.Now()))
+	if err != nil || !bytes.Equal(b, body) {
+		t.Fatalf("legit replica request has been rejected (%v)", err)
+	}
+
+	_, err = reqFromReplica(newRequest("guess", time.Now()))
+	if err == nil {
+		t.Fatalf("request signed with an invalid secret has been accepted")
+	}
+
+	_, err = reqFromReplica(newRequest("s3cr3t", time.Now().Add(-time.Hour)))
+	if err == nil {
+		t.Fatalf("replayed request has been accepted")
+	}
+
+	// master API key is no longer accepted
+	r := httptest.NewRequest(http.MethodPost, ClusterDisconnectRoute, bytes.NewReader(body))
+	r.Header.Set(HttpHeaderAuthApiKey, "s3cr3t")
+	_, err = reqFromReplica(r)
+	if err == nil {
+		t.Fatalf("unsigned request has been accepted")
+	}
+}
+
+// testReplica mocks a Kahuna replica, answering forwarded RPCs
+func testReplica(t *testing.T, status int, resp ClusterRpcResponse, calls *atomic.Int32) *httptest.Server {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_, err := reqFromReplica(r)
+		if err != nil {
+			t.Errorf("forwarded request is not signed: %v", err)
+		}
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClusterForwardRPC(t *testing.T) {
+	setClusterTestCfg(t, "https://kahuna-1.acme.com")
+
+	var calls atomic.Int32
+	down := testReplica(t, http.StatusServiceUnavailable, ClusterRpcResponse{}, &calls)
+	left := testReplica(t, http.StatusOK, ClusterRpcResponse{Error: ErrClusterNoEligibleAgent}, &calls)
+	up := testReplica(t, http.StatusOK, ClusterRpcResponse{Reply: json.RawMessage(`"pong"`)}, &calls)
+	failing := testReplica(t, http.StatusOK, ClusterRpcResponse{Error: "libvirt failure"}, &calls)
+
+	client := clusterClient
+	t.Cleanup(func() {
+		clusterClient = client
+	})
+	clusterClient = up.Client()
+
+	// replicas which could not process request are skipped
+	var reply string
+	err := forwardRPCTo([]AgentPresence{
+		{ID: "agent-1", ReplicaURL: down.URL},
+		{ID: "agent-2", ReplicaURL: left.URL},
+		{ID: "agent-3", ReplicaURL: "http://127.0.0.1:1"},
+		{ID: "agent-4", ReplicaURL: up.URL},
+	}, "Ping", nil, &reply)
+	if err != nil || reply != "pong" {
+		t.Fatalf("RPC has not been forwarded (%v)", err)
+	}
+
+	// agents failures are not retried, RPCs not being idempotent
+	calls.Store(0)
+	err = forwardRPCTo([]AgentPresence{
+		{ID: "agent-1", ReplicaURL: failing.URL},
+		{ID: "agent-2", ReplicaURL: failing.URL},
+	}, "Ping", nil, &reply)
+	if err == nil || err.Error() != "libvirt failure" || calls.Load() != 1 {
+		t.Fatalf("failed RPC has been retried (%d calls, %v)", calls.Load(), err)
+	}
+
+	// none available
+	err = forwardRPCTo([]AgentPresence{
+		{ID: "agent-1", ReplicaURL: down.URL},
+	}, "Ping", nil, &reply)
+	if !isClusterRetryable(err) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -19,25 +19,91 @@ var codename = "was not built correctly" // set via the Makefile
 const (
 	KahunaCfgFileDefault = "/etc/kowabunga/kahuna.yml"
 
-	flagDescConfig  = "YAML config file to be used"
-	flagDescDebug   = "Enable verbose/debug output"
-	flagDescMigrate = "Perform any required database schema migration and gracefully exit afterwards"
-	flagDescVersion = "Display version"
+	flagDescConfig   = "YAML config file to be used"
+	flagDescDebug    = "Enable verbose/debug output"
+	flagDescMigrate  = "Perform any required database schema migration and gracefully exit afterwards"
+	flagDescDryRun   = "Only report pending database migrations and affected documents, without applying them"
+	flagDescRollback = "Roll back applied database migrations, down to (and including) the named one, and gracefully exit afterwards"
+	flagDescVersion  = "Display version"
+
+	cmdDescServe   = "Serve Kowabunga API (default)"
+	cmdDescBackup  = "Backup database into a compressed archive and exit"
+	cmdDescRestore = "Restore database from a backup archive and exit"
+	cmdDescCheck   = "Check database consistency with agents state, report issues and exit"
+
+	flagDescArchive    = "Backup archive file"
+	flagDescPassphrase = "File containing the passphrase secrets are (to be) encrypted with"
+	flagDescForce      = "Overwrite existing database collections"
+	flagDescRepair     = "Repair inconsistencies instead of only reporting them (dry-run)"
+
+	KahunaCommandServe   = "serve"
+	KahunaCommandBackup  = "backup"
+	KahunaCommandRestore = "restore"
+	KahunaCommandCheck   = "check"
 )
 
-func ParseCommands() (*os.File, bool, bool) {
+type KahunaCommand struct {
+	Name           string
+	ConfigFile     *os.File
+	Debug          bool
+	Migrate        bool
+	DryRun         bool
+	Rollback       string
+	Archive        string
+	PassphraseFile string
+	Force          bool
+	Repair         bool
+}
+
+func ParseCommands() KahunaCommand {
 
 	configFile := kingpin.Flag("config", flagDescConfig).Short('c').Default(KahunaCfgFileDefault).File()
 	debug := kingpin.Flag("debug", flagDescDebug).Short('d').Bool()
 	migrate := kingpin.Flag("migrate", flagDescMigrate).Short('m').Bool()
+	dryRun := kingpin.Flag("dry-run", flagDescDryRun).Bool()
+	rollback := kingpin.Flag("rollback", flagDescRollback).String()
 	vers := kingpin.Flag("version", flagDescVersion).Short('v').Bool()
 
-	kingpin.Parse()
+	kingpin.Command(KahunaCommandServe, cmdDescServe).Default()
+
+	backup := kingpin.Command(KahunaCommandBackup, cmdDescBackup)
+	backupArchive := backup.Flag("output", flagDescArchive).Short('o').Required().String()
+	backupPassphrase := backup.Flag("passphrase-file", flagDescPassphrase).ExistingFile()
+
+	restore := kingpin.Command(KahunaCommandRestore, cmdDescRestore)
+	restoreArchive := restore.Flag("input", flagDescArchive).Short('i').Required().ExistingFile()
+	restorePassphrase := restore.Flag("passphrase-file", flagDescPassphrase).ExistingFile()
+	restoreForce := restore.Flag("force", flagDescForce).Bool()
+
+	check := kingpin.Command(KahunaCommandCheck, cmdDescCheck)
+	checkRepair := check.Flag("repair", flagDescRepair).Bool()
+
+	cmd := KahunaCommand{
+		Name: kingpin.Parse(),
+	}
 
 	if *vers {
 		fmt.Printf("%s (%s)\n", version, codename)
 		os.Exit(0)
 	}
 
-	return *configFile, *debug, *migrate
+	cmd.ConfigFile = *configFile
+	cmd.Debug = *debug
+	cmd.Migrate = *migrate
+	cmd.DryRun = *dryRun
+	cmd.Rollback = *rollback
+
+	switch cmd.Name {
+	case KahunaCommandBackup:
+		cmd.Archive = *backupArchive
+		cmd.PassphraseFile = *backupPassphrase
+	case KahunaCommandRestore:
+		cmd.Archive = *restoreArchive
+		cmd.PassphraseFile = *restorePassphrase
+		cmd.Force = *restoreForce
+	case KahunaCommandCheck:
+		cmd.Repair = *checkRepair
+	}
+
+	return cmd
 }
@@ -40,16 +40,22 @@ type KowabungaConfig struct {
 }
 
 type KowabungaGlobalConfig struct {
-	LogLevel   string                   `yaml:"logLevel"`
-	PublicURL  string                   `yaml:"publicUrl"`
-	AdminEmail string                   `yaml:"adminEmail"`
-	JWT        KowabungaJwtConfig       `yaml:"jwt"`
-	HTTP       KowabungaHTTPConfig      `yaml:"http"`
-	APIKey     string                   `yaml:"apiKey"`
-	DB         KowabungaDBConfig        `yaml:"db"`
-	Cache      KowabungaCacheConfig     `yaml:"cache"`
-	Bootstrap  KowabungaBootstrapConfig `yaml:"bootstrap"`
-	SMTP       KowabungaSmtpConfig      `yaml:"smtp"`
+	LogLevel           string                     `yaml:"logLevel"`
+	PublicURL          string                     `yaml:"publicUrl"`
+	AdminEmail         string                     `yaml:"adminEmail"`
+	JWT                KowabungaJwtConfig         `yaml:"jwt"`
+	HTTP               KowabungaHTTPConfig        `yaml:"http"`
+	APIKey             string                     `yaml:"apiKey"`
+	AllowLegacyApiKeys bool                       `yaml:"allowLegacyApiKeys"`
+	DB                 KowabungaDBConfig          `yaml:"db"`
+	Cache              KowabungaCacheConfig       `yaml:"cache"`
+	Bootstrap          KowabungaBootstrapConfig   `yaml:"bootstrap"`
+	SMTP               KowabungaSmtpConfig        `yaml:"smtp"`
+	Idempotency        KowabungaIdempotencyConfig `yaml:"idempotency"`
+	Trash              KowabungaTrashConfig       `yaml:"trash"`
+	Cluster            KowabungaClusterConfig     `yaml:"cluster"`
+	OIDC               KowabungaOidcConfig        `yaml:"oidc"`
+	Egress             KowabungaEgressConfig      `yaml:"egress"`
 }
 
 type KowabungaJwtConfig struct {
@@ -58,20 +64,35 @@ type KowabungaJwtConfig struct {
 }
 
 type KowabungaHTTPConfig struct {
-	Address string `yaml:"address"`
-	Port    int    `yaml:"port"`
+	Address string             `yaml:"address"`
+	Port    int                `yaml:"port"`
+	TLS     KowabungaTLSConfig `yaml:"tls"`
+}
+
+type KowabungaTLSConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	Cert           string `yaml:"cert"`
+	Key            string `yaml:"key"`
+	AgentsCA       string `yaml:"agentsCA"` // enables agents mutual TLS authentication
+	ReloadInterval int    `yaml:"reloadIntervalSeconds"`
 }
 
 type KowabungaDBConfig struct {
-	URI  string `yaml:"uri"`
-	Name string `yaml:"name"`
+	URI           string `yaml:"uri"`
+	Name          string `yaml:"name"`
+	EncryptionKey string `yaml:"encryptionKey"` // encrypts sensitive settings at rest
 }
 
 type KowabungaCacheConfig struct {
-	Enabled bool   `yaml:"enabled"`
-	Type    string `yaml:"type"`
-	Size    int    `yaml:"sizeMB"`
-	TTL     int    `yaml:"expirationMinutes"`
+	Enabled bool                 `yaml:"enabled"`
+	Type    string               `yaml:"type"`
+	Size    int                  `yaml:"sizeMB"`
+	TTL     int                  `yaml:"expirationMinutes"`
+	Redis   KowabungaRedisConfig `yaml:"redis"`
+}
+
+type KowabungaRedisConfig struct {
+	URI string `yaml:"uri"`
 }
 
 type KowabungaBootstrapConfig struct {
@@ -87,6 +108,42 @@ type KowabungaSmtpConfig struct {
 	Password string `yaml:"password"`
 }
 
+type KowabungaIdempotencyConfig struct {
+	Retention int `yaml:"retentionHours"`
+}
+
+type KowabungaTrashConfig struct {
+	Retention int `yaml:"retentionHours"`
+}
+
+type KowabungaClusterConfig struct {
+	AdvertiseURL string `yaml:"advertiseUrl"` // HTTPS URL other replicas reach this one at
+	Secret       string `yaml:"secret"`       // shared amongst replicas, signs forwarded requests
+}
+
+type KowabungaOidcConfig struct {
+	Enabled      bool                       `yaml:"enabled"`
+	Issuer       string                     `yaml:"issuer"`
+	ClientID     string                     `yaml:"clientId"`
+	ClientSecret string                     `yaml:"clientSecret"`
+	RedirectURL  string                     `yaml:"redirectUrl"`
+	Scopes       []string                   `yaml:"scopes"`
+	GroupsClaim  string                     `yaml:"groupsClaim"`
+	DefaultRole  string                     `yaml:"defaultRole"`
+	LinkByEmail  bool                       `yaml:"linkByEmail"` // binds existing local accounts with matching verified email
+	Groups       []KowabungaOidcGroupConfig `yaml:"groups"`
+}
+
+type KowabungaOidcGroupConfig struct {
+	Name string `yaml:"name"`
+	Team string `yaml:"team"`
+	Role string `yaml:"role"`
+}
+
+type KowabungaEgressConfig struct {
+	AllowedNetworks []string `yaml:"allowedNetworks"` // non-public networks webhooks and notifiers may reach
+}
+
 type KowabungaCloudInitConfig struct {
 	Linux   KowabungaCloudInitBaseConfig `yaml:"linux"`
 	Windows KowabungaCloudInitBaseConfig `yaml:"windows"`
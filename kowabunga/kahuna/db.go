@@ -8,6 +8,7 @@ package kahuna
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 
@@ -25,13 +26,35 @@ type KowabungaDB struct {
 	DB     *mongo.Database
 }
 
+const (
+	DbEventOperationInsert  = "insert"
+	DbEventOperationUpdate  = "update"
+	DbEventOperationReplace = "replace"
+	DbEventOperationDelete  = "delete"
+)
+
 type KowabungaDbEvent struct {
-	DocumentKey KowabungaDocumentKey `bson:"documentKey"`
-	Operation   string               `bson:"operationType"`
+	DocumentKey  KowabungaDocumentKey  `bson:"documentKey"`
+	Operation    string                `bson:"operationType"`
+	Namespace    KowabungaNamespace    `bson:"ns"`
+	FullDocument KowabungaDocumentRefs `bson:"fullDocument"`
+	ClusterTime  bson.Timestamp        `bson:"clusterTime"`
 }
 type KowabungaDocumentKey struct {
 	ID bson.ObjectID `bson:"_id"`
 }
+type KowabungaNamespace struct {
+	DB         string `bson:"db"`
+	Collection string `bson:"coll"`
+}
+
+// KowabungaDocumentRefs only retains document ownership references
+type KowabungaDocumentRefs struct {
+	ProjectID      string `bson:"project_id"`
+	UserID         string `bson:"user_id"`
+	KawaiiID       string `bson:"kawaii_id"`
+	OrganizationID string `bson:"organization_id"`
+}
 
 // database singleton
 var dbLock = &sync.Mutex{}
@@ -99,6 +122,34 @@ func (db *KowabungaDB) Insert(collection string, obj interface{}) (interface{},
 	return c.InsertOne(context.TODO(), obj)
 }
 
+// revisioned documents are protected against concurrent modifications
+type revisioned interface {
+	revision() int64
+	setRevision(rev int64)
+}
+
+type KowabungaDbConflictError struct {
+	Collection string
+	ID         string
+}
+
+func (e *KowabungaDbConflictError) Error() string {
+	return fmt.Sprintf("document '%s' from '%s' has been concurrently modified", e.ID, e.Collection)
+}
+
+func IsDbConflictError(err error) bool {
+	var conflict *KowabungaDbConflictError
+	return errors.As(err, &conflict)
+}
+
+func revisionFilter(rev int64) bson.E {
+	if rev == 0 {
+		// documents created prior to revision tracking have none
+		return bson.E{Key: "revision", Value: bson.D{bson.E{Key: "$in", Value: bson.A{0, nil}}}}
+	}
+	return bson.E{Key: "revision", Value: rev}
+}
+
 func (db *KowabungaDB) Update(collection string, id bson.ObjectID, obj interface{}) (interface{}, error) {
 	// cleanup cache data, if any
 	defer func() {
@@ -106,7 +157,86 @@ func (db *KowabungaDB) Update(collection string, id bson.ObjectID, obj interface
 	}()
 
 	c := db.DB.Collection(collection)
-	return c.ReplaceOne(context.TODO(), bson.D{bson.E{Key: "_id", Value: id}}, obj)
+	filter := bson.D{bson.E{Key: "_id", Value: id}}
+
+	doc, ok := obj.(revisioned)
+	if !ok {
+		return c.ReplaceOne(context.TODO(), filter, obj)
+	}
+
+	// conditional replace: only if document hasn't been modified since it's been read
+	rev := doc.revision()
+	filter = append(filter, revisionFilter(rev))
+	doc.setRevision(rev + 1)
+
+	res, err := c.ReplaceOne(context.TODO(), filter, obj)
+	if err != nil {
+		doc.setRevision(rev)
+		return res, err
+	}
+
+	if res.MatchedCount == 0 {
+		doc.setRevision(rev)
+		// either document does not exist (yet) or it has a different revision
+		count, err := c.CountDocuments(context.TODO(), bson.D{bson.E{Key: "_id", Value: id}})
+		if err != nil {
+			return res, err
+		}
+		if count > 0 {
+			return res, &KowabungaDbConflictError{Collection: collection, ID: id.Hex()}
+		}
+	}
+
+	return res, nil
+}
+
+// FindRevision returns the current revision of a document
+func (db *KowabungaDB) FindRevision(collection, id string) (int64, error) {
+	oid, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, err
+	}
+
+	var doc struct {
+		Revision int64 `bson:"revision"`
+	}
+
+	c := db.DB.Collection(collection)
+	opts := options.FindOne().SetProjection(bson.D{bson.E{Key: "revision", Value: 1}})
+	err = c.FindOne(context.TODO(), bson.D{bson.E{Key: "_id", Value: oid}}, opts).Decode(&doc)
+	return doc.Revision, err
+}
+
+// CheckRevision ensures a document hasn't been modified since the specified revision has been read
+func (db *KowabungaDB) CheckRevision(collection string, id bson.ObjectID, rev int64) error {
+	current, err := db.FindRevision(collection, id.Hex())
+	if err != nil {
+		return err
+	}
+	if current != rev {
+		return &KowabungaDbConflictError{Collection: collection, ID: id.Hex()}
+	}
+	return nil
+}
+
+// FindRefs returns the ownership references of a document
+func (db *KowabungaDB) FindRefs(collection, id string) (KowabungaDocumentRefs, error) {
+	var refs KowabungaDocumentRefs
+
+	oid, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return refs, err
+	}
+
+	c := db.DB.Collection(collection)
+	opts := options.FindOne().SetProjection(bson.D{
+		bson.E{Key: "project_id", Value: 1},
+		bson.E{Key: "user_id", Value: 1},
+		bson.E{Key: "kawaii_id", Value: 1},
+		bson.E{Key: "organization_id", Value: 1},
+	})
+	err = c.FindOne(context.TODO(), bson.D{bson.E{Key: "_id", Value: oid}}, opts).Decode(&refs)
+	return refs, err
 }
 
 func (db *KowabungaDB) Rename(collection string, id bson.ObjectID, from, to string) error {
@@ -162,6 +292,26 @@ func (db *KowabungaDB) SetSchemaVersion(collection string, id bson.ObjectID, sch
 	return nil
 }
 
+// Patch partially updates a document, bypassing its revision check
+func (db *KowabungaDB) Patch(collection string, id bson.ObjectID, update bson.D) error {
+	// cleanup cache data, if any
+	defer func() {
+		_ = GetCache().Delete(collection, id.Hex())
+	}()
+
+	c := db.DB.Collection(collection)
+	filter := bson.D{bson.E{Key: "_id", Value: id}}
+	update = append(update, bson.E{Key: "$inc", Value: bson.D{bson.E{Key: "revision", Value: 1}}})
+	res, err := c.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
 func (db *KowabungaDB) FindAll(collection string, results interface{}) error {
 	c := db.DB.Collection(collection)
 	cursor, err := c.Find(context.TODO(), bson.D{})
@@ -182,6 +332,34 @@ func (db *KowabungaDB) FindAllByKey(collection, key, value string, results inter
 	return cursor.All(context.TODO(), results)
 }
 
+func (db *KowabungaDB) FindAllByFilter(collection string, filter, sort bson.D, limit int64, results interface{}) error {
+	opts := options.Find()
+	if len(sort) > 0 {
+		opts.SetSort(sort)
+	}
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	c := db.DB.Collection(collection)
+	cursor, err := c.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return err
+	}
+
+	return cursor.All(context.TODO(), results)
+}
+
+func (db *KowabungaDB) Aggregate(collection string, pipeline bson.A, results interface{}) error {
+	c := db.DB.Collection(collection)
+	cursor, err := c.Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return err
+	}
+
+	return cursor.All(context.TODO(), results)
+}
+
 func (db *KowabungaDB) Find(collection, k, v string, result interface{}) error {
 	c := db.DB.Collection(collection)
 	return c.FindOne(context.TODO(), bson.D{bson.E{Key: k, Value: v}}, nil).Decode(result)
@@ -231,8 +409,151 @@ func (db *KowabungaDB) FindByIP(collection, ip string, result interface{}) error
 	return db.Find(collection, "local_ip", ip, result)
 }
 
+// Watch opens a change stream over the whole database, optionally resuming after a previously seen event
+func (db *KowabungaDB) Watch(ctx context.Context, resumeToken bson.Raw, excludedCollections []string) (*mongo.ChangeStream, error) {
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if resumeToken != nil {
+		opts.SetResumeAfter(resumeToken)
+	}
+
+	ops := []string{DbEventOperationInsert, DbEventOperationUpdate, DbEventOperationReplace, DbEventOperationDelete}
+	pipeline := mongo.Pipeline{
+		bson.D{bson.E{Key: "$match", Value: bson.D{
+			bson.E{Key: "operationType", Value: bson.D{bson.E{Key: "$in", Value: ops}}},
+			bson.E{Key: "ns.coll", Value: bson.D{bson.E{Key: "$nin", Value: excludedCollections}}},
+		}}},
+	}
+
+	return db.DB.Watch(ctx, pipeline, opts)
+}
+
+func (db *KowabungaDB) ReplaceByKey(collection, key, value string, obj interface{}) error {
+	c := db.DB.Collection(collection)
+	_, err := c.ReplaceOne(context.TODO(), bson.D{bson.E{Key: key, Value: value}}, obj)
+	return err
+}
+
+// ReplaceByFilter replaces the document matching filter, if any, telling whether it did
+func (db *KowabungaDB) ReplaceByFilter(collection string, filter bson.D, obj interface{}) (bool, error) {
+	c := db.DB.Collection(collection)
+	res, err := c.ReplaceOne(context.TODO(), filter, obj)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (db *KowabungaDB) UpsertByKey(collection, key, value string, obj interface{}) error {
+	c := db.DB.Collection(collection)
+	opts := options.Replace().SetUpsert(true)
+	_, err := c.ReplaceOne(context.TODO(), bson.D{bson.E{Key: key, Value: value}}, obj, opts)
+	return err
+}
+
+func (db *KowabungaDB) DeleteByKey(collection, key, value string) error {
+	c := db.DB.Collection(collection)
+	_, err := c.DeleteOne(context.TODO(), bson.D{bson.E{Key: key, Value: value}})
+	return err
+}
+
+// UpdateByFilter partially updates the document matching filter, if any, bypassing its revision check
+func (db *KowabungaDB) UpdateByFilter(collection string, filter, update bson.D) error {
+	c := db.DB.Collection(collection)
+	_, err := c.UpdateOne(context.TODO(), filter, update)
+	return err
+}
+
+func (db *KowabungaDB) DeleteAllByFilter(collection string, filter bson.D) error {
+	c := db.DB.Collection(collection)
+	_, err := c.DeleteMany(context.TODO(), filter)
+	return err
+}
+
 func (db *KowabungaDB) Delete(collection string, id bson.ObjectID) error {
 	c := db.DB.Collection(collection)
 	_, err := c.DeleteOne(context.TODO(), bson.D{bson.E{Key: "_id", Value: id}})
 	return err
 }
+
+func (db *KowabungaDB) ListCollections() ([]string, error) {
+	return db.DB.ListCollectionNames(context.TODO(), bson.D{})
+}
+
+func (db *KowabungaDB) CountAll(collection string) (int64, error) {
+	c := db.DB.Collection(collection)
+	return c.CountDocuments(context.TODO(), bson.D{})
+}
+
+func (db *KowabungaDB) Count(collection string, filter bson.D) (int64, error) {
+	c := db.DB.Collection(collection)
+	return c.CountDocuments(context.TODO(), filter)
+}
+
+func (db *KowabungaDB) DropCollection(collection string) error {
+	c := db.DB.Collection(collection)
+	return c.Drop(context.TODO())
+}
+
+func (db *KowabungaDB) InsertMany(collection string, docs []any) error {
+	c := db.DB.Collection(collection)
+	_, err := c.InsertMany(context.TODO(), docs)
+	return err
+}
+
+func (db *KowabungaDB) ListIndexes(collection string) ([]mongo.IndexSpecification, error) {
+	if !db.HasCollection(collection) {
+		return []mongo.IndexSpecification{}, nil
+	}
+	c := db.DB.Collection(collection)
+	return c.Indexes().ListSpecifications(context.TODO())
+}
+
+func (db *KowabungaDB) CreateIndex(collection, name string, keys bson.D, unique, expires bool) error {
+	c := db.DB.Collection(collection)
+	opts := options.Index().SetName(name).SetUnique(unique)
+	if expires {
+		opts.SetExpireAfterSeconds(0)
+	}
+	_, err := c.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    keys,
+		Options: opts,
+	})
+	return err
+}
+
+// Snapshot runs fn with a context, all reads of which see the same database point in time
+func (db *KowabungaDB) Snapshot(fn func(ctx context.Context) error) error {
+	sess, err := db.Client.StartSession(options.Session().SetSnapshot(true))
+	if err != nil {
+		return err
+	}
+	defer sess.EndSession(context.TODO())
+
+	return fn(mongo.NewSessionContext(context.TODO(), sess))
+}
+
+// Dump iterates over all collection documents
+func (db *KowabungaDB) Dump(ctx context.Context, collection string, fn func(doc bson.D) error) error {
+	c := db.DB.Collection(collection)
+	cursor, err := c.Find(ctx, bson.D{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	for cursor.Next(ctx) {
+		var doc bson.D
+		err = cursor.Decode(&doc)
+		if err != nil {
+			return err
+		}
+		err = fn(doc)
+		if err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * Outgoing HTTP requests to user-supplied URLs (webhooks, notification channels)
+ * must not reach control-plane's own network. Target addresses are checked once
+ * resolved, right before connecting, so that DNS can't be used to bypass checks,
+ * and redirects are never followed.
+ */
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"syscall"
+	"time"
+)
+
+const (
+	ErrEgressForbiddenAddress = "outgoing connection to non-public address is forbidden"
+	ErrEgressRedirect         = "outgoing request redirections are not followed"
+)
+
+// non-public ranges not covered by netip.Addr helpers
+var egressReservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local-use NAT64
+	netip.MustParsePrefix("100::/64"),        // discard-only
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("fec0::/10"),       // deprecated site-local
+}
+
+// egressAllowedPrefixes returns the non-public networks explicitly allowed by configuration
+func egressAllowedPrefixes() []netip.Prefix {
+	prefixes := []netip.Prefix{}
+	cfg := GetCfg()
+	if cfg == nil {
+		return prefixes
+	}
+	for _, n := range cfg.Global.Egress.AllowedNetworks {
+		p, err := netip.ParsePrefix(n)
+		if err == nil {
+			prefixes = append(prefixes, p.Masked())
+		}
+	}
+	return prefixes
+}
+
+// isPublicAddress tells whether address can be reached by outgoing requests
+func isPublicAddress(addr netip.Addr, allowed []netip.Prefix) bool {
+	addr = addr.Unmap()
+
+	if slices.ContainsFunc(allowed, func(p netip.Prefix) bool { return p.Contains(addr) }) {
+		return true
+	}
+
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+
+	return !slices.ContainsFunc(egressReservedPrefixes, func(p netip.Prefix) bool { return p.Contains(addr) })
+}
+
+// egressDialControl refuses connections to non-public addresses, once host has been resolved
+func egressDialControl(network, address string, c syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !isPublicAddress(ap.Addr(), egressAllowedPrefixes()) {
+		return fmt.Errorf("%s: %s", ErrEgressForbiddenAddress, ap.Addr())
+	}
+	return nil
+}
+
+// NewEgressHttpClient returns an HTTP client suited for user-supplied URLs
+func NewEgressHttpClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: egressDialControl,
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:               nil, // a proxy would connect on our behalf, bypassing address checks
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return fmt.Errorf("%s", ErrEgressRedirect)
+		},
+	}
+}
+
+// validateEgressURL ensures URL uses one of the allowed schemes and, if host is an IP address, that it is a public one
+func validateEgressURL(uri string, schemes []string) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(schemes, u.Scheme) || u.Hostname() == "" {
+		return fmt.Errorf("unsupported URL %s", uri)
+	}
+
+	addr, err := netip.ParseAddr(u.Hostname())
+	if err == nil && !isPublicAddress(addr, egressAllowedPrefixes()) {
+		return fmt.Errorf("%s: %s", ErrEgressForbiddenAddress, addr)
+	}
+
+	return nil
+}
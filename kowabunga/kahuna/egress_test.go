@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEgressPublicAddress(t *testing.T) {
+	for addr, public := range map[string]bool{
+		"1.1.1.1":          true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.0.0.1":         false,
+		"172.16.3.4":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"::ffff:127.0.0.1": false,
+	} {
+		if isPublicAddress(netip.MustParseAddr(addr), nil) != public {
+			t.Errorf("%s should be public: %v", addr, public)
+		}
+	}
+
+	allowed := []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")}
+	if !isPublicAddress(netip.MustParseAddr("10.1.2.3"), allowed) {
+		t.Errorf("explicitly allowed network has been rejected")
+	}
+}
+
+func TestEgressHttpClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	client := NewEgressHttpClient(5 * time.Second)
+
+	// test server listens on loopback
+	_, err := client.Get(srv.URL)
+	if err == nil || !strings.Contains(err.Error(), ErrEgressForbiddenAddress) {
+		t.Fatalf("connection to loopback address has been allowed (%v)", err)
+	}
+
+	SetCfg(&KowabungaConfig{
+		Global: KowabungaGlobalConfig{
+			Egress: KowabungaEgressConfig{
+				AllowedNetworks: []string{"127.0.0.0/8"},
+			},
+		},
+	})
+	defer SetCfg(nil)
+
+	_, err = client.Get(srv.URL)
+	if err == nil || !strings.Contains(err.Error(), ErrEgressRedirect) {
+		t.Fatalf("redirection has been followed (%v)", err)
+	}
+}
@@ -7,6 +7,9 @@
 package kahuna
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/kowabunga-cloud/common/klog"
 	"github.com/kowabunga-cloud/kowabunga/kowabunga/sdk"
 )
@@ -37,12 +40,15 @@ func (ke *KahunaEngine) Cleanup() {
 	}
 }
 
-func (ke *KahunaEngine) RegisterApiHandlers() {
-	// register API handlers
-	routers := []sdk.Router{
+// apiRouters returns all API handlers
+func apiRouters() []sdk.Router {
+	return []sdk.Router{
 		NewAdapterRouter(),
 		NewAgentRouter(),
+		NewAuditRouter(),
+		NewCheckRouter(),
 		NewDnsRecordRouter(),
+		NewEventRouter(),
 		NewInstanceRouter(),
 		NewKaktusRouter(),
 		NewKawaiiRouter(),
@@ -50,27 +56,116 @@ func (ke *KahunaEngine) RegisterApiHandlers() {
 		NewKomputeRouter(),
 		NewKonveyRouter(),
 		NewKyloRouter(),
+		NewManifestRouter(),
 		NewNfsRouter(),
+		NewNotificationRouter(),
+		NewOidcRouter(),
+		NewOrganizationRouter(),
 		NewProjectRouter(),
+		NewRbacRouter(),
 		NewRegionRouter(),
+		NewSearchRouter(),
 		NewStoragePoolRouter(),
 		NewSubnetRouter(),
+		NewTaskRouter(),
 		NewTeamRouter(),
 		NewTemplateRouter(),
 		NewTokenRouter(),
+		NewTokenScopeRouter(),
+		NewTrashRouter(),
 		NewUserRouter(),
 		NewVNetRouter(),
 		NewVolumeRouter(),
+		NewWebhookRouter(),
 		NewZoneRouter(),
 	}
-	ke.ApiRouters = append(ke.ApiRouters, routers...)
 }
 
-func (ke *KahunaEngine) MigrateDatabase(cfg KowabungaConfig) error {
+// IsApiOperation tells whether an API operation exists
+func IsApiOperation(name string) bool {
+	for _, api := range apiRouters() {
+		_, ok := api.Routes()[name]
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (ke *KahunaEngine) RegisterApiHandlers() {
+	// register API handlers
+	ke.ApiRouters = append(ke.ApiRouters, apiRouters()...)
+}
+
+func (ke *KahunaEngine) MigrateDatabase(cfg KowabungaConfig, dryRun bool) error {
 	// disable cache
-	GetCache().Init(false, cfg.Global.Cache.Type, cfg.Global.Cache.Size, cfg.Global.Cache.TTL)
+	GetCache().Disable()
+
+	err := MigrateDatabaseSchema(dryRun)
+	if err != nil {
+		return err
+	}
 
-	return MigrateDatabaseSchema()
+	_, err = EnsureIndexes(dryRun)
+	return err
+}
+
+func (ke *KahunaEngine) RollbackDatabase(cfg KowabungaConfig, migration string, dryRun bool) error {
+	// disable cache
+	GetCache().Disable()
+
+	return RollbackDatabaseSchema(migration, dryRun)
+}
+
+func (ke *KahunaEngine) BackupDatabase(cfg KowabungaConfig, archive, passphraseFile string) error {
+	passphrase, err := ReadBackupPassphrase(passphraseFile)
+	if err != nil {
+		return err
+	}
+
+	return BackupDatabase(archive, passphrase)
+}
+
+func (ke *KahunaEngine) RestoreDatabase(cfg KowabungaConfig, archive, passphraseFile string, force bool) error {
+	// disable cache
+	GetCache().Disable()
+
+	passphrase, err := ReadBackupPassphrase(passphraseFile)
+	if err != nil {
+		return err
+	}
+
+	return RestoreDatabase(archive, passphrase, force)
+}
+
+func (ke *KahunaEngine) CheckDatabase(cfg KowabungaConfig, repair bool) error {
+	// disable cache
+	GetCache().Disable()
+
+	// agents are not connected to offline Kahuna
+	report, err := CheckConsistency(repair, false)
+	if err != nil {
+		return err
+	}
+	klog.Infof("Libvirt domains and RBD images are not checked offline, use /check endpoint of a running Kahuna to do so")
+
+	for _, issue := range report.Issues {
+		switch {
+		case issue.Repaired:
+			klog.Infof("[repaired] %s %s (%s): %s", issue.Collection, issue.ID, issue.Name, issue.Message)
+		case issue.Repairable:
+			klog.Warningf("[repairable] %s %s (%s): %s", issue.Collection, issue.ID, issue.Name, issue.Message)
+		default:
+			klog.Warningf("[%s] %s %s (%s): %s", issue.Kind, issue.Collection, issue.ID, issue.Name, issue.Message)
+		}
+	}
+	klog.Infof("Checked %d documents, found %d issues, repaired %d", report.Documents, len(report.Issues), report.Repaired)
+
+	if report.Unrepaired() > 0 {
+		return fmt.Errorf("%s: %d", ErrConsistencyIssuesRemaining, report.Unrepaired())
+	}
+
+	return nil
 }
 
 func (ke *KahunaEngine) Run(cfg KowabungaConfig) {
@@ -78,7 +173,37 @@ func (ke *KahunaEngine) Run(cfg KowabungaConfig) {
 	defer ke.Cleanup()
 
 	// cache initialization
-	GetCache().Init(cfg.Global.Cache.Enabled, cfg.Global.Cache.Type, cfg.Global.Cache.Size, cfg.Global.Cache.TTL)
+	err := GetCache().Init(cfg.Global.Cache.Enabled, cfg.Global.Cache)
+	if err != nil {
+		klog.Fatalf("Unable to initialize cache: %v", err)
+	}
+
+	// create missing database indexes, if any
+	_, err = EnsureIndexes(false)
+	if err != nil {
+		klog.Errorf("Unable to ensure database indexes: %v", err)
+	}
+
+	// flag tasks interrupted by a previous shutdown
+	RecoverTasks()
+
+	// relay database changes as resource events
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go GetEventBus().Watch(ctx)
+
+	// flag tasks interrupted by any replica shutdown
+	go TaskReaper(ctx)
+
+	// share agents presence with other replicas
+	go ClusterHeartbeat(ctx)
+	defer RemoveReplicaAgentPresences()
+
+	// purge expired trashed resources
+	go TrashPurger(ctx)
+
+	// send out webhooks deliveries
+	go WebhookDispatcher(ctx)
 
 	// register prometheus exporter
 	ke.Exporter = NewExporter()
@@ -86,7 +211,7 @@ func (ke *KahunaEngine) Run(cfg KowabungaConfig) {
 	// register API handlers
 	ke.RegisterApiHandlers()
 
-	srv := NewHTTPServer(ke, cfg.Global.HTTP.Address, cfg.Global.HTTP.Port)
+	srv := NewHTTPServer(ke, cfg.Global.HTTP)
 	defer func() {
 		if err := srv.Shutdown(); err != nil {
 			// error handle
@@ -0,0 +1,280 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * Kowabunga event bus relays MongoDB change streams as typed resource events
+ * (creation, update, deletion) to API subscribers, each one only being notified
+ * about resources from projects it is member of.
+ */
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/kowabunga-cloud/common/klog"
+)
+
+const (
+	EventTypeCreated = "created"
+	EventTypeUpdated = "updated"
+	EventTypeDeleted = "deleted"
+
+	EventSubscriberQueueSize    = 256
+	EventWatchRetryDelaySeconds = 30
+)
+
+// collections which are never streamed to API subscribers
+var eventIgnoredCollections = []string{
+	MongoCollectionAuditName,
+	MongoCollectionTaskName,
+	MongoCollectionWebhookDeliveryName,
+}
+
+// runtime-only collections, neither cached nor streamed, which changes are not even watched
+var eventUnwatchedCollections = []string{
+	MongoCollectionAgentPresenceName,
+	MongoCollectionIdempotencyName,
+	MongoCollectionMigrationLockName,
+	MongoCollectionOidcLoginName,
+}
+
+type KowabungaEvent struct {
+	Type       string
+	Collection string
+	ID         string
+	Timestamp  time.Time
+
+	// ownership references
+	ProjectID string
+	UserID    string
+}
+
+type KowabungaEventModel struct {
+	Type       string `json:"type"`
+	Collection string `json:"collection"`
+	Id         string `json:"id"`
+	Timestamp  string `json:"timestamp"`
+}
+
+func NewEventFromDb(e *KowabungaDbEvent) (*KowabungaEvent, bool) {
+	var tp string
+	switch e.Operation {
+	case DbEventOperationInsert:
+		tp = EventTypeCreated
+	case DbEventOperationUpdate, DbEventOperationReplace:
+		tp = EventTypeUpdated
+	case DbEventOperationDelete:
+		tp = EventTypeDeleted
+	default:
+		return nil, false
+	}
+
+	if slices.Contains(eventIgnoredCollections, e.Namespace.Collection) {
+		return nil, false
+	}
+
+	ts := time.Now()
+	if e.ClusterTime.T != 0 {
+		ts = time.Unix(int64(e.ClusterTime.T), 0)
+	}
+
+	return &KowabungaEvent{
+		Type:       tp,
+		Collection: e.Namespace.Collection,
+		ID:         e.DocumentKey.ID.Hex(),
+		Timestamp:  ts,
+		ProjectID:  e.FullDocument.ProjectID,
+		UserID:     e.FullDocument.UserID,
+	}, true
+}
+
+func (e *KowabungaEvent) Model() KowabungaEventModel {
+	return KowabungaEventModel{
+		Type:       e.Type,
+		Collection: e.Collection,
+		Id:         e.ID,
+		Timestamp:  e.Timestamp.UTC().Format(time.RFC3339),
+	}
+}
+
+type EventSubscriber struct {
+	Events chan KowabungaEvent
+
+	userId     string
+	superAdmin bool
+	projects   []string
+	visible    map[string]bool
+}
+
+func NewEventSubscriber(userId string, superAdmin bool) *EventSubscriber {
+	s := EventSubscriber{
+		Events:     make(chan KowabungaEvent, EventSubscriberQueueSize),
+		userId:     userId,
+		superAdmin: superAdmin,
+		visible:    map[string]bool{},
+	}
+	s.refresh()
+	return &s
+}
+
+// refresh re-computes subscriber's projects membership, and related resources
+func (s *EventSubscriber) refresh() {
+	if s.superAdmin {
+		return
+	}
+
+	projects, err := userAllowedProjects(s.userId)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+
+	s.projects = []string{}
+	for _, prj := range projects {
+		s.projects = append(s.projects, prj.String())
+		for _, id := range prj.Resources() {
+			s.visible[id] = true
+		}
+	}
+}
+
+// IsAllowed tells whether subscriber is permitted to be notified about event's resource.
+// Not thread-safe, expected to be called from subscriber's consumer only.
+func (s *EventSubscriber) IsAllowed(e *KowabungaEvent) bool {
+	if s.superAdmin {
+		return true
+	}
+
+	// deleted documents can't be looked up anymore, rely on what subscriber previously had access to
+	if e.Type == EventTypeDeleted {
+		allowed := s.visible[e.ID]
+		delete(s.visible, e.ID)
+		return allowed
+	}
+
+	// project teams may have changed
+	if e.Collection == MongoCollectionProjectName {
+		s.refresh()
+	}
+
+	allowed := s.visible[e.ID] ||
+		e.ID == s.userId ||
+		(e.UserID != "" && e.UserID == s.userId) ||
+		(e.ProjectID != "" && slices.Contains(s.projects, e.ProjectID))
+	if allowed {
+		s.visible[e.ID] = true
+	}
+
+	return allowed
+}
+
+type EventBus struct {
+	mutex       sync.RWMutex
+	subscribers map[*EventSubscriber]struct{}
+}
+
+// event bus singleton
+var eventBusLock = &sync.Mutex{}
+var kEventBus *EventBus
+
+func GetEventBus() *EventBus {
+	if kEventBus == nil {
+		eventBusLock.Lock()
+		defer eventBusLock.Unlock()
+		klog.Debugf("Creating Kowabunga event bus instance")
+		kEventBus = &EventBus{
+			subscribers: map[*EventSubscriber]struct{}{},
+		}
+	}
+
+	return kEventBus
+}
+
+func (eb *EventBus) Subscribe(s *EventSubscriber) {
+	eb.mutex.Lock()
+	defer eb.mutex.Unlock()
+	eb.subscribers[s] = struct{}{}
+}
+
+func (eb *EventBus) Unsubscribe(s *EventSubscriber) {
+	eb.mutex.Lock()
+	defer eb.mutex.Unlock()
+	delete(eb.subscribers, s)
+}
+
+func (eb *EventBus) Publish(e *KowabungaEvent) {
+	eb.mutex.RLock()
+	defer eb.mutex.RUnlock()
+
+	for s := range eb.subscribers {
+		select {
+		case s.Events <- *e:
+		default:
+			// never block the whole bus for a slow consumer
+			klog.Warningf("Event queue is full for user %s, dropping %s event on %s/%s", s.userId, e.Type, e.Collection, e.ID)
+		}
+	}
+}
+
+func (eb *EventBus) watch(ctx context.Context, resumeToken bson.Raw) bson.Raw {
+	stream, err := GetDB().Watch(ctx, resumeToken, eventUnwatchedCollections)
+	if err != nil {
+		klog.Errorf("Unable to watch MongoDB change stream: %v", err)
+		// resume point may have expired from oplog, start over from now on
+		return nil
+	}
+	defer func() {
+		_ = stream.Close(context.TODO())
+	}()
+
+	klog.Infof("Watching MongoDB change stream for resource events")
+	for stream.Next(ctx) {
+		var dbEvent KowabungaDbEvent
+		err := stream.Decode(&dbEvent)
+		if err != nil {
+			klog.Errorf("Unable to decode MongoDB change stream event: %v", err)
+			continue
+		}
+		resumeToken = stream.ResumeToken()
+
+		// evict changed document from cache, as it may have been updated by another replica
+		_ = GetCache().Delete(dbEvent.Namespace.Collection, dbEvent.DocumentKey.ID.Hex())
+
+		e, ok := NewEventFromDb(&dbEvent)
+		if !ok {
+			continue
+		}
+		eb.Publish(e)
+	}
+
+	if stream.Err() != nil {
+		klog.Errorf("MongoDB change stream interrupted: %v", stream.Err())
+	}
+
+	return resumeToken
+}
+
+// Watch relays database change events to subscribers and evicts changed documents
+// from cache, so that replicas don't serve stale data, until context is cancelled.
+// Change streams require MongoDB to run as a replica set.
+func (eb *EventBus) Watch(ctx context.Context) {
+	var resumeToken bson.Raw
+	for {
+		resumeToken = eb.watch(ctx, resumeToken)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(EventWatchRetryDelaySeconds * time.Second):
+		}
+	}
+}
@@ -35,16 +35,18 @@ type HTTPServer struct {
 	interrupted  bool
 	interrupt    chan os.Signal
 	httpServer   *http.Server
+	tls          KowabungaTLSConfig
 }
 
-func NewHTTPServer(ke *KahunaEngine, address string, port int) *HTTPServer {
+func NewHTTPServer(ke *KahunaEngine, cfg KowabungaHTTPConfig) *HTTPServer {
 	rt := NewRouter(ke)
 	s := HTTPServer{
 		shutdown:  make(chan struct{}),
 		interrupt: make(chan os.Signal, 1),
+		tls:       cfg.TLS,
 		httpServer: &http.Server{
 			Handler:        rt,
-			Addr:           fmt.Sprintf("%s:%d", address, port),
+			Addr:           fmt.Sprintf("%s:%d", cfg.Address, cfg.Port),
 			MaxHeaderBytes: HttpMaxHeaderBytes,
 			ReadTimeout:    HttpReadTimeoutSeconds * time.Second,
 			WriteTimeout:   HttpWriteTimeoutSeconds * time.Second,
@@ -105,14 +107,32 @@ func (s *HTTPServer) Serve() (err error) {
 	signalNotify(s.interrupt)
 	go handleInterrupt(once, s)
 
+	scheme := "http"
+	if s.tls.Enabled {
+		certs, err := NewTLSCertReloader(s.tls)
+		if err != nil {
+			return err
+		}
+		s.httpServer.TLSConfig = certs.TLSConfig()
+		go certs.Watch(s.shutdown)
+		scheme = "https"
+	}
+
 	wg.Add(1)
-	klog.Infof("Serving kowabunga at http://%s", s.httpServer.Addr)
+	klog.Infof("Serving kowabunga at %s://%s", scheme, s.httpServer.Addr)
 	go func() {
 		defer wg.Done()
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if s.tls.Enabled {
+			// certificates are provided by server's TLS configuration
+			err = s.httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = s.httpServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			klog.Fatalf("%v", err)
 		}
-		klog.Infof("Stopped serving kowabunga at http://%s", s.httpServer.Addr)
+		klog.Infof("Stopped serving kowabunga at %s://%s", scheme, s.httpServer.Addr)
 	}()
 
 	wg.Add(1)
@@ -0,0 +1,298 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * Idempotency keys allow API clients to safely retry resource creation requests:
+ * the response to the first successful request is recorded and replayed for any
+ * subsequent request sharing the same key (and payload) within a configurable time
+ * window. Failed requests didn't create anything, their key can be retried.
+ * Keys are leased while request is being processed, so that a Kahuna crash
+ * doesn't lock them out until records expire.
+ */
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/kowabunga-cloud/common/klog"
+)
+
+const (
+	MongoCollectionIdempotencySchemaVersion = 1
+	MongoCollectionIdempotencyName          = "idempotency"
+
+	HttpHeaderIdempotencyKey      = "Idempotency-Key"
+	HttpHeaderIdempotencyReplayed = "Idempotent-Replayed"
+
+	IdempotencyKeyMaxLength              = 255
+	IdempotencyDefaultRetentionHours     = 24
+	IdempotencyLeaseSeconds              = 60
+	IdempotencyLeaseRenewIntervalSeconds = IdempotencyLeaseSeconds / 3
+	IdempotencyCreateRoutePrefix         = "Create"
+
+	ErrIdempotencyKeyTooLong   = "Idempotency-Key header is too long"
+	ErrIdempotencyKeyInUse     = "a request with the same Idempotency-Key is still being processed"
+	ErrIdempotencyKeyMismatch  = "Idempotency-Key has already been used with a different request payload"
+	ErrIdempotencyRecordFailed = "unable to record idempotency key"
+)
+
+type IdempotencyRecord struct {
+	ID            string    `bson:"_id"`
+	SchemaVersion int       `bson:"schema_version"`
+	CreatedAt     time.Time `bson:"created_at"`
+	ExpiresAt     time.Time `bson:"expires_at"`   // removed by MongoDB afterwards
+	LockedUntil   time.Time `bson:"locked_until"` // while being processed, renewed until completion
+
+	// request
+	UserID        string `bson:"user_id"`
+	Key           string `bson:"key"`
+	Route         string `bson:"route"`
+	PayloadDigest string `bson:"payload_digest"`
+
+	// recorded response
+	Completed   bool   `bson:"completed"`
+	Status      int    `bson:"status"`
+	ContentType string `bson:"content_type"`
+	Body        []byte `bson:"body"`
+}
+
+func idempotencyRetention() time.Duration {
+	retention := IdempotencyDefaultRetentionHours
+	if GetCfg() != nil && GetCfg().Global.Idempotency.Retention > 0 {
+		retention = GetCfg().Global.Idempotency.Retention
+	}
+	return time.Duration(retention) * time.Hour
+}
+
+// idempotencyRecordID scopes keys per user, so that clients can't replay others' responses
+func idempotencyRecordID(userId, key string) string {
+	h := sha256.Sum256([]byte(userId + "/" + key))
+	return hex.EncodeToString(h[:])
+}
+
+func idempotencyPayloadDigest(r *http.Request, payload []byte) string {
+	h := sha256.New()
+	_, _ = h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	_, _ = h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func NewIdempotencyRecord(userId, key, route, digest string) *IdempotencyRecord {
+	now := time.Now()
+	return &IdempotencyRecord{
+		ID:            idempotencyRecordID(userId, key),
+		SchemaVersion: MongoCollectionIdempotencySchemaVersion,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(idempotencyRetention()),
+		LockedUntil:   now.Add(IdempotencyLeaseSeconds * time.Second),
+		UserID:        userId,
+		Key:           key,
+		Route:         route,
+		PayloadDigest: digest,
+	}
+}
+
+func FindIdempotencyRecord(userId, key string) (*IdempotencyRecord, error) {
+	var rec IdempotencyRecord
+	err := GetDB().Find(MongoCollectionIdempotencyName, "_id", idempotencyRecordID(userId, key), &rec)
+	return &rec, err
+}
+
+// IsStale tells whether record no longer holds its key: either past its retention window (but not yet
+// removed by MongoDB) or abandoned while being processed (e.g. Kahuna crashed).
+func (rec *IdempotencyRecord) IsStale(now time.Time) bool {
+	return now.After(rec.ExpiresAt) || (!rec.Completed && now.After(rec.LockedUntil))
+}
+
+// Acquire records the key as being processed. It returns the already existing record, if any.
+func (rec *IdempotencyRecord) Acquire() (*IdempotencyRecord, error) {
+	_, err := GetDB().Insert(MongoCollectionIdempotencyName, rec)
+	if err == nil {
+		return nil, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, err
+	}
+
+	prev, err := FindIdempotencyRecord(rec.UserID, rec.Key)
+	if err != nil || !prev.IsStale(time.Now()) {
+		return prev, err
+	}
+
+	// take stale record over, unless a concurrent request just did
+	filter := bson.D{
+		bson.E{Key: "_id", Value: prev.ID},
+		bson.E{Key: "locked_until", Value: prev.LockedUntil},
+		bson.E{Key: "expires_at", Value: prev.ExpiresAt},
+	}
+	acquired, err := GetDB().ReplaceByFilter(MongoCollectionIdempotencyName, filter, rec)
+	if err != nil || acquired {
+		return nil, err
+	}
+
+	return FindIdempotencyRecord(rec.UserID, rec.Key)
+}
+
+// Renew extends record's lease while request is being processed
+func (rec *IdempotencyRecord) Renew() {
+	filter := bson.D{
+		bson.E{Key: "_id", Value: rec.ID},
+		bson.E{Key: "completed", Value: false},
+	}
+	lockedUntil := time.Now().Add(IdempotencyLeaseSeconds * time.Second)
+	update := bson.D{bson.E{Key: "$set", Value: bson.D{bson.E{Key: "locked_until", Value: lockedUntil}}}}
+	err := GetDB().UpdateByFilter(MongoCollectionIdempotencyName, filter, update)
+	if err != nil {
+		klog.Error(err)
+	}
+}
+
+func (rec *IdempotencyRecord) Complete(status int, contentType string, body []byte) {
+	rec.Completed = true
+	rec.Status = status
+	rec.ContentType = contentType
+	rec.Body = body
+	err := GetDB().ReplaceByKey(MongoCollectionIdempotencyName, "_id", rec.ID, rec)
+	if err != nil {
+		klog.Error(err)
+	}
+}
+
+func (rec *IdempotencyRecord) Release() {
+	err := GetDB().DeleteByKey(MongoCollectionIdempotencyName, "_id", rec.ID)
+	if err != nil {
+		klog.Error(err)
+	}
+}
+
+func (rec *IdempotencyRecord) Replay(w http.ResponseWriter) {
+	if rec.ContentType != "" {
+		w.Header().Set("Content-Type", rec.ContentType)
+	}
+	w.Header().Set(HttpHeaderIdempotencyReplayed, "true")
+	w.WriteHeader(rec.Status)
+	_, err := w.Write(rec.Body)
+	if err != nil {
+		klog.Error(err)
+	}
+}
+
+// idempotencyResponseWriter records response for later replays
+type idempotencyResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *idempotencyResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *idempotencyResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// idempotencyRecordable tells whether response status denotes a performed operation, to be replayed. Any
+// other means resource has not been created (e.g. invalid parameters, creation failure reported as 422
+// or conflicting resource), client may retry with the same key.
+func idempotencyRecordable(status int) bool {
+	return status >= http.StatusOK && status < http.StatusMultipleChoices
+}
+
+func idempotencyMiddleware(next http.Handler, name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(HttpHeaderIdempotencyKey)
+		if key == "" || r.Method != http.MethodPost || !strings.HasPrefix(name, IdempotencyCreateRoutePrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > IdempotencyKeyMaxLength {
+			HttpMiddlewareError(w, r, HttpBadParams, errors.New(ErrIdempotencyKeyTooLong))
+			return
+		}
+
+		payload, err := io.ReadAll(r.Body)
+		if err != nil {
+			HttpMiddlewareError(w, r, HttpBadParams, err)
+			return
+		}
+		_ = r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(payload))
+
+		rec := NewIdempotencyRecord(ctxGetUserId(r.Context()), key, name, idempotencyPayloadDigest(r, payload))
+		prev, err := rec.Acquire()
+		if err != nil {
+			klog.Errorf("%s: %v", ErrIdempotencyRecordFailed, err)
+			HttpMiddlewareError(w, r, HttpServerError, errors.New(ErrIdempotencyRecordFailed))
+			return
+		}
+
+		if prev != nil {
+			switch {
+			case prev.PayloadDigest != rec.PayloadDigest:
+				HttpMiddlewareError(w, r, HttpConflict, errors.New(ErrIdempotencyKeyMismatch))
+			case !prev.Completed:
+				HttpMiddlewareError(w, r, HttpConflict, errors.New(ErrIdempotencyKeyInUse))
+			default:
+				klog.Debugf("Replaying %s response for idempotency key %s", name, key)
+				prev.Replay(w)
+			}
+			return
+		}
+
+		// hold key until request completes
+		done := make(chan struct{})
+		go func() {
+			ticker := time.NewTicker(IdempotencyLeaseRenewIntervalSeconds * time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					rec.Renew()
+				}
+			}
+		}()
+
+		iw := &idempotencyResponseWriter{
+			ResponseWriter: w,
+		}
+		func() {
+			defer close(done)
+			next.ServeHTTP(iw, r)
+		}()
+
+		if iw.status == 0 {
+			iw.status = http.StatusOK
+		}
+
+		// failed requests created nothing, let client retry with the same key
+		if !idempotencyRecordable(iw.status) {
+			rec.Release()
+			return
+		}
+
+		rec.Complete(iw.status, iw.Header().Get("Content-Type"), iw.body.Bytes())
+	})
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyRecordStale(t *testing.T) {
+	rec := NewIdempotencyRecord("user", "key", "CreateProjectZoneInstance", "digest")
+	now := time.Now()
+
+	// in-flight, within its lease
+	if rec.IsStale(now) {
+		t.Fatalf("in-flight record should hold its key")
+	}
+
+	// in-flight, abandoned by a crashed Kahuna
+	if !rec.IsStale(now.Add(2 * IdempotencyLeaseSeconds * time.Second)) {
+		t.Fatalf("abandoned record should not hold its key")
+	}
+
+	// completed, replayed until retention window expires
+	rec.Completed = true
+	if rec.IsStale(now.Add(2 * IdempotencyLeaseSeconds * time.Second)) {
+		t.Fatalf("completed record should hold its key")
+	}
+	if !rec.IsStale(now.Add(idempotencyRetention() + time.Second)) {
+		t.Fatalf("expired record should not hold its key")
+	}
+}
+
+func TestIdempotencyRetention(t *testing.T) {
+	if idempotencyRetention() != IdempotencyDefaultRetentionHours*time.Hour {
+		t.Fatalf("unexpected default retention %s", idempotencyRetention())
+	}
+
+	SetCfg(&KowabungaConfig{
+		Global: KowabungaGlobalConfig{
+			Idempotency: KowabungaIdempotencyConfig{Retention: 2},
+		},
+	})
+	defer SetCfg(nil)
+	if idempotencyRetention() != 2*time.Hour {
+		t.Fatalf("unexpected retention %s", idempotencyRetention())
+	}
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	// record original response
+	w := httptest.NewRecorder()
+	iw := &idempotencyResponseWriter{ResponseWriter: w}
+	iw.Header().Set("Content-Type", "application/json")
+	iw.WriteHeader(http.StatusCreated)
+	_, _ = iw.Write([]byte(`{"id":"abc"}`))
+
+	rec := NewIdempotencyRecord("user", "key", "CreateProjectZoneInstance", "digest")
+	rec.Completed = true
+	rec.Status = iw.status
+	rec.ContentType = iw.Header().Get("Content-Type")
+	rec.Body = iw.body.Bytes()
+
+	// and replay it
+	w = httptest.NewRecorder()
+	rec.Replay(w)
+	if w.Code != http.StatusCreated || w.Body.String() != `{"id":"abc"}` {
+		t.Fatalf("unexpected replayed response %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get(HttpHeaderIdempotencyReplayed) != "true" || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected replayed headers: %v", w.Header())
+	}
+}
+
+func TestIdempotencyErrors(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/project", nil)
+	HttpMiddlewareError(w, r, HttpConflict, errors.New(ErrIdempotencyKeyInUse))
+
+	if w.Code != http.StatusConflict || w.Header().Get("Content-Type") != "application/json; charset=UTF-8" {
+		t.Fatalf("unexpected response %d (%s)", w.Code, w.Header().Get("Content-Type"))
+	}
+	var msg string
+	err := json.NewDecoder(w.Body).Decode(&msg)
+	if err != nil || msg != ErrIdempotencyKeyInUse {
+		t.Fatalf("unexpected error message %s (%v)", msg, err)
+	}
+}
+
+func TestIdempotencyRecordable(t *testing.T) {
+	for status, expected := range map[int]bool{
+		http.StatusOK:                  true,
+		http.StatusCreated:             true,
+		http.StatusAccepted:            true,
+		http.StatusBadRequest:          false,
+		http.StatusForbidden:           false,
+		http.StatusConflict:            false,
+		http.StatusUnprocessableEntity: false, // HttpServerError
+		http.StatusInsufficientStorage: false,
+		http.StatusInternalServerError: false,
+	} {
+		if idempotencyRecordable(status) != expected {
+			t.Errorf("status %d: expected recordable %v", status, expected)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/kowabunga-cloud/common/klog"
+)
+
+const (
+	MongoIndexDefault = "_id_"
+
+	IndexDriftMissing    = "missing"
+	IndexDriftMismatch   = "mismatch"
+	IndexDriftUndeclared = "undeclared"
+	IndexDriftFailed     = "failed"
+)
+
+// MongoIndex declares a collection index, on ascending (or descending, if "-" prefixed) keys
+type MongoIndex struct {
+	Keys    []string
+	Unique  bool
+	Expires bool // documents are removed by MongoDB once (single) key date has passed
+}
+
+func index(keys ...string) MongoIndex {
+	return MongoIndex{
+		Keys: keys,
+	}
+}
+
+func uniqueIndex(keys ...string) MongoIndex {
+	return MongoIndex{
+		Keys:   keys,
+		Unique: true,
+	}
+}
+
+func expiringIndex(key string) MongoIndex {
+	return MongoIndex{
+		Keys:    []string{key},
+		Expires: true,
+	}
+}
+
+// Name follows MongoDB default index naming
+func (idx MongoIndex) Name() string {
+	parts := []string{}
+	for _, e := range idx.keys() {
+		parts = append(parts, fmt.Sprintf("%s_%d", e.Key, e.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+func (idx MongoIndex) keys() bson.D {
+	keys := bson.D{}
+	for _, k := range idx.Keys {
+		order := 1
+		if strings.HasPrefix(k, "-") {
+			order = -1
+		}
+		keys = append(keys, bson.E{Key: strings.TrimPrefix(k, "-"), Value: order})
+	}
+	return keys
+}
+
+// expected database indexes, per collection
+var dbIndexes = map[string][]MongoIndex{
+	MongoCollectionAdapterName:         {index("name"), index("subnet_id")},
+	MongoCollectionAgentName:           {uniqueIndex("name")},
+	MongoCollectionAgentPresenceName:   {index("replica_id"), index("heartbeat_at")},
+	MongoCollectionAuditName:           {index("-timestamp"), index("user_id", "-timestamp"), index("project_id", "-timestamp"), index("resource_id", "-timestamp")},
+	MongoCollectionDnsRecordName:       {index("name"), index("project_id"), index("region_id")},
+	MongoCollectionHarName:             {index("name"), index("project_id")},
+	MongoCollectionIdempotencyName:     {expiringIndex("expires_at")},
+	MongoCollectionInstanceName:        {index("name"), index("project_id"), index("kaktus_id"), index("local_ip"), index("trash.purge_at"), index("labels.key", "labels.value")},
+	MongoCollectionIPsecName:           {index("kawaii_id")},
+	MongoCollectionKaktusName:          {uniqueIndex("name"), index("zone_id")},
+	MongoCollectionKawaiiName:          {index("name"), index("project_id")},
+	MongoCollectionKiwiName:            {uniqueIndex("name"), index("region_id")},
+	MongoCollectionKomputeName:         {index("name"), index("project_id"), index("trash.purge_at"), index("labels.key", "labels.value")},
+	MongoCollectionKonveyName:          {index("name"), index("project_id"), index("labels.key", "labels.value")},
+	MongoCollectionKyloName:            {index("name"), index("project_id"), index("nfs_id")},
+	MongoCollectionMzrName:             {index("name"), index("project_id")},
+	MongoCollectionNfsName:             {uniqueIndex("name"), index("region_id")},
+	MongoCollectionOidcLoginName:       {index("expires_at")},
+	MongoCollectionOrganizationName:    {uniqueIndex("name"), index("admin_ids")},
+	MongoCollectionProjectName:         {uniqueIndex("name")},
+	MongoCollectionRegionName:          {uniqueIndex("name")},
+	MongoCollectionStoragePoolName:     {uniqueIndex("name"), index("region_id")},
+	MongoCollectionSubnetName:          {uniqueIndex("name"), index("project_id"), index("vnet_id"), index("labels.key", "labels.value")},
+	MongoCollectionTaskName:            {index("user_id"), index("status", "heartbeat_at")},
+	MongoCollectionTeamName:            {uniqueIndex("name")},
+	MongoCollectionTemplateName:        {index("name"), index("storage_pool_id")},
+	MongoCollectionTokenName:           {index("name"), index("agent_id"), index("legacy")},
+	MongoCollectionUserName:            {uniqueIndex("name"), uniqueIndex("email")},
+	MongoCollectionVNetName:            {uniqueIndex("name"), index("region_id")},
+	MongoCollectionVolumeName:          {index("name"), index("project_id"), index("storage_pool_id"), index("trash.purge_at"), index("labels.key", "labels.value")},
+	MongoCollectionWebhookName:         {index("project_id")},
+	MongoCollectionWebhookDeliveryName: {index("webhook_id", "-created_at"), index("status", "next_attempt_at"), index("created_at")},
+	MongoCollectionZoneName:            {uniqueIndex("name"), index("region_id")},
+}
+
+type IndexDrift struct {
+	Collection string
+	Index      string
+	Kind       string
+	Message    string
+}
+
+func (d IndexDrift) String() string {
+	return fmt.Sprintf("%s index %s on collection %s: %s", d.Kind, d.Index, d.Collection, d.Message)
+}
+
+func indexSpecificationKeys(spec mongo.IndexSpecification) (bson.D, error) {
+	var keys bson.D
+	err := bson.Unmarshal(spec.KeysDocument, &keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// normalize sort orders, whatever their numeric type
+	for i, e := range keys {
+		switch v := e.Value.(type) {
+		case int32:
+			keys[i].Value = int(v)
+		case int64:
+			keys[i].Value = int(v)
+		case float64:
+			keys[i].Value = int(v)
+		}
+	}
+	return keys, nil
+}
+
+// indexDrift compares collection's declared and existing indexes, returning the ones to be created and drifting ones
+func indexDrift(collection string, declared []MongoIndex, existing []mongo.IndexSpecification) ([]MongoIndex, []IndexDrift) {
+	specs := map[string]mongo.IndexSpecification{}
+	for _, spec := range existing {
+		specs[spec.Name] = spec
+	}
+
+	missing := []MongoIndex{}
+	drifts := []IndexDrift{}
+	for _, idx := range declared {
+		spec, ok := specs[idx.Name()]
+		if !ok {
+			missing = append(missing, idx)
+			continue
+		}
+		delete(specs, idx.Name())
+
+		keys, err := indexSpecificationKeys(spec)
+		unique := spec.Unique != nil && *spec.Unique
+		expires := spec.ExpireAfterSeconds != nil && *spec.ExpireAfterSeconds == 0
+		if err != nil || !slices.Equal(keys, idx.keys()) || unique != idx.Unique || expires != idx.Expires {
+			drifts = append(drifts, IndexDrift{
+				Collection: collection,
+				Index:      idx.Name(),
+				Kind:       IndexDriftMismatch,
+				Message:    fmt.Sprintf("existing index (keys %v, unique %t, expires %t) differs from expected one (keys %v, unique %t, expires %t)", keys, unique, expires, idx.keys(), idx.Unique, idx.Expires),
+			})
+		}
+	}
+
+	delete(specs, MongoIndexDefault)
+	for _, name := range slices.Sorted(maps.Keys(specs)) {
+		drifts = append(drifts, IndexDrift{
+			Collection: collection,
+			Index:      name,
+			Kind:       IndexDriftUndeclared,
+			Message:    "index is not declared by Kahuna",
+		})
+	}
+
+	return missing, drifts
+}
+
+// EnsureIndexes creates missing database indexes and reports the ones drifting from declaration
+func EnsureIndexes(dryRun bool) ([]IndexDrift, error) {
+	drifts := []IndexDrift{}
+
+	for _, collection := range slices.Sorted(maps.Keys(dbIndexes)) {
+		existing, err := GetDB().ListIndexes(collection)
+		if err != nil {
+			return nil, err
+		}
+
+		missing, diff := indexDrift(collection, dbIndexes[collection], existing)
+		for _, d := range diff {
+			klog.Warningf("DB index drift: %s", d.String())
+			drifts = append(drifts, d)
+		}
+
+		for _, idx := range missing {
+			if dryRun {
+				klog.Infof("[dry-run] DB index %s is to be created on collection %s", idx.Name(), collection)
+				drifts = append(drifts, IndexDrift{
+					Collection: collection,
+					Index:      idx.Name(),
+					Kind:       IndexDriftMissing,
+					Message:    "index is to be created",
+				})
+				continue
+			}
+
+			klog.Infof("Creating DB index %s on collection %s ...", idx.Name(), collection)
+			err := GetDB().CreateIndex(collection, idx.Name(), idx.keys(), idx.Unique, idx.Expires)
+			if err != nil {
+				// most likely duplicated values on unique index, report and carry on
+				d := IndexDrift{
+					Collection: collection,
+					Index:      idx.Name(),
+					Kind:       IndexDriftFailed,
+					Message:    err.Error(),
+				}
+				klog.Errorf("DB index drift: %s", d.String())
+				drifts = append(drifts, d)
+			}
+		}
+	}
+
+	return drifts, nil
+}
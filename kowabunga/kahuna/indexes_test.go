@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func testIndexSpecification(t *testing.T, name string, keys bson.D, unique bool) mongo.IndexSpecification {
+	raw, err := bson.Marshal(keys)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	return mongo.IndexSpecification{
+		Name:         name,
+		KeysDocument: raw,
+		Unique:       &unique,
+	}
+}
+
+func TestIndexName(t *testing.T) {
+	if index("name").Name() != "name_1" {
+		t.Fatalf("unexpected index name: %s", index("name").Name())
+	}
+	if index("user_id", "-timestamp").Name() != "user_id_1_timestamp_-1" {
+		t.Fatalf("unexpected index name: %s", index("user_id", "-timestamp").Name())
+	}
+}
+
+func TestIndexDrift(t *testing.T) {
+	declared := []MongoIndex{uniqueIndex("name"), uniqueIndex("email"), index("project_id")}
+	existing := []mongo.IndexSpecification{
+		testIndexSpecification(t, MongoIndexDefault, bson.D{bson.E{Key: "_id", Value: int32(1)}}, false),
+		testIndexSpecification(t, "name_1", bson.D{bson.E{Key: "name", Value: int32(1)}}, true),
+		testIndexSpecification(t, "email_1", bson.D{bson.E{Key: "email", Value: int32(1)}}, false),
+		testIndexSpecification(t, "custom", bson.D{bson.E{Key: "tags", Value: int32(1)}}, false),
+	}
+
+	missing, drifts := indexDrift(MongoCollectionUserName, declared, existing)
+	if len(missing) != 1 || missing[0].Name() != "project_id_1" {
+		t.Fatalf("unexpected missing indexes: %+v", missing)
+	}
+	if len(drifts) != 2 {
+		t.Fatalf("unexpected drifts: %+v", drifts)
+	}
+	if drifts[0].Kind != IndexDriftMismatch || drifts[0].Index != "email_1" {
+		t.Fatalf("non-unique index was not reported: %+v", drifts[0])
+	}
+	if drifts[1].Kind != IndexDriftUndeclared || drifts[1].Index != "custom" {
+		t.Fatalf("undeclared index was not reported: %+v", drifts[1])
+	}
+}
+
+func TestIndexDriftExpiring(t *testing.T) {
+	declared := []MongoIndex{expiringIndex("expires_at")}
+
+	spec := testIndexSpecification(t, "expires_at_1", bson.D{bson.E{Key: "expires_at", Value: int32(1)}}, false)
+	_, drifts := indexDrift(MongoCollectionIdempotencyName, declared, []mongo.IndexSpecification{spec})
+	if len(drifts) != 1 || drifts[0].Kind != IndexDriftMismatch {
+		t.Fatalf("non-expiring index was not reported: %+v", drifts)
+	}
+
+	expireAfter := int32(0)
+	spec.ExpireAfterSeconds = &expireAfter
+	_, drifts = indexDrift(MongoCollectionIdempotencyName, declared, []mongo.IndexSpecification{spec})
+	if len(drifts) != 0 {
+		t.Fatalf("unexpected drifts: %+v", drifts)
+	}
+}
@@ -13,8 +13,14 @@ import (
 	"github.com/kowabunga-cloud/common/metadata"
 )
 
-func GetInstanceMetadata(srcIp, instanceId string) (metadata.InstanceMetadata, error) {
-	meta := metadata.InstanceMetadata{}
+// KahunaInstanceMetadata extends common instance metadata with resource labels
+type KahunaInstanceMetadata struct {
+	metadata.InstanceMetadata
+	Labels map[string]string `json:"labels"`
+}
+
+func GetInstanceMetadata(srcIp, instanceId string) (KahunaInstanceMetadata, error) {
+	meta := KahunaInstanceMetadata{}
 	var err error
 	i, err := FindInstanceByIP(srcIp)
 	if err != nil {
@@ -32,6 +38,7 @@ func GetInstanceMetadata(srcIp, instanceId string) (metadata.InstanceMetadata, e
 	meta.Memory = bytesToGB(i.Memory)
 	meta.LocalIPv4 = i.LocalIP
 	meta.Cost = fmt.Sprintf("%f %s", i.Cost.Price, i.Cost.Currency)
+	meta.Labels = i.GetLabels()
 
 	for _, volumeId := range i.Volumes() {
 		v, err := FindVolumeByID(volumeId)
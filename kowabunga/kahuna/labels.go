@@ -0,0 +1,603 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * Resources labels are free-form key/value pairs, set at resource creation
+ * or update time, that list endpoints can filter on, through Kubernetes-style
+ * label selectors (e.g. ?labels=env=prod,tier!=db).
+ *
+ * SDK-generated models and controllers being unaware of labels, these are
+ * extracted from requests payload and injected into responses ones by the
+ * labels middleware. Updated resources get their labels saved along with
+ * them, created ones right after (or by the task creating them), requests
+ * failing if labels could not be set.
+ *
+ * Labels keys may hold dots (e.g. app.kubernetes.io/name), they are then
+ * stored as an array of key/value pairs rather than as a document.
+ */
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/kowabunga-cloud/common/klog"
+)
+
+const (
+	LabelsField = "labels"
+
+	HttpRequestContextLabels = "labels"
+
+	LabelNameMaxLength   = 63
+	LabelPrefixMaxLength = 253
+
+	LabelSelectorOpEquals    = "="
+	LabelSelectorOpDEquals   = "=="
+	LabelSelectorOpNotEquals = "!="
+	LabelSelectorOpIn        = "in"
+	LabelSelectorOpNotIn     = "notin"
+	LabelSelectorOpExists    = "exists"
+	LabelSelectorOpNotExists = "!"
+)
+
+var (
+	labelNameRegex   = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	labelPrefixRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+	labelSetRegex    = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+)
+
+// labelledRouteOperations are routes whose resources labels are exposed
+var labelledRouteOperations = []string{
+	"Create",
+	"Update",
+	"Read",
+	"List",
+}
+
+// labelsRouteOperations are routes allowed to set resources labels
+var labelsRouteOperations = []string{
+	"Create",
+	"Update",
+}
+
+type ResourceLabel struct {
+	Key   string `bson:"key"`
+	Value string `bson:"value"`
+}
+
+// GetLabels returns resource labels as a key/value map
+func (r *Resource) GetLabels() map[string]string {
+	labels := map[string]string{}
+	for _, l := range r.Labels {
+		labels[l.Key] = l.Value
+	}
+	return labels
+}
+
+// newResourceLabels returns labels as sorted key/value pairs
+func newResourceLabels(labels map[string]string) []ResourceLabel {
+	res := []ResourceLabel{}
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		res = append(res, ResourceLabel{
+			Key:   k,
+			Value: labels[k],
+		})
+	}
+	return res
+}
+
+func validateLabelName(name string) bool {
+	return len(name) <= LabelNameMaxLength && labelNameRegex.MatchString(name)
+}
+
+// ValidateLabelKey ensures key is an optionally prefixed name, e.g. app.kubernetes.io/name
+func ValidateLabelKey(key string) error {
+	name := key
+	prefix, n, found := strings.Cut(key, "/")
+	if found {
+		if len(prefix) > LabelPrefixMaxLength || !labelPrefixRegex.MatchString(prefix) {
+			return fmt.Errorf("invalid label key prefix '%s'", prefix)
+		}
+		name = n
+	}
+
+	if !validateLabelName(name) {
+		return fmt.Errorf("invalid label key '%s'", key)
+	}
+	return nil
+}
+
+// ValidateLabelValue ensures value is either empty or a valid name
+func ValidateLabelValue(value string) error {
+	if value != "" && !validateLabelName(value) {
+		return fmt.Errorf("invalid label value '%s'", value)
+	}
+	return nil
+}
+
+func ValidateLabels(labels map[string]string) error {
+	for k, v := range labels {
+		err := ValidateLabelKey(k)
+		if err != nil {
+			return err
+		}
+		err = ValidateLabelValue(v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitLabelSelector splits selector into requirements, ignoring commas from set-based values
+func splitLabelSelector(selector string) []string {
+	requirements := []string{}
+	depth := 0
+	start := 0
+	for i, c := range selector {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				requirements = append(requirements, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(requirements, selector[start:])
+}
+
+func labelMatch(key string, value any) bson.D {
+	match := bson.D{bson.E{Key: "key", Value: key}}
+	if value != nil {
+		match = append(match, bson.E{Key: "value", Value: value})
+	}
+	return bson.D{bson.E{Key: LabelsField, Value: bson.D{bson.E{Key: "$elemMatch", Value: match}}}}
+}
+
+func labelNotMatch(key string, value any) bson.D {
+	match := labelMatch(key, value)[0].Value
+	return bson.D{bson.E{Key: LabelsField, Value: bson.D{bson.E{Key: "$not", Value: match}}}}
+}
+
+// labelRequirementFilter returns the MongoDB filter for a single selector requirement
+func labelRequirementFilter(req string) (bson.D, error) {
+	// set-based requirement, e.g. env in (prod,staging)
+	if m := labelSetRegex.FindStringSubmatch(req); m != nil {
+		key := m[1]
+		err := ValidateLabelKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		values := []string{}
+		for _, v := range strings.Split(m[3], ",") {
+			v = strings.TrimSpace(v)
+			err := ValidateLabelValue(v)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+
+		set := bson.D{bson.E{Key: "$in", Value: values}}
+		if m[2] == LabelSelectorOpNotIn {
+			return labelNotMatch(key, set), nil
+		}
+		return labelMatch(key, set), nil
+	}
+
+	// equality-based requirements, e.g. env=prod, env==prod or env!=prod
+	op := LabelSelectorOpExists
+	key := req
+	value := ""
+	for _, o := range []string{LabelSelectorOpNotEquals, LabelSelectorOpDEquals, LabelSelectorOpEquals} {
+		k, v, found := strings.Cut(req, o)
+		if found {
+			op = o
+			key = strings.TrimSpace(k)
+			value = strings.TrimSpace(v)
+			break
+		}
+	}
+
+	// existence requirements, e.g. env or !env
+	if op == LabelSelectorOpExists && strings.HasPrefix(key, LabelSelectorOpNotExists) {
+		op = LabelSelectorOpNotExists
+		key = strings.TrimSpace(strings.TrimPrefix(key, LabelSelectorOpNotExists))
+	}
+
+	err := ValidateLabelKey(key)
+	if err != nil {
+		return nil, err
+	}
+	err = ValidateLabelValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case LabelSelectorOpEquals, LabelSelectorOpDEquals:
+		return labelMatch(key, value), nil
+	case LabelSelectorOpNotEquals:
+		return labelNotMatch(key, value), nil
+	case LabelSelectorOpNotExists:
+		return labelNotMatch(key, nil), nil
+	}
+	return labelMatch(key, nil), nil
+}
+
+// ParseLabelSelector converts a Kubernetes-style label selector into a MongoDB filter,
+// resources having to satisfy all of its requirements
+func ParseLabelSelector(selector string) (bson.D, error) {
+	requirements := bson.A{}
+	for _, req := range splitLabelSelector(selector) {
+		req = strings.TrimSpace(req)
+		if req == "" {
+			continue
+		}
+
+		filter, err := labelRequirementFilter(req)
+		if err != nil {
+			return nil, err
+		}
+		requirements = append(requirements, filter)
+	}
+
+	if len(requirements) == 0 {
+		return nil, fmt.Errorf("empty label selector")
+	}
+
+	return bson.D{bson.E{Key: "$and", Value: requirements}}, nil
+}
+
+// SetResourceLabels replaces the labels of a resource
+func SetResourceLabels(collection, id string, labels map[string]string) error {
+	oid, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	update := bson.D{bson.E{Key: "$unset", Value: bson.D{bson.E{Key: LabelsField, Value: ""}}}}
+	if len(labels) > 0 {
+		update = bson.D{bson.E{Key: "$set", Value: bson.D{bson.E{Key: LabelsField, Value: newResourceLabels(labels)}}}}
+	}
+
+	err = GetDB().Patch(collection, oid, update)
+	if err != nil {
+		return err
+	}
+
+	return propagateResourceLabels(collection, id, labels)
+}
+
+// propagateResourceLabels sets labels of resources inheriting them
+func propagateResourceLabels(collection, id string, labels map[string]string) error {
+	// Kompute's instance inherits its labels, so that these show up in instance metadata
+	if collection == MongoCollectionKomputeName {
+		k, err := FindKomputeByID(id)
+		if err != nil {
+			return err
+		}
+		return SetResourceLabels(MongoCollectionInstanceName, k.InstanceID, labels)
+	}
+
+	return nil
+}
+
+// requestLabels are the labels request sets on the resource it creates or updates
+type requestLabels struct {
+	collection string
+	labels     map[string]string
+	pinned     bool // set on resource, to be saved along with it
+	applied    bool // saved, along with inherited ones
+}
+
+func ctxSetLabels(ctx context.Context, rl *requestLabels) context.Context {
+	return ctxSet(ctx, HttpRequestContextLabels, rl)
+}
+
+func ctxGetLabels(ctx context.Context) *requestLabels {
+	value := ctxGet(ctx, HttpRequestContextLabels)
+	if value == nil {
+		return nil
+	}
+	return value.(*requestLabels)
+}
+
+// ctxPinLabels sets request's labels, if any, on the resource service is about to update
+func ctxPinLabels(ctx context.Context, r *Resource) {
+	rl := ctxGetLabels(ctx)
+	if rl == nil || rl.labels == nil {
+		return
+	}
+
+	r.Labels = nil
+	if len(rl.labels) > 0 {
+		r.Labels = newResourceLabels(rl.labels)
+	}
+	rl.pinned = true
+}
+
+// ctxApplyLabels sets request's labels, if any, on the resource service has just created
+func ctxApplyLabels(ctx context.Context, id string) error {
+	rl := ctxGetLabels(ctx)
+	if rl == nil || rl.labels == nil {
+		return nil
+	}
+
+	err := SetResourceLabels(rl.collection, id, rl.labels)
+	if err != nil {
+		return fmt.Errorf("unable to set %s labels: %w", rl.collection, err)
+	}
+	rl.applied = true
+	return nil
+}
+
+// FindResourcesLabels returns the labels of a collection's resources, by ID
+func FindResourcesLabels(collection string, ids []string) (map[string]map[string]string, error) {
+	var docs []struct {
+		Resource `bson:"inline"`
+	}
+	err := GetDB().FindAllByFilter(collection, listFilterByIDs(ids), nil, 0, &docs)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]map[string]string{}
+	for _, d := range docs {
+		labels[d.String()] = d.GetLabels()
+	}
+	return labels, nil
+}
+
+// routeResourceCollection returns the collection of the resources a route deals with,
+// as inferred from the last static part of its path (e.g. /project/{projectId}/zone/{zoneId}/instances)
+func routeResourceCollection(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+
+	parts := strings.Split(strings.TrimPrefix(tpl, SdkBaseRoute), "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if parts[i] == "" || strings.HasPrefix(parts[i], "{") {
+			continue
+		}
+
+		// singular or plural forms
+		for _, name := range []string{parts[i], strings.TrimSuffix(parts[i], "s"), strings.TrimSuffix(parts[i], "es")} {
+			collection, ok := revisionRouteVarCollections[name+"Id"]
+			if ok {
+				return collection
+			}
+		}
+		return ""
+	}
+
+	return ""
+}
+
+// extractLabels removes labels from request payload, returning them
+func extractLabels(payload []byte) (map[string]string, []byte, error) {
+	var body map[string]json.RawMessage
+	err := json.Unmarshal(payload, &body)
+	if err != nil {
+		// not our business, let SDK controllers complain about it
+		return nil, payload, nil
+	}
+
+	raw, ok := body[LabelsField]
+	if !ok {
+		return nil, payload, nil
+	}
+
+	labels := map[string]string{}
+	err = json.Unmarshal(raw, &labels)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid labels: %w", err)
+	}
+	err = ValidateLabels(labels)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	delete(body, LabelsField)
+	payload, err = json.Marshal(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return labels, payload, nil
+}
+
+func labelsModelID(obj any) string {
+	m, ok := obj.(map[string]any)
+	if !ok {
+		return ""
+	}
+	id, _ := m["id"].(string)
+	return id
+}
+
+// injectLabels adds labels to resources model(s) from response payload, returning whether it did
+func injectLabels(collection string, payload any) bool {
+	models := []any{payload}
+	list, ok := payload.([]any)
+	if ok {
+		models = list
+	}
+
+	ids := []string{}
+	for _, m := range models {
+		id := labelsModelID(m)
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return false
+	}
+
+	labels, err := FindResourcesLabels(collection, ids)
+	if err != nil {
+		klog.Error(err)
+		return false
+	}
+
+	for _, m := range models {
+		id := labelsModelID(m)
+		if id == "" {
+			continue
+		}
+		m.(map[string]any)[LabelsField] = labels[id]
+	}
+
+	return true
+}
+
+// labelsResponseWriter holds response back, until labels are applied and exposed
+type labelsResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *labelsResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *labelsResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+// apply saves request's labels not already saved by service, if any
+func (rl *requestLabels) apply(id string) error {
+	switch {
+	case rl.labels == nil || rl.applied:
+		return nil
+	case rl.pinned:
+		return propagateResourceLabels(rl.collection, id, rl.labels)
+	default:
+		return SetResourceLabels(rl.collection, id, rl.labels)
+	}
+}
+
+func (w *labelsResponseWriter) flush(r *http.Request, rl *requestLabels) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	payload := w.body.Bytes()
+	if w.status >= http.StatusOK && w.status < http.StatusMultipleChoices && len(payload) > 0 {
+		var body any
+		d := json.NewDecoder(bytes.NewReader(payload))
+		d.UseNumber()
+		if d.Decode(&body) == nil {
+			// asynchronous creation tasks set labels themselves
+			id := labelsModelID(body)
+			if id != "" && w.status != http.StatusAccepted {
+				err := rl.apply(id)
+				if err != nil {
+					klog.Errorf("Unable to set labels of %s/%s: %v", rl.collection, id, err)
+					HttpMiddlewareError(w.ResponseWriter, r, HttpServerError, err)
+					return
+				}
+			}
+
+			if injectLabels(rl.collection, body) {
+				var b bytes.Buffer
+				if json.NewEncoder(&b).Encode(body) == nil {
+					payload = b.Bytes()
+				}
+			}
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	_, err := w.ResponseWriter.Write(payload)
+	if err != nil {
+		klog.Error(err)
+	}
+}
+
+func isLabelledRoute(name string, operations []string) bool {
+	for _, op := range operations {
+		if strings.HasPrefix(name, op) {
+			return true
+		}
+	}
+	return false
+}
+
+// labelsMiddleware sets resources labels from requests payload and exposes them in responses one
+func labelsMiddleware(next http.Handler, name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isLabelledRoute(name, labelledRouteOperations) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		collection := routeResourceCollection(r)
+		if collection == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rl := &requestLabels{
+			collection: collection,
+		}
+		if isLabelledRoute(name, labelsRouteOperations) && r.Body != nil {
+			payload, err := io.ReadAll(r.Body)
+			if err != nil {
+				HttpMiddlewareError(w, r, HttpBadParams, err)
+				return
+			}
+			_ = r.Body.Close()
+
+			rl.labels, payload, err = extractLabels(payload)
+			if err != nil {
+				HttpMiddlewareError(w, r, HttpBadParams, err)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(payload))
+			r.ContentLength = int64(len(payload))
+		}
+
+		lw := &labelsResponseWriter{
+			ResponseWriter: w,
+		}
+		r = r.WithContext(ctxSetLabels(r.Context(), rl))
+		next.ServeHTTP(lw, r)
+		lw.flush(r, rl)
+	})
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLabelKeys(t *testing.T) {
+	for _, key := range []string{"env", "app.kubernetes.io/name", "tier_1", "a"} {
+		if err := ValidateLabelKey(key); err != nil {
+			t.Fatalf("valid label key rejected: %s", err.Error())
+		}
+	}
+	for _, key := range []string{"", "-env", "env-", "Acme.com/env", "acme.com/", "a/b/c", "env=prod"} {
+		if err := ValidateLabelKey(key); err == nil {
+			t.Fatalf("invalid label key accepted: %s", key)
+		}
+	}
+}
+
+func TestLabelSelector(t *testing.T) {
+	requirements := splitLabelSelector("env=prod,tier notin (db,cache),!legacy")
+	if len(requirements) != 3 || requirements[1] != "tier notin (db,cache)" {
+		t.Fatalf("unexpected requirements: %v", requirements)
+	}
+
+	filter, err := ParseLabelSelector("env=prod, tier!=db, region in (eu-west, eu-south), backup, !legacy")
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	expected := `{"$and":[` +
+		`{"labels":{"$elemMatch":{"key":"env","value":"prod"}}},` +
+		`{"labels":{"$not":{"$elemMatch":{"key":"tier","value":"db"}}}},` +
+		`{"labels":{"$elemMatch":{"key":"region","value":{"$in":["eu-west","eu-south"]}}}},` +
+		`{"labels":{"$elemMatch":{"key":"backup"}}},` +
+		`{"labels":{"$not":{"$elemMatch":{"key":"legacy"}}}}` +
+		`]}`
+	if filter.String() != expected {
+		t.Fatalf("unexpected filter: %v", filter)
+	}
+
+	for _, selector := range []string{"", "env=prod!", "env in (a b)", "=prod"} {
+		if _, err := ParseLabelSelector(selector); err == nil {
+			t.Fatalf("invalid selector accepted: %s", selector)
+		}
+	}
+}
+
+func TestExtractLabels(t *testing.T) {
+	labels, payload, err := extractLabels([]byte(`{"name":"web","labels":{"env":"prod"}}`))
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if len(labels) != 1 || labels["env"] != "prod" || string(payload) != `{"name":"web"}` {
+		t.Fatalf("unexpected labels extraction: %v, %s", labels, payload)
+	}
+
+	labels, payload, err = extractLabels([]byte(`{"name":"web"}`))
+	if err != nil || labels != nil || string(payload) != `{"name":"web"}` {
+		t.Fatalf("unexpected labels extraction: %v, %s", labels, payload)
+	}
+
+	_, _, err = extractLabels([]byte(`{"name":"web","labels":{"env":"-prod"}}`))
+	if err == nil {
+		t.Fatalf("invalid label value accepted")
+	}
+}
+
+func TestPinLabels(t *testing.T) {
+	rl := &requestLabels{
+		collection: MongoCollectionInstanceName,
+		labels:     map[string]string{"env": "prod"},
+	}
+	ctx := ctxSetLabels(context.Background(), rl)
+
+	r := Resource{Labels: []ResourceLabel{{Key: "tier", Value: "db"}}}
+	err := ctxPinRevision(ctx, &r)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if !rl.pinned || len(r.Labels) != 1 || r.GetLabels()["env"] != "prod" {
+		t.Fatalf("labels have not been set on updated resource: %v", r.Labels)
+	}
+
+	// clearing labels
+	rl = &requestLabels{labels: map[string]string{}}
+	ctxPinLabels(ctxSetLabels(context.Background(), rl), &r)
+	if !rl.pinned || r.Labels != nil {
+		t.Fatalf("labels have not been cleared from updated resource: %v", r.Labels)
+	}
+
+	// no labels requested, resource's ones are left untouched
+	r.Labels = newResourceLabels(map[string]string{"tier": "db"})
+	rl = &requestLabels{}
+	ctxPinLabels(ctxSetLabels(context.Background(), rl), &r)
+	if rl.pinned || len(r.Labels) != 1 {
+		t.Fatalf("unrequested labels have been changed: %v", r.Labels)
+	}
+	if rl.apply(r.String()) != nil {
+		t.Fatalf("unrequested labels have been applied")
+	}
+}
@@ -0,0 +1,369 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * List endpoints query support: cursor-based pagination, field filters,
+ * sort order and optional expansion of resource IDs into full models.
+ *
+ * Resources can also be filtered on their labels, see labels.go.
+ *
+ * Query parameters are parsed once by the list query middleware and handed
+ * over to API services through request context, as SDK-generated controllers
+ * do not forward them.
+ */
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/kowabunga-cloud/kowabunga/kowabunga/sdk"
+)
+
+const (
+	HttpHeaderNextCursor = "X-Next-Cursor"
+
+	ListQueryParamLimit  = "limit"
+	ListQueryParamCursor = "cursor"
+	ListQueryParamSort   = "sort"
+	ListQueryParamExpand = "expand"
+	ListQueryParamLabels = "labels"
+
+	ListQueryMaxLimit = 1000
+
+	ListSortDescendingPrefix = "-"
+)
+
+// query parameter -> document field
+var listQuerySortFields = map[string]string{
+	"id":         "_id",
+	"name":       "name",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// query parameter -> document field
+var listQueryFilterFields = map[string]string{
+	"name":       "name",
+	"project_id": "project_id",
+	"kaktus_id":  "kaktus_id",
+	"tags":       "tags",
+}
+
+type ListQuery struct {
+	Limit   int64
+	Sort    string
+	Order   int
+	Filters bson.D
+	Expand  bool
+
+	cursor     *listCursor
+	nextCursor string
+}
+
+type listCursor struct {
+	Sort  string        `bson:"s"`
+	Value bson.RawValue `bson:"v"`
+	ID    bson.ObjectID `bson:"id"`
+}
+
+func NewListQuery() *ListQuery {
+	return &ListQuery{
+		Sort:    "_id",
+		Order:   1,
+		Filters: bson.D{},
+	}
+}
+
+func ParseListQuery(r *http.Request) (*ListQuery, error) {
+	q := NewListQuery()
+	query := r.URL.Query()
+
+	if query.Has(ListQueryParamLimit) {
+		limit, err := strconv.ParseInt(query.Get(ListQueryParamLimit), 10, 64)
+		if err != nil || limit < 0 {
+			return nil, &sdk.ParsingError{Param: ListQueryParamLimit, Err: fmt.Errorf("invalid limit value")}
+		}
+		q.Limit = min(limit, ListQueryMaxLimit)
+	}
+
+	if query.Has(ListQueryParamSort) {
+		param := query.Get(ListQueryParamSort)
+		if strings.HasPrefix(param, ListSortDescendingPrefix) {
+			q.Order = -1
+			param = strings.TrimPrefix(param, ListSortDescendingPrefix)
+		}
+		field, ok := listQuerySortFields[param]
+		if !ok {
+			return nil, &sdk.ParsingError{Param: ListQueryParamSort, Err: fmt.Errorf("unsupported sort field '%s'", param)}
+		}
+		q.Sort = field
+	}
+
+	if query.Has(ListQueryParamCursor) {
+		c, err := decodeListCursor(query.Get(ListQueryParamCursor))
+		if err != nil || c.Sort != q.Sort {
+			return nil, &sdk.ParsingError{Param: ListQueryParamCursor, Err: fmt.Errorf("invalid cursor")}
+		}
+		q.cursor = c
+	}
+
+	if query.Has(ListQueryParamExpand) {
+		expand, err := strconv.ParseBool(query.Get(ListQueryParamExpand))
+		if err != nil {
+			return nil, &sdk.ParsingError{Param: ListQueryParamExpand, Err: err}
+		}
+		q.Expand = expand
+	}
+
+	if query.Has(ListQueryParamLabels) {
+		selector, err := ParseLabelSelector(query.Get(ListQueryParamLabels))
+		if err != nil {
+			return nil, &sdk.ParsingError{Param: ListQueryParamLabels, Err: err}
+		}
+		q.Filters = append(q.Filters, selector...)
+	}
+
+	for _, param := range slices.Sorted(maps.Keys(listQueryFilterFields)) {
+		field := listQueryFilterFields[param]
+		if !query.Has(param) {
+			continue
+		}
+		value := query.Get(param)
+		if field == "tags" {
+			// resources must hold all requested tags
+			tags := strings.Split(value, ",")
+			q.Filters = append(q.Filters, bson.E{Key: field, Value: bson.D{bson.E{Key: "$all", Value: tags}}})
+			continue
+		}
+		q.Filters = append(q.Filters, bson.E{Key: field, Value: value})
+	}
+
+	return q, nil
+}
+
+func decodeListCursor(cursor string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var c listCursor
+	err = bson.Unmarshal(data, &c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func encodeListCursor(sort string, doc any) (string, error) {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := bson.Raw(raw).LookupErr("_id")
+	if err != nil {
+		return "", err
+	}
+	value, err := bson.Raw(raw).LookupErr(sort)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := bson.Marshal(listCursor{
+		Sort:  sort,
+		Value: value,
+		ID:    id.ObjectID(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// filter returns the MongoDB filter for requested page, on top of a base filter
+func (q *ListQuery) filter(base bson.D) bson.D {
+	filter := bson.D{}
+	filter = append(filter, base...)
+	filter = append(filter, q.Filters...)
+
+	if q.cursor == nil {
+		return filter
+	}
+
+	// keyset pagination, resume right after cursor's document
+	op := "$gt"
+	if q.Order < 0 {
+		op = "$lt"
+	}
+
+	if q.Sort == "_id" {
+		return append(filter, bson.E{Key: "_id", Value: bson.D{bson.E{Key: op, Value: q.cursor.ID}}})
+	}
+
+	after := bson.A{
+		bson.D{bson.E{Key: q.Sort, Value: bson.D{bson.E{Key: op, Value: q.cursor.Value}}}},
+		bson.D{
+			bson.E{Key: q.Sort, Value: q.cursor.Value},
+			bson.E{Key: "_id", Value: bson.D{bson.E{Key: op, Value: q.cursor.ID}}},
+		},
+	}
+	return append(filter, bson.E{Key: "$or", Value: after})
+}
+
+func (q *ListQuery) sort() bson.D {
+	sort := bson.D{bson.E{Key: q.Sort, Value: q.Order}}
+	if q.Sort != "_id" {
+		// ensure stable ordering for pagination
+		sort = append(sort, bson.E{Key: "_id", Value: q.Order})
+	}
+	return sort
+}
+
+func (q *ListQuery) NextCursor() string {
+	return q.nextCursor
+}
+
+// listFilterByIDs restricts a list query to a given set of resources
+func listFilterByIDs(ids []string) bson.D {
+	oids := []bson.ObjectID{}
+	for _, id := range ids {
+		oid, err := bson.ObjectIDFromHex(id)
+		if err != nil {
+			continue
+		}
+		oids = append(oids, oid)
+	}
+	return bson.D{bson.E{Key: "_id", Value: bson.D{bson.E{Key: "$in", Value: oids}}}}
+}
+
+// listFilterByKey restricts a list query to resources with matching key
+func listFilterByKey(key, value string) bson.D {
+	return bson.D{bson.E{Key: key, Value: value}}
+}
+
+func FindResourcesByQuery[T any](collection string, base bson.D, q *ListQuery) ([]T, error) {
+	// fetch one extra document to find out whether there's a next page
+	limit := q.Limit
+	if limit > 0 {
+		limit++
+	}
+
+	res := []T{}
+	err := GetDB().FindAllByFilter(collection, q.filter(base), q.sort(), limit, &res)
+	if err != nil {
+		return res, err
+	}
+
+	if q.Limit > 0 && int64(len(res)) > q.Limit {
+		res = res[:q.Limit]
+		q.nextCursor, err = encodeListCursor(q.Sort, res[len(res)-1])
+	}
+
+	return res, err
+}
+
+type listableResource[T any, M any] interface {
+	*T
+	String() string
+	Model() M
+}
+
+// listModel returns resource's model as exposed in lists, which may hide sensitive fields
+func listModel[T any, M any, PT listableResource[T, M]](r *T) M {
+	lm, ok := any(PT(r)).(interface{ ListModel() M })
+	if ok {
+		return lm.ListModel()
+	}
+	return PT(r).Model()
+}
+
+// HttpListResources returns the (paginated, filtered, sorted) list of resources IDs, or their models if expanded
+func HttpListResources[T any, M any, PT listableResource[T, M]](ctx context.Context, collection string, base bson.D) (sdk.ImplResponse, error) {
+	q := ctxGetListQuery(ctx)
+
+	// deleted resources only show up in trash
+	filter := bson.D{}
+	filter = append(filter, base...)
+	filter = append(filter, listFilterNotTrashed()...)
+
+	resources, err := FindResourcesByQuery[T](collection, filter, q)
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	if q.Expand {
+		payload := []M{}
+		for i := range resources {
+			payload = append(payload, listModel[T, M, PT](&resources[i]))
+		}
+		return HttpOK(payload)
+	}
+
+	var payload []string
+	for i := range resources {
+		payload = append(payload, PT(&resources[i]).String())
+	}
+
+	return HttpOK(payload)
+}
+
+// listResponseWriter exposes next page cursor, once service has been processed
+type listResponseWriter struct {
+	http.ResponseWriter
+	q           *ListQuery
+	wroteHeader bool
+}
+
+func (w *listResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if w.q.NextCursor() != "" {
+			w.Header().Set(HttpHeaderNextCursor, w.q.NextCursor())
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *listResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func listQueryMiddleware(next http.Handler, name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || !strings.HasPrefix(name, "List") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		q, err := ParseListQuery(r)
+		if err != nil {
+			sdk.DefaultErrorHandler(w, r, err, nil)
+			return
+		}
+
+		lw := &listResponseWriter{
+			ResponseWriter: w,
+			q:              q,
+		}
+		next.ServeHTTP(lw, r.WithContext(ctxSetListQuery(r.Context(), q)))
+	})
+}
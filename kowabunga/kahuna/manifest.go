@@ -0,0 +1,978 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * Declarative project manifests: a manifest describes the desired set of a
+ * project's resources, keyed by name. Planning compares it against current
+ * state and results in a list of creations, updates and deletions, which can
+ * then be applied through the very same services the regular API relies on.
+ *
+ * Only manifest sections which are present are reconciled, an omitted section
+ * leaves related resources untouched while an empty one deletes them all.
+ * Likewise, unset resource properties are left untouched. Placement (zone,
+ * region, storage pool, template, NFS) is only considered at creation time.
+ */
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kowabunga-cloud/common/klog"
+	"github.com/kowabunga-cloud/kowabunga/kowabunga/sdk"
+)
+
+const (
+	ManifestActionCreate = "create"
+	ManifestActionUpdate = "update"
+	ManifestActionDelete = "delete"
+
+	ManifestResultDone    = "done"
+	ManifestResultFailed  = "failed"
+	ManifestResultSkipped = "skipped"
+
+	ManifestKindKawaii    = "kawaii"
+	ManifestKindVolume    = "volume"
+	ManifestKindKompute   = "kompute"
+	ManifestKindKylo      = "kylo"
+	ManifestKindKonvey    = "konvey"
+	ManifestKindDnsRecord = "dns_record"
+
+	ErrManifestDuplicateName = "manifest has duplicate resource name"
+	ErrManifestNoPlacement   = "manifest resource has no placement"
+)
+
+type ManifestKompute struct {
+	sdk.Kompute
+	Zone     string `json:"zone,omitempty"`
+	Pool     string `json:"pool,omitempty"`
+	Template string `json:"template,omitempty"`
+	Public   bool   `json:"public,omitempty"`
+}
+
+type ManifestVolume struct {
+	sdk.Volume
+	Region   string `json:"region,omitempty"`
+	Pool     string `json:"pool,omitempty"`
+	Template string `json:"template,omitempty"`
+}
+
+// ManifestKawaii is project's Internet gateway, there's at most one per region
+type ManifestKawaii struct {
+	Region      string               `json:"region"`
+	Description string               `json:"description,omitempty"`
+	Firewall    sdk.KawaiiFirewall   `json:"firewall,omitempty"`
+	Dnat        []sdk.KawaiiDNatRule `json:"dnat,omitempty"`
+}
+
+type ManifestKonvey struct {
+	sdk.Konvey
+	Region string `json:"region,omitempty"`
+	Zone   string `json:"zone,omitempty"`
+}
+
+type ManifestKylo struct {
+	sdk.Kylo
+	Region string `json:"region,omitempty"`
+	Nfs    string `json:"nfs,omitempty"`
+}
+
+type ManifestDnsRecord struct {
+	sdk.DnsRecord
+}
+
+type ProjectManifest struct {
+	Kawaiis    []ManifestKawaii    `json:"kawaiis,omitempty"`
+	Volumes    []ManifestVolume    `json:"volumes,omitempty"`
+	Komputes   []ManifestKompute   `json:"komputes,omitempty"`
+	Kylos      []ManifestKylo      `json:"kylos,omitempty"`
+	Konveys    []ManifestKonvey    `json:"konveys,omitempty"`
+	DnsRecords []ManifestDnsRecord `json:"dns_records,omitempty"`
+}
+
+type manifestRunFunc func(ctx context.Context) (sdk.ImplResponse, error)
+
+type ManifestAction struct {
+	Action  string   `json:"action"`
+	Kind    string   `json:"kind"`
+	Name    string   `json:"name"`
+	Id      string   `json:"id,omitempty"`
+	Changes []string `json:"changes,omitempty"`
+	Result  string   `json:"result,omitempty"`
+	Error   string   `json:"error,omitempty"`
+
+	run manifestRunFunc
+}
+
+type ManifestPlan struct {
+	Project string           `json:"project"`
+	Actions []ManifestAction `json:"actions"`
+}
+
+// DecodeProjectManifest reads a YAML or JSON (as a YAML subset) manifest
+func DecodeProjectManifest(r io.Reader) (*ProjectManifest, error) {
+	var doc any
+	err := yaml.NewDecoder(r).Decode(&doc)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	// manifest types are described by their JSON representation
+	content, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var m ProjectManifest
+	d := json.NewDecoder(bytes.NewReader(content))
+	d.DisallowUnknownFields()
+	err = d.Decode(&m)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// EncodeProjectManifestYAML converts manifest to YAML, keeping its JSON keys
+func EncodeProjectManifestYAML(m *ProjectManifest) ([]byte, error) {
+	content, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc any
+	err = json.Unmarshal(content, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(doc)
+}
+
+// manifestIsUnset tells whether a manifest property has been left unset
+func manifestIsUnset(v []byte) bool {
+	switch string(v) {
+	case "null", "[]", "{}", `""`, "0", "false":
+		return true
+	}
+	return false
+}
+
+// diff records field as changed if its desired value is set and differs from current one
+func (a *ManifestAction) diff(field string, want, have any) bool {
+	w, _ := json.Marshal(want)
+	h, _ := json.Marshal(have)
+	if manifestIsUnset(w) || bytes.Equal(w, h) {
+		return false
+	}
+	a.Changes = append(a.Changes, field)
+	return true
+}
+
+func (p *ManifestPlan) add(a ManifestAction) {
+	if a.Action == ManifestActionUpdate && len(a.Changes) == 0 {
+		return
+	}
+	p.Actions = append(p.Actions, a)
+}
+
+func manifestCheckNames(kind string, names []string) error {
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			return fmt.Errorf("%s: %s %s", ErrManifestDuplicateName, kind, name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+func manifestNoPlacement(kind, name string) error {
+	return fmt.Errorf("%s: %s %s", ErrManifestNoPlacement, kind, name)
+}
+
+// manifestKomputes returns project's user-managed Komputes,
+// along with IDs of all volumes attached to any of project's Komputes
+func manifestKomputes(prj *Project) ([]Kompute, map[string]bool, error) {
+	komputes, err := FindKomputesByProject(prj.String())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	managed := []Kompute{}
+	volumes := map[string]bool{}
+	for _, k := range komputes {
+		if k.IsTrashed() {
+			continue
+		}
+
+		i, err := k.Instance()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, v := range i.Volumes() {
+			volumes[v] = true
+		}
+
+		// as-a-service Komputes belong to Kawaii and Konvey
+		if i.Profile != "" || i.ProfileID != "" {
+			continue
+		}
+		managed = append(managed, k)
+	}
+
+	return managed, volumes, nil
+}
+
+// manifestVolumes returns project's standalone volumes
+func manifestVolumes(prj *Project, komputeVolumes map[string]bool) ([]Volume, error) {
+	volumes, err := FindVolumesByProject(prj.String())
+	if err != nil {
+		return nil, err
+	}
+
+	managed := []Volume{}
+	for _, v := range volumes {
+		if komputeVolumes[v.String()] || v.Type == VolumeTypeIso || v.IsTrashed() {
+			continue
+		}
+		managed = append(managed, v)
+	}
+
+	return managed, nil
+}
+
+func kawaiiRegion(k *Kawaii) string {
+	mzr, err := k.MZR()
+	if err != nil {
+		return ""
+	}
+	return mzr.RegionID
+}
+
+// normalizeKawaiiModel applies the same defaults Kawaii services do
+func normalizeKawaiiModel(m ManifestKawaii) ManifestKawaii {
+	rules := len(m.Firewall.Ingress) + len(m.Firewall.Egress)
+	if m.Firewall.EgressPolicy == "" && rules > 0 {
+		m.Firewall.EgressPolicy = KawaiiFirewallPolicyAccept
+	}
+	for i := range m.Firewall.Ingress {
+		if m.Firewall.Ingress[i].Source == "" {
+			m.Firewall.Ingress[i].Source = KawaiiFirewallWildcardNetwork
+		}
+		if m.Firewall.Ingress[i].Protocol == "" {
+			m.Firewall.Ingress[i].Protocol = KawaiiFirewallProtocolTCP
+		}
+	}
+	for i := range m.Firewall.Egress {
+		if m.Firewall.Egress[i].Destination == "" {
+			m.Firewall.Egress[i].Destination = KawaiiFirewallWildcardNetwork
+		}
+		if m.Firewall.Egress[i].Protocol == "" {
+			m.Firewall.Egress[i].Protocol = KawaiiFirewallProtocolTCP
+		}
+	}
+	for i := range m.Dnat {
+		if m.Dnat[i].Protocol == "" {
+			m.Dnat[i].Protocol = KawaiiFirewallProtocolTCP
+		}
+	}
+	return m
+}
+
+func (p *ManifestPlan) planKawaiis(prj *Project, want []ManifestKawaii) error {
+	regions := []string{}
+	for _, k := range want {
+		if k.Region == "" {
+			return manifestNoPlacement(ManifestKindKawaii, k.Description)
+		}
+		regions = append(regions, k.Region)
+	}
+	err := manifestCheckNames(ManifestKindKawaii, regions)
+	if err != nil {
+		return err
+	}
+
+	kawaiis, err := FindKawaiisByProject(prj.String())
+	if err != nil {
+		return err
+	}
+	current := map[string]*Kawaii{}
+	for _, k := range kawaiis {
+		current[kawaiiRegion(&k)] = &k
+	}
+
+	for _, w := range want {
+		w = normalizeKawaiiModel(w)
+		k, ok := current[w.Region]
+		if !ok {
+			regionId := w.Region
+			spec := sdk.Kawaii{
+				Description: w.Description,
+				Firewall:    w.Firewall,
+				Dnat:        w.Dnat,
+			}
+			p.add(ManifestAction{
+				Action: ManifestActionCreate,
+				Kind:   ManifestKindKawaii,
+				Name:   KawaiiDefaultNamePrefix + "-" + prj.Name,
+				run: func(ctx context.Context) (sdk.ImplResponse, error) {
+					return (&ProjectService{}).CreateProjectRegionKawaii(ctx, prj.String(), regionId, spec)
+				},
+			})
+			continue
+		}
+		delete(current, w.Region)
+
+		have := k.Model()
+		spec := sdk.Kawaii{
+			Description: have.Description,
+			Firewall:    have.Firewall,
+			Dnat:        have.Dnat,
+		}
+		a := ManifestAction{
+			Action: ManifestActionUpdate,
+			Kind:   ManifestKindKawaii,
+			Name:   k.Name,
+			Id:     k.String(),
+		}
+		if a.diff("description", w.Description, have.Description) {
+			spec.Description = w.Description
+		}
+		if a.diff("firewall", w.Firewall, have.Firewall) {
+			spec.Firewall = w.Firewall
+		}
+		if a.diff("dnat", w.Dnat, have.Dnat) {
+			spec.Dnat = w.Dnat
+		}
+		id := k.String()
+		a.run = func(ctx context.Context) (sdk.ImplResponse, error) {
+			return (&KawaiiService{}).UpdateKawaii(ctx, id, spec)
+		}
+		p.add(a)
+	}
+
+	for _, k := range current {
+		id := k.String()
+		p.add(ManifestAction{
+			Action: ManifestActionDelete,
+			Kind:   ManifestKindKawaii,
+			Name:   k.Name,
+			Id:     id,
+			run: func(ctx context.Context) (sdk.ImplResponse, error) {
+				return (&KawaiiService{}).DeleteKawaii(ctx, id)
+			},
+		})
+	}
+
+	return nil
+}
+
+func (p *ManifestPlan) planVolumes(prj *Project, want []ManifestVolume, komputeVolumes map[string]bool) error {
+	names := []string{}
+	for _, v := range want {
+		names = append(names, v.Name)
+	}
+	err := manifestCheckNames(ManifestKindVolume, names)
+	if err != nil {
+		return err
+	}
+
+	volumes, err := manifestVolumes(prj, komputeVolumes)
+	if err != nil {
+		return err
+	}
+	current := map[string]*Volume{}
+	for _, v := range volumes {
+		current[v.Name] = &v
+	}
+
+	for _, w := range want {
+		v, ok := current[w.Name]
+		if !ok {
+			if w.Region == "" {
+				return manifestNoPlacement(ManifestKindVolume, w.Name)
+			}
+			spec := w
+			spec.Id = ""
+			p.add(ManifestAction{
+				Action: ManifestActionCreate,
+				Kind:   ManifestKindVolume,
+				Name:   w.Name,
+				run: func(ctx context.Context) (sdk.ImplResponse, error) {
+					return (&ProjectService{}).CreateProjectRegionVolume(ctx, prj.String(), spec.Region, spec.Volume, spec.Pool, spec.Template)
+				},
+			})
+			continue
+		}
+		delete(current, w.Name)
+
+		spec := v.Model()
+		a := ManifestAction{
+			Action: ManifestActionUpdate,
+			Kind:   ManifestKindVolume,
+			Name:   v.Name,
+			Id:     v.String(),
+		}
+		if a.diff("description", w.Description, spec.Description) {
+			spec.Description = w.Description
+		}
+		if a.diff("size", w.Size, spec.Size) {
+			spec.Size = w.Size
+		}
+		id := v.String()
+		a.run = func(ctx context.Context) (sdk.ImplResponse, error) {
+			return (&VolumeService{}).UpdateVolume(ctx, id, spec)
+		}
+		p.add(a)
+	}
+
+	for _, v := range current {
+		id := v.String()
+		p.add(ManifestAction{
+			Action: ManifestActionDelete,
+			Kind:   ManifestKindVolume,
+			Name:   v.Name,
+			Id:     id,
+			run: func(ctx context.Context) (sdk.ImplResponse, error) {
+				return (&VolumeService{}).DeleteVolume(ctx, id)
+			},
+		})
+	}
+
+	return nil
+}
+
+func (p *ManifestPlan) planKomputes(prj *Project, want []ManifestKompute, komputes []Kompute) error {
+	names := []string{}
+	for _, k := range want {
+		names = append(names, k.Name)
+	}
+	err := manifestCheckNames(ManifestKindKompute, names)
+	if err != nil {
+		return err
+	}
+
+	current := map[string]*Kompute{}
+	for _, k := range komputes {
+		current[k.Name] = &k
+	}
+
+	for _, w := range want {
+		k, ok := current[w.Name]
+		if !ok {
+			if w.Zone == "" {
+				return manifestNoPlacement(ManifestKindKompute, w.Name)
+			}
+			spec := w
+			spec.Id = ""
+			p.add(ManifestAction{
+				Action: ManifestActionCreate,
+				Kind:   ManifestKindKompute,
+				Name:   w.Name,
+				run: func(ctx context.Context) (sdk.ImplResponse, error) {
+					return (&ProjectService{}).CreateProjectZoneKompute(ctx, prj.String(), spec.Zone, spec.Kompute, spec.Pool, spec.Template, spec.Public)
+				},
+			})
+			continue
+		}
+		delete(current, w.Name)
+
+		spec := k.Model()
+		a := ManifestAction{
+			Action: ManifestActionUpdate,
+			Kind:   ManifestKindKompute,
+			Name:   k.Name,
+			Id:     k.String(),
+		}
+		if a.diff("description", w.Description, spec.Description) {
+			spec.Description = w.Description
+		}
+		if a.diff("vcpus", w.Vcpus, spec.Vcpus) {
+			spec.Vcpus = w.Vcpus
+		}
+		if a.diff("memory", w.Memory, spec.Memory) {
+			spec.Memory = w.Memory
+		}
+		if a.diff("disk", w.Disk, spec.Disk) {
+			spec.Disk = w.Disk
+		}
+		if a.diff("data_disk", w.DataDisk, spec.DataDisk) {
+			spec.DataDisk = w.DataDisk
+		}
+		id := k.String()
+		a.run = func(ctx context.Context) (sdk.ImplResponse, error) {
+			return (&KomputeService{}).UpdateKompute(ctx, id, spec)
+		}
+		p.add(a)
+	}
+
+	for _, k := range current {
+		id := k.String()
+		p.add(ManifestAction{
+			Action: ManifestActionDelete,
+			Kind:   ManifestKindKompute,
+			Name:   k.Name,
+			Id:     id,
+			run: func(ctx context.Context) (sdk.ImplResponse, error) {
+				return (&KomputeService{}).DeleteKompute(ctx, id)
+			},
+		})
+	}
+
+	return nil
+}
+
+func (p *ManifestPlan) planKylos(prj *Project, want []ManifestKylo) error {
+	names := []string{}
+	for _, k := range want {
+		names = append(names, k.Name)
+	}
+	err := manifestCheckNames(ManifestKindKylo, names)
+	if err != nil {
+		return err
+	}
+
+	kylos, err := FindKyloByProject(prj.String())
+	if err != nil {
+		return err
+	}
+	current := map[string]*Kylo{}
+	for _, k := range kylos {
+		current[k.Name] = &k
+	}
+
+	for _, w := range want {
+		k, ok := current[w.Name]
+		if !ok {
+			if w.Region == "" {
+				return manifestNoPlacement(ManifestKindKylo, w.Name)
+			}
+			spec := w
+			spec.Id = ""
+			p.add(ManifestAction{
+				Action: ManifestActionCreate,
+				Kind:   ManifestKindKylo,
+				Name:   w.Name,
+				run: func(ctx context.Context) (sdk.ImplResponse, error) {
+					return (&ProjectService{}).CreateProjectRegionKylo(ctx, prj.String(), spec.Region, spec.Kylo, spec.Nfs)
+				},
+			})
+			continue
+		}
+		delete(current, w.Name)
+
+		spec := k.Model()
+		a := ManifestAction{
+			Action: ManifestActionUpdate,
+			Kind:   ManifestKindKylo,
+			Name:   k.Name,
+			Id:     k.String(),
+		}
+		if a.diff("description", w.Description, spec.Description) {
+			spec.Description = w.Description
+		}
+		if a.diff("access", w.Access, spec.Access) {
+			spec.Access = w.Access
+		}
+		if a.diff("protocols", w.Protocols, spec.Protocols) {
+			spec.Protocols = w.Protocols
+		}
+		id := k.String()
+		a.run = func(ctx context.Context) (sdk.ImplResponse, error) {
+			return (&KyloService{}).UpdateKylo(ctx, id, spec)
+		}
+		p.add(a)
+	}
+
+	for _, k := range current {
+		id := k.String()
+		p.add(ManifestAction{
+			Action: ManifestActionDelete,
+			Kind:   ManifestKindKylo,
+			Name:   k.Name,
+			Id:     id,
+			run: func(ctx context.Context) (sdk.ImplResponse, error) {
+				return (&KyloService{}).DeleteKylo(ctx, id)
+			},
+		})
+	}
+
+	return nil
+}
+
+func (p *ManifestPlan) planKonveys(prj *Project, want []ManifestKonvey) error {
+	names := []string{}
+	for i := range want {
+		// Konvey services name it after project unless specified
+		if want[i].Name == "" {
+			want[i].Name = prj.Name
+		}
+		names = append(names, want[i].Name)
+	}
+	err := manifestCheckNames(ManifestKindKonvey, names)
+	if err != nil {
+		return err
+	}
+
+	konveys, err := FindKonveysByProject(prj.String())
+	if err != nil {
+		return err
+	}
+	current := map[string]*Konvey{}
+	for _, k := range konveys {
+		current[k.Model().Name] = &k
+	}
+
+	for _, w := range want {
+		k, ok := current[w.Name]
+		if !ok {
+			spec := w
+			spec.Id = ""
+			a := ManifestAction{
+				Action: ManifestActionCreate,
+				Kind:   ManifestKindKonvey,
+				Name:   KonveyDefaultNamePrefix + "-" + w.Name,
+			}
+			switch {
+			case w.Zone != "":
+				a.run = func(ctx context.Context) (sdk.ImplResponse, error) {
+					return (&ProjectService{}).CreateProjectZoneKonvey(ctx, prj.String(), spec.Zone, spec.Konvey)
+				}
+			case w.Region != "":
+				a.run = func(ctx context.Context) (sdk.ImplResponse, error) {
+					return (&ProjectService{}).CreateProjectRegionKonvey(ctx, prj.String(), spec.Region, spec.Konvey)
+				}
+			default:
+				return manifestNoPlacement(ManifestKindKonvey, w.Name)
+			}
+			p.add(a)
+			continue
+		}
+		delete(current, w.Name)
+
+		// only endpoints can be updated
+		spec := k.Model()
+		a := ManifestAction{
+			Action: ManifestActionUpdate,
+			Kind:   ManifestKindKonvey,
+			Name:   k.Name,
+			Id:     k.String(),
+		}
+		if a.diff("endpoints", w.Endpoints, spec.Endpoints) {
+			spec.Endpoints = w.Endpoints
+		}
+		id := k.String()
+		a.run = func(ctx context.Context) (sdk.ImplResponse, error) {
+			return (&KonveyService{}).UpdateKonvey(ctx, id, spec)
+		}
+		p.add(a)
+	}
+
+	for _, k := range current {
+		id := k.String()
+		p.add(ManifestAction{
+			Action: ManifestActionDelete,
+			Kind:   ManifestKindKonvey,
+			Name:   k.Name,
+			Id:     id,
+			run: func(ctx context.Context) (sdk.ImplResponse, error) {
+				return (&KonveyService{}).DeleteKonvey(ctx, id)
+			},
+		})
+	}
+
+	return nil
+}
+
+func (p *ManifestPlan) planDnsRecords(prj *Project, want []ManifestDnsRecord) error {
+	names := []string{}
+	for _, r := range want {
+		names = append(names, r.Name)
+	}
+	err := manifestCheckNames(ManifestKindDnsRecord, names)
+	if err != nil {
+		return err
+	}
+
+	records, err := FindDnsRecordsByProject(prj.String())
+	if err != nil {
+		return err
+	}
+	current := map[string]*DnsRecord{}
+	for _, r := range records {
+		current[r.Name] = &r
+	}
+
+	for _, w := range want {
+		r, ok := current[w.Name]
+		if !ok {
+			spec := w.DnsRecord
+			spec.Id = ""
+			p.add(ManifestAction{
+				Action: ManifestActionCreate,
+				Kind:   ManifestKindDnsRecord,
+				Name:   w.Name,
+				run: func(ctx context.Context) (sdk.ImplResponse, error) {
+					return (&ProjectService{}).CreateProjectDnsRecord(ctx, prj.String(), spec)
+				},
+			})
+			continue
+		}
+		delete(current, w.Name)
+
+		spec := r.Model()
+		a := ManifestAction{
+			Action: ManifestActionUpdate,
+			Kind:   ManifestKindDnsRecord,
+			Name:   r.Name,
+			Id:     r.String(),
+		}
+		if a.diff("description", w.Description, spec.Description) {
+			spec.Description = w.Description
+		}
+		if a.diff("addresses", w.Addresses, spec.Addresses) {
+			spec.Addresses = w.Addresses
+		}
+		id := r.String()
+		a.run = func(ctx context.Context) (sdk.ImplResponse, error) {
+			return (&DnsRecordService{}).UpdateDnsRecord(ctx, id, spec)
+		}
+		p.add(a)
+	}
+
+	for _, r := range current {
+		id := r.String()
+		p.add(ManifestAction{
+			Action: ManifestActionDelete,
+			Kind:   ManifestKindDnsRecord,
+			Name:   r.Name,
+			Id:     id,
+			run: func(ctx context.Context) (sdk.ImplResponse, error) {
+				return (&DnsRecordService{}).DeleteDnsRecord(ctx, id)
+			},
+		})
+	}
+
+	return nil
+}
+
+// NewManifestPlan computes actions required for project to match manifest.
+// Creations and updates come first, with deletions last, in reverse dependency order.
+func NewManifestPlan(prj *Project, m *ProjectManifest) (*ManifestPlan, error) {
+	komputes, komputeVolumes, err := manifestKomputes(prj)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := []func(p *ManifestPlan) error{}
+	if m.Kawaiis != nil {
+		steps = append(steps, func(p *ManifestPlan) error { return p.planKawaiis(prj, m.Kawaiis) })
+	}
+	if m.Volumes != nil {
+		steps = append(steps, func(p *ManifestPlan) error { return p.planVolumes(prj, m.Volumes, komputeVolumes) })
+	}
+	if m.Komputes != nil {
+		steps = append(steps, func(p *ManifestPlan) error { return p.planKomputes(prj, m.Komputes, komputes) })
+	}
+	if m.Kylos != nil {
+		steps = append(steps, func(p *ManifestPlan) error { return p.planKylos(prj, m.Kylos) })
+	}
+	if m.Konveys != nil {
+		steps = append(steps, func(p *ManifestPlan) error { return p.planKonveys(prj, m.Konveys) })
+	}
+	if m.DnsRecords != nil {
+		steps = append(steps, func(p *ManifestPlan) error { return p.planDnsRecords(prj, m.DnsRecords) })
+	}
+
+	plans := []*ManifestPlan{}
+	for _, step := range steps {
+		p := ManifestPlan{}
+		err := step(&p)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, &p)
+	}
+
+	plan := ManifestPlan{
+		Project: prj.String(),
+		Actions: []ManifestAction{},
+	}
+	for _, p := range plans {
+		for _, a := range p.Actions {
+			if a.Action != ManifestActionDelete {
+				plan.Actions = append(plan.Actions, a)
+			}
+		}
+	}
+	for i := len(plans) - 1; i >= 0; i-- {
+		for _, a := range plans[i].Actions {
+			if a.Action == ManifestActionDelete {
+				plan.Actions = append(plan.Actions, a)
+			}
+		}
+	}
+
+	return &plan, nil
+}
+
+// Apply executes plan actions in order, stopping at the first failure
+func (p *ManifestPlan) Apply(ctx context.Context) error {
+	var failure error
+	for i := range p.Actions {
+		a := &p.Actions[i]
+		if failure != nil {
+			a.Result = ManifestResultSkipped
+			continue
+		}
+
+		klog.Infof("Manifest: %s %s %s", a.Action, a.Kind, a.Name)
+		res, err := a.run(ctx)
+		if err == nil && (res.Code < http.StatusOK || res.Code >= http.StatusMultipleChoices) {
+			err = fmt.Errorf("%s", http.StatusText(res.Code))
+		}
+		if err != nil {
+			a.Result = ManifestResultFailed
+			a.Error = err.Error()
+			failure = fmt.Errorf("unable to %s %s %s: %w", a.Action, a.Kind, a.Name, err)
+			continue
+		}
+		a.Result = ManifestResultDone
+	}
+
+	return failure
+}
+
+// NewProjectManifest exports project's current resources as a manifest
+func NewProjectManifest(prj *Project) (*ProjectManifest, error) {
+	m := ProjectManifest{
+		Kawaiis:    []ManifestKawaii{},
+		Volumes:    []ManifestVolume{},
+		Komputes:   []ManifestKompute{},
+		Kylos:      []ManifestKylo{},
+		Konveys:    []ManifestKonvey{},
+		DnsRecords: []ManifestDnsRecord{},
+	}
+
+	kawaiis, err := FindKawaiisByProject(prj.String())
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range kawaiis {
+		model := k.Model()
+		m.Kawaiis = append(m.Kawaiis, ManifestKawaii{
+			Region:      kawaiiRegion(&k),
+			Description: model.Description,
+			Firewall:    model.Firewall,
+			Dnat:        model.Dnat,
+		})
+	}
+
+	komputes, komputeVolumes, err := manifestKomputes(prj)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes, err := manifestVolumes(prj, komputeVolumes)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range volumes {
+		mv := ManifestVolume{
+			Volume:   v.Model(),
+			Pool:     v.StoragePoolID,
+			Template: v.TemplateID,
+		}
+		mv.Id = ""
+		pool, err := v.StoragePool()
+		if err == nil {
+			mv.Region = pool.RegionID
+		}
+		m.Volumes = append(m.Volumes, mv)
+	}
+
+	for _, k := range komputes {
+		mk := ManifestKompute{
+			Kompute: k.Model(),
+		}
+		mk.Id = ""
+		mk.Ip = ""
+		i, err := k.Instance()
+		if err == nil {
+			mk.Public = i.GetIpAddress(false) != ""
+			kaktus, err := i.Kaktus()
+			if err == nil {
+				mk.Zone = kaktus.ZoneID
+			}
+			osVolume, err := i.GetOsVolume()
+			if err == nil {
+				mk.Pool = osVolume.StoragePoolID
+				mk.Template = osVolume.TemplateID
+			}
+		}
+		m.Komputes = append(m.Komputes, mk)
+	}
+
+	kylos, err := FindKyloByProject(prj.String())
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range kylos {
+		mk := ManifestKylo{
+			Kylo: k.Model(),
+			Nfs:  k.NfsID,
+		}
+		mk.Id = ""
+		mk.Endpoint = ""
+		mk.Size = 0
+		nfs, err := k.Nfs()
+		if err == nil {
+			mk.Region = nfs.RegionID
+		}
+		m.Kylos = append(m.Kylos, mk)
+	}
+
+	konveys, err := FindKonveysByProject(prj.String())
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range konveys {
+		mk := ManifestKonvey{
+			Konvey: k.Model(),
+		}
+		mk.Id = ""
+		mk.Vip = ""
+		har, err := k.HAR()
+		if err == nil {
+			mk.Region = har.RegionID
+		}
+		m.Konveys = append(m.Konveys, mk)
+	}
+
+	records, err := FindDnsRecordsByProject(prj.String())
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		mr := ManifestDnsRecord{
+			DnsRecord: r.Model(),
+		}
+		mr.Id = ""
+		mr.Domain = ""
+		m.DnsRecords = append(m.DnsRecords, mr)
+	}
+
+	return &m, nil
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testManifest = `
+komputes:
+  - name: web
+    description: front web server
+    vcpus: 2
+    memory: 4294967296
+    disk: 21474836480
+    zone: zone-a
+    public: true
+dns_records: []
+`
+
+func TestDecodeProjectManifest(t *testing.T) {
+	m, err := DecodeProjectManifest(strings.NewReader(testManifest))
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	if len(m.Komputes) != 1 {
+		t.Fatalf("unexpected komputes: %+v", m.Komputes)
+	}
+	k := m.Komputes[0]
+	if k.Name != "web" || k.Vcpus != 2 || k.Memory != 4294967296 || k.Zone != "zone-a" || !k.Public {
+		t.Fatalf("unexpected kompute: %+v", k)
+	}
+
+	// omitted sections are left untouched, empty ones are reconciled
+	if m.Volumes != nil {
+		t.Fatalf("unexpected volumes section: %+v", m.Volumes)
+	}
+	if m.DnsRecords == nil || len(m.DnsRecords) != 0 {
+		t.Fatalf("unexpected DNS records section: %+v", m.DnsRecords)
+	}
+
+	// JSON is a YAML subset
+	_, err = DecodeProjectManifest(strings.NewReader(`{"volumes": [{"name": "data", "size": 1024}]}`))
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	_, err = DecodeProjectManifest(strings.NewReader("instances: []"))
+	if err == nil {
+		t.Fatalf("unknown manifest section was accepted")
+	}
+}
+
+func TestManifestActionDiff(t *testing.T) {
+	a := ManifestAction{}
+	if a.diff("description", "", "current") {
+		t.Fatalf("unset property was reported as changed")
+	}
+	if a.diff("vcpus", int64(2), int64(2)) {
+		t.Fatalf("unchanged property was reported as changed")
+	}
+	if !a.diff("addresses", []string{"10.0.0.2"}, []string{"10.0.0.1"}) {
+		t.Fatalf("changed property was not reported")
+	}
+	if !slices.Equal(a.Changes, []string{"addresses"}) {
+		t.Fatalf("unexpected changes: %v", a.Changes)
+	}
+}
@@ -9,16 +9,36 @@ package kahuna
 /* This file is a specific collection of DB schema migration or pruner helpers
    Kowabunga DB has changed over the course of time and we need to ensure that all objects
    are properly setup with the right fields before going any further.
-   Check (and update when required) will only be done once, at startup.
+   Migrations are registered in order, applied only once and recorded as such.
 */
 
 import (
+	"context"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
 
 	"github.com/kowabunga-cloud/common/klog"
 )
 
 func kawaiiCleanupDereferencedChildren() error {
+	_, err := cleanupDereferencedChildren(true)
+	return err
+}
+
+// cleanupDereferencedChildren drops references to non-existing children resources (only
+// reporting them unless applied), returning the count of documents referencing some
+func cleanupDereferencedChildren(apply bool) (int64, error) {
+	report := klog.Errorf
+	if !apply {
+		report = klog.Debugf
+	}
+	count := int64(0)
+
 	// projects
 	for _, prj := range FindProjects() {
 		updated := false
@@ -27,7 +47,7 @@ func kawaiiCleanupDereferencedChildren() error {
 		for _, instanceId := range prj.Instances() {
 			instance, err := FindInstanceByID(instanceId)
 			if err != nil || instance.String() == ResourceUnknown {
-				klog.Errorf("Project %s is referencing instance %s, which doesn't seem to exists anymore, dropping it ...", prj.Name, instanceId)
+				report("Project %s is referencing instance %s, which doesn't seem to exists anymore, dropping it ...", prj.Name, instanceId)
 				updated = true
 				instancesToRemove = append(instancesToRemove, instanceId)
 			}
@@ -37,7 +57,7 @@ func kawaiiCleanupDereferencedChildren() error {
 		for _, volumeId := range prj.Volumes() {
 			volume, err := FindVolumeByID(volumeId)
 			if err != nil || volume.String() == ResourceUnknown {
-				klog.Errorf("Project %s is referencing volume %s, which doesn't seem to exists anymore, dropping it ...", prj.Name, volumeId)
+				report("Project %s is referencing volume %s, which doesn't seem to exists anymore, dropping it ...", prj.Name, volumeId)
 				updated = true
 				volumesToRemove = append(volumesToRemove, volumeId)
 			}
@@ -47,7 +67,7 @@ func kawaiiCleanupDereferencedChildren() error {
 		for _, komputeId := range prj.Komputes() {
 			kompute, err := FindKomputeByID(komputeId)
 			if err != nil || kompute.String() == ResourceUnknown {
-				klog.Errorf("Project %s is referencing Kompute %s, which doesn't seem to exists anymore, dropping it ...", prj.Name, komputeId)
+				report("Project %s is referencing Kompute %s, which doesn't seem to exists anymore, dropping it ...", prj.Name, komputeId)
 				updated = true
 				komputesToRemove = append(komputesToRemove, komputeId)
 			}
@@ -57,7 +77,7 @@ func kawaiiCleanupDereferencedChildren() error {
 		for _, kawaiiId := range prj.Kawaiis() {
 			kawaii, err := FindKawaiiByID(kawaiiId)
 			if err != nil || kawaii.String() == ResourceUnknown {
-				klog.Errorf("Project %s is referencing Kawaii %s, which doesn't seem to exists anymore, dropping it ...", prj.Name, kawaiiId)
+				report("Project %s is referencing Kawaii %s, which doesn't seem to exists anymore, dropping it ...", prj.Name, kawaiiId)
 				updated = true
 				kawaiisToRemove = append(kawaiisToRemove, kawaiiId)
 			}
@@ -67,13 +87,17 @@ func kawaiiCleanupDereferencedChildren() error {
 		for _, kyloId := range prj.Kylos() {
 			kylo, err := FindKyloByID(kyloId)
 			if err != nil || kylo.String() == ResourceUnknown {
-				klog.Errorf("Project %s is referencing Kylo %s, which doesn't seem to exists anymore, dropping it ...", prj.Name, kyloId)
+				report("Project %s is referencing Kylo %s, which doesn't seem to exists anymore, dropping it ...", prj.Name, kyloId)
 				updated = true
 				kyloToRemove = append(kyloToRemove, kyloId)
 			}
 		}
 
 		if updated {
+			count++
+			if !apply {
+				continue
+			}
 			for _, instanceId := range instancesToRemove {
 				RemoveChildRef(&prj.InstanceIDs, instanceId)
 			}
@@ -101,13 +125,17 @@ func kawaiiCleanupDereferencedChildren() error {
 		for _, adapterId := range subnet.Adapters() {
 			adapter, err := FindAdapterByID(adapterId)
 			if err != nil || adapter.String() == ResourceUnknown {
-				klog.Errorf("Subnet %s is referencing adapter %s, which doesn't seem to exists anymore, dropping it ...", subnet.Name, adapterId)
+				report("Subnet %s is referencing adapter %s, which doesn't seem to exists anymore, dropping it ...", subnet.Name, adapterId)
 				updated = true
 				adaptersToRemove = append(adaptersToRemove, adapterId)
 			}
 		}
 
 		if updated {
+			count++
+			if !apply {
+				continue
+			}
 			for _, adapterId := range adaptersToRemove {
 				RemoveChildRef(&subnet.AdapterIDs, adapterId)
 			}
@@ -115,7 +143,7 @@ func kawaiiCleanupDereferencedChildren() error {
 		}
 	}
 
-	return nil
+	return count, nil
 }
 
 func migrateInstances() error {
@@ -151,149 +179,354 @@ func migrateInstances() error {
 	return nil
 }
 
-func dbSchemaMigration() error {
-	var err error
+const (
+	MongoCollectionMigrationSchemaVersion = 1
+	MongoCollectionMigrationName          = "migration"
+	MongoCollectionMigrationLockName      = "migration_lock"
 
-	err = AdapterMigrateSchema()
-	if err != nil {
-		return err
-	}
+	MigrationLockID           = "migration"
+	MigrationLockLeaseSeconds = 60
+	MigrationLockRenewSeconds = 20
 
-	err = AgentMigrateSchema()
-	if err != nil {
-		return err
-	}
+	ErrMigrationUnknown      = "no such database migration"
+	ErrMigrationNotApplied   = "database migration has not been applied"
+	ErrMigrationIrreversible = "database migration can't be rolled back"
+	ErrMigrationLocked       = "database migration is already in progress"
+)
 
-	err = DnsRecordMigrateSchema()
-	if err != nil {
-		return err
-	}
+// Migration is a named database migration step, applied only once
+type Migration struct {
+	Name        string
+	Description string
+	Up          func() error
+	Down        func() error          // optional, migration is irreversible otherwise
+	Pending     func() (int64, error) // optional, count of documents to be migrated
+}
 
-	err = HarMigrateSchema()
-	if err != nil {
-		return err
-	}
+// AppliedMigration records a migration as being applied
+type AppliedMigration struct {
+	Name          string    `bson:"_id"`
+	SchemaVersion int       `bson:"schema_version"`
+	AppliedAt     time.Time `bson:"applied_at"`
+}
 
-	err = InstanceMigrateSchema()
-	if err != nil {
-		return err
+// countOutdatedDocuments returns the number of collection documents older than schema version
+func countOutdatedDocuments(collection, legacy string, version int) (int64, error) {
+	count := int64(0)
+	if legacy != "" && GetDB().HasCollection(legacy) {
+		c, err := GetDB().CountAll(legacy)
+		if err != nil {
+			return 0, err
+		}
+		count += c
 	}
 
-	err = KaktusMigrateSchema()
+	filter := bson.D{bson.E{Key: "schema_version", Value: bson.D{bson.E{Key: "$not", Value: bson.D{bson.E{Key: "$gte", Value: version}}}}}}
+	c, err := GetDB().Count(collection, filter)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	err = KawaiiMigrateSchema()
-	if err != nil {
-		return err
-	}
+	return count + c, nil
+}
 
-	err = KiwiMigrateSchema()
-	if err != nil {
-		return err
+// schemaMigration wraps resource's schema migration, from its legacy collection, if any
+func schemaMigration(collection, legacy string, version int, up func() error) Migration {
+	return Migration{
+		Name:        fmt.Sprintf("%s-schema-v%d", strings.ReplaceAll(collection, "_", "-"), version),
+		Description: fmt.Sprintf("migrate %s documents to schema v%d", collection, version),
+		Up:          up,
+		Pending: func() (int64, error) {
+			return countOutdatedDocuments(collection, legacy, version)
+		},
 	}
+}
 
-	err = KomputeMigrateSchema()
-	if err != nil {
-		return err
-	}
+// registered database migrations, in order. Applied migrations are never to be renamed nor removed.
+var dbMigrations = []Migration{
+	schemaMigration(MongoCollectionAdapterName, "adapters", 2, AdapterMigrateSchema),
+	schemaMigration(MongoCollectionAgentName, "agents", 2, AgentMigrateSchema),
+	schemaMigration(MongoCollectionDnsRecordName, "records", 2, DnsRecordMigrateSchema),
+	schemaMigration(MongoCollectionHarName, "", 2, HarMigrateSchema),
+	schemaMigration(MongoCollectionInstanceName, "instances", 2, InstanceMigrateSchema),
+	schemaMigration(MongoCollectionKaktusName, "hosts", 2, KaktusMigrateSchema),
+	schemaMigration(MongoCollectionKawaiiName, "kgws", 2, KawaiiMigrateSchema),
+	schemaMigration(MongoCollectionKiwiName, "netgws", 2, KiwiMigrateSchema),
+	schemaMigration(MongoCollectionKomputeName, "kces", 2, KomputeMigrateSchema),
+	schemaMigration(MongoCollectionKonveyName, "", 2, KonveyMigrateSchema),
+	schemaMigration(MongoCollectionKyloName, "kfs", 2, KyloMigrateSchema),
+	schemaMigration(MongoCollectionMzrName, "", 2, MzrMigrateSchema),
+	schemaMigration(MongoCollectionNfsName, "", 2, NfsMigrateSchema),
+	schemaMigration(MongoCollectionProjectName, "projects", 2, ProjectMigrateSchema),
+	schemaMigration(MongoCollectionRegionName, "regions", 2, RegionMigrateSchema),
+	schemaMigration(MongoCollectionStoragePoolName, "pools", 2, StoragePoolMigrateSchema),
+	schemaMigration(MongoCollectionSubnetName, "subnets", 2, SubnetMigrateSchema),
+	schemaMigration(MongoCollectionTeamName, "groups", 2, TeamMigrateSchema),
+	schemaMigration(MongoCollectionTemplateName, "templates", 2, TemplateMigrateSchema),
+	schemaMigration(MongoCollectionTokenName, "tokens", 2, TokenMigrateSchema),
+	schemaMigration(MongoCollectionUserName, "users", 2, UserMigrateSchema),
+	schemaMigration(MongoCollectionVNetName, "vnets", 2, VNetMigrateSchema),
+	schemaMigration(MongoCollectionVolumeName, "volumes", 2, VolumeMigrateSchema),
+	schemaMigration(MongoCollectionZoneName, "zones", 2, ZoneMigrateSchema),
+	{
+		Name:        "instance-local-ip",
+		Description: "set instances local IP address from their first network adapter",
+		Up:          migrateInstances,
+		Pending: func() (int64, error) {
+			return GetDB().Count(MongoCollectionInstanceName, bson.D{bson.E{Key: "local_ip", Value: bson.D{bson.E{Key: "$in", Value: bson.A{"", nil}}}}})
+		},
+	},
+	{
+		Name:        "cleanup-dereferenced-children",
+		Description: "drop references to non-existing children resources",
+		Up:          kawaiiCleanupDereferencedChildren,
+		Pending: func() (int64, error) {
+			return cleanupDereferencedChildren(false)
+		},
+	},
+	{
+		Name:        "token-schema-v3",
+		Description: "flag legacy API keys tokens",
+		Up:          TokenMigrateSchemaV3,
+		Down:        TokenRollbackSchemaV3,
+		Pending: func() (int64, error) {
+			return countOutdatedDocuments(MongoCollectionTokenName, "", 3)
+		},
+	},
+	{
+		Name:        "user-schema-v3",
+		Description: "turn users notifications flag into email notification channel",
+		Up:          UserMigrateSchemaV3,
+		Down:        UserRollbackSchemaV3,
+		Pending: func() (int64, error) {
+			return countOutdatedDocuments(MongoCollectionUserName, "", 3)
+		},
+	},
+	{
+		Name:        "organization-schema-v2",
+		Description: "encrypt organizations IdP client secrets",
+		Up:          OrganizationMigrateSchemaV2,
+		Down:        OrganizationRollbackSchemaV2,
+		Pending: func() (int64, error) {
+			return countOutdatedDocuments(MongoCollectionOrganizationName, "", 2)
+		},
+	},
+}
 
-	err = KonveyMigrateSchema()
-	if err != nil {
-		return err
-	}
+// MigrationLock prevents concurrent migrations, leased to its owner as long as it keeps renewing it
+type MigrationLock struct {
+	ID          string    `bson:"_id"`
+	Owner       string    `bson:"owner"`
+	LockedUntil time.Time `bson:"locked_until"`
+}
 
-	err = KyloMigrateSchema()
-	if err != nil {
-		return err
+// lockMigrations acquires database migration lock, taking over a stale one left by an
+// interrupted migration, and returns the function releasing it
+func lockMigrations() (func(), error) {
+	lock := MigrationLock{
+		ID:          MigrationLockID,
+		Owner:       taskOwner(),
+		LockedUntil: time.Now().Add(MigrationLockLeaseSeconds * time.Second),
 	}
 
-	err = MzrMigrateSchema()
+	_, err := GetDB().Insert(MongoCollectionMigrationLockName, lock)
 	if err != nil {
-		return err
-	}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
 
-	err = NfsMigrateSchema()
-	if err != nil {
-		return err
+		filter := bson.D{
+			bson.E{Key: "_id", Value: MigrationLockID},
+			bson.E{Key: "locked_until", Value: bson.D{bson.E{Key: "$lt", Value: time.Now()}}},
+		}
+		acquired, err := GetDB().ReplaceByFilter(MongoCollectionMigrationLockName, filter, lock)
+		if err != nil {
+			return nil, err
+		}
+		if !acquired {
+			return nil, fmt.Errorf("%s", ErrMigrationLocked)
+		}
 	}
 
-	err = ProjectMigrateSchema()
-	if err != nil {
-		return err
+	owned := bson.D{
+		bson.E{Key: "_id", Value: MigrationLockID},
+		bson.E{Key: "owner", Value: lock.Owner},
 	}
 
-	err = RegionMigrateSchema()
-	if err != nil {
-		return err
-	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(MigrationLockRenewSeconds * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				lockedUntil := time.Now().Add(MigrationLockLeaseSeconds * time.Second)
+				update := bson.D{bson.E{Key: "$set", Value: bson.D{bson.E{Key: "locked_until", Value: lockedUntil}}}}
+				err := GetDB().UpdateByFilter(MongoCollectionMigrationLockName, owned, update)
+				if err != nil {
+					klog.Error(err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		err := GetDB().DeleteAllByFilter(MongoCollectionMigrationLockName, owned)
+		if err != nil {
+			klog.Error(err)
+		}
+	}, nil
+}
 
-	err = StoragePoolMigrateSchema()
+func findAppliedMigrations() (map[string]AppliedMigration, error) {
+	migrations := []AppliedMigration{}
+	err := GetDB().FindAll(MongoCollectionMigrationName, &migrations)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = SubnetMigrateSchema()
-	if err != nil {
-		return err
+	applied := map[string]AppliedMigration{}
+	for _, m := range migrations {
+		applied[m.Name] = m
 	}
+	return applied, nil
+}
 
-	err = TeamMigrateSchema()
-	if err != nil {
-		return err
+// pendingMigrations returns the registered migrations yet to be applied, in order
+func pendingMigrations(registry []Migration, applied map[string]AppliedMigration) []Migration {
+	pending := []Migration{}
+	for _, m := range registry {
+		_, ok := applied[m.Name]
+		if !ok {
+			pending = append(pending, m)
+		}
 	}
+	return pending
+}
 
-	err = TemplateMigrateSchema()
-	if err != nil {
-		return err
+// rollbackMigrations returns the applied migrations to be rolled back, down to (and including) the named one, in reverse order
+func rollbackMigrations(registry []Migration, applied map[string]AppliedMigration, name string) ([]Migration, error) {
+	idx := slices.IndexFunc(registry, func(m Migration) bool {
+		return m.Name == name
+	})
+	if idx < 0 {
+		return nil, fmt.Errorf("%s: %s", ErrMigrationUnknown, name)
+	}
+	_, ok := applied[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %s", ErrMigrationNotApplied, name)
 	}
 
-	err = TokenMigrateSchema()
-	if err != nil {
-		return err
+	rollback := []Migration{}
+	for _, m := range slices.Backward(registry[idx:]) {
+		_, ok := applied[m.Name]
+		if !ok {
+			continue
+		}
+		if m.Down == nil {
+			return nil, fmt.Errorf("%s: %s", ErrMigrationIrreversible, m.Name)
+		}
+		rollback = append(rollback, m)
 	}
+	return rollback, nil
+}
 
-	err = UserMigrateSchema()
-	if err != nil {
-		return err
+func MigrateDatabaseSchema(dryRun bool) error {
+	klog.Infof("Migrating DB schema ...")
+
+	if !dryRun {
+		unlock, err := lockMigrations()
+		if err != nil {
+			return err
+		}
+		defer unlock()
 	}
 
-	err = VNetMigrateSchema()
+	applied, err := findAppliedMigrations()
 	if err != nil {
 		return err
 	}
 
-	err = VolumeMigrateSchema()
-	if err != nil {
-		return err
+	pending := pendingMigrations(dbMigrations, applied)
+	if len(pending) == 0 {
+		klog.Infof("DB schema is up-to-date.")
+		return nil
 	}
 
-	err = ZoneMigrateSchema()
-	if err != nil {
-		return err
+	for _, m := range pending {
+		if dryRun {
+			if m.Pending == nil {
+				klog.Infof("[dry-run] %s: %s", m.Name, m.Description)
+				continue
+			}
+			count, err := m.Pending()
+			if err != nil {
+				return err
+			}
+			klog.Infof("[dry-run] %s: %s (%d documents)", m.Name, m.Description, count)
+			continue
+		}
+
+		klog.Infof("Applying DB migration %s: %s ...", m.Name, m.Description)
+		err := m.Up()
+		if err != nil {
+			return fmt.Errorf("%s: %w", m.Name, err)
+		}
+
+		_, err = GetDB().Insert(MongoCollectionMigrationName, AppliedMigration{
+			Name:          m.Name,
+			SchemaVersion: MongoCollectionMigrationSchemaVersion,
+			AppliedAt:     time.Now(),
+		})
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func MigrateDatabaseSchema() error {
-	klog.Infof("Migrating DB schema ...")
+func RollbackDatabaseSchema(name string, dryRun bool) error {
+	klog.Infof("Rolling back DB schema down to %s migration ...", name)
 
-	err := dbSchemaMigration()
-	if err != nil {
-		return err
+	if !dryRun {
+		unlock, err := lockMigrations()
+		if err != nil {
+			return err
+		}
+		defer unlock()
 	}
 
-	err = migrateInstances()
+	applied, err := findAppliedMigrations()
 	if err != nil {
 		return err
 	}
 
-	err = kawaiiCleanupDereferencedChildren()
+	rollback, err := rollbackMigrations(dbMigrations, applied, name)
 	if err != nil {
 		return err
 	}
 
+	for _, m := range rollback {
+		if dryRun {
+			klog.Infof("[dry-run] %s: %s", m.Name, m.Description)
+			continue
+		}
+
+		klog.Infof("Rolling back DB migration %s: %s ...", m.Name, m.Description)
+		err := m.Down()
+		if err != nil {
+			return fmt.Errorf("%s: %w", m.Name, err)
+		}
+
+		err = GetDB().DeleteByKey(MongoCollectionMigrationName, "_id", m.Name)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
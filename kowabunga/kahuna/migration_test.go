@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"testing"
+)
+
+func testMigrationNames(migrations []Migration) []string {
+	names := []string{}
+	for _, m := range migrations {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+func TestMigrationsRegistry(t *testing.T) {
+	seen := map[string]bool{}
+	for _, m := range dbMigrations {
+		if m.Name == "" || m.Up == nil {
+			t.Fatalf("invalid migration: %+v", m)
+		}
+		// dry-run reports affected documents count of all migrations
+		if m.Pending == nil {
+			t.Fatalf("migration %s does not report pending documents", m.Name)
+		}
+		if seen[m.Name] {
+			t.Fatalf("duplicated migration: %s", m.Name)
+		}
+		seen[m.Name] = true
+	}
+}
+
+func TestMigrationsOrder(t *testing.T) {
+	noop := func() error { return nil }
+	registry := []Migration{
+		{Name: "a", Up: noop, Down: noop},
+		{Name: "b", Up: noop},
+		{Name: "c", Up: noop, Down: noop},
+		{Name: "d", Up: noop, Down: noop},
+	}
+	applied := map[string]AppliedMigration{
+		"a": {Name: "a"},
+		"b": {Name: "b"},
+		"d": {Name: "d"},
+	}
+
+	pending := testMigrationNames(pendingMigrations(registry, applied))
+	if len(pending) != 1 || pending[0] != "c" {
+		t.Fatalf("unexpected pending migrations: %v", pending)
+	}
+
+	rollback, err := rollbackMigrations(registry, applied, "c")
+	if err == nil {
+		t.Fatalf("non-applied migration was rolled back: %v", testMigrationNames(rollback))
+	}
+
+	rollback, err = rollbackMigrations(registry, applied, "b")
+	if err == nil {
+		t.Fatalf("irreversible migration was rolled back: %v", testMigrationNames(rollback))
+	}
+
+	applied["c"] = AppliedMigration{Name: "c"}
+	rollback, err = rollbackMigrations(registry, applied, "c")
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	names := testMigrationNames(rollback)
+	if len(names) != 2 || names[0] != "d" || names[1] != "c" {
+		t.Fatalf("unexpected rollback order: %v", names)
+	}
+}
@@ -145,26 +145,32 @@ func NewMultiZonesResource(projectId, regionId, namePrefix, desc, profile, profi
 		VirtualIPs:        []VirtualIP{},
 	}
 
+	saga := NewSaga(fmt.Sprintf("Multi-Zones Resource %s", namePrefix))
+
+	// virtual IPs adapters are reserved zone after zone, release whatever has been reserved
+	saga.Compensate("virtual IPs adapters", mzr.deleteAdapters)
+
 	if mzr.Profile == CloudinitProfileKawaii {
 		// reserve public adapters for virtual IPs
 		// NOTE: NAT rules public IPs are not allocated here,
 		// they should have first be registered by API calls
 		err := mzr.RequestPublicVIPs()
 		if err != nil {
-			return nil, err
+			return nil, saga.Rollback(err)
 		}
 	} else {
 		// MZR must reserve (and bind) private virtual IPs.
 		// Note: kawaii doesn't need to, as it's using fixed zone-local gateways instead
 		err := mzr.RequestPrivateVIPs()
 		if err != nil {
-			return nil, err
+			return nil, saga.Rollback(err)
 		}
 	}
 
+	saga.Compensate("VRRP IDs", mzr.releaseVRIDs)
 	err = mzr.GetVirtualIPs()
 	if err != nil {
-		return nil, err
+		return nil, saga.Rollback(err)
 	}
 
 	// create a Kompute instance in each zone
@@ -172,14 +178,14 @@ func NewMultiZonesResource(projectId, regionId, namePrefix, desc, profile, profi
 	for _, zoneId := range r.Zones() {
 		z, err := FindZoneByID(zoneId)
 		if err != nil {
-			return nil, err
+			return nil, saga.Rollback(err)
 		}
 
 		// pick best host from zone
 		mzrName := fmt.Sprintf("%s-%s", namePrefix, z.Name)
 		h, err := z.ElectMostFavorableKaktus(mzrName, z.Kaktuses())
 		if err != nil {
-			return nil, err
+			return nil, saga.Rollback(err)
 		}
 
 		name := fmt.Sprintf("%s-1", mzrName)
@@ -188,18 +194,9 @@ func NewMultiZonesResource(projectId, regionId, namePrefix, desc, profile, profi
 		kompute, err := NewKompute(projectId, zoneId, h.String(), poolId, templateId,
 			name, desc, mzr.Profile, profileId, cpu, mem, disk, 0, false, subnetPeerings)
 		if err != nil {
-			for _, komputeId := range komputes {
-				komputeToDel, err := FindKomputeByID(komputeId)
-				if err != nil {
-					return nil, err
-				}
-				err = komputeToDel.Delete()
-				if err != nil {
-					klog.Error("Cleaning underlying Kompute " + komputeId + "failed. Continue cleaning " + err.Error() + ". ")
-				}
-			}
-			return nil, err
+			return nil, saga.Rollback(err)
 		}
+		saga.Compensate(fmt.Sprintf("Kompute %s", name), kompute.Delete)
 
 		komputes = append(komputes, kompute.String())
 	}
@@ -208,7 +205,7 @@ func NewMultiZonesResource(projectId, regionId, namePrefix, desc, profile, profi
 	klog.Debugf("Created new Multi-Zones Resource %s", mzr.String())
 	_, err = GetDB().Insert(MongoCollectionMzrName, mzr)
 	if err != nil {
-		return nil, err
+		return nil, saga.Rollback(err)
 	}
 
 	return &mzr, nil
@@ -471,6 +468,22 @@ func (mzr *MultiZonesResource) Delete() error {
 	}
 
 	// Destroy adapters
+	err := mzr.deleteAdapters()
+	if err != nil {
+		return err
+	}
+
+	// drop VRRP IDs from project
+	err = mzr.releaseVRIDs()
+	if err != nil {
+		return err
+	}
+
+	return GetDB().Delete(MongoCollectionMzrName, mzr.ID)
+}
+
+// deleteAdapters destroys virtual IPs adapters
+func (mzr *MultiZonesResource) deleteAdapters() error {
 	adapters := []string{}
 	for _, privateAdapterId := range mzr.PrivateAdapterIDs {
 		adapters = append(adapters, privateAdapterId)
@@ -488,8 +501,11 @@ func (mzr *MultiZonesResource) Delete() error {
 			return err
 		}
 	}
+	return nil
+}
 
-	// drop VRRP IDs from project
+// releaseVRIDs drops virtual IPs VRRP IDs from project
+func (mzr *MultiZonesResource) releaseVRIDs() error {
 	for _, vip := range mzr.VirtualIPs {
 		// read project reference multiple times, it's been updated at each iteration
 		prj, err := mzr.Project()
@@ -499,6 +515,5 @@ func (mzr *MultiZonesResource) Delete() error {
 
 		prj.RemoveVRID(vip.VRRP)
 	}
-
-	return GetDB().Delete(MongoCollectionMzrName, mzr.ID)
+	return nil
 }
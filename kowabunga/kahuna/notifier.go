@@ -0,0 +1,222 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * Notifications are delivered to users through the channels of their choice:
+ * email, generic HTTP webhook or Matrix/Slack-compatible incoming webhook.
+ */
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/kowabunga-cloud/common/klog"
+)
+
+const (
+	NotificationChannelEmail   = "email"
+	NotificationChannelWebhook = "webhook"
+	NotificationChannelChat    = "chat" // Matrix (hookshot) or Slack incoming webhook
+
+	NotifierTimeoutSeconds = 10
+
+	ErrNotificationChannelUnsupported = "unsupported notification channel"
+	ErrNotificationChannelInvalidURL  = "invalid notification channel URL, only https ones are supported"
+	ErrNotificationChannelDuplicated  = "duplicated notification channel"
+)
+
+// NotificationRecipient is who a notification is sent to, and where
+type NotificationRecipient struct {
+	Name  string
+	Email string
+	URL   string
+}
+
+// Notifier delivers notifications through a given channel
+type Notifier interface {
+	Notify(to NotificationRecipient, n *Notification) error
+}
+
+var notifiers = map[string]Notifier{
+	NotificationChannelEmail:   &SmtpNotifier{},
+	NotificationChannelWebhook: &WebhookNotifier{},
+	NotificationChannelChat:    &ChatNotifier{},
+}
+
+func GetNotifier(channel string) (Notifier, error) {
+	n, ok := notifiers[channel]
+	if !ok {
+		return nil, fmt.Errorf("%s: %s", ErrNotificationChannelUnsupported, channel)
+	}
+	return n, nil
+}
+
+// NotifyAdmin sends notification to platform administrator, by email
+func NotifyAdmin(n *Notification) error {
+	notifier, err := GetNotifier(NotificationChannelEmail)
+	if err != nil {
+		return err
+	}
+	return notifier.Notify(NotificationRecipient{Email: GetCfg().Global.AdminEmail}, n)
+}
+
+// UserNotificationChannel is one of user's notification preferences
+type UserNotificationChannel struct {
+	Type string `bson:"type"`
+	URL  string `bson:"url,omitempty"` // webhook and chat channels only, email ones being sent to user's address
+}
+
+type UserNotificationChannelModel struct {
+	Type string `json:"type"`
+	URL  string `json:"url,omitempty"`
+}
+
+func NewUserNotificationChannels(channels []UserNotificationChannelModel) ([]UserNotificationChannel, error) {
+	res := []UserNotificationChannel{}
+	for _, m := range channels {
+		c := UserNotificationChannel{
+			Type: m.Type,
+			URL:  m.URL,
+		}
+
+		_, err := GetNotifier(c.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.Type == NotificationChannelEmail {
+			c.URL = ""
+		} else {
+			err := validateEgressURL(c.URL, notifierSchemes)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", ErrNotificationChannelInvalidURL, err)
+			}
+		}
+
+		if slices.Contains(res, c) {
+			return nil, fmt.Errorf("%s: %s", ErrNotificationChannelDuplicated, c.Type)
+		}
+		res = append(res, c)
+	}
+	return res, nil
+}
+
+func (c *UserNotificationChannel) Model() UserNotificationChannelModel {
+	return UserNotificationChannelModel{
+		Type: c.Type,
+		URL:  c.URL,
+	}
+}
+
+// notify sends notification through all channels, only failing if none succeeded
+func notify(to NotificationRecipient, channels []UserNotificationChannel, n *Notification) error {
+	errs := []error{}
+	for _, c := range channels {
+		notifier, err := GetNotifier(c.Type)
+		if err == nil {
+			to.URL = c.URL
+			err = notifier.Notify(to, n)
+		}
+		if err != nil {
+			klog.Errorf("Unable to send %s notification to %s: %v", c.Type, to.Name, err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 && len(errs) == len(channels) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// notifications may carry personal data, which must not travel in clear
+var notifierSchemes = []string{"https"}
+
+// notifierClient sends notifications, never reaching control-plane's network
+var notifierClient = NewEgressHttpClient(NotifierTimeoutSeconds * time.Second)
+
+// notifierPost sends a JSON payload to an incoming webhook
+func notifierPost(uri string, payload any) error {
+	// channel may have been registered before being restricted
+	err := validateEgressURL(uri, notifierSchemes)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, uri, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := notifierClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected %s response status: %s", uri, resp.Status)
+	}
+
+	return nil
+}
+
+// WebhookNotifier POSTs notifications as generic JSON documents
+type WebhookNotifier struct{}
+
+type WebhookNotification struct {
+	Recipient string `json:"recipient"`
+	Subject   string `json:"subject"`
+	Text      string `json:"text"`
+	Timestamp string `json:"timestamp"`
+}
+
+func (s *WebhookNotifier) Notify(to NotificationRecipient, n *Notification) error {
+	text, err := n.Text()
+	if err != nil {
+		return err
+	}
+
+	return notifierPost(to.URL, WebhookNotification{
+		Recipient: to.Name,
+		Subject:   n.Subject,
+		Text:      text,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// ChatNotifier POSTs notifications to Matrix or Slack-compatible incoming webhooks
+type ChatNotifier struct{}
+
+type ChatNotification struct {
+	Text string `json:"text"`
+}
+
+func (s *ChatNotifier) Notify(to NotificationRecipient, n *Notification) error {
+	text, err := n.Text()
+	if err != nil {
+		return err
+	}
+
+	return notifierPost(to.URL, ChatNotification{
+		Text: fmt.Sprintf("%s\n\n%s", n.Subject, text),
+	})
+}
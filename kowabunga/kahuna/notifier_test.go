@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matcornic/hermes"
+)
+
+func TestUserNotificationChannels(t *testing.T) {
+	channels, err := NewUserNotificationChannels([]UserNotificationChannelModel{
+		{Type: NotificationChannelEmail, URL: "https://ignored.acme.com"},
+		{Type: NotificationChannelChat, URL: "https://hooks.slack.com/services/T0/B0/X"},
+	})
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if len(channels) != 2 || channels[0].URL != "" {
+		t.Fatalf("unexpected channels: %+v", channels)
+	}
+
+	invalid := [][]UserNotificationChannelModel{
+		{{Type: "pigeon"}},
+		{{Type: NotificationChannelWebhook, URL: "ftp://acme.com"}},
+		{{Type: NotificationChannelWebhook, URL: "http://acme.com/hook"}},
+		{{Type: NotificationChannelChat, URL: "https://169.254.169.254/hook"}},
+		{{Type: NotificationChannelEmail}, {Type: NotificationChannelEmail}},
+	}
+	for _, c := range invalid {
+		_, err := NewUserNotificationChannels(c)
+		if err == nil {
+			t.Fatalf("invalid channels have been accepted: %+v", c)
+		}
+	}
+}
+
+func TestUserEmailNotifications(t *testing.T) {
+	u := newUser("john", "", UserRoleStandard, true)
+	if !u.HasNotificationChannel(NotificationChannelEmail) {
+		t.Fatalf("email channel has not been set")
+	}
+
+	u.NotificationChannels = append(u.NotificationChannels, UserNotificationChannel{Type: NotificationChannelChat, URL: "https://matrix.acme.com/hook"})
+	u.setEmailNotifications(false)
+	if u.HasNotificationChannel(NotificationChannelEmail) || !u.HasNotificationChannel(NotificationChannelChat) {
+		t.Fatalf("unexpected channels: %+v", u.NotificationChannels)
+	}
+}
+
+func TestChatNotifier(t *testing.T) {
+	var received ChatNotification
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer srv.Close()
+
+	// test server listens on loopback, with a self-signed certificate
+	SetCfg(&KowabungaConfig{
+		Global: KowabungaGlobalConfig{
+			Egress: KowabungaEgressConfig{
+				AllowedNetworks: []string{"127.0.0.0/8"},
+			},
+		},
+	})
+	defer SetCfg(nil)
+	client := notifierClient
+	defer func() {
+		notifierClient = client
+	}()
+	notifierClient = NewEgressHttpClient(NotifierTimeoutSeconds * time.Second)
+	notifierClient.Transport.(*http.Transport).TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig
+
+	n := NewNotification("Your new instance has been created", hermes.Email{
+		Body: hermes.Body{
+			Intros: []string{"Congratulations"},
+		},
+	})
+
+	to := NotificationRecipient{Name: "john", URL: srv.URL}
+	err := notify(to, []UserNotificationChannel{{Type: NotificationChannelChat, URL: srv.URL}}, n)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if !strings.HasPrefix(received.Text, n.Subject) || !strings.Contains(received.Text, "Congratulations") {
+		t.Fatalf("unexpected chat message: %s", received.Text)
+	}
+
+	// a single failing channel is not an error, all of them is
+	channels := []UserNotificationChannel{
+		{Type: NotificationChannelWebhook, URL: "https://127.0.0.1:1"},
+		{Type: NotificationChannelChat, URL: srv.URL},
+	}
+	if notify(to, channels, n) != nil {
+		t.Fatalf("notification should have succeeded on chat channel")
+	}
+	if notify(to, channels[:1], n) == nil {
+		t.Fatalf("notification should have failed")
+	}
+
+	// channels registered before plain HTTP was refused are not notified anymore
+	if notifierPost(strings.Replace(srv.URL, "https://", "http://", 1), ChatNotification{}) == nil {
+		t.Fatalf("notification has been sent over plain HTTP")
+	}
+}
+
+// testNotifier records the channels notifications are sent through
+type testNotifier struct {
+	channel string
+	sent    *[]string
+}
+
+func (n *testNotifier) Notify(to NotificationRecipient, _ *Notification) error {
+	*n.sent = append(*n.sent, n.channel)
+	return nil
+}
+
+func TestUserNotifyAccount(t *testing.T) {
+	sent := []string{}
+	prev := notifiers
+	defer func() {
+		notifiers = prev
+	}()
+	notifiers = map[string]Notifier{}
+	for channel := range prev {
+		notifiers[channel] = &testNotifier{channel: channel, sent: &sent}
+	}
+
+	n := NewNotification("A new Kowabunga API key has been set !", hermes.Email{})
+
+	// credentials go through user's own channels
+	u := newUser("john", "", UserRoleStandard, false)
+	u.NotificationChannels = []UserNotificationChannel{{Type: NotificationChannelChat, URL: "https://matrix.acme.com/hook"}}
+	err := u.NotifyAccount(n)
+	if err != nil || len(sent) != 1 || sent[0] != NotificationChannelChat {
+		t.Fatalf("unexpected account notification channels: %v (%v)", sent, err)
+	}
+
+	// users with no channel are notified by email
+	sent = sent[:0]
+	u.NotificationChannels = []UserNotificationChannel{}
+	err = u.NotifyAccount(n)
+	if err != nil || len(sent) != 1 || sent[0] != NotificationChannelEmail {
+		t.Fatalf("unexpected account notification channels: %v (%v)", sent, err)
+	}
+
+	// but they opted out of other notifications
+	sent = sent[:0]
+	err = u.Notify(n)
+	if err != nil || len(sent) != 0 {
+		t.Fatalf("unexpected notification channels: %v (%v)", sent, err)
+	}
+}
@@ -0,0 +1,642 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * OpenID Connect authentication, through authorization code flow with PKCE
+ * (RFC 7636). Users are provisioned on first login and IdP groups claims are
+ * mapped to Kowabunga teams and roles, as set in configuration.
+ */
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/kowabunga-cloud/common/klog"
+)
+
+const (
+	MongoCollectionOidcLoginSchemaVersion = 1
+	MongoCollectionOidcLoginName          = "oidc_login"
+
+	OidcDiscoveryPath        = "/.well-known/openid-configuration"
+	OidcLoginTTLMinutes      = 10
+	OidcHttpTimeoutSeconds   = 10
+	OidcRandomBytesLength    = 32
+	OidcCodeChallengeMethod  = "S256"
+	OidcDefaultGroupsClaim   = "groups"
+	OidcTeamDescription      = "Managed through OpenID Connect groups mapping"
+	OidcCallbackRoute        = "/auth/oidc/callback"
+	OidcAllowedClockSkewSecs = 30
+
+	ErrOidcDisabled        = "OpenID Connect authentication is disabled"
+	ErrOidcInvalidState    = "invalid or expired OpenID Connect login state"
+	ErrOidcNoIdToken       = "no ID token in OpenID Connect token response"
+	ErrOidcNoSuchKey       = "no such OpenID Connect signing key"
+	ErrOidcInvalidNonce    = "OpenID Connect ID token nonce mismatch"
+	ErrOidcMissingEmail    = "OpenID Connect ID token holds no verified email address"
+	ErrOidcSubjectMismatch = "user account is already bound to another OpenID Connect identity"
+	ErrOidcOrganization    = "user account belongs to another organization"
+	ErrOidcUserDisabled    = "user account is disabled"
+	ErrOidcLinkDisabled    = "user account can't be linked to an OpenID Connect identity by email"
+)
+
+var oidcDefaultScopes = []string{"openid", "email", "profile"}
+
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+// roles by increasing privileges
+var oidcRolesPrecedence = []string{
+	UserRoleStandard,
+	UserRoleProjectAdmin,
+	UserRoleSuperAdmin,
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+type oidcJwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type oidcTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IdToken     string `json:"id_token"`
+}
+
+type OidcClaims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+	Groups            []string
+}
+
+type OidcProvider struct {
+	cfg            KowabungaOidcConfig
+	organizationID string // empty for platform-wide IdP
+	client         *http.Client
+	discovery      oidcDiscovery
+
+	mutex sync.RWMutex
+	keys  map[string]any
+}
+
+// OpenID Connect providers, platform-wide one and per-organization ones
+var oidcLock = &sync.Mutex{}
+var kOidc = map[string]*OidcProvider{}
+
+// GetOidcProvider returns organization's IdP, or platform-wide one if no organization is specified
+func GetOidcProvider(organizationId string) (*OidcProvider, error) {
+	oidcLock.Lock()
+	defer oidcLock.Unlock()
+
+	p, ok := kOidc[organizationId]
+	if ok {
+		return p, nil
+	}
+
+	cfg := GetCfg().Global.OIDC
+	if organizationId != "" {
+		o, err := FindOrganizationByID(organizationId)
+		if err != nil {
+			return nil, err
+		}
+		cfg, err = o.Auth.OidcConfig()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !cfg.Enabled {
+		return nil, fmt.Errorf("%s", ErrOidcDisabled)
+	}
+	if cfg.RedirectURL == "" {
+		cfg.RedirectURL = strings.TrimSuffix(GetCfg().Global.PublicURL, "/") + SdkBaseRoute + OidcCallbackRoute
+	}
+
+	p, err := NewOidcProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	p.organizationID = organizationId
+	kOidc[organizationId] = p
+
+	return p, nil
+}
+
+// InvalidateOidcProvider drops organization's IdP, to be reloaded on next use
+func InvalidateOidcProvider(organizationId string) {
+	oidcLock.Lock()
+	defer oidcLock.Unlock()
+	delete(kOidc, organizationId)
+}
+
+// NewOidcProvider retrieves IdP's metadata from its discovery endpoint
+func NewOidcProvider(cfg KowabungaOidcConfig) (*OidcProvider, error) {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = oidcDefaultScopes
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = OidcDefaultGroupsClaim
+	}
+
+	p := OidcProvider{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout: OidcHttpTimeoutSeconds * time.Second,
+		},
+		keys: map[string]any{},
+	}
+
+	err := p.getJSON(strings.TrimSuffix(cfg.Issuer, "/")+OidcDiscoveryPath, &p.discovery)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.discovery.Issuer != cfg.Issuer {
+		return nil, fmt.Errorf("OpenID Connect issuer mismatch: expected %s, got %s", cfg.Issuer, p.discovery.Issuer)
+	}
+
+	klog.Infof("Using OpenID Connect provider %s", p.discovery.Issuer)
+	return &p, nil
+}
+
+func (p *OidcProvider) getJSON(uri string, result any) error {
+	resp, err := p.client.Get(uri)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected %s response status: %s", uri, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func oidcRandomString() (string, error) {
+	b := make([]byte, OidcRandomBytesLength)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func oidcCodeChallenge(verifier string) string {
+	h := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
+
+// AuthCodeURL returns IdP's URL where user-agent is to be redirected to authenticate
+func (p *OidcProvider) AuthCodeURL(state, nonce, verifier string) string {
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", p.cfg.ClientID)
+	v.Set("redirect_uri", p.cfg.RedirectURL)
+	v.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", oidcCodeChallenge(verifier))
+	v.Set("code_challenge_method", OidcCodeChallengeMethod)
+
+	sep := "?"
+	if strings.Contains(p.discovery.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.discovery.AuthorizationEndpoint + sep + v.Encode()
+}
+
+// Exchange trades authorization code for an ID token
+func (p *OidcProvider) Exchange(code, verifier string) (string, error) {
+	v := url.Values{}
+	v.Set("grant_type", "authorization_code")
+	v.Set("code", code)
+	v.Set("redirect_uri", p.cfg.RedirectURL)
+	v.Set("client_id", p.cfg.ClientID)
+	v.Set("code_verifier", verifier)
+	if p.cfg.ClientSecret != "" {
+		v.Set("client_secret", p.cfg.ClientSecret)
+	}
+
+	resp, err := p.client.PostForm(p.discovery.TokenEndpoint, v)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected OpenID Connect token response status: %s", resp.Status)
+	}
+
+	var token oidcTokenResponse
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return "", err
+	}
+
+	if token.IdToken == "" {
+		return "", fmt.Errorf("%s", ErrOidcNoIdToken)
+	}
+
+	return token.IdToken, nil
+}
+
+func oidcDecodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func (k *oidcJwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := oidcDecodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := oidcDecodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported JWK curve %s", k.Crv)
+		}
+		x, err := oidcDecodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := oidcDecodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported JWK key type %s", k.Kty)
+}
+
+func (p *OidcProvider) refreshKeys() error {
+	var jwks struct {
+		Keys []oidcJwk `json:"keys"`
+	}
+	err := p.getJSON(p.discovery.JwksURI, &jwks)
+	if err != nil {
+		return err
+	}
+
+	keys := map[string]any{}
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			klog.Warningf("Ignoring OpenID Connect signing key %s: %v", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.keys = keys
+
+	return nil
+}
+
+func (p *OidcProvider) key(kid string) (any, bool) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	k, ok := p.keys[kid]
+	return k, ok
+}
+
+// signingKey looks up for ID token's signing key, refreshing IdP's key set on rotation
+func (p *OidcProvider) signingKey(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	k, ok := p.key(kid)
+	if ok {
+		return k, nil
+	}
+
+	err := p.refreshKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	k, ok = p.key(kid)
+	if !ok {
+		return nil, fmt.Errorf("%s: %s", ErrOidcNoSuchKey, kid)
+	}
+
+	return k, nil
+}
+
+// VerifyIdToken checks ID token's signature and validity, and returns its claims
+func (p *OidcProvider) VerifyIdToken(raw, nonce string) (*OidcClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, p.signingKey,
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(p.discovery.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(OidcAllowedClockSkewSecs*time.Second))
+	if err != nil {
+		return nil, err
+	}
+
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return nil, fmt.Errorf("%s", ErrOidcInvalidNonce)
+	}
+
+	c := OidcClaims{
+		Groups: []string{},
+	}
+	c.Subject, _ = claims["sub"].(string)
+	c.Email, _ = claims["email"].(string)
+	c.Name, _ = claims["name"].(string)
+	c.PreferredUsername, _ = claims["preferred_username"].(string)
+
+	// email is only trusted when IdP explicitly states it has been verified
+	c.EmailVerified, _ = claims["email_verified"].(bool)
+
+	groups, _ := claims[p.cfg.GroupsClaim].([]any)
+	for _, g := range groups {
+		if name, ok := g.(string); ok {
+			c.Groups = append(c.Groups, name)
+		}
+	}
+
+	return &c, nil
+}
+
+// oidcGroupsMapping returns the role granted by IdP groups (empty if none), the teams granted by IdP groups,
+// as well as all teams managed by mapping
+func oidcGroupsMapping(cfg KowabungaOidcConfig, groups []string) (string, []string, []string) {
+	role := ""
+	teams := []string{}
+	managedTeams := []string{}
+	for _, m := range cfg.Groups {
+		if m.Team != "" && !slices.Contains(managedTeams, m.Team) {
+			managedTeams = append(managedTeams, m.Team)
+		}
+
+		if !slices.Contains(groups, m.Name) {
+			continue
+		}
+
+		if m.Team != "" && !slices.Contains(teams, m.Team) {
+			teams = append(teams, m.Team)
+		}
+		if IsValidUserRole(m.Role) && slices.Index(oidcRolesPrecedence, m.Role) > slices.Index(oidcRolesPrecedence, role) {
+			role = m.Role
+		}
+	}
+
+	return role, teams, managedTeams
+}
+
+// oidcDefaultRole returns the role granted to newly provisioned users
+func oidcDefaultRole(cfg KowabungaOidcConfig, groupRole string) string {
+	role := cfg.DefaultRole
+	if !IsValidUserRole(role) {
+		role = UserRoleStandard
+	}
+	if slices.Index(oidcRolesPrecedence, groupRole) > slices.Index(oidcRolesPrecedence, role) {
+		role = groupRole
+	}
+	return role
+}
+
+// OidcLogin is a pending authorization request, awaiting for IdP's callback
+type OidcLogin struct {
+	ID             string    `bson:"_id"` // state
+	SchemaVersion  int       `bson:"schema_version"`
+	CreatedAt      time.Time `bson:"created_at"`
+	ExpiresAt      time.Time `bson:"expires_at"`
+	OrganizationID string    `bson:"organization_id"`
+	Nonce          string    `bson:"nonce"`
+	Verifier       string    `bson:"verifier"`
+}
+
+func NewOidcLogin(organizationId string) (*OidcLogin, error) {
+	state, err := oidcRandomString()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := oidcRandomString()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := oidcRandomString()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	l := OidcLogin{
+		ID:             state,
+		SchemaVersion:  MongoCollectionOidcLoginSchemaVersion,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(OidcLoginTTLMinutes * time.Minute),
+		OrganizationID: organizationId,
+		Nonce:          nonce,
+		Verifier:       verifier,
+	}
+
+	_, err = GetDB().Insert(MongoCollectionOidcLoginName, l)
+	if err != nil {
+		return nil, err
+	}
+
+	return &l, nil
+}
+
+// ConsumeOidcLogin retrieves pending authorization request, which can only be used once
+func ConsumeOidcLogin(state string) (*OidcLogin, error) {
+	filter := bson.D{bson.E{Key: "expires_at", Value: bson.D{bson.E{Key: "$lt", Value: time.Now()}}}}
+	err := GetDB().DeleteAllByFilter(MongoCollectionOidcLoginName, filter)
+	if err != nil {
+		klog.Error(err)
+	}
+
+	var l OidcLogin
+	err = GetDB().Find(MongoCollectionOidcLoginName, "_id", state, &l)
+	if err != nil || l.ID != state {
+		return nil, fmt.Errorf("%s", ErrOidcInvalidState)
+	}
+
+	err = GetDB().DeleteByKey(MongoCollectionOidcLoginName, "_id", state)
+	if err != nil {
+		return nil, err
+	}
+
+	return &l, nil
+}
+
+// ProvisionUser finds or creates the Kowabunga user matching OpenID Connect identity,
+// and synchronizes its role and teams membership with IdP groups.
+// Organization IdPs can only provision users and teams of their own organization.
+func (p *OidcProvider) ProvisionUser(c *OidcClaims) (*User, error) {
+	var o *Organization
+	if p.organizationID != "" {
+		var err error
+		o, err = FindOrganizationByID(p.organizationID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Email == "" || !c.EmailVerified {
+		return nil, fmt.Errorf("%s", ErrOidcMissingEmail)
+	}
+
+	name := c.PreferredUsername
+	if name == "" {
+		name = c.Name
+	}
+	if name == "" {
+		name, _, _ = strings.Cut(c.Email, "@")
+	}
+
+	groupRole, teams, managedTeams := oidcGroupsMapping(p.cfg, c.Groups)
+
+	u, err := FindUserByEmail(c.Email)
+	if err != nil {
+		u, err = NewOidcUser(name, c.Email, oidcDefaultRole(p.cfg, groupRole), p.discovery.Issuer, c.Subject)
+		if err != nil {
+			return nil, err
+		}
+		if o != nil {
+			err = o.AddUser(u)
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		if u.OrganizationID != p.organizationID {
+			return nil, fmt.Errorf("%s", ErrOidcOrganization)
+		}
+		if u.OidcSubject != "" && (u.OidcSubject != c.Subject || u.OidcIssuer != p.discovery.Issuer) {
+			return nil, fmt.Errorf("%s", ErrOidcSubjectMismatch)
+		}
+		// local accounts are only bound to IdP identity when explicitly allowed
+		if u.OidcSubject == "" && !p.cfg.LinkByEmail {
+			return nil, fmt.Errorf("%s", ErrOidcLinkDisabled)
+		}
+		if !u.Enabled {
+			return nil, fmt.Errorf("%s", ErrOidcUserDisabled)
+		}
+
+		// existing users are only ever promoted by groups mapping, never demoted
+		role := ""
+		if slices.Index(oidcRolesPrecedence, groupRole) > slices.Index(oidcRolesPrecedence, u.Role) {
+			role = groupRole
+		}
+		u.OidcIssuer = p.discovery.Issuer
+		u.OidcSubject = c.Subject
+		err = u.Update(u.Name, u.Description, "", role, u.HasNotificationChannel(NotificationChannelEmail))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// synchronize membership of teams managed by groups mapping
+	for _, name := range managedTeams {
+		member := slices.Contains(teams, name)
+
+		t, err := FindTeamByName(name)
+		if err != nil {
+			if !member {
+				continue
+			}
+			t, err = NewTeam(name, OidcTeamDescription, []string{u.String()})
+			if err != nil {
+				return nil, err
+			}
+			if o != nil {
+				err = o.AddTeam(t)
+				if err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		if t.OrganizationID != p.organizationID {
+			klog.Warningf("Team %s belongs to another organization, can't be managed by OpenID Connect groups mapping", name)
+			continue
+		}
+
+		users := t.Users()
+		switch {
+		case member && !slices.Contains(users, u.String()):
+			users = append(users, u.String())
+		case !member && slices.Contains(users, u.String()):
+			users = slices.DeleteFunc(slices.Clone(users), func(id string) bool {
+				return id == u.String()
+			})
+		default:
+			continue
+		}
+		err = t.Update(t.Name, t.Description, users)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// teams membership changes have been recorded by user's database copy
+	return FindUserByID(u.String())
+}
@@ -0,0 +1,240 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	TestOidcClientID     = "kowabunga"
+	TestOidcClientSecret = "secret"
+	TestOidcRedirectURL  = "https://kahuna.acme.com/api/v1/auth/oidc/callback"
+	TestOidcKeyID        = "test-key"
+	TestOidcCode         = "test-code"
+)
+
+// mockOidcIdP is a minimal OpenID Connect provider, issuing ID tokens for a single authorization code
+type mockOidcIdP struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	challenge string
+	nonce     string
+	groups    []string
+	noVerify  bool // omits email_verified claim
+}
+
+func newMockOidcIdP(t *testing.T) *mockOidcIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	idp := &mockOidcIdP{
+		key: key,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(OidcDiscoveryPath, idp.discovery)
+	mux.HandleFunc("/keys", idp.jwks)
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		idp.token(t, w, r)
+	})
+	idp.server = httptest.NewServer(mux)
+
+	return idp
+}
+
+func (idp *mockOidcIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	_ = json.NewEncoder(w).Encode(oidcDiscovery{
+		Issuer:                idp.server.URL,
+		AuthorizationEndpoint: idp.server.URL + "/authorize",
+		TokenEndpoint:         idp.server.URL + "/token",
+		JwksURI:               idp.server.URL + "/keys",
+	})
+}
+
+func (idp *mockOidcIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	enc := base64.RawURLEncoding
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"keys": []oidcJwk{
+			{
+				Kid: TestOidcKeyID,
+				Kty: "RSA",
+				Use: "sig",
+				N:   enc.EncodeToString(idp.key.N.Bytes()),
+				E:   enc.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+			},
+		},
+	})
+}
+
+func (idp *mockOidcIdP) token(t *testing.T, w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		t.Errorf("%s", err.Error())
+	}
+
+	if r.PostForm.Get("code") != TestOidcCode || r.PostForm.Get("client_secret") != TestOidcClientSecret {
+		http.Error(w, "invalid_grant", http.StatusBadRequest)
+		return
+	}
+
+	// PKCE: verifier must match previously sent challenge
+	if oidcCodeChallenge(r.PostForm.Get("code_verifier")) != idp.challenge {
+		http.Error(w, "invalid_grant", http.StatusBadRequest)
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":                idp.server.URL,
+		"aud":                TestOidcClientID,
+		"sub":                "1234",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"nonce":              idp.nonce,
+		"email":              "john.doe@acme.com",
+		"email_verified":     true,
+		"preferred_username": "jdoe",
+		"groups":             idp.groups,
+	}
+	if idp.noVerify {
+		delete(claims, "email_verified")
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = TestOidcKeyID
+	idToken, err := token.SignedString(idp.key)
+	if err != nil {
+		t.Errorf("%s", err.Error())
+	}
+
+	_ = json.NewEncoder(w).Encode(oidcTokenResponse{
+		AccessToken: "access",
+		TokenType:   "Bearer",
+		IdToken:     idToken,
+	})
+}
+
+func TestOidcAuthorizationCodeFlow(t *testing.T) {
+	idp := newMockOidcIdP(t)
+	defer idp.server.Close()
+	idp.groups = []string{"cloud-admins", "developers"}
+
+	p, err := NewOidcProvider(KowabungaOidcConfig{
+		Enabled:      true,
+		Issuer:       idp.server.URL,
+		ClientID:     TestOidcClientID,
+		ClientSecret: TestOidcClientSecret,
+		RedirectURL:  TestOidcRedirectURL,
+	})
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	verifier, _ := oidcRandomString()
+	nonce, _ := oidcRandomString()
+	authURL, err := url.Parse(p.AuthCodeURL("state", nonce, verifier))
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	query := authURL.Query()
+	if query.Get("code_challenge_method") != OidcCodeChallengeMethod || query.Get("redirect_uri") != TestOidcRedirectURL {
+		t.Errorf("unexpected authorization request: %s", authURL)
+	}
+	idp.challenge = query.Get("code_challenge")
+	idp.nonce = query.Get("nonce")
+
+	// PKCE verifier mismatch must be rejected
+	_, err = p.Exchange(TestOidcCode, "wrong-verifier")
+	if err == nil {
+		t.Errorf("code exchange succeeded with invalid verifier")
+	}
+
+	idToken, err := p.Exchange(TestOidcCode, verifier)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	_, err = p.VerifyIdToken(idToken, "wrong-nonce")
+	if err == nil {
+		t.Errorf("ID token verified with invalid nonce")
+	}
+
+	claims, err := p.VerifyIdToken(idToken, nonce)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if claims.Subject != "1234" || claims.Email != "john.doe@acme.com" || claims.PreferredUsername != "jdoe" {
+		t.Errorf("unexpected ID token claims: %+v", claims)
+	}
+	if !slices.Equal(claims.Groups, idp.groups) {
+		t.Errorf("unexpected groups claim: %v", claims.Groups)
+	}
+	if !claims.EmailVerified {
+		t.Errorf("email should be verified")
+	}
+
+	// missing email_verified claim must not be trusted
+	idp.noVerify = true
+	idToken, err = p.Exchange(TestOidcCode, verifier)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	claims, err = p.VerifyIdToken(idToken, nonce)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if claims.EmailVerified {
+		t.Errorf("email without email_verified claim is considered verified")
+	}
+}
+
+func TestOidcGroupsMapping(t *testing.T) {
+	cfg := KowabungaOidcConfig{
+		DefaultRole: UserRoleStandard,
+		Groups: []KowabungaOidcGroupConfig{
+			{Name: "cloud-admins", Role: UserRoleSuperAdmin, Team: "admins"},
+			{Name: "project-leads", Role: UserRoleProjectAdmin},
+			{Name: "developers", Team: "devs"},
+		},
+	}
+
+	role, teams, managed := oidcGroupsMapping(cfg, []string{"developers", "project-leads"})
+	if role != UserRoleProjectAdmin {
+		t.Errorf("unexpected role %s", role)
+	}
+	if !slices.Equal(teams, []string{"devs"}) {
+		t.Errorf("unexpected teams %v", teams)
+	}
+	if !slices.Equal(managed, []string{"admins", "devs"}) {
+		t.Errorf("unexpected managed teams %v", managed)
+	}
+
+	role, teams, _ = oidcGroupsMapping(cfg, []string{"unknown"})
+	if role != "" || len(teams) != 0 {
+		t.Errorf("unexpected mapping %s %v", role, teams)
+	}
+	if oidcDefaultRole(cfg, role) != UserRoleStandard {
+		t.Errorf("unexpected default role %s", oidcDefaultRole(cfg, role))
+	}
+
+	cfg.DefaultRole = UserRoleProjectAdmin
+	if oidcDefaultRole(cfg, UserRoleSuperAdmin) != UserRoleSuperAdmin || oidcDefaultRole(cfg, "") != UserRoleProjectAdmin {
+		t.Errorf("unexpected default role")
+	}
+}
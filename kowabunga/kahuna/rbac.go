@@ -0,0 +1,402 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * Role-based access control: teams are bound to projects with a role (viewer,
+ * operator or admin), granting a set of permissions on project's resources.
+ * Each API operation requires a permission, and the resource it targets is
+ * resolved to its owning project, whose bindings tell whether user is granted it.
+ */
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/kowabunga-cloud/common/klog"
+)
+
+const (
+	RbacPermissionRead  = "read"
+	RbacPermissionPower = "power"
+	RbacPermissionWrite = "write"
+
+	ProjectRoleViewer   = "viewer"
+	ProjectRoleOperator = "operator"
+	ProjectRoleAdmin    = "admin"
+
+	// teams granted access to a project without explicit binding
+	ProjectRoleDefault = ProjectRoleAdmin
+
+	ErrRbacInvalidRole = "invalid project role"
+	ErrRbacInvalidTeam = "unknown team in project role binding"
+)
+
+// project roles, by increasing privileges
+var projectRoles = []string{
+	ProjectRoleViewer,
+	ProjectRoleOperator,
+	ProjectRoleAdmin,
+}
+
+var projectRolePermissions = map[string][]string{
+	ProjectRoleViewer:   {RbacPermissionRead},
+	ProjectRoleOperator: {RbacPermissionRead, RbacPermissionPower},
+	ProjectRoleAdmin:    {RbacPermissionRead, RbacPermissionPower, RbacPermissionWrite},
+}
+
+// API operation -> required permission.
+// Operations not listed here require read permission on GET and write permission otherwise.
+var rbacRoutePermissions = map[string]string{
+	"StartInstance":    RbacPermissionPower,
+	"StopInstance":     RbacPermissionPower,
+	"RebootInstance":   RbacPermissionPower,
+	"ResetInstance":    RbacPermissionPower,
+	"SuspendInstance":  RbacPermissionPower,
+	"ResumeInstance":   RbacPermissionPower,
+	"ShutdownInstance": RbacPermissionPower,
+	"StartKompute":     RbacPermissionPower,
+	"StopKompute":      RbacPermissionPower,
+	"RebootKompute":    RbacPermissionPower,
+	"ResetKompute":     RbacPermissionPower,
+	"SuspendKompute":   RbacPermissionPower,
+	"ResumeKompute":    RbacPermissionPower,
+	"ShutdownKompute":  RbacPermissionPower,
+
+	"PlanProjectManifest": RbacPermissionRead,
+}
+
+// API operations on user's credentials, notification channels and account, which organization
+// administrators are not granted on their organization's members
+var rbacOrganizationAdminDeniedRoutes = []string{
+	"DeleteUser",
+	"SetUserPassword",
+	"ResetUserPassword",
+	"SetUserApiToken",
+	"SetUserNotificationChannels",
+}
+
+// rbacDirectory looks up the resources authorization decisions rely on
+type rbacDirectory struct {
+	refs                      func(collection, id string) (KowabungaDocumentRefs, error)
+	project                   func(id string) (*Project, error)
+	organization              func(id string) (*Organization, error)
+	administeredOrganizations func(userId string) ([]Organization, error)
+	user                      func(id string) (*User, error)
+}
+
+var rbacDir = rbacDirectory{
+	refs: func(collection, id string) (KowabungaDocumentRefs, error) {
+		return GetDB().FindRefs(collection, id)
+	},
+	project:                   FindProjectByID,
+	organization:              FindOrganizationByID,
+	administeredOrganizations: FindAdministeredOrganizations,
+	user:                      FindUserByID,
+}
+
+type ProjectRoleBinding struct {
+	TeamID string `bson:"team_id"`
+	Role   string `bson:"role"`
+}
+
+type ProjectRoleBindingModel struct {
+	Team string `json:"team"`
+	Role string `json:"role"`
+}
+
+type ProjectPermissionsModel struct {
+	Project     string   `json:"project"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+type UserPermissionsModel struct {
+	Role          string                    `json:"role"`
+	Organizations []string                  `json:"organizations"`
+	Projects      []ProjectPermissionsModel `json:"projects"`
+}
+
+func IsValidProjectRole(role string) bool {
+	return slices.Contains(projectRoles, role)
+}
+
+func NewProjectRoleBinding(m ProjectRoleBindingModel) (ProjectRoleBinding, error) {
+	b := ProjectRoleBinding{
+		TeamID: m.Team,
+		Role:   m.Role,
+	}
+
+	if !IsValidProjectRole(m.Role) {
+		return b, fmt.Errorf("%s: %s", ErrRbacInvalidRole, m.Role)
+	}
+
+	_, err := FindTeamByID(m.Team)
+	if err != nil {
+		return b, fmt.Errorf("%s: %s", ErrRbacInvalidTeam, m.Team)
+	}
+
+	return b, nil
+}
+
+func (b *ProjectRoleBinding) Model() ProjectRoleBindingModel {
+	return ProjectRoleBindingModel{
+		Team: b.TeamID,
+		Role: b.Role,
+	}
+}
+
+// RoutePermission returns the permission required to perform an API operation
+func RoutePermission(name, method string) string {
+	perm, ok := rbacRoutePermissions[name]
+	if ok {
+		return perm
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return RbacPermissionRead
+	}
+	return RbacPermissionWrite
+}
+
+// ProjectRolePermissions returns the permissions granted by a project role
+func ProjectRolePermissions(role string) []string {
+	return projectRolePermissions[role]
+}
+
+// highestProjectRole returns the most privileged of two roles
+func highestProjectRole(a, b string) string {
+	if slices.Index(projectRoles, a) > slices.Index(projectRoles, b) {
+		return a
+	}
+	return b
+}
+
+// rbacTarget references the project and organizations owning a request's targeted resources
+type rbacTarget struct {
+	ProjectID       string
+	OrganizationIDs []string
+	PrivilegedUser  bool // targets a user with a role above standard
+	Ambiguous       bool
+}
+
+func (t *rbacTarget) setProject(projectId string) {
+	if t.ProjectID != "" && t.ProjectID != projectId {
+		t.Ambiguous = true
+		return
+	}
+	t.ProjectID = projectId
+}
+
+func (t *rbacTarget) addOrganization(organizationId string) {
+	if organizationId != "" && !slices.Contains(t.OrganizationIDs, organizationId) {
+		t.OrganizationIDs = append(t.OrganizationIDs, organizationId)
+	}
+}
+
+// rbacResolveTarget resolves request's route variables to the project and organizations they belong to
+func rbacResolveTarget(r *http.Request) rbacTarget {
+	target := rbacTarget{
+		OrganizationIDs: []string{},
+	}
+
+	for varName, id := range mux.Vars(r) {
+		collection, ok := revisionRouteVarCollections[varName]
+		if !ok || id == "" {
+			continue
+		}
+
+		if collection == MongoCollectionProjectName {
+			target.setProject(id)
+			continue
+		}
+		if collection == MongoCollectionOrganizationName {
+			target.addOrganization(id)
+			continue
+		}
+		if collection == MongoCollectionUserName {
+			u, err := rbacDir.user(id)
+			if err == nil && u.IsProjectAdmin() {
+				target.PrivilegedUser = true
+			}
+		}
+
+		refs, err := rbacDir.refs(collection, id)
+		if err != nil {
+			continue
+		}
+		target.addOrganization(refs.OrganizationID)
+
+		// Kawaii IPsec connections belong to their Kawaii's project
+		if refs.KawaiiID != "" {
+			refs, err = rbacDir.refs(MongoCollectionKawaiiName, refs.KawaiiID)
+			if err != nil {
+				continue
+			}
+		}
+		if refs.ProjectID != "" {
+			target.setProject(refs.ProjectID)
+		}
+	}
+
+	if target.ProjectID != "" {
+		prj, err := rbacDir.project(target.ProjectID)
+		if err == nil {
+			target.addOrganization(prj.OrganizationID)
+		}
+	}
+
+	return target
+}
+
+// userProjectRole returns the role user is granted on project, through its teams
+func userProjectRole(u *User, prj *Project) (string, bool) {
+	role := ""
+	for _, teamId := range u.Teams() {
+		teamRole, ok := prj.TeamRole(teamId)
+		if !ok {
+			continue
+		}
+		role = highestProjectRole(role, teamRole)
+	}
+	return role, role != ""
+}
+
+// rbacOrganizationAdminAllowed tells whether request may be granted to target's organization administrators
+func rbacOrganizationAdminAllowed(r *http.Request, name string, target *rbacTarget) bool {
+	if slices.Contains(rbacOrganizationAdminDeniedRoutes, name) {
+		return false
+	}
+	if target.PrivilegedUser && RoutePermission(name, r.Method) != RbacPermissionRead {
+		return false
+	}
+	return true
+}
+
+// userIsOrganizationsAdmin tells whether user administrates all of the specified organizations
+func userIsOrganizationsAdmin(userId string, organizationIds []string) bool {
+	if len(organizationIds) == 0 {
+		return false
+	}
+
+	for _, id := range organizationIds {
+		o, err := rbacDir.organization(id)
+		if err != nil || !o.IsAdmin(userId) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// reqIsRbacAuthorized tells whether user is granted the permission required by request on its target
+func reqIsRbacAuthorized(r *http.Request, name, userId string) bool {
+	target := rbacResolveTarget(r)
+	if target.Ambiguous {
+		klog.Debugf("Request %s %s targets resources from different projects", r.Method, name)
+		return false
+	}
+
+	// organization administrators manage all of their organization's tenants, but their members' credentials
+	// and accounts, as well as privileged members
+	if rbacOrganizationAdminAllowed(r, name, &target) && userIsOrganizationsAdmin(userId, target.OrganizationIDs) {
+		return true
+	}
+
+	if target.ProjectID == "" {
+		return false
+	}
+
+	u, err := rbacDir.user(userId)
+	if err != nil {
+		return false
+	}
+
+	prj, err := rbacDir.project(target.ProjectID)
+	if err != nil {
+		return false
+	}
+
+	role, ok := userProjectRole(u, prj)
+	if !ok {
+		return false
+	}
+
+	perm := RoutePermission(name, r.Method)
+	if !slices.Contains(ProjectRolePermissions(role), perm) {
+		klog.Debugf("Request %s %s requires %s permission, not granted by %s role on project %s", r.Method, name, perm, role, prj.Name)
+		return false
+	}
+
+	return true
+}
+
+// rbacProjectsFilter restricts projects list queries to the ones user has access to, either
+// through its teams or as organization administrator
+func rbacProjectsFilter(ctx context.Context) (bson.D, error) {
+	if ctxGetSuperAdminRole(ctx) {
+		return nil, nil
+	}
+
+	u, err := rbacDir.user(ctxGetUserId(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	organizations := []string{}
+	administered, err := rbacDir.administeredOrganizations(u.String())
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range administered {
+		organizations = append(organizations, o.String())
+	}
+
+	teams := append([]string{}, u.Teams()...)
+	return bson.D{bson.E{Key: "$or", Value: bson.A{
+		bson.D{bson.E{Key: "team_ids", Value: bson.D{bson.E{Key: "$in", Value: teams}}}},
+		bson.D{bson.E{Key: "organization_id", Value: bson.D{bson.E{Key: "$in", Value: organizations}}}},
+	}}}, nil
+}
+
+// UserPermissions returns user's effective permissions on all projects it has access to
+func UserPermissions(u *User) UserPermissionsModel {
+	perms := UserPermissionsModel{
+		Role:          u.Role,
+		Organizations: []string{},
+		Projects:      []ProjectPermissionsModel{},
+	}
+
+	organizations, err := FindAdministeredOrganizations(u.String())
+	if err == nil {
+		for _, o := range organizations {
+			perms.Organizations = append(perms.Organizations, o.String())
+		}
+	}
+
+	for _, prj := range FindProjects() {
+		role, ok := userProjectRole(u, &prj)
+		if u.IsSuperAdmin() || slices.Contains(perms.Organizations, prj.OrganizationID) {
+			role, ok = ProjectRoleAdmin, true
+		}
+		if !ok {
+			continue
+		}
+
+		perms.Projects = append(perms.Projects, ProjectPermissionsModel{
+			Project:     prj.String(),
+			Role:        role,
+			Permissions: ProjectRolePermissions(role),
+		})
+	}
+
+	return perms
+}
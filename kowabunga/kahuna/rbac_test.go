@@ -0,0 +1,463 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/kowabunga-cloud/kowabunga/kowabunga/sdk"
+)
+
+// testRbacFixture is an in-memory directory of the resources authorization decisions rely on
+type testRbacFixture struct {
+	refs          map[string]KowabungaDocumentRefs // by collection/id
+	projects      map[string]*Project
+	organizations map[string]*Organization
+	users         map[string]*User
+}
+
+func newTestRbacFixture(t *testing.T) *testRbacFixture {
+	f := &testRbacFixture{
+		refs:          map[string]KowabungaDocumentRefs{},
+		projects:      map[string]*Project{},
+		organizations: map[string]*Organization{},
+		users:         map[string]*User{},
+	}
+
+	prev := rbacDir
+	t.Cleanup(func() {
+		rbacDir = prev
+	})
+
+	rbacDir = rbacDirectory{
+		refs: func(collection, id string) (KowabungaDocumentRefs, error) {
+			refs, ok := f.refs[collection+"/"+id]
+			if !ok {
+				return refs, fmt.Errorf("no such document %s/%s", collection, id)
+			}
+			return refs, nil
+		},
+		project: func(id string) (*Project, error) {
+			p, ok := f.projects[id]
+			if !ok {
+				return nil, fmt.Errorf("no such project %s", id)
+			}
+			return p, nil
+		},
+		organization: func(id string) (*Organization, error) {
+			o, ok := f.organizations[id]
+			if !ok {
+				return nil, fmt.Errorf("no such organization %s", id)
+			}
+			return o, nil
+		},
+		administeredOrganizations: func(userId string) ([]Organization, error) {
+			organizations := []Organization{}
+			for _, o := range f.organizations {
+				if o.IsAdmin(userId) {
+					organizations = append(organizations, *o)
+				}
+			}
+			return organizations, nil
+		},
+		user: func(id string) (*User, error) {
+			u, ok := f.users[id]
+			if !ok {
+				return nil, fmt.Errorf("no such user %s", id)
+			}
+			return u, nil
+		},
+	}
+
+	return f
+}
+
+func (f *testRbacFixture) organization(name string) *Organization {
+	o := &Organization{
+		Resource: NewResource(name, "", MongoCollectionOrganizationSchemaVersion),
+		AdminIDs: []string{},
+	}
+	f.organizations[o.String()] = o
+	return o
+}
+
+func (f *testRbacFixture) user(name, role string, o *Organization, teams ...string) *User {
+	u := &User{
+		Resource: NewResource(name, "", MongoCollectionUserSchemaVersion),
+		Role:     role,
+		TeamIDs:  teams,
+	}
+	refs := KowabungaDocumentRefs{}
+	if o != nil {
+		u.OrganizationID = o.String()
+		refs.OrganizationID = o.String()
+	}
+	f.users[u.String()] = u
+	f.refs[MongoCollectionUserName+"/"+u.String()] = refs
+	return u
+}
+
+func (f *testRbacFixture) project(name string, o *Organization) *Project {
+	p := &Project{
+		Resource: NewResource(name, "", MongoCollectionProjectSchemaVersion),
+		TeamIDs:  []string{},
+	}
+	if o != nil {
+		p.OrganizationID = o.String()
+	}
+	f.projects[p.String()] = p
+	return p
+}
+
+// bind grants team the specified role on project
+func (f *testRbacFixture) bind(p *Project, team, role string) {
+	p.TeamIDs = append(p.TeamIDs, team)
+	p.RoleBindings = append(p.RoleBindings, ProjectRoleBinding{
+		TeamID: team,
+		Role:   role,
+	})
+}
+
+// resource registers a project's resource
+func (f *testRbacFixture) resource(collection string, p *Project) string {
+	r := NewResource("", "", 1)
+	f.refs[collection+"/"+r.String()] = KowabungaDocumentRefs{
+		ProjectID:      p.String(),
+		OrganizationID: p.OrganizationID,
+	}
+	return r.String()
+}
+
+func TestRbacProjectsFilter(t *testing.T) {
+	f := newTestRbacFixture(t)
+	acme := f.organization("acme")
+	u := f.user("john", UserRoleStandard, acme, "team-a")
+
+	ctx := ctxSetUserId(context.Background(), u.String())
+	filter, err := rbacProjectsFilter(ctx)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	expected := `{"$or":[{"team_ids":{"$in":["team-a"]}},{"organization_id":{"$in":[]}}]}`
+	if filter.String() != expected {
+		t.Fatalf("unexpected projects filter: %v", filter)
+	}
+
+	// organization administrators see all of their organization's projects
+	acme.AdminIDs = []string{u.String()}
+	filter, err = rbacProjectsFilter(ctx)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	expected = fmt.Sprintf(`{"$or":[{"team_ids":{"$in":["team-a"]}},{"organization_id":{"$in":["%s"]}}]}`, acme.String())
+	if filter.String() != expected {
+		t.Fatalf("unexpected projects filter: %v", filter)
+	}
+
+	// users with no team see none but their organization's ones
+	acme.AdminIDs = []string{}
+	loner := f.user("jane", UserRoleStandard, nil)
+	filter, err = rbacProjectsFilter(ctxSetUserId(context.Background(), loner.String()))
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	expected = `{"$or":[{"team_ids":{"$in":[]}},{"organization_id":{"$in":[]}}]}`
+	if filter.String() != expected {
+		t.Fatalf("unexpected projects filter: %v", filter)
+	}
+
+	_, err = rbacProjectsFilter(ctxSetUserId(context.Background(), "unknown"))
+	if err == nil {
+		t.Fatalf("unknown user has been granted projects listing")
+	}
+
+	filter, err = rbacProjectsFilter(ctxSetSuperAdminRole(context.Background()))
+	if err != nil || filter != nil {
+		t.Fatalf("super-administrator projects listing has been restricted: %v", filter)
+	}
+}
+
+func TestProjectListModel(t *testing.T) {
+	p := Project{
+		Resource:     NewResource("acme", "", MongoCollectionProjectSchemaVersion),
+		RootPassword: "s3cr3t",
+	}
+
+	m := listModel[Project, sdk.Project](&p)
+	if m.RootPassword != "" {
+		t.Fatalf("project root password is exposed in lists")
+	}
+	if p.Model().RootPassword != "s3cr3t" {
+		t.Fatalf("project root password is not exposed to project readers")
+	}
+}
+
+func testRbacRequest(method string, vars map[string]string) *http.Request {
+	return mux.SetURLVars(httptest.NewRequest(method, "/", nil), vars)
+}
+
+func TestRbacOrganizationAdminUsers(t *testing.T) {
+	f := newTestRbacFixture(t)
+	acme := f.organization("acme")
+	admin := f.user("admin", UserRoleStandard, acme)
+	acme.AdminIDs = []string{admin.String()}
+	member := f.user("john", UserRoleStandard, acme)
+	privileged := f.user("root", UserRoleSuperAdmin, acme)
+
+	for _, tc := range []struct {
+		method  string
+		name    string
+		user    *User
+		allowed bool
+	}{
+		{http.MethodGet, "ReadUser", member, true},
+		{http.MethodPut, "UpdateUser", member, true},
+		{http.MethodDelete, "DeleteUser", member, false},
+		{http.MethodPut, "SetUserPassword", member, false},
+		{http.MethodPut, "ResetUserPassword", member, false},
+		{http.MethodPut, "SetUserApiToken", member, false},
+		{http.MethodPut, "SetUserNotificationChannels", member, false},
+		{http.MethodGet, "ReadUser", privileged, true},
+		{http.MethodPut, "UpdateUser", privileged, false},
+	} {
+		r := testRbacRequest(tc.method, map[string]string{"userId": tc.user.String()})
+		if reqIsRbacAuthorized(r, tc.name, admin.String()) != tc.allowed {
+			t.Errorf("organization administrator %s on %s user: expected %v", tc.name, tc.user.Role, tc.allowed)
+		}
+	}
+}
+
+func TestUserUpdateAllowed(t *testing.T) {
+	f := newTestRbacFixture(t)
+	acme := f.organization("acme")
+	admin := f.user("admin", UserRoleStandard, acme)
+	member := f.user("john", UserRoleStandard, acme)
+	member.Email = "john@acme.com"
+
+	ctx := ctxSetUserId(context.Background(), admin.String())
+	if userUpdateAllowed(ctx, member, sdk.User{Name: "johnny"}) != nil {
+		t.Fatalf("organization administrator has been denied member's name update")
+	}
+	if userUpdateAllowed(ctx, member, sdk.User{Email: "admin@evil.com"}) == nil {
+		t.Fatalf("organization administrator has been allowed member's email change")
+	}
+	if userUpdateAllowed(ctx, member, sdk.User{Email: member.Email}) != nil {
+		t.Fatalf("unchanged email has been denied")
+	}
+	if userUpdateAllowed(ctx, member, sdk.User{Role: UserRoleSuperAdmin}) == nil {
+		t.Fatalf("organization administrator has been allowed member's role change")
+	}
+
+	self := ctxSetUserId(context.Background(), member.String())
+	if userUpdateAllowed(self, member, sdk.User{Email: "john@example.com"}) != nil {
+		t.Fatalf("user has been denied its own email change")
+	}
+
+	if userUpdateAllowed(ctxSetSuperAdminRole(ctx), member, sdk.User{Email: "john@example.com", Role: UserRoleProjectAdmin}) != nil {
+		t.Fatalf("super-administrator has been denied user update")
+	}
+}
+
+func TestRbacProjectRoles(t *testing.T) {
+	f := newTestRbacFixture(t)
+	acme := f.organization("acme")
+	prj := f.project("webapp", acme)
+	instanceId := f.resource(MongoCollectionInstanceName, prj)
+
+	// operations and the permission they require, explicitly or by method
+	type operation struct {
+		method string
+		name   string
+		perm   string
+	}
+	operations := []operation{
+		{http.MethodGet, "ReadInstance", RbacPermissionRead},
+		{http.MethodPut, "UpdateInstance", RbacPermissionWrite},
+		{http.MethodDelete, "DeleteInstance", RbacPermissionWrite},
+	}
+	for name, perm := range rbacRoutePermissions {
+		operations = append(operations, operation{http.MethodPost, name, perm})
+	}
+
+	for _, role := range projectRoles {
+		team := "team-" + role
+		f.bind(prj, team, role)
+		u := f.user(role, UserRoleStandard, acme, team)
+
+		for _, op := range operations {
+			if RoutePermission(op.name, op.method) != op.perm {
+				t.Fatalf("%s requires %s permission, expected %s", op.name, RoutePermission(op.name, op.method), op.perm)
+			}
+
+			r := testRbacRequest(op.method, map[string]string{"instanceId": instanceId})
+			expected := slices.Contains(ProjectRolePermissions(role), op.perm)
+			if reqIsRbacAuthorized(r, op.name, u.String()) != expected {
+				t.Errorf("project %s %s: expected %v", role, op.name, expected)
+			}
+		}
+	}
+
+	// teams with no explicit binding are granted default role
+	prj.TeamIDs = append(prj.TeamIDs, "team-default")
+	u := f.user("default", UserRoleStandard, acme, "team-default")
+	r := testRbacRequest(http.MethodDelete, map[string]string{"instanceId": instanceId})
+	if !reqIsRbacAuthorized(r, "DeleteInstance", u.String()) {
+		t.Errorf("project default role has been denied write permission")
+	}
+
+	// users with no team on project are granted nothing
+	outsider := f.user("outsider", UserRoleStandard, acme, "team-other")
+	r = testRbacRequest(http.MethodGet, map[string]string{"instanceId": instanceId})
+	if reqIsRbacAuthorized(r, "ReadInstance", outsider.String()) {
+		t.Errorf("user with no project role has been granted read permission")
+	}
+}
+
+func TestRbacOrganizationAdminRoutes(t *testing.T) {
+	f := newTestRbacFixture(t)
+	acme := f.organization("acme")
+	admin := f.user("admin", UserRoleStandard, acme)
+	acme.AdminIDs = []string{admin.String()}
+	member := f.user("john", UserRoleStandard, acme)
+	prj := f.project("webapp", acme)
+	instanceId := f.resource(MongoCollectionInstanceName, prj)
+
+	for _, name := range rbacOrganizationAdminDeniedRoutes {
+		for _, method := range []string{http.MethodPut, http.MethodPost, http.MethodDelete} {
+			r := testRbacRequest(method, map[string]string{"userId": member.String()})
+			if reqIsRbacAuthorized(r, name, admin.String()) {
+				t.Errorf("organization administrator has been granted %s %s on member", method, name)
+			}
+		}
+	}
+
+	// organization administrators manage all of their organization's projects resources, with no team
+	for _, tc := range []struct {
+		method string
+		name   string
+	}{
+		{http.MethodGet, "ReadInstance"},
+		{http.MethodPut, "UpdateInstance"},
+		{http.MethodDelete, "DeleteInstance"},
+		{http.MethodPost, "RebootInstance"},
+	} {
+		r := testRbacRequest(tc.method, map[string]string{"instanceId": instanceId})
+		if !reqIsRbacAuthorized(r, tc.name, admin.String()) {
+			t.Errorf("organization administrator has been denied %s on organization's instance", tc.name)
+		}
+	}
+
+	r := testRbacRequest(http.MethodPut, map[string]string{"organizationId": acme.String()})
+	if !reqIsRbacAuthorized(r, "UpdateOrganization", admin.String()) {
+		t.Errorf("organization administrator has been denied organization update")
+	}
+	if reqIsRbacAuthorized(r, "UpdateOrganization", member.String()) {
+		t.Errorf("organization member has been granted organization update")
+	}
+}
+
+func TestRbacAmbiguousTarget(t *testing.T) {
+	f := newTestRbacFixture(t)
+	acme := f.organization("acme")
+	webapp := f.project("webapp", acme)
+	backend := f.project("backend", acme)
+	f.bind(webapp, "team-a", ProjectRoleAdmin)
+	f.bind(backend, "team-a", ProjectRoleAdmin)
+	u := f.user("john", UserRoleStandard, acme, "team-a")
+	admin := f.user("admin", UserRoleStandard, acme)
+	acme.AdminIDs = []string{admin.String()}
+
+	instanceId := f.resource(MongoCollectionInstanceName, webapp)
+	volumeId := f.resource(MongoCollectionVolumeName, backend)
+
+	for _, vars := range []map[string]string{
+		{"instanceId": instanceId, "volumeId": volumeId},
+		{"projectId": backend.String(), "instanceId": instanceId},
+	} {
+		r := testRbacRequest(http.MethodPut, vars)
+		target := rbacResolveTarget(r)
+		if !target.Ambiguous {
+			t.Errorf("target %v has not been detected as ambiguous", vars)
+		}
+		if reqIsRbacAuthorized(r, "UpdateInstance", u.String()) {
+			t.Errorf("ambiguous target %v has been granted to project administrator", vars)
+		}
+		if reqIsRbacAuthorized(r, "UpdateInstance", admin.String()) {
+			t.Errorf("ambiguous target %v has been granted to organization administrator", vars)
+		}
+	}
+
+	// resources of the same project are not ambiguous
+	r := testRbacRequest(http.MethodPut, map[string]string{"projectId": webapp.String(), "instanceId": instanceId})
+	target := rbacResolveTarget(r)
+	if target.Ambiguous || target.ProjectID != webapp.String() {
+		t.Errorf("unexpected target: %+v", target)
+	}
+
+	// Kawaii IPsec connections resolve to their Kawaii's project
+	kawaiiId := f.resource(MongoCollectionKawaiiName, webapp)
+	ipsec := NewResource("", "", 1)
+	f.refs[MongoCollectionIPsecName+"/"+ipsec.String()] = KowabungaDocumentRefs{KawaiiID: kawaiiId}
+	r = testRbacRequest(http.MethodPut, map[string]string{"kawaiiId": kawaiiId, "KawaiiIpSecId": ipsec.String()})
+	target = rbacResolveTarget(r)
+	if target.Ambiguous || target.ProjectID != webapp.String() {
+		t.Errorf("unexpected Kawaii IPsec target: %+v", target)
+	}
+}
+
+func TestRbacCrossOrganizationTarget(t *testing.T) {
+	f := newTestRbacFixture(t)
+	acme := f.organization("acme")
+	globex := f.organization("globex")
+	admin := f.user("admin", UserRoleStandard, acme, "team-a")
+	acme.AdminIDs = []string{admin.String()}
+
+	foreign := f.project("foreign", globex)
+	instanceId := f.resource(MongoCollectionInstanceName, foreign)
+	member := f.user("hank", UserRoleStandard, globex)
+
+	// other organizations' resources and members
+	for _, tc := range []struct {
+		name string
+		vars map[string]string
+	}{
+		{"UpdateInstance", map[string]string{"instanceId": instanceId}},
+		{"UpdateProject", map[string]string{"projectId": foreign.String()}},
+		{"UpdateOrganization", map[string]string{"organizationId": globex.String()}},
+		{"UpdateUser", map[string]string{"userId": member.String()}},
+		{"UpdateInstance", map[string]string{"organizationId": acme.String(), "instanceId": instanceId}},
+	} {
+		r := testRbacRequest(http.MethodPut, tc.vars)
+		if reqIsRbacAuthorized(r, tc.name, admin.String()) {
+			t.Errorf("organization administrator has been granted %s on %v", tc.name, tc.vars)
+		}
+	}
+
+	r := testRbacRequest(http.MethodPut, map[string]string{"organizationId": acme.String(), "instanceId": instanceId})
+	target := rbacResolveTarget(r)
+	if !slices.Contains(target.OrganizationIDs, acme.String()) || !slices.Contains(target.OrganizationIDs, globex.String()) {
+		t.Errorf("unexpected cross-organization target: %+v", target)
+	}
+
+	// project roles still apply to other organizations' projects teams are bound to
+	f.bind(foreign, "team-a", ProjectRoleViewer)
+	r = testRbacRequest(http.MethodGet, map[string]string{"instanceId": instanceId})
+	if !reqIsRbacAuthorized(r, "ReadInstance", admin.String()) {
+		t.Errorf("project viewer has been denied read permission")
+	}
+	r = testRbacRequest(http.MethodPut, map[string]string{"instanceId": instanceId})
+	if reqIsRbacAuthorized(r, "UpdateInstance", admin.String()) {
+		t.Errorf("project viewer has been granted write permission")
+	}
+}
@@ -7,6 +7,7 @@
 package kahuna
 
 import (
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -20,6 +21,8 @@ const (
 	CostErrorUnknown    = "Unknown Cost"
 
 	ResourceUnknown = "000000000000000000000000"
+
+	ResourceUpdateMaxRetries = 5
 )
 
 type Resources struct {
@@ -31,9 +34,14 @@ type Resource struct {
 	CreatedAt     time.Time     `bson:"created_at"`
 	UpdatedAt     time.Time     `bson:"updated_at"`
 	SchemaVersion int           `bson:"schema_version"`
+	Revision      int64         `bson:"revision"`
+
+	Name        string          `bson:"name"`
+	Description string          `bson:"description"`
+	Labels      []ResourceLabel `bson:"labels,omitempty"`
 
-	Name        string `bson:"name"`
-	Description string `bson:"description"`
+	// set once resource has been deleted, until it gets purged
+	Trashed *ResourceTrash `bson:"trash,omitempty"`
 }
 
 func NewResource(name, desc string, schemaVersion int) Resource {
@@ -56,6 +64,32 @@ func (r *Resource) Updated() {
 	r.UpdatedAt = time.Now()
 }
 
+// IsTrashed tells whether the resource has been deleted and awaits purge
+func (r *Resource) IsTrashed() bool {
+	return r.Trashed != nil
+}
+
+func (r *Resource) trash() *ResourceTrash {
+	return r.Trashed
+}
+
+func (r *Resource) revision() int64 {
+	return r.Revision
+}
+
+func (r *Resource) setRevision(rev int64) {
+	r.Revision = rev
+}
+
+// ETag returns the HTTP entity tag of the resource current revision
+func (r *Resource) ETag() string {
+	return ResourceETag(r.Revision)
+}
+
+func ResourceETag(rev int64) string {
+	return fmt.Sprintf(`"%d"`, rev)
+}
+
 func (r *Resource) UpdateResourceDefaults(name, desc string) {
 	SetFieldStr(&r.Name, name)
 	SetFieldStr(&r.Description, desc)
@@ -76,6 +110,40 @@ func FindResourceByID[T any](collection, id string) (*T, error) {
 	return &res, err
 }
 
+type updatableResource[T any] interface {
+	*T
+	String() string
+	Updated()
+}
+
+// UpdateResource applies a change to a resource and saves it back to DB.
+// Should the resource have been concurrently modified in the meantime,
+// it is reloaded from DB and the change is applied again.
+func UpdateResource[T any, PT updatableResource[T]](collection string, obj PT, change func(PT)) error {
+	id, err := bson.ObjectIDFromHex(obj.String())
+	if err != nil {
+		return err
+	}
+
+	for range ResourceUpdateMaxRetries {
+		change(obj)
+		obj.Updated()
+		_, err = GetDB().Update(collection, id, obj)
+		if !IsDbConflictError(err) {
+			return err
+		}
+
+		klog.Debugf("Resource %s/%s has been concurrently modified, reloading", collection, obj.String())
+		latest, err := FindResourceByID[T](collection, obj.String())
+		if err != nil {
+			return err
+		}
+		*obj = *latest
+	}
+
+	return err
+}
+
 func FindResourceByName[T any](collection, name string) (*T, error) {
 	var res T
 	err := GetDB().FindByName(collection, name, &res)
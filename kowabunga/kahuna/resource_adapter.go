@@ -283,24 +283,29 @@ func (a *Adapter) Subnet() (*Subnet, error) {
 	return FindSubnetByID(a.SubnetID)
 }
 
-func (a *Adapter) Update(name, desc, mac string, addresses []string, reserved bool) {
+func (a *Adapter) Update(name, desc, mac string, addresses []string, reserved bool) error {
 	// ensure the requested adapter is correctly flagged
 	err := verifyAdapterSettings(a.SubnetID, mac, addresses, true)
 	if err != nil {
 		klog.Error(err)
-		return
+		return err
 	}
 
 	a.UpdateResourceDefaults(name, desc)
 	SetFieldStr(&a.MAC, mac)
 	a.Addresses = addresses
 	a.Reserved = reserved
-	a.Save()
+	return a.save()
 }
 
-func (a *Adapter) Save() {
+func (a *Adapter) save() error {
 	a.Updated()
 	_, err := GetDB().Update(MongoCollectionAdapterName, a.ID, a)
+	return err
+}
+
+func (a *Adapter) Save() {
+	err := a.save()
 	if err != nil {
 		klog.Error(err)
 	}
@@ -128,14 +128,19 @@ func (a *Agent) Token() (*Token, error) {
 	return FindTokenByID(a.TokenID)
 }
 
-func (a *Agent) Update(name, desc string) {
+func (a *Agent) Update(name, desc string) error {
 	a.UpdateResourceDefaults(name, desc)
-	a.Save()
+	return a.save()
 }
 
-func (a *Agent) Save() {
+func (a *Agent) save() error {
 	a.Updated()
 	_, err := GetDB().Update(MongoCollectionAgentName, a.ID, a)
+	return err
+}
+
+func (a *Agent) Save() {
+	err := a.save()
 	if err != nil {
 		klog.Error(err)
 	}
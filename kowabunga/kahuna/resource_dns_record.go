@@ -206,7 +206,7 @@ func (r *DnsRecord) DeleteDnsRecord() error {
 	return nil
 }
 
-func (r *DnsRecord) Update(name, desc string, addresses []string) {
+func (r *DnsRecord) Update(name, desc string, addresses []string) error {
 	r.UpdateResourceDefaults(name, desc)
 	r.Addresses = addresses
 
@@ -218,12 +218,17 @@ func (r *DnsRecord) Update(name, desc string, addresses []string) {
 		}
 	}
 
-	r.Save()
+	return r.save()
 }
 
-func (r *DnsRecord) Save() {
+func (r *DnsRecord) save() error {
 	r.Updated()
 	_, err := GetDB().Update(MongoCollectionDnsRecordName, r.ID, r)
+	return err
+}
+
+func (r *DnsRecord) Save() {
+	err := r.save()
 	if err != nil {
 		klog.Error(err)
 	}
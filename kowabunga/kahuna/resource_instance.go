@@ -251,54 +251,58 @@ func NewInstance(projectId, kaktusId, name, desc, profile, profileId string, cpu
 		return nil, err
 	}
 
-	err = instance.CreateDnsRecord()
+	saga := NewSaga(fmt.Sprintf("instance %s", instance.Name))
+
+	err = saga.Step("DNS record", instance.CreateDnsRecord, instance.DeleteDnsRecord)
 	if err != nil {
-		klog.Error(err)
-		return nil, err
+		return nil, saga.Rollback(err)
 	}
 
 	switch instance.Profile {
 	case CloudinitProfileKawaii, CloudinitProfileKonvey:
-		err = instance.CreateAgent()
+		err = saga.Step("agent", instance.CreateAgent, instance.DeleteAgent)
 		if err != nil {
-			klog.Error(err)
-			// cleanup
-			_ = instance.Delete()
-			return nil, err
+			return nil, saga.Rollback(err)
 		}
 	}
 
-	err = instance.CreateCloudInitVolume()
+	err = saga.Step("cloud-init volume", instance.CreateCloudInitVolume, instance.DeleteCloudInitVolume)
 	if err != nil {
-		klog.Error(err)
-		return nil, err
+		return nil, saga.Rollback(err)
 	}
 
-	err = instance.CreateInstance()
+	err = saga.Step("virtual machine", instance.CreateInstance, func() error {
+		err := instance.Stop()
+		if err != nil {
+			klog.Error(err)
+			// nevermind, kill it
+		}
+		return instance.delete()
+	})
 	if err != nil {
-		klog.Error(err)
-		// cleanup
-		_ = instance.Delete()
-		return nil, err
+		return nil, saga.Rollback(err)
 	}
 
-	_, err = GetDB().Insert(MongoCollectionInstanceName, instance)
+	err = saga.Step("database record", func() error {
+		_, err := GetDB().Insert(MongoCollectionInstanceName, instance)
+		return err
+	}, func() error {
+		return GetDB().Delete(MongoCollectionInstanceName, instance.ID)
+	})
 	if err != nil {
-		// cleanup
-		_ = instance.Delete()
-		return nil, err
+		return nil, saga.Rollback(err)
 	}
 	klog.Infof("Created new instance %s (%s)", instance.String(), instance.Name)
 
 	// setup initial cost
 	azr, err := instance.AverageZoneResources()
 	if err != nil {
-		return nil, err
+		return nil, saga.Rollback(err)
 	}
 
 	err = instance.ComputeCost(azr)
 	if err != nil {
-		return nil, err
+		return nil, saga.Rollback(err)
 	}
 
 	// add instance to project
@@ -309,12 +313,13 @@ func NewInstance(projectId, kaktusId, name, desc, profile, profileId string, cpu
 
 	// notify project's users
 	for _, u := range prj.NotifiableUsers() {
-		err := NewEmailInstanceCreated(&instance, u)
+		err := NotifyInstanceCreated(&instance, u)
 		if err != nil {
 			klog.Error(err)
 			// not a blocker
 		}
 	}
+	FireWebhookEvent(WebhookEventInstanceCreated, prj.String(), instance.Model())
 
 	return &instance, nil
 }
@@ -328,7 +333,7 @@ func FindInstancesByProject(projectId string) ([]Instance, error) {
 }
 
 func FindInstancesByKaktus(kaktusId string) ([]Instance, error) {
-	return FindResourcesByKey[Instance](MongoCollectionKaktusName, "kaktus_id", kaktusId)
+	return FindResourcesByKey[Instance](MongoCollectionInstanceName, "kaktus_id", kaktusId)
 }
 
 func FindInstanceByID(id string) (*Instance, error) {
@@ -486,6 +491,23 @@ func (i *Instance) CreateCloudInitVolume() error {
 	return nil
 }
 
+func (i *Instance) DeleteCloudInitVolume() error {
+	if i.CloudInitVolumeId == "" {
+		return nil
+	}
+
+	v, err := FindVolumeByID(i.CloudInitVolumeId)
+	if err != nil {
+		return err
+	}
+
+	if v.Type == VolumeTypeIso {
+		return v.Delete()
+	}
+
+	return nil
+}
+
 func (i *Instance) create(xml string) error {
 	args := proto.KaktusCreateInstanceArgs{
 		Name: i.Name,
@@ -576,6 +598,31 @@ func (i *Instance) CreateAgent() error {
 	return nil
 }
 
+func (i *Instance) DeleteAgent() error {
+	switch i.Profile {
+	case CloudinitProfileKawaii, CloudinitProfileKonvey:
+		if i.AgentID == "" {
+			return nil
+		}
+
+		a, err := FindAgentByID(i.AgentID)
+		if err != nil {
+			return err
+		}
+
+		// remove agent
+		err = a.Delete()
+		if err != nil {
+			return err
+		}
+
+		// disconnect any live agent WebSocket, if any
+		DisconnectAgent(i.AgentID)
+	}
+
+	return nil
+}
+
 func (i *Instance) CreateDnsRecord() error {
 	prj, err := i.Project()
 	if err != nil {
@@ -736,6 +783,12 @@ func (i *Instance) Update(name, desc string, cpu, mem int64, adapters, volumes [
 	}
 
 	if hasChanged {
+		// ensure instance hasn't been concurrently modified before reconfiguring it
+		err = GetDB().CheckRevision(MongoCollectionInstanceName, i.ID, i.Revision)
+		if err != nil {
+			return err
+		}
+
 		data, err := VirtualInstanceToXml(d)
 		if err != nil {
 			return err
@@ -768,13 +821,25 @@ func (i *Instance) Update(name, desc string, cpu, mem int64, adapters, volumes [
 		azr, err := i.AverageZoneResources()
 		if err != nil {
 			klog.Error(err)
+		} else {
+			i.setCost(azr)
 		}
+	}
 
-		err = i.ComputeCost(azr)
-		if err != nil {
-			klog.Error(err)
+	err = i.save()
+	if err != nil {
+		if hasChanged {
+			// instance has been concurrently modified meanwhile, restore its previous domain definition,
+			// to be applied on next reboot
+			rerr := i.update(xml)
+			if rerr != nil {
+				klog.Errorf("unable to restore instance %s description: %v", i.Name, rerr)
+			}
 		}
+		return err
+	}
 
+	if hasChanged {
 		// update project usage counter
 		prj.UpdateInstanceUsage(cpuDelta, memDelta)
 
@@ -782,7 +847,7 @@ func (i *Instance) Update(name, desc string, cpu, mem int64, adapters, volumes [
 		k.UpdateInstanceUsage(cpuDelta, memDelta)
 	}
 
-	i.Save()
+	FireWebhookEvent(WebhookEventInstanceUpdated, i.ProjectID, i.Model())
 	return nil
 }
 
@@ -800,9 +865,14 @@ func (i *Instance) HasChildren() bool {
 	return false
 }
 
-func (i *Instance) Save() {
+func (i *Instance) save() error {
 	i.Updated()
 	_, err := GetDB().Update(MongoCollectionInstanceName, i.ID, i)
+	return err
+}
+
+func (i *Instance) Save() {
+	err := i.save()
 	if err != nil {
 		klog.Error(err)
 	}
@@ -837,37 +907,15 @@ func (i *Instance) Delete() error {
 	}
 
 	// delete associated cloud-init volume, if any
-	if i.CloudInitVolumeId != "" {
-		v, err := FindVolumeByID(i.CloudInitVolumeId)
-		if err != nil {
-			klog.Error(err)
-		}
-		if v.Type == VolumeTypeIso {
-			err := v.Delete()
-			if err != nil {
-				return err
-			}
-		}
+	err = i.DeleteCloudInitVolume()
+	if err != nil {
+		return err
 	}
 
 	// remove agent, if any
-	switch i.Profile {
-	case CloudinitProfileKawaii, CloudinitProfileKonvey:
-		if i.AgentID != "" {
-			a, err := FindAgentByID(i.AgentID)
-			if err != nil {
-				return err
-			}
-
-			// remove agent
-			err = a.Delete()
-			if err != nil {
-				return err
-			}
-
-			// disconnect any live agent WebSocket, if any
-			DisconnectAgent(i.AgentID)
-		}
+	err = i.DeleteAgent()
+	if err != nil {
+		return err
 	}
 
 	// delete associated DNS record, if any
@@ -890,7 +938,50 @@ func (i *Instance) Delete() error {
 	}
 	k.RemoveInstance(i.String())
 
-	return GetDB().Delete(MongoCollectionInstanceName, i.ID)
+	payload := i.Model()
+	err = GetDB().Delete(MongoCollectionInstanceName, i.ID)
+	if err != nil {
+		return err
+	}
+	FireWebhookEvent(WebhookEventInstanceDeleted, i.ProjectID, payload)
+
+	return nil
+}
+
+// MoveToTrash stops instance and flags it as deleted, holding its resources until purged
+func (i *Instance) MoveToTrash(parentId string) error {
+	klog.Infof("Moving instance %s (%s) to trash", i.String(), i.Name)
+
+	err := i.Stop()
+	if err != nil {
+		klog.Error(err)
+		// not a blocker, will be killed on purge
+	}
+
+	err = UpdateResource(MongoCollectionInstanceName, i, func(i *Instance) {
+		i.Trashed = NewResourceTrash(parentId)
+	})
+	if err != nil {
+		return err
+	}
+	FireWebhookEvent(WebhookEventInstanceTrashed, i.ProjectID, i.Model())
+
+	return nil
+}
+
+// RestoreFromTrash brings a trashed instance back to life
+func (i *Instance) RestoreFromTrash() error {
+	klog.Infof("Restoring instance %s (%s) from trash", i.String(), i.Name)
+
+	err := UpdateResource(MongoCollectionInstanceName, i, func(i *Instance) {
+		i.Trashed = nil
+	})
+	if err != nil {
+		return err
+	}
+	FireWebhookEvent(WebhookEventInstanceRestored, i.ProjectID, i.Model())
+
+	return i.Start()
 }
 
 func (i *Instance) Model() sdk.Instance {
@@ -915,6 +1006,13 @@ func (i *Instance) AverageZoneResources() (*ZoneVirtualResources, error) {
 }
 
 func (i *Instance) ComputeCost(res *ZoneVirtualResources) error {
+	i.setCost(res)
+	i.Save()
+
+	return nil
+}
+
+func (i *Instance) setCost(res *ZoneVirtualResources) {
 	vcpus := i.CPU
 	vmem_gb := float64(bytesToGB(i.Memory))
 	currency := res.Computing.Currency
@@ -923,9 +1021,6 @@ func (i *Instance) ComputeCost(res *ZoneVirtualResources) error {
 
 	i.Cost.Price = price
 	i.Cost.Currency = currency
-	i.Save()
-
-	return nil
 }
 
 func (i *Instance) GetState() (sdk.InstanceState, error) {
@@ -319,7 +319,7 @@ func (k *Kaktus) VirtualResourcesComputation() {
 	}
 }
 
-func (k *Kaktus) Update(name, desc string, cpu_price float32, cpu_currency string, memory_price float32, memory_currency string, overcommit_cpu, overcommit_memory int64, agts []string) {
+func (k *Kaktus) Update(name, desc string, cpu_price float32, cpu_currency string, memory_price float32, memory_currency string, overcommit_cpu, overcommit_memory int64, agts []string) error {
 	k.UpdateResourceDefaults(name, desc)
 
 	k.Costs.CPU.Price = cpu_price
@@ -331,12 +331,17 @@ func (k *Kaktus) Update(name, desc string, cpu_price float32, cpu_currency strin
 	k.AgentIDs = VerifyAgents(agts, common.KowabungaKaktusAgent)
 	k.VirtualResourcesComputation()
 
-	k.Save()
+	return k.save()
 }
 
-func (k *Kaktus) Save() {
+func (k *Kaktus) save() error {
 	k.Updated()
 	_, err := GetDB().Update(MongoCollectionKaktusName, k.ID, k)
+	return err
+}
+
+func (k *Kaktus) Save() {
+	err := k.save()
 	if err != nil {
 		klog.Error(err)
 	}
@@ -394,29 +399,38 @@ func (k *Kaktus) Instance(id string) (*Instance, error) {
 
 func (k *Kaktus) AddInstance(id string) {
 	klog.Debugf("Adding instance %s to kaktus %s", id, k.String())
-	AddChildRef(&k.InstanceIDs, id)
-	k.Save() // save DB before looking back
 
-	// find instance again
-	instance, err := k.Instance(id)
+	instance, err := FindInstanceByID(id)
 	if err != nil {
 		klog.Error(err)
 		return
 	}
 
-	// increase usage counters
-	k.Usage.InstancesCount += 1
-	k.Usage.VCPUs += uint16(instance.CPU)
-	k.Usage.MemorySize += uint64(instance.Memory)
+	err = UpdateResource(MongoCollectionKaktusName, k, func(k *Kaktus) {
+		if HasChildRef(&k.InstanceIDs, id) {
+			return
+		}
+		AddChildRef(&k.InstanceIDs, id)
 
-	k.Save()
+		// increase usage counters
+		k.Usage.InstancesCount += 1
+		k.Usage.VCPUs += uint16(instance.CPU)
+		k.Usage.MemorySize += uint64(instance.Memory)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (k *Kaktus) UpdateInstanceUsage(cpu, mem int64) {
 	// increase usage counters
-	k.Usage.VCPUs += uint16(cpu)
-	k.Usage.MemorySize += uint64(mem)
-	k.Save()
+	err := UpdateResource(MongoCollectionKaktusName, k, func(k *Kaktus) {
+		k.Usage.VCPUs += uint16(cpu)
+		k.Usage.MemorySize += uint64(mem)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (k *Kaktus) RemoveInstance(id string) {
@@ -429,11 +443,19 @@ func (k *Kaktus) RemoveInstance(id string) {
 		return
 	}
 
-	// decrease usage counters
-	k.Usage.InstancesCount -= 1
-	k.Usage.VCPUs -= uint16(ist.CPU)
-	k.Usage.MemorySize -= uint64(ist.Memory)
+	err = UpdateResource(MongoCollectionKaktusName, k, func(k *Kaktus) {
+		if !HasChildRef(&k.InstanceIDs, id) {
+			return
+		}
 
-	RemoveChildRef(&k.InstanceIDs, id)
-	k.Save()
+		// decrease usage counters
+		k.Usage.InstancesCount -= 1
+		k.Usage.VCPUs -= uint16(ist.CPU)
+		k.Usage.MemorySize -= uint64(ist.Memory)
+
+		RemoveChildRef(&k.InstanceIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
@@ -330,7 +330,10 @@ func (k *Kawaii) Update(desc string, fw KawaiiFirewall, dnat []KawaiiDNatRule) e
 	k.Description = desc
 	k.Firewall = fw
 	k.DNatRules = dnat
-	k.Save()
+	err := k.save()
+	if err != nil {
+		return err
+	}
 
 	mzr, err := k.MZR()
 	if err != nil {
@@ -359,9 +362,14 @@ func (k *Kawaii) Update(desc string, fw KawaiiFirewall, dnat []KawaiiDNatRule) e
 	return nil
 }
 
-func (k *Kawaii) Save() {
+func (k *Kawaii) save() error {
 	k.Updated()
 	_, err := GetDB().Update(MongoCollectionKawaiiName, k.ID, k)
+	return err
+}
+
+func (k *Kawaii) Save() {
+	err := k.save()
 	if err != nil {
 		klog.Error(err)
 	}
@@ -734,14 +742,22 @@ func (k *Kawaii) Metadata(instanceId string) metadata.KawaiiMetadata {
 
 func (k *Kawaii) AddIPsec(ipsecID string) {
 	klog.Debugf("Adding IPsec %s to pool %s", ipsecID, k.String())
-	AddChildRef(&k.IPsecIDs, ipsecID)
-	k.Save()
+	err := UpdateResource(MongoCollectionKawaiiName, k, func(k *Kawaii) {
+		AddChildRef(&k.IPsecIDs, ipsecID)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (k *Kawaii) RemoveIPsec(ipsecID string) {
 	klog.Debugf("Removing IPsec %s from pool %s", ipsecID, k.String())
-	RemoveChildRef(&k.IPsecIDs, ipsecID)
-	k.Save()
+	err := UpdateResource(MongoCollectionKawaiiName, k, func(k *Kawaii) {
+		RemoveChildRef(&k.IPsecIDs, ipsecID)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func IPsecIngressRuleToMetadata(rule *KawaiiFirewallIngressRule) *metadata.KawaiiFirewallRuleMetadata {
@@ -161,9 +161,14 @@ func FindIPsecByID(id string) (*KawaiiIPsec, error) {
 	return FindResourceByID[KawaiiIPsec](MongoCollectionIPsecName, id)
 }
 
-func (k *KawaiiIPsec) Save() {
+func (k *KawaiiIPsec) save() error {
 	k.Updated()
 	_, err := GetDB().Update(MongoCollectionIPsecName, k.ID, k)
+	return err
+}
+
+func (k *KawaiiIPsec) Save() {
+	err := k.save()
 	if err != nil {
 		klog.Error(err)
 	}
@@ -192,7 +197,10 @@ func (k *KawaiiIPsec) Update(name, desc, remotePeer, remoteSubnet, preSharedKey
 	k.Phase2DHGroupNumber = p2DHGroupNumber
 	k.Phase2IntegrityAlgorithm = p2IntegrityAlgorithm
 	k.Phase2EncryptionAlgorithm = p2EncryptionAlgorithm
-	k.Save()
+	err := k.save()
+	if err != nil {
+		return err
+	}
 
 	parentKawaii, err := FindKawaiiByID(k.KawaiiID)
 	if err != nil {
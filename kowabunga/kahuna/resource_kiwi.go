@@ -126,17 +126,22 @@ func (k *Kiwi) RPC(method string, args, reply any) error {
 	return RPC(k.AgentIDs, method, args, reply)
 }
 
-func (k *Kiwi) Update(name, desc string, agts []string) {
+func (k *Kiwi) Update(name, desc string, agts []string) error {
 	k.UpdateResourceDefaults(name, desc)
 
 	k.AgentIDs = VerifyAgents(agts, common.KowabungaKiwiAgent)
 
-	k.Save()
+	return k.save()
 }
 
-func (k *Kiwi) Save() {
+func (k *Kiwi) save() error {
 	k.Updated()
 	_, err := GetDB().Update(MongoCollectionKiwiName, k.ID, k)
+	return err
+}
+
+func (k *Kiwi) Save() {
+	err := k.save()
 	if err != nil {
 		klog.Error(err)
 	}
@@ -84,6 +84,7 @@ func NewKompute(projectId, zoneId, hostId, poolId, templateId, name, desc, profi
 		return nil, err
 	}
 
+	saga := NewSaga(fmt.Sprintf("Kompute %s", name))
 	volumes := []string{}
 
 	// create an OS volume from template
@@ -94,19 +95,19 @@ func NewKompute(projectId, zoneId, hostId, poolId, templateId, name, desc, profi
 	if err != nil {
 		return nil, err
 	}
+	saga.Compensate("OS volume", osVolume.Delete)
 	volumes = append(volumes, osVolume.String())
 
 	// create an optional data volume
-	var dataVolume *Volume
 	if data > 0 {
 		dataVolumeName := fmt.Sprintf("%s-data", name)
 		dataVolumeDescription := fmt.Sprintf("Data Volume for %s", name)
 		klog.Debugf("Creating data volume for %s", name)
-		dataVolume, err = NewVolume(projectId, poolId, "", dataVolumeName, dataVolumeDescription, VolumeTypeRaw, data)
+		dataVolume, err := NewVolume(projectId, poolId, "", dataVolumeName, dataVolumeDescription, VolumeTypeRaw, data)
 		if err != nil {
-			_ = osVolume.Delete()
-			return nil, err
+			return nil, saga.Rollback(err)
 		}
+		saga.Compensate("data volume", dataVolume.Delete)
 		volumes = append(volumes, dataVolume.String())
 	}
 
@@ -118,12 +119,9 @@ func NewKompute(projectId, zoneId, hostId, poolId, templateId, name, desc, profi
 	klog.Debugf("Creating public adapter for %s on subnet %s", name, publicSubnetId)
 	publicAdapter, err := NewAdapter(publicSubnetId, publicAdapterName, publicAdapterDescription, "", []string{}, false, public)
 	if err != nil {
-		_ = osVolume.Delete()
-		if dataVolume != nil {
-			_ = dataVolume.Delete()
-		}
-		return nil, err
+		return nil, saga.Rollback(err)
 	}
+	saga.Compensate("public adapter", publicAdapter.Delete)
 	adapters = append(adapters, publicAdapter.String())
 
 	// create a private network interface on the requested subnet (auto-assgined private IPv4)
@@ -132,13 +130,9 @@ func NewKompute(projectId, zoneId, hostId, poolId, templateId, name, desc, profi
 	klog.Debugf("Creating private adapter for %s on subnet %s", name, privateSubnetId)
 	privateAdapter, err := NewAdapter(privateSubnetId, privateAdapterName, privateAdapterDescription, "", []string{}, false, true)
 	if err != nil {
-		_ = osVolume.Delete()
-		if dataVolume != nil {
-			_ = dataVolume.Delete()
-		}
-		_ = publicAdapter.Delete()
-		return nil, err
+		return nil, saga.Rollback(err)
 	}
+	saga.Compensate("private adapter", privateAdapter.Delete)
 	adapters = append(adapters, privateAdapter.String())
 
 	// optionally create extra private network interfaces on peering subnets for Kawaii (auto-assigned private IPv4)
@@ -148,19 +142,9 @@ func NewKompute(projectId, zoneId, hostId, poolId, templateId, name, desc, profi
 		klog.Debugf("Creating peering private adapter for %s on subnet %s", name, spId)
 		spAdapter, err := NewAdapter(spId, spAdapterName, spAdapterDescription, "", []string{}, false, true)
 		if err != nil {
-			_ = osVolume.Delete()
-			if dataVolume != nil {
-				_ = dataVolume.Delete()
-			}
-			for _, adapterId := range adapters {
-				a, err := FindAdapterByID(adapterId)
-				if err != nil {
-					_ = a.Delete()
-				}
-			}
-
-			return nil, err
+			return nil, saga.Rollback(err)
 		}
+		saga.Compensate("peering private adapter", spAdapter.Delete)
 		adapters = append(adapters, spAdapter.String())
 	}
 
@@ -168,18 +152,9 @@ func NewKompute(projectId, zoneId, hostId, poolId, templateId, name, desc, profi
 	klog.Debugf("Creating new instance for %s", name)
 	instance, err := NewInstance(projectId, hostId, name, desc, profile, profileId, cpu, mem, adapters, volumes)
 	if err != nil {
-		_ = osVolume.Delete()
-		if dataVolume != nil {
-			_ = dataVolume.Delete()
-		}
-		for _, adapterId := range adapters {
-			a, err := FindAdapterByID(adapterId)
-			if err != nil {
-				_ = a.Delete()
-			}
-		}
-		return nil, err
+		return nil, saga.Rollback(err)
 	}
+	saga.Compensate("instance", instance.Delete)
 
 	kompute := Kompute{
 		Resource:   NewResource(name, desc, MongoCollectionKomputeSchemaVersion),
@@ -187,9 +162,14 @@ func NewKompute(projectId, zoneId, hostId, poolId, templateId, name, desc, profi
 		InstanceID: instance.String(),
 	}
 
-	_, err = GetDB().Insert(MongoCollectionKomputeName, kompute)
+	err = saga.Step("database record", func() error {
+		_, err := GetDB().Insert(MongoCollectionKomputeName, kompute)
+		return err
+	}, func() error {
+		return GetDB().Delete(MongoCollectionKomputeName, kompute.ID)
+	})
 	if err != nil {
-		return nil, err
+		return nil, saga.Rollback(err)
 	}
 
 	klog.Debugf("Created new Kompute virtual machine %s", kompute.String())
@@ -197,7 +177,7 @@ func NewKompute(projectId, zoneId, hostId, poolId, templateId, name, desc, profi
 	// read project object back, as it's been updated when creating volumes and instances
 	prj, err = FindProjectByID(projectId)
 	if err != nil {
-		return nil, err
+		return nil, saga.Rollback(err)
 	}
 
 	// add Kompute to project
@@ -290,8 +270,7 @@ func (k *Kompute) Update(name, desc string, cpu, mem, disk, data int64) error {
 		return err
 	}
 
-	k.Save()
-	return nil
+	return k.save()
 }
 
 func (k *Kompute) Project() (*Project, error) {
@@ -302,9 +281,14 @@ func (k *Kompute) Instance() (*Instance, error) {
 	return FindInstanceByID(k.InstanceID)
 }
 
-func (k *Kompute) Save() {
+func (k *Kompute) save() error {
 	k.Updated()
 	_, err := GetDB().Update(MongoCollectionKomputeName, k.ID, k)
+	return err
+}
+
+func (k *Kompute) Save() {
+	err := k.save()
 	if err != nil {
 		klog.Error(err)
 	}
@@ -380,6 +364,75 @@ func (k *Kompute) Delete() error {
 	return GetDB().Delete(MongoCollectionKomputeName, k.ID)
 }
 
+// MoveToTrash flags Kompute, along with its instance and volumes, as deleted, holding them until purged
+func (k *Kompute) MoveToTrash() error {
+	klog.Infof("Moving Kompute %s (%s) to trash", k.String(), k.Name)
+
+	i, err := k.Instance()
+	if err != nil {
+		klog.Error(err)
+		return err
+	}
+
+	for _, volumeId := range i.Volumes() {
+		v, err := FindVolumeByID(volumeId)
+		if err != nil {
+			klog.Error(err)
+			continue
+		}
+
+		err = v.MoveToTrash(k.String())
+		if err != nil {
+			klog.Error(err)
+			return err
+		}
+	}
+
+	err = i.MoveToTrash(k.String())
+	if err != nil {
+		klog.Error(err)
+		return err
+	}
+
+	return UpdateResource(MongoCollectionKomputeName, k, func(k *Kompute) {
+		k.Trashed = NewResourceTrash("")
+	})
+}
+
+// RestoreFromTrash brings a trashed Kompute, along with its instance and volumes, back to life
+func (k *Kompute) RestoreFromTrash() error {
+	klog.Infof("Restoring Kompute %s (%s) from trash", k.String(), k.Name)
+
+	i, err := k.Instance()
+	if err != nil {
+		klog.Error(err)
+		return err
+	}
+
+	for _, volumeId := range i.Volumes() {
+		v, err := FindVolumeByID(volumeId)
+		if err != nil {
+			klog.Error(err)
+			continue
+		}
+
+		err = v.RestoreFromTrash()
+		if err != nil {
+			klog.Error(err)
+			return err
+		}
+	}
+
+	err = UpdateResource(MongoCollectionKomputeName, k, func(k *Kompute) {
+		k.Trashed = nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return i.RestoreFromTrash()
+}
+
 func (k *Kompute) Model() sdk.Kompute {
 	kompute := sdk.Kompute{
 		Id:          k.String(),
@@ -201,7 +201,10 @@ func (k *Konvey) HAR() (*HighlyAvailableResource, error) {
 func (k *Konvey) Update(desc string, endpoints []KonveyEndpoint) error {
 	k.Description = desc
 	k.Endpoints = endpoints
-	k.Save()
+	err := k.save()
+	if err != nil {
+		return err
+	}
 
 	har, err := k.HAR()
 	if err != nil {
@@ -230,9 +233,14 @@ func (k *Konvey) Update(desc string, endpoints []KonveyEndpoint) error {
 	return nil
 }
 
-func (k *Konvey) Save() {
+func (k *Konvey) save() error {
 	k.Updated()
 	_, err := GetDB().Update(MongoCollectionKonveyName, k.ID, k)
+	return err
+}
+
+func (k *Konvey) Save() {
+	err := k.save()
 	if err != nil {
 		klog.Error(err)
 	}
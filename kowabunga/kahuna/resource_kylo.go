@@ -299,8 +299,7 @@ func (k *Kylo) Update(name, desc, access string, protocols []int32) error {
 		klog.Error(err)
 	}
 
-	k.Save()
-	return nil
+	return k.save()
 }
 
 func (k *Kylo) Project() (*Project, error) {
@@ -320,9 +319,14 @@ func (k *Kylo) RPC(method string, args, reply any) error {
 	return nfs.RPC(method, args, reply)
 }
 
-func (k *Kylo) Save() {
+func (k *Kylo) save() error {
 	k.Updated()
 	_, err := GetDB().Update(MongoCollectionKyloName, k.ID, k)
+	return err
+}
+
+func (k *Kylo) Save() {
+	err := k.save()
 	if err != nil {
 		klog.Error(err)
 	}
@@ -173,7 +173,7 @@ func (n *NFS) FindKylo() ([]Kylo, error) {
 	return FindKyloByNfs(n.String())
 }
 
-func (n *NFS) Update(name, desc, endpoint, fs string, backends []string, port int) {
+func (n *NFS) Update(name, desc, endpoint, fs string, backends []string, port int) error {
 	n.UpdateResourceDefaults(name, desc)
 
 	SetFieldStr(&n.Endpoint, endpoint)
@@ -184,12 +184,17 @@ func (n *NFS) Update(name, desc, endpoint, fs string, backends []string, port in
 		n.Ganesha.Port = NfsBackendApiPort
 	}
 
-	n.Save()
+	return n.save()
 }
 
-func (n *NFS) Save() {
+func (n *NFS) save() error {
 	n.Updated()
 	_, err := GetDB().Update(MongoCollectionNfsName, n.ID, n)
+	return err
+}
+
+func (n *NFS) Save() {
+	err := n.save()
 	if err != nil {
 		klog.Error(err)
 	}
@@ -236,16 +241,24 @@ func (n *NFS) Kylo(id string) (*Kylo, error) {
 
 func (n *NFS) AddKylo(id, exportId string) {
 	klog.Debugf("Adding Kylo %s to NFS storage %s", id, n.String())
-	AddChildRef(&n.KyloIDs, id)
-	AddChildRef(&n.Exports, exportId)
-	n.Save()
+	err := UpdateResource(MongoCollectionNfsName, n, func(n *NFS) {
+		AddChildRef(&n.KyloIDs, id)
+		AddChildRef(&n.Exports, exportId)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (n *NFS) RemoveKylo(id, exportId string) {
 	klog.Debugf("Removing Kylo %s from NFS storage %s", id, n.String())
-	RemoveChildRef(&n.KyloIDs, id)
-	RemoveChildRef(&n.Exports, exportId)
-	n.Save()
+	err := UpdateResource(MongoCollectionNfsName, n, func(n *NFS) {
+		RemoveChildRef(&n.KyloIDs, id)
+		RemoveChildRef(&n.Exports, exportId)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (n *NFS) NewExportId() string {
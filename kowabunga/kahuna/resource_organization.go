@@ -0,0 +1,585 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"fmt"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/kowabunga-cloud/common/klog"
+	"github.com/kowabunga-cloud/kowabunga/kowabunga/sdk"
+)
+
+const (
+	MongoCollectionOrganizationSchemaVersion = 2
+	MongoCollectionOrganizationName          = "organization"
+
+	OrganizationAuthSchemeLocal = "local"
+	OrganizationAuthSchemeOidc  = "oidc"
+
+	ErrOrganizationInvalidAuthScheme = "unsupported organization authentication scheme"
+	ErrOrganizationMissingOidc       = "organization OpenID Connect issuer and client ID are required"
+	ErrOrganizationAlreadyMember     = "resource already belongs to another organization"
+	ErrOrganizationNotMember         = "resource does not belong to organization"
+	ErrOrganizationAuthDenied        = "organization authentication scheme and identity provider can only be set by super-administrators"
+)
+
+type Organization struct {
+	// anonymous field, inheritance
+	Resource `bson:"inline"`
+
+	// parents
+
+	// properties
+	Auth     OrganizationAuth `bson:"auth"`
+	Quotas   ProjectResources `bson:"quotas"` // limits for all projects, 0 for unlimited
+	AdminIDs []string         `bson:"admin_ids"`
+
+	// children references
+	UserIDs    []string `bson:"user_ids"`
+	TeamIDs    []string `bson:"team_ids"`
+	ProjectIDs []string `bson:"project_ids"`
+}
+
+type OrganizationAuth struct {
+	Scheme string           `bson:"scheme"`
+	Oidc   OrganizationOidc `bson:"oidc"`
+}
+
+type OrganizationOidc struct {
+	Issuer       string                  `bson:"issuer"`
+	ClientID     string                  `bson:"client_id"`
+	ClientSecret string                  `bson:"client_secret"` // encrypted at rest
+	Scopes       []string                `bson:"scopes"`
+	GroupsClaim  string                  `bson:"groups_claim"`
+	LinkByEmail  bool                    `bson:"link_by_email"`
+	Groups       []OrganizationOidcGroup `bson:"groups"`
+}
+
+type OrganizationOidcGroup struct {
+	Name string `bson:"name"`
+	Team string `bson:"team"`
+}
+
+type OrganizationModel struct {
+	Id          string                `json:"id,omitempty"`
+	Name        string                `json:"name"`
+	Description string                `json:"description,omitempty"`
+	Auth        OrganizationAuthModel `json:"auth"`
+	Quotas      sdk.ProjectResources  `json:"quotas,omitempty"`
+	Usage       sdk.ProjectResources  `json:"usage,omitempty"`
+	Admins      []string              `json:"admins,omitempty"`
+	Users       []string              `json:"users,omitempty"`
+	Teams       []string              `json:"teams,omitempty"`
+	Projects    []string              `json:"projects,omitempty"`
+}
+
+type OrganizationAuthModel struct {
+	Scheme string                 `json:"scheme"`
+	Oidc   *OrganizationOidcModel `json:"oidc,omitempty"`
+}
+
+type OrganizationOidcModel struct {
+	Issuer       string                       `json:"issuer"`
+	ClientID     string                       `json:"clientId"`
+	ClientSecret string                       `json:"clientSecret,omitempty"`
+	Scopes       []string                     `json:"scopes,omitempty"`
+	GroupsClaim  string                       `json:"groupsClaim,omitempty"`
+	LinkByEmail  bool                         `json:"linkByEmail,omitempty"`
+	Groups       []OrganizationOidcGroupModel `json:"groups,omitempty"`
+}
+
+type OrganizationOidcGroupModel struct {
+	Name string `json:"name"`
+	Team string `json:"team"`
+}
+
+func OrganizationMigrateSchemaV2() error {
+	for _, o := range FindOrganizations() {
+		if o.SchemaVersion < 2 {
+			err := o.migrateSchemaV2()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func OrganizationRollbackSchemaV2() error {
+	for _, o := range FindOrganizations() {
+		if o.SchemaVersion == 2 {
+			err := o.rollbackSchemaV2()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// IdP client secrets used to be stored in clear text
+func (o *Organization) migrateSchemaV2() error {
+	err := o.Auth.encryptSecrets()
+	if err != nil {
+		return err
+	}
+	o.SchemaVersion = 2
+	return o.save()
+}
+
+func (o *Organization) rollbackSchemaV2() error {
+	secret, err := DecryptSecret(o.Auth.Oidc.ClientSecret)
+	if err != nil {
+		return err
+	}
+	o.Auth.Oidc.ClientSecret = secret
+	o.SchemaVersion = 1
+	return o.save()
+}
+
+func NewOrganizationAuth(m OrganizationAuthModel) (OrganizationAuth, error) {
+	auth := OrganizationAuth{
+		Scheme: m.Scheme,
+	}
+
+	switch auth.Scheme {
+	case "", OrganizationAuthSchemeLocal:
+		auth.Scheme = OrganizationAuthSchemeLocal
+		return auth, nil
+	case OrganizationAuthSchemeOidc:
+		break
+	default:
+		return auth, fmt.Errorf("%s: %s", ErrOrganizationInvalidAuthScheme, m.Scheme)
+	}
+
+	if m.Oidc == nil || m.Oidc.Issuer == "" || m.Oidc.ClientID == "" {
+		return auth, fmt.Errorf("%s", ErrOrganizationMissingOidc)
+	}
+
+	auth.Oidc = OrganizationOidc{
+		Issuer:       m.Oidc.Issuer,
+		ClientID:     m.Oidc.ClientID,
+		ClientSecret: m.Oidc.ClientSecret,
+		Scopes:       m.Oidc.Scopes,
+		GroupsClaim:  m.Oidc.GroupsClaim,
+		LinkByEmail:  m.Oidc.LinkByEmail,
+		Groups:       []OrganizationOidcGroup{},
+	}
+	for _, g := range m.Oidc.Groups {
+		auth.Oidc.Groups = append(auth.Oidc.Groups, OrganizationOidcGroup{
+			Name: g.Name,
+			Team: g.Team,
+		})
+	}
+
+	return auth, nil
+}
+
+func (a *OrganizationAuth) Model() OrganizationAuthModel {
+	m := OrganizationAuthModel{
+		Scheme: a.Scheme,
+	}
+	if a.Scheme != OrganizationAuthSchemeOidc {
+		return m
+	}
+
+	m.Oidc = &OrganizationOidcModel{
+		Issuer:      a.Oidc.Issuer,
+		ClientID:    a.Oidc.ClientID,
+		Scopes:      a.Oidc.Scopes,
+		GroupsClaim: a.Oidc.GroupsClaim,
+		LinkByEmail: a.Oidc.LinkByEmail,
+		Groups:      []OrganizationOidcGroupModel{},
+	}
+	for _, g := range a.Oidc.Groups {
+		m.Oidc.Groups = append(m.Oidc.Groups, OrganizationOidcGroupModel{
+			Name: g.Name,
+			Team: g.Team,
+		})
+	}
+
+	return m
+}
+
+// IsSameProvider tells whether both authentication settings rely on the same identity provider
+func (a *OrganizationAuth) IsSameProvider(b *OrganizationAuth) bool {
+	if a.Scheme != b.Scheme {
+		return false
+	}
+	if a.Scheme != OrganizationAuthSchemeOidc {
+		return true
+	}
+	return a.Oidc.Issuer == b.Oidc.Issuer && a.Oidc.ClientID == b.Oidc.ClientID && a.Oidc.LinkByEmail == b.Oidc.LinkByEmail
+}
+
+func (a *OrganizationAuth) encryptSecrets() error {
+	secret, err := EncryptSecret(a.Oidc.ClientSecret)
+	if err != nil {
+		return err
+	}
+	a.Oidc.ClientSecret = secret
+	return nil
+}
+
+// OidcConfig returns organization's IdP settings. Organization IdPs can't grant platform-wide roles.
+func (a *OrganizationAuth) OidcConfig() (KowabungaOidcConfig, error) {
+	secret, err := DecryptSecret(a.Oidc.ClientSecret)
+	if err != nil {
+		return KowabungaOidcConfig{}, err
+	}
+
+	cfg := KowabungaOidcConfig{
+		Enabled:      a.Scheme == OrganizationAuthSchemeOidc,
+		Issuer:       a.Oidc.Issuer,
+		ClientID:     a.Oidc.ClientID,
+		ClientSecret: secret,
+		RedirectURL:  GetCfg().Global.OIDC.RedirectURL,
+		Scopes:       a.Oidc.Scopes,
+		GroupsClaim:  a.Oidc.GroupsClaim,
+		DefaultRole:  UserRoleStandard,
+		LinkByEmail:  a.Oidc.LinkByEmail,
+		Groups:       []KowabungaOidcGroupConfig{},
+	}
+	for _, g := range a.Oidc.Groups {
+		cfg.Groups = append(cfg.Groups, KowabungaOidcGroupConfig{
+			Name: g.Name,
+			Team: g.Team,
+		})
+	}
+	return cfg, nil
+}
+
+func NewOrganization(name, desc string, admins []string, auth OrganizationAuth, quotas sdk.ProjectResources) (*Organization, error) {
+	o := Organization{
+		Resource:   NewResource(name, desc, MongoCollectionOrganizationSchemaVersion),
+		Auth:       auth,
+		AdminIDs:   []string{},
+		UserIDs:    []string{},
+		TeamIDs:    []string{},
+		ProjectIDs: []string{},
+	}
+	o.Quotas.Update(quotas)
+
+	err := o.Auth.encryptSecrets()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = GetDB().Insert(MongoCollectionOrganizationName, o)
+	if err != nil {
+		return nil, err
+	}
+
+	klog.Debugf("Created new organization %s (%s)", o.String(), o.Name)
+
+	// administrators are organization's users
+	for _, userId := range admins {
+		u, err := FindUserByID(userId)
+		if err != nil {
+			continue
+		}
+		err = o.AddUser(u)
+		if err != nil {
+			klog.Error(err)
+		}
+	}
+	o.SetAdmins(admins)
+	o.Save()
+
+	return &o, nil
+}
+
+func FindOrganizations() []Organization {
+	return FindResources[Organization](MongoCollectionOrganizationName)
+}
+
+func FindOrganizationByID(id string) (*Organization, error) {
+	return FindResourceByID[Organization](MongoCollectionOrganizationName, id)
+}
+
+func FindOrganizationByName(name string) (*Organization, error) {
+	return FindResourceByName[Organization](MongoCollectionOrganizationName, name)
+}
+
+// FindAdministeredOrganizations returns organizations user is administrator of
+func FindAdministeredOrganizations(userId string) ([]Organization, error) {
+	return FindResourcesByKey[Organization](MongoCollectionOrganizationName, "admin_ids", userId)
+}
+
+func (o *Organization) IsAdmin(userId string) bool {
+	return slices.Contains(o.AdminIDs, userId)
+}
+
+// SetAdmins sets organization's administrators, which must be organization's users
+func (o *Organization) SetAdmins(admins []string) {
+	o.AdminIDs = []string{}
+	for _, userId := range admins {
+		if !slices.Contains(o.UserIDs, userId) {
+			klog.Warningf("User %s is not part of organization %s, can't be granted administrator", userId, o.String())
+			continue
+		}
+		AddChildRef(&o.AdminIDs, userId)
+	}
+}
+
+func (o *Organization) HasChildren() bool {
+	return HasChildRefs(o.UserIDs, o.TeamIDs, o.ProjectIDs)
+}
+
+func (o *Organization) IsOidcEnabled() bool {
+	return o.Auth.Scheme == OrganizationAuthSchemeOidc
+}
+
+// Usage sums up all organization's projects resources usage
+func (o *Organization) Usage() (ProjectResources, error) {
+	pipeline := bson.A{
+		bson.D{bson.E{Key: "$match", Value: bson.D{bson.E{Key: "organization_id", Value: o.String()}}}},
+		bson.D{bson.E{Key: "$group", Value: bson.D{
+			bson.E{Key: "_id", Value: nil},
+			bson.E{Key: "vcpus", Value: bson.D{bson.E{Key: "$sum", Value: "$usage.vcpus"}}},
+			bson.E{Key: "memory", Value: bson.D{bson.E{Key: "$sum", Value: "$usage.memory"}}},
+			bson.E{Key: "storage", Value: bson.D{bson.E{Key: "$sum", Value: "$usage.storage"}}},
+			bson.E{Key: "instances", Value: bson.D{bson.E{Key: "$sum", Value: "$usage.instances"}}},
+		}}},
+	}
+
+	results := []ProjectResources{}
+	err := GetDB().Aggregate(MongoCollectionProjectName, pipeline, &results)
+	if err != nil || len(results) == 0 {
+		return ProjectResources{}, err
+	}
+	return results[0], nil
+}
+
+func (o *Organization) AllowInstanceCreationOrUpdate(instances, cpu, mem int64) bool {
+	// ensure the new instance characteristics comply with the organization quota
+	usage, err := o.Usage()
+	if err != nil {
+		klog.Error(err)
+		return false
+	}
+	if o.Quotas.InstancesCount > 0 {
+		if usage.InstancesCount+uint16(instances) > o.Quotas.InstancesCount {
+			return false
+		}
+	}
+	if o.Quotas.VCPUs > 0 {
+		if usage.VCPUs+uint16(cpu) > o.Quotas.VCPUs {
+			return false
+		}
+	}
+	if o.Quotas.MemorySize > 0 {
+		if usage.MemorySize+uint64(mem) > o.Quotas.MemorySize {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *Organization) AllowVolumeCreationOrUpdate(vol int64) bool {
+	// ensure the new volume characteristics comply with the organization quota
+	if o.Quotas.StorageSize > 0 {
+		usage, err := o.Usage()
+		if err != nil {
+			klog.Error(err)
+			return false
+		}
+		if usage.StorageSize+uint64(vol) > o.Quotas.StorageSize {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *Organization) Update(name, desc string, auth *OrganizationAuth, admins []string, quotas *sdk.ProjectResources) error {
+	o.UpdateResourceDefaults(name, desc)
+	if auth != nil {
+		err := auth.encryptSecrets()
+		if err != nil {
+			return err
+		}
+		o.Auth = *auth
+	}
+	if admins != nil {
+		o.SetAdmins(admins)
+	}
+	if quotas != nil {
+		o.Quotas.Update(*quotas)
+	}
+	err := o.save()
+	if err != nil {
+		return err
+	}
+
+	// IdP settings may have changed
+	InvalidateOidcProvider(o.String())
+	return nil
+}
+
+func (o *Organization) save() error {
+	o.Updated()
+	_, err := GetDB().Update(MongoCollectionOrganizationName, o.ID, o)
+	return err
+}
+
+func (o *Organization) Save() {
+	err := o.save()
+	if err != nil {
+		klog.Error(err)
+	}
+}
+
+func (o *Organization) Delete() error {
+	klog.Debugf("Deleting organization %s", o.String())
+
+	if o.String() == ResourceUnknown {
+		return nil
+	}
+
+	InvalidateOidcProvider(o.String())
+
+	return GetDB().Delete(MongoCollectionOrganizationName, o.ID)
+}
+
+func (o *Organization) Model() OrganizationModel {
+	usage, err := o.Usage()
+	if err != nil {
+		klog.Error(err)
+	}
+	return OrganizationModel{
+		Id:          o.String(),
+		Name:        o.Name,
+		Description: o.Description,
+		Auth:        o.Auth.Model(),
+		Quotas:      o.Quotas.Model(),
+		Usage:       usage.Model(),
+		Admins:      o.AdminIDs,
+		Users:       o.UserIDs,
+		Teams:       o.TeamIDs,
+		Projects:    o.ProjectIDs,
+	}
+}
+
+// Users
+
+func (o *Organization) AddUser(u *User) error {
+	if u.OrganizationID != "" && u.OrganizationID != o.String() {
+		return fmt.Errorf("%s", ErrOrganizationAlreadyMember)
+	}
+
+	klog.Debugf("Adding user %s to organization %s", u.String(), o.String())
+	err := UpdateResource(MongoCollectionUserName, u, func(u *User) {
+		u.OrganizationID = o.String()
+	})
+	if err != nil {
+		return err
+	}
+
+	return UpdateResource(MongoCollectionOrganizationName, o, func(o *Organization) {
+		AddChildRef(&o.UserIDs, u.String())
+	})
+}
+
+func (o *Organization) RemoveUser(u *User) error {
+	if u.OrganizationID != o.String() {
+		return fmt.Errorf("%s", ErrOrganizationNotMember)
+	}
+
+	klog.Debugf("Removing user %s from organization %s", u.String(), o.String())
+	err := UpdateResource(MongoCollectionUserName, u, func(u *User) {
+		u.OrganizationID = ""
+	})
+	if err != nil {
+		return err
+	}
+
+	return UpdateResource(MongoCollectionOrganizationName, o, func(o *Organization) {
+		RemoveChildRef(&o.UserIDs, u.String())
+		RemoveChildRef(&o.AdminIDs, u.String())
+	})
+}
+
+// Teams
+
+func (o *Organization) AddTeam(t *Team) error {
+	if t.OrganizationID != "" && t.OrganizationID != o.String() {
+		return fmt.Errorf("%s", ErrOrganizationAlreadyMember)
+	}
+
+	klog.Debugf("Adding team %s to organization %s", t.String(), o.String())
+	err := UpdateResource(MongoCollectionTeamName, t, func(t *Team) {
+		t.OrganizationID = o.String()
+	})
+	if err != nil {
+		return err
+	}
+
+	return UpdateResource(MongoCollectionOrganizationName, o, func(o *Organization) {
+		AddChildRef(&o.TeamIDs, t.String())
+	})
+}
+
+func (o *Organization) RemoveTeam(t *Team) error {
+	if t.OrganizationID != o.String() {
+		return fmt.Errorf("%s", ErrOrganizationNotMember)
+	}
+
+	klog.Debugf("Removing team %s from organization %s", t.String(), o.String())
+	err := UpdateResource(MongoCollectionTeamName, t, func(t *Team) {
+		t.OrganizationID = ""
+	})
+	if err != nil {
+		return err
+	}
+
+	return UpdateResource(MongoCollectionOrganizationName, o, func(o *Organization) {
+		RemoveChildRef(&o.TeamIDs, t.String())
+	})
+}
+
+// Projects
+
+func (o *Organization) AddProject(p *Project) error {
+	if p.OrganizationID != "" && p.OrganizationID != o.String() {
+		return fmt.Errorf("%s", ErrOrganizationAlreadyMember)
+	}
+
+	klog.Debugf("Adding project %s to organization %s", p.String(), o.String())
+	err := UpdateResource(MongoCollectionProjectName, p, func(p *Project) {
+		p.OrganizationID = o.String()
+	})
+	if err != nil {
+		return err
+	}
+
+	return UpdateResource(MongoCollectionOrganizationName, o, func(o *Organization) {
+		AddChildRef(&o.ProjectIDs, p.String())
+	})
+}
+
+func (o *Organization) RemoveProject(p *Project) error {
+	if p.OrganizationID != o.String() {
+		return fmt.Errorf("%s", ErrOrganizationNotMember)
+	}
+
+	klog.Debugf("Removing project %s from organization %s", p.String(), o.String())
+	err := UpdateResource(MongoCollectionProjectName, p, func(p *Project) {
+		p.OrganizationID = ""
+	})
+	if err != nil {
+		return err
+	}
+
+	return UpdateResource(MongoCollectionOrganizationName, o, func(o *Organization) {
+		RemoveChildRef(&o.ProjectIDs, p.String())
+	})
+}
@@ -36,20 +36,22 @@ type Project struct {
 	Resource `bson:"inline"`
 
 	// parents
+	OrganizationID string `bson:"organization_id"`
 
 	// properties
-	Domain          string             `bson:"domain"`
-	RootPassword    string             `bson:"default_root_password"`
-	BootstrapUser   string             `bson:"bootstrap_user"`
-	BootstrapPubkey string             `bson:"bootstrap_pubkey"`
-	Tags            []string           `bson:"tags"`
-	Meta            []ResourceMetadata `bson:"metadatas"`
-	Quotas          ProjectResources   `bson:"quotas"` // limits, 0 for unlimited
-	Usage           ProjectResources   `bson:"usage"`  // usage, 0 for un-used
-	Cost            ProjectCost        `bson:"cost"`
-	TeamIDs         []string           `bson:"team_ids"`
-	RegionIDs       []string           `bson:"region_ids"`
-	VrrpIDs         []int              `bson:"reserved_vrrp_ids"`
+	Domain          string               `bson:"domain"`
+	RootPassword    string               `bson:"default_root_password"`
+	BootstrapUser   string               `bson:"bootstrap_user"`
+	BootstrapPubkey string               `bson:"bootstrap_pubkey"`
+	Tags            []string             `bson:"tags"`
+	Meta            []ResourceMetadata   `bson:"metadatas"`
+	Quotas          ProjectResources     `bson:"quotas"` // limits, 0 for unlimited
+	Usage           ProjectResources     `bson:"usage"`  // usage, 0 for un-used
+	Cost            ProjectCost          `bson:"cost"`
+	TeamIDs         []string             `bson:"team_ids"`
+	RoleBindings    []ProjectRoleBinding `bson:"role_bindings"`
+	RegionIDs       []string             `bson:"region_ids"`
+	VrrpIDs         []int                `bson:"reserved_vrrp_ids"`
 
 	// children references
 	InstanceIDs    []string          `bson:"instance_ids"`
@@ -189,12 +191,13 @@ func NewProject(name, desc, domain, pwd, user, pubkey string, teams, regions, ta
 
 	// notify project's users
 	for _, u := range p.NotifiableUsers() {
-		err := NewEmailProjectCreated(&p, u)
+		err := NotifyProjectCreated(&p, u)
 		if err != nil {
 			klog.Error(err)
 			// not a blocker
 		}
 	}
+	FireWebhookEvent(WebhookEventProjectCreated, p.String(), p.webhookModel())
 
 	return &p, nil
 }
@@ -273,6 +276,13 @@ func (p *Project) migrateSchemaV2() error {
 	return nil
 }
 
+func (p *Project) Organization() (*Organization, error) {
+	if p.OrganizationID == "" {
+		return nil, fmt.Errorf("%s", ErrOrganizationNotMember)
+	}
+	return FindOrganizationByID(p.OrganizationID)
+}
+
 func (p *Project) Resources() []string {
 	res := []string{p.String()}
 	res = append(res, p.InstanceIDs...)
@@ -285,6 +295,30 @@ func (p *Project) Resources() []string {
 	return res
 }
 
+// TeamRole returns the role team is granted on project, if any
+func (p *Project) TeamRole(teamId string) (string, bool) {
+	if !slices.Contains(p.TeamIDs, teamId) {
+		return "", false
+	}
+	for _, b := range p.RoleBindings {
+		if b.TeamID == teamId {
+			return b.Role, true
+		}
+	}
+	return ProjectRoleDefault, true
+}
+
+// SetRoleBindings replaces project's teams role bindings, granting bound teams access to project
+func (p *Project) SetRoleBindings(bindings []ProjectRoleBinding) error {
+	p.RoleBindings = bindings
+	for _, b := range bindings {
+		if !slices.Contains(p.TeamIDs, b.TeamID) {
+			p.TeamIDs = append(p.TeamIDs, b.TeamID)
+		}
+	}
+	return p.save()
+}
+
 func (p *Project) HasChildren() bool {
 	return HasChildRefs(p.InstanceIDs, p.VolumeIDs, p.KomputeIDs, p.KyloIDs, p.KawaiiIDs, p.KonveyIDs, p.RecordIDs)
 }
@@ -323,7 +357,7 @@ func (p *Project) NotifiableUsers() []*User {
 				// not a blocker
 				continue
 			}
-			if !u.NotificationsEnabled {
+			if len(u.NotificationChannels) == 0 {
 				continue
 			}
 
@@ -334,7 +368,7 @@ func (p *Project) NotifiableUsers() []*User {
 	return users
 }
 
-func (p *Project) Update(name, desc, pwd, user, pubkey string, teams, regions, tags []string, meta map[string]string, quotas sdk.ProjectResources) {
+func (p *Project) Update(name, desc, pwd, user, pubkey string, teams, regions, tags []string, meta map[string]string, quotas sdk.ProjectResources) error {
 	p.UpdateResourceDefaults(name, desc)
 	SetFieldStr(&p.RootPassword, pwd)
 	SetFieldStr(&p.BootstrapUser, user)
@@ -343,12 +377,22 @@ func (p *Project) Update(name, desc, pwd, user, pubkey string, teams, regions, t
 	p.Meta = getMetadatas(meta)
 	p.Quotas.Update(quotas)
 	p.TeamIDs = teams
+	p.RoleBindings = slices.DeleteFunc(p.RoleBindings, func(b ProjectRoleBinding) bool {
+		return !slices.Contains(teams, b.TeamID)
+	})
 	p.RegionIDs = regions
 	err := p.AssignZoneGatewayAddresses()
 	if err != nil {
 		klog.Error(err)
 	}
-	p.Save()
+
+	err = p.save()
+	if err != nil {
+		return err
+	}
+
+	FireWebhookEvent(WebhookEventProjectUpdated, p.String(), p.webhookModel())
+	return nil
 }
 
 func (p *Project) AllocatePrivateSubnets(subnetSize int) error {
@@ -492,9 +536,14 @@ func (p *Project) DeleteDnsZone() error {
 	return nil
 }
 
-func (p *Project) Save() {
+func (p *Project) save() error {
 	p.Updated()
 	_, err := GetDB().Update(MongoCollectionProjectName, p.ID, p)
+	return err
+}
+
+func (p *Project) Save() {
+	err := p.save()
 	if err != nil {
 		klog.Error(err)
 	}
@@ -519,7 +568,36 @@ func (p *Project) Delete() error {
 		// not a blocker
 	}
 
-	return GetDB().Delete(MongoCollectionProjectName, p.ID)
+	// remove project's reference from organization, if any
+	o, err := p.Organization()
+	if err == nil {
+		err = o.RemoveProject(p)
+		if err != nil {
+			klog.Error(err)
+		}
+	}
+
+	// project's subscriptions won't ever be triggered anymore
+	err = DeleteProjectWebhooks(p.String())
+	if err != nil {
+		klog.Error(err)
+		// not a blocker
+	}
+
+	err = GetDB().Delete(MongoCollectionProjectName, p.ID)
+	if err != nil {
+		return err
+	}
+	FireWebhookEvent(WebhookEventProjectDeleted, p.String(), p.webhookModel())
+
+	return nil
+}
+
+// webhookModel is project's model, as sent to webhooks, without secrets
+func (p *Project) webhookModel() sdk.Project {
+	m := p.Model()
+	m.RootPassword = ""
+	return m
 }
 
 func (p *Project) Model() sdk.Project {
@@ -559,6 +637,13 @@ func (p *Project) Model() sdk.Project {
 	}
 }
 
+// ListModel is project's model, as exposed in lists, with no credentials
+func (p *Project) ListModel() sdk.Project {
+	m := p.Model()
+	m.RootPassword = ""
+	return m
+}
+
 func (p *Project) GetCost() sdk.Cost {
 
 	var price float32 = 0
@@ -605,9 +690,16 @@ func (p *Project) AllocateVRID() (int, error) {
 		if slices.Contains(p.VrrpIDs, vrrpId) {
 			continue
 		}
-		p.VrrpIDs = append(p.VrrpIDs, vrrpId)
-		sort.Ints(p.VrrpIDs)
-		p.Save()
+		err := UpdateResource(MongoCollectionProjectName, p, func(p *Project) {
+			// ID may have been concurrently allocated
+			if !slices.Contains(p.VrrpIDs, vrrpId) {
+				p.VrrpIDs = append(p.VrrpIDs, vrrpId)
+				sort.Ints(p.VrrpIDs)
+			}
+		})
+		if err != nil {
+			return 0, err
+		}
 		return vrrpId, nil
 	}
 
@@ -616,13 +708,17 @@ func (p *Project) AllocateVRID() (int, error) {
 
 func (p *Project) RemoveVRID(vrrpId int) {
 	klog.Debugf("Removing VRRP ID %d from project %s", vrrpId, p.String())
-	for idx, id := range p.VrrpIDs {
-		if id == vrrpId {
-			p.VrrpIDs = append((p.VrrpIDs)[:idx], (p.VrrpIDs)[idx+1:]...)
-			break
+	err := UpdateResource(MongoCollectionProjectName, p, func(p *Project) {
+		for idx, id := range p.VrrpIDs {
+			if id == vrrpId {
+				p.VrrpIDs = append((p.VrrpIDs)[:idx], (p.VrrpIDs)[idx+1:]...)
+				break
+			}
 		}
+	})
+	if err != nil {
+		klog.Error(err)
 	}
-	p.Save()
 }
 
 // Instances
@@ -637,29 +733,38 @@ func (p *Project) Instance(id string) (*Instance, error) {
 
 func (p *Project) AddInstance(id string) {
 	klog.Debugf("Adding instance %s to project %s", id, p.String())
-	AddChildRef(&p.InstanceIDs, id)
-	p.Save() // save DB before looking back
 
-	// find instance again
-	i, err := p.Instance(id)
+	i, err := FindInstanceByID(id)
 	if err != nil {
 		klog.Error(err)
 		return
 	}
 
-	// increase usage counters
-	p.Usage.InstancesCount += 1
-	p.Usage.VCPUs += uint16(i.CPU)
-	p.Usage.MemorySize += uint64(i.Memory)
+	err = UpdateResource(MongoCollectionProjectName, p, func(p *Project) {
+		if HasChildRef(&p.InstanceIDs, id) {
+			return
+		}
+		AddChildRef(&p.InstanceIDs, id)
 
-	p.Save()
+		// increase usage counters
+		p.Usage.InstancesCount += 1
+		p.Usage.VCPUs += uint16(i.CPU)
+		p.Usage.MemorySize += uint64(i.Memory)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (p *Project) UpdateInstanceUsage(cpu, mem int64) {
 	// increase usage counters
-	p.Usage.VCPUs += uint16(cpu)
-	p.Usage.MemorySize += uint64(mem)
-	p.Save()
+	err := UpdateResource(MongoCollectionProjectName, p, func(p *Project) {
+		p.Usage.VCPUs += uint16(cpu)
+		p.Usage.MemorySize += uint64(mem)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (p *Project) RemoveInstance(id string) {
@@ -672,13 +777,21 @@ func (p *Project) RemoveInstance(id string) {
 		return
 	}
 
-	// decrease usage counters
-	p.Usage.InstancesCount -= 1
-	p.Usage.VCPUs -= uint16(ist.CPU)
-	p.Usage.MemorySize -= uint64(ist.Memory)
+	err = UpdateResource(MongoCollectionProjectName, p, func(p *Project) {
+		if !HasChildRef(&p.InstanceIDs, id) {
+			return
+		}
 
-	RemoveChildRef(&p.InstanceIDs, id)
-	p.Save()
+		// decrease usage counters
+		p.Usage.InstancesCount -= 1
+		p.Usage.VCPUs -= uint16(ist.CPU)
+		p.Usage.MemorySize -= uint64(ist.Memory)
+
+		RemoveChildRef(&p.InstanceIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (p *Project) AllowInstanceCreationOrUpdate(instances, cpu, mem int64) bool {
@@ -698,6 +811,13 @@ func (p *Project) AllowInstanceCreationOrUpdate(instances, cpu, mem int64) bool
 			return false
 		}
 	}
+
+	// as well as with organization's one
+	o, err := p.Organization()
+	if err == nil {
+		return o.AllowInstanceCreationOrUpdate(instances, cpu, mem)
+	}
+
 	return true
 }
 
@@ -713,26 +833,35 @@ func (p *Project) Volume(id string) (*Volume, error) {
 
 func (p *Project) AddVolume(id string) {
 	klog.Debugf("Adding volume %s to project %s", id, p.String())
-	AddChildRef(&p.VolumeIDs, id)
-	p.Save() // save DB before looking back
 
-	// find volume again
-	v, err := p.Volume(id)
+	v, err := FindVolumeByID(id)
 	if err != nil {
 		klog.Error(err)
 		return
 	}
 
-	// increase usage counters
-	p.Usage.StorageSize += uint64(v.Size)
+	err = UpdateResource(MongoCollectionProjectName, p, func(p *Project) {
+		if HasChildRef(&p.VolumeIDs, id) {
+			return
+		}
+		AddChildRef(&p.VolumeIDs, id)
 
-	p.Save()
+		// increase usage counters
+		p.Usage.StorageSize += uint64(v.Size)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (p *Project) UpdateVolumeUsage(size int64) {
 	// increase usage counters
-	p.Usage.StorageSize += uint64(size)
-	p.Save()
+	err := UpdateResource(MongoCollectionProjectName, p, func(p *Project) {
+		p.Usage.StorageSize += uint64(size)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (p *Project) RemoveVolume(id string) {
@@ -744,11 +873,19 @@ func (p *Project) RemoveVolume(id string) {
 		return
 	}
 
-	// decrease usage counters
-	p.Usage.StorageSize -= uint64(vol.Size)
+	err = UpdateResource(MongoCollectionProjectName, p, func(p *Project) {
+		if !HasChildRef(&p.VolumeIDs, id) {
+			return
+		}
+
+		// decrease usage counters
+		p.Usage.StorageSize -= uint64(vol.Size)
 
-	RemoveChildRef(&p.VolumeIDs, id)
-	p.Save()
+		RemoveChildRef(&p.VolumeIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (p *Project) AllowVolumeCreationOrUpdate(vol int64) bool {
@@ -758,6 +895,13 @@ func (p *Project) AllowVolumeCreationOrUpdate(vol int64) bool {
 			return false
 		}
 	}
+
+	// as well as with organization's one
+	o, err := p.Organization()
+	if err == nil {
+		return o.AllowVolumeCreationOrUpdate(vol)
+	}
+
 	return true
 }
 
@@ -773,14 +917,22 @@ func (p *Project) FindKomputeByID(id string) (*Kompute, error) {
 
 func (p *Project) AddKompute(id string) {
 	klog.Debugf("Adding Kompute %s to project %s", id, p.String())
-	AddChildRef(&p.KomputeIDs, id)
-	p.Save()
+	err := UpdateResource(MongoCollectionProjectName, p, func(p *Project) {
+		AddChildRef(&p.KomputeIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (p *Project) RemoveKompute(id string) {
 	klog.Debugf("Removing Kompute %s from project %s", id, p.String())
-	RemoveChildRef(&p.KomputeIDs, id)
-	p.Save()
+	err := UpdateResource(MongoCollectionProjectName, p, func(p *Project) {
+		RemoveChildRef(&p.KomputeIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 // Kawaiis
@@ -795,14 +947,22 @@ func (p *Project) FindKawaiiByID(id string) (*Kawaii, error) {
 
 func (p *Project) AddKawaii(id string) {
 	klog.Debugf("Adding Kawaii %s to project %s", id, p.String())
-	AddChildRef(&p.KawaiiIDs, id)
-	p.Save()
+	err := UpdateResource(MongoCollectionProjectName, p, func(p *Project) {
+		AddChildRef(&p.KawaiiIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (p *Project) RemoveKawaii(id string) {
 	klog.Debugf("Removing Kawaii %s from project %s", id, p.String())
-	RemoveChildRef(&p.KawaiiIDs, id)
-	p.Save()
+	err := UpdateResource(MongoCollectionProjectName, p, func(p *Project) {
+		RemoveChildRef(&p.KawaiiIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 // Konvey
@@ -817,14 +977,22 @@ func (p *Project) FindKonveyByID(id string) (*Konvey, error) {
 
 func (p *Project) AddKonvey(id string) {
 	klog.Debugf("Adding Konvey %s to project %s", id, p.String())
-	AddChildRef(&p.KonveyIDs, id)
-	p.Save()
+	err := UpdateResource(MongoCollectionProjectName, p, func(p *Project) {
+		AddChildRef(&p.KonveyIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (p *Project) RemoveKonvey(id string) {
 	klog.Debugf("Removing Konvey %s from project %s", id, p.String())
-	RemoveChildRef(&p.KonveyIDs, id)
-	p.Save()
+	err := UpdateResource(MongoCollectionProjectName, p, func(p *Project) {
+		RemoveChildRef(&p.KonveyIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 // Kylo
@@ -839,14 +1007,22 @@ func (p *Project) FindKyloByID(id string) (*Kylo, error) {
 
 func (p *Project) AddKylo(id string) {
 	klog.Debugf("Adding Kylo %s to project %s", id, p.String())
-	AddChildRef(&p.KyloIDs, id)
-	p.Save()
+	err := UpdateResource(MongoCollectionProjectName, p, func(p *Project) {
+		AddChildRef(&p.KyloIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (p *Project) RemoveKylo(id string) {
 	klog.Debugf("Removing Kylo %s from project %s", id, p.String())
-	RemoveChildRef(&p.KyloIDs, id)
-	p.Save()
+	err := UpdateResource(MongoCollectionProjectName, p, func(p *Project) {
+		RemoveChildRef(&p.KyloIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 // DNS Records
@@ -861,12 +1037,20 @@ func (p *Project) FindDnsRecordByID(id string) (*DnsRecord, error) {
 
 func (p *Project) AddDnsRecord(id string) {
 	klog.Debugf("Adding DNS Record %s to project %s", id, p.String())
-	AddChildRef(&p.RecordIDs, id)
-	p.Save()
+	err := UpdateResource(MongoCollectionProjectName, p, func(p *Project) {
+		AddChildRef(&p.RecordIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (p *Project) RemoveDnsRecord(id string) {
 	klog.Debugf("Removing DNS Record %s from project %s", id, p.String())
-	RemoveChildRef(&p.RecordIDs, id)
-	p.Save()
+	err := UpdateResource(MongoCollectionProjectName, p, func(p *Project) {
+		RemoveChildRef(&p.RecordIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
@@ -198,15 +198,20 @@ func (r *Region) AverageVirtualResources() *RegionVirtualResources {
 	return &r.VirtualResources
 }
 
-func (r *Region) Update(name, desc, domain string) {
+func (r *Region) Update(name, desc, domain string) error {
 	r.UpdateResourceDefaults(name, desc)
 	SetFieldStr(&r.Domain, domain)
-	r.Save()
+	return r.save()
 }
 
-func (r *Region) Save() {
+func (r *Region) save() error {
 	r.Updated()
 	_, err := GetDB().Update(MongoCollectionRegionName, r.ID, r)
+	return err
+}
+
+func (r *Region) Save() {
+	err := r.save()
 	if err != nil {
 		klog.Error(err)
 	}
@@ -277,14 +282,22 @@ func (r *Region) Zone(id string) (*Zone, error) {
 
 func (r *Region) AddZone(id string) {
 	klog.Debugf("Adding zone %s to region %s", id, r.String())
-	AddChildRef(&r.ZoneIDs, id)
-	r.Save()
+	err := UpdateResource(MongoCollectionRegionName, r, func(r *Region) {
+		AddChildRef(&r.ZoneIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (r *Region) RemoveZone(id string) {
 	klog.Debugf("Removing zone %s from region %s", id, r.String())
-	RemoveChildRef(&r.ZoneIDs, id)
-	r.Save()
+	err := UpdateResource(MongoCollectionRegionName, r, func(r *Region) {
+		RemoveChildRef(&r.ZoneIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 // Network Gateways
@@ -299,14 +312,22 @@ func (r *Region) Kiwi(id string) (*Kiwi, error) {
 
 func (r *Region) AddKiwi(id string) {
 	klog.Debugf("Adding network gateway %s to region %s", id, r.String())
-	AddChildRef(&r.KiwiIDs, id)
-	r.Save()
+	err := UpdateResource(MongoCollectionRegionName, r, func(r *Region) {
+		AddChildRef(&r.KiwiIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (r *Region) RemoveKiwi(id string) {
 	klog.Debugf("Removing kiwi %s from region %s", id, r.String())
-	RemoveChildRef(&r.KiwiIDs, id)
-	r.Save()
+	err := UpdateResource(MongoCollectionRegionName, r, func(r *Region) {
+		RemoveChildRef(&r.KiwiIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 // Virtual Networks
@@ -321,14 +342,22 @@ func (r *Region) VNet(id string) (*VNet, error) {
 
 func (r *Region) AddVNet(id string) {
 	klog.Debugf("Adding virtual network %s to region %s", id, r.String())
-	AddChildRef(&r.VNetIDs, id)
-	r.Save()
+	err := UpdateResource(MongoCollectionRegionName, r, func(r *Region) {
+		AddChildRef(&r.VNetIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (r *Region) RemoveVNet(id string) {
 	klog.Debugf("Removing virtual network %s from region %s", id, r.String())
-	RemoveChildRef(&r.VNetIDs, id)
-	r.Save()
+	err := UpdateResource(MongoCollectionRegionName, r, func(r *Region) {
+		RemoveChildRef(&r.VNetIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (r *Region) GetPublicSubnet() (string, error) {
@@ -457,14 +486,22 @@ func (r *Region) FindDnsRecordByID(id string) (*DnsRecord, error) {
 
 func (r *Region) AddDnsRecord(id string) {
 	klog.Debugf("Adding DNS Record %s to region %s", id, r.String())
-	AddChildRef(&r.RecordIDs, id)
-	r.Save()
+	err := UpdateResource(MongoCollectionRegionName, r, func(r *Region) {
+		AddChildRef(&r.RecordIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (r *Region) RemoveDnsRecord(id string) {
 	klog.Debugf("Removing DNS Record %s from region %s", id, r.String())
-	RemoveChildRef(&r.RecordIDs, id)
-	r.Save()
+	err := UpdateResource(MongoCollectionRegionName, r, func(r *Region) {
+		RemoveChildRef(&r.RecordIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 // Cost
@@ -275,7 +275,7 @@ func (p *StoragePool) Scan() {
 	}
 }
 
-func (p *StoragePool) Update(name, desc, pool, address string, port int, secret string, price float32, currency string, agts []string) {
+func (p *StoragePool) Update(name, desc, pool, address string, port int, secret string, price float32, currency string, agts []string) error {
 	p.UpdateResourceDefaults(name, desc)
 
 	SetFieldStr(&p.Pool, pool)
@@ -289,12 +289,17 @@ func (p *StoragePool) Update(name, desc, pool, address string, port int, secret
 	SetFieldStr(&p.Cost.Currency, currency)
 	p.AgentIDs = VerifyAgents(agts, common.KowabungaKaktusAgent)
 
-	p.Save()
+	return p.save()
 }
 
-func (p *StoragePool) Save() {
+func (p *StoragePool) save() error {
 	p.Updated()
 	_, err := GetDB().Update(MongoCollectionStoragePoolName, p.ID, p)
+	return err
+}
+
+func (p *StoragePool) Save() {
+	err := p.save()
 	if err != nil {
 		klog.Error(err)
 	}
@@ -389,12 +394,20 @@ func (p *StoragePool) Volume(id string) (*Volume, error) {
 
 func (p *StoragePool) AddVolume(id string) {
 	klog.Debugf("Adding volume %s to pool %s", id, p.String())
-	AddChildRef(&p.VolumeIDs, id)
-	p.Save()
+	err := UpdateResource(MongoCollectionStoragePoolName, p, func(p *StoragePool) {
+		AddChildRef(&p.VolumeIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (p *StoragePool) RemoveVolume(id string) {
 	klog.Debugf("Removing volume %s from pool %s", id, p.String())
-	RemoveChildRef(&p.VolumeIDs, id)
-	p.Save()
+	err := UpdateResource(MongoCollectionStoragePoolName, p, func(p *StoragePool) {
+		RemoveChildRef(&p.VolumeIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
@@ -500,14 +500,17 @@ func (s *Subnet) Update(name, desc, gw, dns string, reserved, gwPool []sdk.IpRan
 	s.Routes = routes
 	s.Application = app
 	// we forbid change of CIDR or privacy, makes no sense
-	s.Save()
-
-	return nil
+	return s.save()
 }
 
-func (s *Subnet) Save() {
+func (s *Subnet) save() error {
 	s.Updated()
 	_, err := GetDB().Update(MongoCollectionSubnetName, s.ID, s)
+	return err
+}
+
+func (s *Subnet) Save() {
+	err := s.save()
 	if err != nil {
 		klog.Error(err)
 	}
@@ -572,12 +575,20 @@ func (s *Subnet) Adapter(id string) (*Adapter, error) {
 
 func (s *Subnet) AddAdapter(id string) {
 	klog.Debugf("Adding adapter %s to subnet %s", id, s.String())
-	AddChildRef(&s.AdapterIDs, id)
-	s.Save()
+	err := UpdateResource(MongoCollectionSubnetName, s, func(s *Subnet) {
+		AddChildRef(&s.AdapterIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (s *Subnet) RemoveAdapter(id string) {
 	klog.Debugf("Removing adapter %s from subnet %s", id, s.String())
-	RemoveChildRef(&s.AdapterIDs, id)
-	s.Save()
+	err := UpdateResource(MongoCollectionSubnetName, s, func(s *Subnet) {
+		RemoveChildRef(&s.AdapterIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
@@ -7,6 +7,7 @@
 package kahuna
 
 import (
+	"fmt"
 	"slices"
 
 	"github.com/kowabunga-cloud/common/klog"
@@ -24,6 +25,7 @@ type Team struct {
 	Resource `bson:"inline"`
 
 	// parents
+	OrganizationID string `bson:"organization_id"`
 
 	// properties
 
@@ -103,6 +105,13 @@ func (t *Team) migrateSchemaV2() error {
 	return nil
 }
 
+func (t *Team) Organization() (*Organization, error) {
+	if t.OrganizationID == "" {
+		return nil, fmt.Errorf("%s", ErrOrganizationNotMember)
+	}
+	return FindOrganizationByID(t.OrganizationID)
+}
+
 func (t *Team) Users() []string {
 	return t.UserIDs
 }
@@ -151,15 +160,20 @@ func (t *Team) SetUsers(users []string) {
 	t.UserIDs = newUsers
 }
 
-func (t *Team) Update(name, desc string, users []string) {
+func (t *Team) Update(name, desc string, users []string) error {
 	t.UpdateResourceDefaults(name, desc)
 	t.SetUsers(users)
-	t.Save()
+	return t.save()
 }
 
-func (t *Team) Save() {
+func (t *Team) save() error {
 	t.Updated()
 	_, err := GetDB().Update(MongoCollectionTeamName, t.ID, t)
+	return err
+}
+
+func (t *Team) Save() {
+	err := t.save()
 	if err != nil {
 		klog.Error(err)
 	}
@@ -172,6 +186,15 @@ func (t *Team) Delete() error {
 		return nil
 	}
 
+	// remove team's reference from organization, if any
+	o, err := t.Organization()
+	if err == nil {
+		err = o.RemoveTeam(t)
+		if err != nil {
+			klog.Error(err)
+		}
+	}
+
 	return GetDB().Delete(MongoCollectionTeamName, t.ID)
 }
 
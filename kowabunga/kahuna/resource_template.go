@@ -117,7 +117,7 @@ func FindTemplates() []Template {
 }
 
 func FindTemplatesByStoragePool(poolId string) ([]Template, error) {
-	return FindResourcesByKey[Template](MongoCollectionTemplateName, "pool_id", poolId)
+	return FindResourcesByKey[Template](MongoCollectionTemplateName, "storage_pool_id", poolId)
 }
 
 func FindTemplateByID(id string) (*Template, error) {
@@ -159,14 +159,19 @@ func (t *Template) Volume() (*Volume, error) {
 	return FindVolumeByID(t.VolumeID)
 }
 
-func (t *Template) Update(name, desc string) {
+func (t *Template) Update(name, desc string) error {
 	t.UpdateResourceDefaults(name, desc)
-	t.Save()
+	return t.save()
 }
 
-func (t *Template) Save() {
+func (t *Template) save() error {
 	t.Updated()
 	_, err := GetDB().Update(MongoCollectionTemplateName, t.ID, t)
+	return err
+}
+
+func (t *Template) Save() {
+	err := t.save()
 	if err != nil {
 		klog.Error(err)
 	}
@@ -8,9 +8,11 @@ package kahuna
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/sethvargo/go-password/password"
+	"go.mongodb.org/mongo-driver/v2/bson"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/kowabunga-cloud/common/klog"
@@ -18,7 +20,7 @@ import (
 )
 
 const (
-	MongoCollectionTokenSchemaVersion = 2
+	MongoCollectionTokenSchemaVersion = 3
 	MongoCollectionTokenName          = "token"
 
 	TokenParentTypeAgent = "agent"
@@ -30,6 +32,14 @@ const (
 	TokenApiKeyLowercaseOnly         = false
 	TokenApiKeyAllowRepeatCharacters = true
 	TokenApiKeyHashCost              = 10
+
+	// API keys are formatted as kw_<token-id>_<secret>
+	TokenApiKeyPrefix    = "kw"
+	TokenApiKeySeparator = "_"
+
+	ErrTokenApiKeyMismatch     = "API key does not match token"
+	ErrTokenLegacyApiKeyDenied = "legacy API keys support is disabled"
+	ErrTokenNoSuchApiKey       = "no token matching API key"
 )
 
 type Token struct {
@@ -41,10 +51,12 @@ type Token struct {
 	UserID  string `bson:"user_id"`
 
 	// properties
-	ParentType     string `bson:"parent_type"`
-	Expire         bool   `bson:"expire"`
-	ExpirationDate string `bson:"expiration_date"`
-	ApiKeyHash     string `bson:"api_key_hash"`
+	ParentType     string     `bson:"parent_type"`
+	Expire         bool       `bson:"expire"`
+	ExpirationDate string     `bson:"expiration_date"`
+	ApiKeyHash     string     `bson:"api_key_hash"`
+	Legacy         bool       `bson:"legacy"` // pre-v3 API key, with no token identifier
+	Scope          TokenScope `bson:"scope"`
 
 	// children references
 }
@@ -68,6 +80,32 @@ func TokenMigrateSchema() error {
 	return nil
 }
 
+func TokenMigrateSchemaV3() error {
+	for _, token := range FindTokens() {
+		if token.SchemaVersion < 3 {
+			err := token.migrateSchemaV3()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func TokenRollbackSchemaV3() error {
+	for _, token := range FindTokens() {
+		if token.SchemaVersion == 3 {
+			err := token.rollbackSchemaV3()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func newToken(name, desc string, expire bool, expirationDate string) (*Token, error) {
 	t := Token{
 		Resource: NewResource(name, desc, MongoCollectionTokenSchemaVersion),
@@ -145,6 +183,11 @@ func FindTokenByName(name string) (*Token, error) {
 	return FindResourceByName[Token](MongoCollectionTokenName, name)
 }
 
+// FindUserApiToken returns user's personal API token
+func FindUserApiToken(u *User) (*Token, error) {
+	return FindTokenByName(fmt.Sprintf("%s-api-key", u.Name))
+}
+
 func FindTokensByAgent(agentId string) ([]Token, error) {
 	return FindResourcesByKey[Token](MongoCollectionTokenName, "agent_id", agentId)
 }
@@ -176,6 +219,79 @@ func (t *Token) migrateSchemaV2() error {
 	return nil
 }
 
+// API keys issued so far can't be converted to the new indexed format, as we only know about their hash.
+// Flag them as legacy ones, so that they can still be verified until they get renewed.
+func (t *Token) migrateSchemaV3() error {
+	t.Legacy = t.ApiKeyHash != ""
+	t.SchemaVersion = 3
+	t.Save()
+	return nil
+}
+
+func (t *Token) rollbackSchemaV3() error {
+	t.Legacy = false
+	t.SchemaVersion = 2
+	t.Save()
+	return nil
+}
+
+// FindTokenByApiKey looks up for the token matching the API key, verifying it
+func FindTokenByApiKey(apiKey string) (*Token, error) {
+	id, _, ok := ParseApiKey(apiKey)
+	if ok {
+		t, err := FindTokenByID(id)
+		if err != nil {
+			return nil, err
+		}
+		err = t.Verify(apiKey)
+		if err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
+
+	// failover: legacy API keys can't be looked up, verify all of them ... ;-(
+	// This is costly enough to be an easy denial of service, hence opt-in only.
+	if !GetCfg().Global.AllowLegacyApiKeys {
+		return nil, fmt.Errorf("%s", ErrTokenLegacyApiKeyDenied)
+	}
+
+	tokens := []Token{}
+	err := GetDB().FindAllByFilter(MongoCollectionTokenName, bson.D{bson.E{Key: "legacy", Value: true}}, nil, 0, &tokens)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tokens {
+		err := t.Verify(apiKey)
+		if err != nil {
+			continue
+		}
+		klog.Warningf("Token %s (%s) relies on a deprecated API key format, it should be renewed", t.String(), t.Name)
+		return &t, nil
+	}
+
+	return nil, fmt.Errorf("%s", ErrTokenNoSuchApiKey)
+}
+
+// ParseApiKey splits API key into token ID and secret parts
+func ParseApiKey(apiKey string) (string, string, bool) {
+	parts := strings.SplitN(apiKey, TokenApiKeySeparator, 3)
+	if len(parts) != 3 || parts[0] != TokenApiKeyPrefix {
+		return "", "", false
+	}
+
+	_, err := bson.ObjectIDFromHex(parts[1])
+	if err != nil || parts[2] == "" {
+		return "", "", false
+	}
+
+	return parts[1], parts[2], true
+}
+
+func (t *Token) formatApiKey(secret string) string {
+	return strings.Join([]string{TokenApiKeyPrefix, t.String(), secret}, TokenApiKeySeparator)
+}
+
 func (t *Token) Agent() (*Agent, error) {
 	return FindAgentByID(t.AgentID)
 }
@@ -215,19 +331,22 @@ func (t *Token) HasExpired() bool {
 
 func (t *Token) SetNewApiKey(notify bool) (string, error) {
 	// generate a new robust api key
-	apiKey, err := password.Generate(TokenApiKeyLength, TokenApiKeyDigitsCount,
+	secret, err := password.Generate(TokenApiKeyLength, TokenApiKeyDigitsCount,
 		TokenApiKeySymbolsCount, TokenApiKeyLowercaseOnly, TokenApiKeyAllowRepeatCharacters)
 	if err != nil {
 		return "", fmt.Errorf("unable to generate new robust api key: %v", err)
 	}
 
-	// generate "hash" for DB storage
-	hash, err := bcrypt.GenerateFromPassword([]byte(apiKey), TokenApiKeyHashCost)
+	apiKey := t.formatApiKey(secret)
+
+	// generate "hash" for DB storage, secret part only (bcrypt is limited to 72 bytes)
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), TokenApiKeyHashCost)
 	if err != nil {
 		return apiKey, fmt.Errorf("unable to generate hash from generated api key: %v", err)
 	}
 
 	t.ApiKeyHash = string(hash)
+	t.Legacy = false
 	t.Save()
 
 	if notify {
@@ -236,12 +355,12 @@ func (t *Token) SetNewApiKey(notify bool) (string, error) {
 		case TokenParentTypeAgent:
 			agent, err := t.Agent()
 			if err == nil {
-				_ = NewEmailAgentApiToken(agent, apiKey)
+				_ = NotifyAgentApiToken(agent, apiKey)
 			}
 		case TokenParentTypeUser:
 			user, err := t.User()
 			if err == nil {
-				_ = NewEmailUserApiToken(user, apiKey)
+				_ = NotifyUserApiToken(user, apiKey)
 			}
 		}
 	}
@@ -250,8 +369,18 @@ func (t *Token) SetNewApiKey(notify bool) (string, error) {
 }
 
 func (t *Token) Verify(apiKey string) error {
-	// comparing the supplied api key with the hashed one from database
-	return bcrypt.CompareHashAndPassword([]byte(t.ApiKeyHash), []byte(apiKey))
+	if t.Legacy {
+		// comparing the supplied api key with the hashed one from database
+		return bcrypt.CompareHashAndPassword([]byte(t.ApiKeyHash), []byte(apiKey))
+	}
+
+	id, secret, ok := ParseApiKey(apiKey)
+	if !ok || id != t.String() {
+		return fmt.Errorf("%s", ErrTokenApiKeyMismatch)
+	}
+
+	// comparing the supplied api key secret with the hashed one from database
+	return bcrypt.CompareHashAndPassword([]byte(t.ApiKeyHash), []byte(secret))
 }
 
 func (t *Token) Update(name, desc string, expire bool, expirationDate string) error {
@@ -262,13 +391,22 @@ func (t *Token) Update(name, desc string, expire bool, expirationDate string) er
 		return err
 	}
 
-	t.Save()
-	return nil
+	return t.save()
 }
 
-func (t *Token) Save() {
+func (t *Token) SetScope(scope TokenScope) error {
+	t.Scope = scope
+	return t.save()
+}
+
+func (t *Token) save() error {
 	t.Updated()
 	_, err := GetDB().Update(MongoCollectionTokenName, t.ID, t)
+	return err
+}
+
+func (t *Token) Save() {
+	err := t.save()
 	if err != nil {
 		klog.Error(err)
 	}
@@ -7,12 +7,15 @@
 package kahuna
 
 import (
+	"context"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/sethvargo/go-password/password"
 	passwordvalidator "github.com/wagslane/go-password-validator"
+	"go.mongodb.org/mongo-driver/v2/bson"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/kowabunga-cloud/common/klog"
@@ -20,10 +23,12 @@ import (
 )
 
 const (
-	MongoCollectionUserSchemaVersion = 2
+	MongoCollectionUserSchemaVersion = 3
 	MongoCollectionUserName          = "user"
 
-	ErrUserNoSuchToken = "no such token in user"
+	ErrUserNoSuchToken       = "no such token in user"
+	ErrUserRoleChangeDenied  = "users role can only be changed by super-administrators"
+	ErrUserEmailChangeDenied = "users email can only be changed by super-administrators or users themselves"
 
 	UserRoleSuperAdmin   = "superAdmin"
 	UserRoleProjectAdmin = "projectAdmin"
@@ -49,17 +54,20 @@ type User struct {
 	Resource `bson:"inline"`
 
 	// parents
+	OrganizationID string `bson:"organization_id"`
 
 	// properties
-	Email                string `bson:"email"`
-	PasswordHash         string `bson:"password_hash"`
-	Role                 string `bson:"role"`
-	PasswordRenewalToken string `bson:"password_renewal_token"`
-	RegistrationToken    string `bson:"registration_token"`
-	Enabled              bool   `bson:"enabled"`
-	NotificationsEnabled bool   `bson:"notifications_enabled"`
-	TokenID              string `bson:"token"`
-	JWT                  string `bson:"jwt"` // ephemeral JWT authentication token
+	Email                string                    `bson:"email"`
+	PasswordHash         string                    `bson:"password_hash"`
+	Role                 string                    `bson:"role"`
+	PasswordRenewalToken string                    `bson:"password_renewal_token"`
+	RegistrationToken    string                    `bson:"registration_token"`
+	Enabled              bool                      `bson:"enabled"`
+	NotificationChannels []UserNotificationChannel `bson:"notification_channels"`
+	TokenID              string                    `bson:"token"`
+	JWT                  string                    `bson:"jwt"` // ephemeral JWT authentication token
+	OidcIssuer           string                    `bson:"oidc_issuer"`
+	OidcSubject          string                    `bson:"oidc_subject"` // OpenID Connect IdP's user identifier
 
 	// children references
 	TeamIDs []string `bson:"team_ids"`
@@ -84,23 +92,61 @@ func UserMigrateSchema() error {
 	return nil
 }
 
-func NewUser(name, desc, email, role string, notifications bool) (*User, error) {
-	// verify role
+func UserMigrateSchemaV3() error {
+	for _, user := range FindUsers() {
+		if user.SchemaVersion < 3 {
+			err := user.migrateSchemaV3()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func UserRollbackSchemaV3() error {
+	for _, user := range FindUsers() {
+		if user.SchemaVersion == 3 {
+			err := user.rollbackSchemaV3()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func IsValidUserRole(role string) bool {
 	switch role {
 	case UserRoleSuperAdmin, UserRoleProjectAdmin, UserRoleStandard:
-		break
-	default:
+		return true
+	}
+	return false
+}
+
+func newUser(name, desc, role string, notifications bool) User {
+	// verify role
+	if !IsValidUserRole(role) {
 		role = UserRoleStandard
 	}
 
 	u := User{
 		Resource:             NewResource(name, desc, MongoCollectionUserSchemaVersion),
 		Role:                 role,
-		NotificationsEnabled: notifications,
+		NotificationChannels: []UserNotificationChannel{},
 		TokenID:              "",
 		JWT:                  "",
 		TeamIDs:              []string{},
 	}
+	u.setEmailNotifications(notifications)
+
+	return u
+}
+
+func NewUser(name, desc, email, role string, notifications bool) (*User, error) {
+	u := newUser(name, desc, role, notifications)
 
 	// verify email
 	err := VerifyEmail(email)
@@ -120,7 +166,7 @@ func NewUser(name, desc, email, role string, notifications bool) (*User, error)
 	u.RegistrationToken = registrationToken
 
 	// notify user of account creation
-	err = NewEmailUserCreated(&u)
+	err = NotifyUserCreated(&u)
 	if err != nil {
 		klog.Error(err)
 		return nil, err
@@ -132,6 +178,26 @@ func NewUser(name, desc, email, role string, notifications bool) (*User, error)
 	}
 
 	klog.Debugf("Created new user %s (%s)", u.String(), u.Name)
+	FireWebhookEvent(WebhookEventUserCreated, "", u.Model())
+
+	return &u, nil
+}
+
+// NewOidcUser provisions an account for an OpenID Connect authenticated user.
+// Identity has been verified by IdP, no registration is required.
+func NewOidcUser(name, email, role, issuer, subject string) (*User, error) {
+	u := newUser(name, "", role, true)
+	u.Email = email
+	u.OidcIssuer = issuer
+	u.OidcSubject = subject
+	u.Enabled = true
+
+	_, err := GetDB().Insert(MongoCollectionUserName, u)
+	if err != nil {
+		return nil, err
+	}
+
+	klog.Debugf("Created new OIDC user %s (%s)", u.String(), u.Name)
 
 	return &u, nil
 }
@@ -174,6 +240,65 @@ func (u *User) migrateSchemaV2() error {
 	return nil
 }
 
+func (u *User) migrateSchemaV3() error {
+	// former notifications flag only stood for emails
+	count, err := GetDB().Count(MongoCollectionUserName, bson.D{
+		bson.E{Key: "_id", Value: u.ID},
+		bson.E{Key: "notifications_enabled", Value: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	channels := []UserNotificationChannel{}
+	if count > 0 {
+		channels = append(channels, UserNotificationChannel{Type: NotificationChannelEmail})
+	}
+
+	return GetDB().Patch(MongoCollectionUserName, u.ID, bson.D{
+		bson.E{Key: "$set", Value: bson.D{
+			bson.E{Key: "notification_channels", Value: channels},
+			bson.E{Key: "schema_version", Value: 3},
+		}},
+		bson.E{Key: "$unset", Value: bson.D{bson.E{Key: "notifications_enabled", Value: ""}}},
+	})
+}
+
+func (u *User) rollbackSchemaV3() error {
+	return GetDB().Patch(MongoCollectionUserName, u.ID, bson.D{
+		bson.E{Key: "$set", Value: bson.D{
+			bson.E{Key: "notifications_enabled", Value: u.HasNotificationChannel(NotificationChannelEmail)},
+			bson.E{Key: "schema_version", Value: 2},
+		}},
+		bson.E{Key: "$unset", Value: bson.D{bson.E{Key: "notification_channels", Value: ""}}},
+	})
+}
+
+// userUpdateAllowed tells whether requester may apply update to user. Only super administrators
+// can change users role, and users email (which password resets are sent to) but their own.
+func userUpdateAllowed(ctx context.Context, u *User, update sdk.User) error {
+	if ctxGetSuperAdminRole(ctx) {
+		return nil
+	}
+
+	if update.Role != "" && update.Role != u.Role {
+		return fmt.Errorf("%s", ErrUserRoleChangeDenied)
+	}
+
+	if update.Email != "" && update.Email != u.Email && ctxGetUserId(ctx) != u.String() {
+		return fmt.Errorf("%s", ErrUserEmailChangeDenied)
+	}
+
+	return nil
+}
+
+func (u *User) Organization() (*Organization, error) {
+	if u.OrganizationID == "" {
+		return nil, fmt.Errorf("%s", ErrOrganizationNotMember)
+	}
+	return FindOrganizationByID(u.OrganizationID)
+}
+
 func (u *User) Teams() []string {
 	return u.TeamIDs
 }
@@ -309,7 +434,7 @@ func (u *User) ResetPasswordRequest() error {
 	u.PasswordRenewalToken = passwordRenewalToken
 	u.Save()
 
-	return NewEmailUserPasswordConfirmation(u)
+	return NotifyUserPasswordConfirmation(u)
 }
 
 func (u *User) ResetPassword() error {
@@ -324,7 +449,7 @@ func (u *User) ResetPassword() error {
 	}
 
 	// send newly generated password by email
-	err = NewEmailUserPassword(u, userPassword)
+	err = NotifyUserPassword(u, userPassword)
 	if err != nil {
 		return err
 	}
@@ -342,7 +467,7 @@ func (u *User) ResetPassword() error {
 	return nil
 }
 
-func (u *User) Update(name, desc, email, role string, notifications bool) {
+func (u *User) Update(name, desc, email, role string, notifications bool) error {
 	u.UpdateResourceDefaults(name, desc)
 
 	if email != "" {
@@ -358,13 +483,80 @@ func (u *User) Update(name, desc, email, role string, notifications bool) {
 		u.Role = role
 	}
 
-	u.NotificationsEnabled = notifications
+	u.setEmailNotifications(notifications)
+	return u.save()
+}
+
+// Notifications
+
+func (u *User) HasNotificationChannel(tp string) bool {
+	return slices.ContainsFunc(u.NotificationChannels, func(c UserNotificationChannel) bool {
+		return c.Type == tp
+	})
+}
+
+func (u *User) NotificationChannelTypes() []string {
+	types := []string{}
+	for _, c := range u.NotificationChannels {
+		if !slices.Contains(types, c.Type) {
+			types = append(types, c.Type)
+		}
+	}
+	return types
+}
+
+// setEmailNotifications toggles email channel, as legacy notifications flag does
+func (u *User) setEmailNotifications(enabled bool) {
+	if enabled == u.HasNotificationChannel(NotificationChannelEmail) {
+		return
+	}
+
+	if enabled {
+		u.NotificationChannels = append(u.NotificationChannels, UserNotificationChannel{Type: NotificationChannelEmail})
+		return
+	}
+
+	u.NotificationChannels = slices.DeleteFunc(u.NotificationChannels, func(c UserNotificationChannel) bool {
+		return c.Type == NotificationChannelEmail
+	})
+}
+
+func (u *User) SetNotificationChannels(channels []UserNotificationChannel) {
+	u.NotificationChannels = channels
 	u.Save()
 }
 
-func (u *User) Save() {
+func (u *User) notificationRecipient() NotificationRecipient {
+	return NotificationRecipient{
+		Name:  u.Name,
+		Email: u.Email,
+	}
+}
+
+// Notify sends notification through user's channels, if any
+func (u *User) Notify(n *Notification) error {
+	return notify(u.notificationRecipient(), u.NotificationChannels, n)
+}
+
+// NotifyAccount sends account-related notification (e.g. registration, password or API key) which user
+// can't opt out of, by email if no other channel is set. As any other notification carrying credentials
+// (e.g. instance root password), it goes through user's own channels, webhook and chat ones being HTTPS-only.
+func (u *User) NotifyAccount(n *Notification) error {
+	channels := u.NotificationChannels
+	if len(channels) == 0 {
+		channels = []UserNotificationChannel{{Type: NotificationChannelEmail}}
+	}
+	return notify(u.notificationRecipient(), channels, n)
+}
+
+func (u *User) save() error {
 	u.Updated()
 	_, err := GetDB().Update(MongoCollectionUserName, u.ID, u)
+	return err
+}
+
+func (u *User) Save() {
+	err := u.save()
 	if err != nil {
 		klog.Error(err)
 	}
@@ -385,6 +577,15 @@ func (u *User) Delete() error {
 		}
 	}
 
+	// remove user's reference from organization, if any
+	o, err := u.Organization()
+	if err == nil {
+		err = o.RemoveUser(u)
+		if err != nil {
+			klog.Error(err)
+		}
+	}
+
 	return GetDB().Delete(MongoCollectionUserName, u.ID)
 }
 
@@ -395,7 +596,7 @@ func (u *User) Model() sdk.User {
 		Description:   u.Description,
 		Email:         u.Email,
 		Role:          u.Role,
-		Notifications: u.NotificationsEnabled,
+		Notifications: u.HasNotificationChannel(NotificationChannelEmail),
 	}
 }
 
@@ -416,12 +617,20 @@ func (u *User) RemoveToken(id string) {
 // Teams
 func (u *User) AddTeam(id string) {
 	klog.Debugf("Adding team %s to user %s", id, u.String())
-	AddChildRef(&u.TeamIDs, id)
-	u.Save() // save DB before looking back
+	err := UpdateResource(MongoCollectionUserName, u, func(u *User) {
+		AddChildRef(&u.TeamIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (u *User) RemoveTeam(id string) {
 	klog.Debugf("Removing team %s from user %s", id, u.String())
-	RemoveChildRef(&u.TeamIDs, id)
-	u.Save()
+	err := UpdateResource(MongoCollectionUserName, u, func(u *User) {
+		RemoveChildRef(&u.TeamIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
@@ -148,17 +148,22 @@ func (v *VNet) FindSubnets() ([]Subnet, error) {
 	return FindSubnetsByVNet(v.String())
 }
 
-func (v *VNet) Update(name, desc string, vlan int, itf string) {
+func (v *VNet) Update(name, desc string, vlan int, itf string) error {
 	v.UpdateResourceDefaults(name, desc)
 	v.VLAN = vlan
 	SetFieldStr(&v.Interface, itf)
 	// we forbid change on privacy
-	v.Save()
+	return v.save()
 }
 
-func (v *VNet) Save() {
+func (v *VNet) save() error {
 	v.Updated()
 	_, err := GetDB().Update(MongoCollectionVNetName, v.ID, v)
+	return err
+}
+
+func (v *VNet) Save() {
+	err := v.save()
 	if err != nil {
 		klog.Error(err)
 	}
@@ -124,6 +124,7 @@ func NewVolume(projectId, poolId, templateId, name, desc, tp string, size int64)
 
 		// add volume to project
 		prj.AddVolume(v.String())
+		FireWebhookEvent(WebhookEventVolumeCreated, prj.String(), v.Model())
 	}
 
 	// add volume to pool
@@ -526,13 +527,24 @@ func (v *Volume) Update(name, desc string, size int64) error {
 		// update project usage counter
 		prj.UpdateVolumeUsage(sizeDelta)
 	}
-	v.Save()
+
+	err = v.save()
+	if err != nil {
+		return err
+	}
+
+	v.fireWebhookEvent(WebhookEventVolumeUpdated)
 	return nil
 }
 
-func (v *Volume) Save() {
+func (v *Volume) save() error {
 	v.Updated()
 	_, err := GetDB().Update(MongoCollectionVolumeName, v.ID, v)
+	return err
+}
+
+func (v *Volume) Save() {
+	err := v.save()
 	if err != nil {
 		klog.Error(err)
 	}
@@ -577,7 +589,51 @@ func (v *Volume) Delete() error {
 	}
 	p.RemoveVolume(v.String())
 
-	return GetDB().Delete(MongoCollectionVolumeName, v.ID)
+	err = GetDB().Delete(MongoCollectionVolumeName, v.ID)
+	if err != nil {
+		return err
+	}
+	v.fireWebhookEvent(WebhookEventVolumeDeleted)
+
+	return nil
+}
+
+// MoveToTrash flags volume as deleted, keeping its RBD image until purged
+func (v *Volume) MoveToTrash(parentId string) error {
+	klog.Infof("Moving %s volume %s (%s) to trash", v.Type, v.String(), v.Name)
+
+	err := UpdateResource(MongoCollectionVolumeName, v, func(v *Volume) {
+		v.Trashed = NewResourceTrash(parentId)
+	})
+	if err != nil {
+		return err
+	}
+	v.fireWebhookEvent(WebhookEventVolumeTrashed)
+
+	return nil
+}
+
+// RestoreFromTrash makes a trashed volume available again
+func (v *Volume) RestoreFromTrash() error {
+	klog.Infof("Restoring %s volume %s (%s) from trash", v.Type, v.String(), v.Name)
+
+	err := UpdateResource(MongoCollectionVolumeName, v, func(v *Volume) {
+		v.Trashed = nil
+	})
+	if err != nil {
+		return err
+	}
+	v.fireWebhookEvent(WebhookEventVolumeRestored)
+
+	return nil
+}
+
+// fireWebhookEvent notifies webhooks of project's volume event, template ones being none of users' business
+func (v *Volume) fireWebhookEvent(event string) {
+	if v.Type == VolumeTypeTemplate {
+		return
+	}
+	FireWebhookEvent(event, v.ProjectID, v.Model())
 }
 
 func (v *Volume) OverwriteCloudInitVolume(iso *CloudInit) error {
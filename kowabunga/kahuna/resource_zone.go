@@ -154,14 +154,19 @@ func (z *Zone) FindKaktus() ([]Kaktus, error) {
 	return FindKaktusByZone(z.String())
 }
 
-func (z *Zone) Update(name, desc string) {
+func (z *Zone) Update(name, desc string) error {
 	z.UpdateResourceDefaults(name, desc)
-	z.Save()
+	return z.save()
 }
 
-func (z *Zone) Save() {
+func (z *Zone) save() error {
 	z.Updated()
 	_, err := GetDB().Update(MongoCollectionZoneName, z.ID, z)
+	return err
+}
+
+func (z *Zone) Save() {
+	err := z.save()
 	if err != nil {
 		klog.Error(err)
 	}
@@ -288,8 +293,12 @@ func (z *Zone) Kaktus(id string) (*Kaktus, error) {
 
 func (z *Zone) AddKaktus(id string) {
 	klog.Debugf("Adding kaktus %s to zone %s", id, z.String())
-	AddChildRef(&z.KaktusIDs, id)
-	z.Save()
+	err := UpdateResource(MongoCollectionZoneName, z, func(z *Zone) {
+		AddChildRef(&z.KaktusIDs, id)
+	})
+	if err != nil {
+		klog.Error(err)
+	}
 }
 
 func (z *Zone) RemoveKaktus(id string) {
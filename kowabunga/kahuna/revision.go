@@ -0,0 +1,236 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * Optimistic concurrency control: resources reads expose their current revision
+ * as an HTTP entity tag (ETag), which clients can send back on updates through
+ * If-Match header, so that changes made in-between are not silently overwritten.
+ */
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kowabunga-cloud/kowabunga/kowabunga/sdk"
+)
+
+const (
+	HttpHeaderETag    = "ETag"
+	HttpHeaderIfMatch = "If-Match"
+
+	HttpETagAny        = "*"
+	HttpETagWeakPrefix = "W/"
+
+	HttpRequestContextRevision = "revision"
+
+	ErrRevisionPreconditionFailed = "resource has been modified since it has been read"
+)
+
+// route path variable -> resource collection
+var revisionRouteVarCollections = map[string]string{
+	"KawaiiIpSecId":  MongoCollectionIPsecName,
+	"adapterId":      MongoCollectionAdapterName,
+	"agentId":        MongoCollectionAgentName,
+	"instanceId":     MongoCollectionInstanceName,
+	"kaktusId":       MongoCollectionKaktusName,
+	"kawaiiId":       MongoCollectionKawaiiName,
+	"kiwiId":         MongoCollectionKiwiName,
+	"komputeId":      MongoCollectionKomputeName,
+	"konveyId":       MongoCollectionKonveyName,
+	"kyloId":         MongoCollectionKyloName,
+	"nfsId":          MongoCollectionNfsName,
+	"organizationId": MongoCollectionOrganizationName,
+	"poolId":         MongoCollectionStoragePoolName,
+	"projectId":      MongoCollectionProjectName,
+	"recordId":       MongoCollectionDnsRecordName,
+	"regionId":       MongoCollectionRegionName,
+	"subnetId":       MongoCollectionSubnetName,
+	"teamId":         MongoCollectionTeamName,
+	"templateId":     MongoCollectionTemplateName,
+	"tokenId":        MongoCollectionTokenName,
+	"userId":         MongoCollectionUserName,
+	"vnetId":         MongoCollectionVNetName,
+	"volumeId":       MongoCollectionVolumeName,
+	"webhookId":      MongoCollectionWebhookName,
+	"zoneId":         MongoCollectionZoneName,
+}
+
+// revisionedRouteOperations are routes exposing (and checking) resources revision
+var revisionedRouteOperations = []string{
+	"Read",
+	"Update",
+}
+
+func isRevisionedRoute(name string) bool {
+	for _, op := range revisionedRouteOperations {
+		if strings.HasPrefix(name, op) {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatch tells whether the If-Match header value matches resource entity tag.
+// Weak entity tags never match, as RFC 9110 requires strong comparison.
+func ifMatch(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, HttpETagWeakPrefix) {
+			continue
+		}
+		if tag == HttpETagAny || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// HttpUpdateError reports a resource update failure, concurrent modifications being conflicts
+func HttpUpdateError(err error) (sdk.ImplResponse, error) {
+	if IsDbConflictError(err) {
+		return HttpConflict(err)
+	}
+	if IsPreconditionFailedError(err) {
+		return HttpPreconditionFailed(err)
+	}
+	return HttpServerError(err)
+}
+
+type KowabungaPreconditionFailedError struct {
+	ETag string
+}
+
+func (e *KowabungaPreconditionFailedError) Error() string {
+	return fmt.Sprintf("%s (current entity tag is %s)", ErrRevisionPreconditionFailed, e.ETag)
+}
+
+func IsPreconditionFailedError(err error) bool {
+	var precondition *KowabungaPreconditionFailedError
+	return errors.As(err, &precondition)
+}
+
+// requestRevision ties request's If-Match precondition to the resource service operates on
+type requestRevision struct {
+	ifMatch  string    // If-Match header, empty if unconditional
+	resource *Resource // resource loaded by service, if any
+}
+
+func ctxSetRevision(ctx context.Context, rev *requestRevision) context.Context {
+	return ctxSet(ctx, HttpRequestContextRevision, rev)
+}
+
+func ctxGetRevision(ctx context.Context) *requestRevision {
+	value := ctxGet(ctx, HttpRequestContextRevision)
+	if value == nil {
+		return nil
+	}
+	return value.(*requestRevision)
+}
+
+// ctxTrackRevision binds the resource service has loaded to the request, for its revision to be exposed as ETag
+func ctxTrackRevision(ctx context.Context, r *Resource) {
+	rev := ctxGetRevision(ctx)
+	if rev != nil {
+		rev.resource = r
+	}
+}
+
+// ctxPinRevision binds the resource service is about to update to the request. Resource's revision must
+// match client's If-Match precondition, if any: as updates are conditioned by the loaded revision,
+// resource can't be modified in-between.
+func ctxPinRevision(ctx context.Context, r *Resource) error {
+	ctxTrackRevision(ctx, r)
+
+	rev := ctxGetRevision(ctx)
+	if rev != nil && rev.ifMatch != "" && !ifMatch(rev.ifMatch, r.ETag()) {
+		return &KowabungaPreconditionFailedError{ETag: r.ETag()}
+	}
+
+	// requested labels are saved along with resource
+	ctxPinLabels(ctx, r)
+
+	return nil
+}
+
+// revisionResponseWriter exposes resource's revision, once service has been processed
+type revisionResponseWriter struct {
+	http.ResponseWriter
+	collection  string
+	id          string
+	revision    *requestRevision
+	wroteHeader bool
+}
+
+func (w *revisionResponseWriter) etag() (string, bool) {
+	if w.revision.resource != nil {
+		return w.revision.resource.ETag(), true
+	}
+
+	// service did not pin the resource it operated on
+	rev, err := GetDB().FindRevision(w.collection, w.id)
+	if err != nil {
+		return "", false
+	}
+	return ResourceETag(rev), true
+}
+
+func (w *revisionResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if status >= http.StatusOK && status < http.StatusMultipleChoices {
+			etag, ok := w.etag()
+			if ok {
+				w.Header().Set(HttpHeaderETag, etag)
+			}
+		}
+		if status == http.StatusPreconditionFailed && w.revision.resource != nil {
+			w.Header().Set(HttpHeaderETag, w.revision.resource.ETag())
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *revisionResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func revisionMiddleware(next http.Handler, name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isRevisionedRoute(name) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		varName, id := routeTargetVar(r)
+		collection, ok := revisionRouteVarCollections[varName]
+		if !ok || id == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// service ensures resource hasn't been modified since client read it
+		rev := &requestRevision{}
+		if r.Method == http.MethodPut || r.Method == http.MethodPatch {
+			rev.ifMatch = r.Header.Get(HttpHeaderIfMatch)
+		}
+
+		rw := &revisionResponseWriter{
+			ResponseWriter: w,
+			collection:     collection,
+			id:             id,
+			revision:       rev,
+		}
+		next.ServeHTTP(rw, r.WithContext(ctxSetRevision(r.Context(), rev)))
+	})
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestRevisionIfMatch(t *testing.T) {
+	etag := ResourceETag(3)
+
+	for header, expected := range map[string]bool{
+		`"3"`:        true,
+		`"2", "3"`:   true,
+		`*`:          true,
+		`"2"`:        false,
+		`W/"3"`:      false,
+		`W/"3", "4"`: false,
+	} {
+		if ifMatch(header, etag) != expected {
+			t.Errorf("If-Match %s against %s should be %v", header, etag, expected)
+		}
+	}
+}
+
+func TestRevisionPin(t *testing.T) {
+	r := Resource{Revision: 3}
+
+	// no If-Match precondition
+	rev := &requestRevision{}
+	ctx := ctxSetRevision(context.Background(), rev)
+	err := ctxPinRevision(ctx, &r)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if rev.resource != &r {
+		t.Errorf("resource has not been pinned")
+	}
+
+	// stale client revision
+	rev = &requestRevision{ifMatch: ResourceETag(2)}
+	ctx = ctxSetRevision(context.Background(), rev)
+	err = ctxPinRevision(ctx, &r)
+	if !IsPreconditionFailedError(err) {
+		t.Fatalf("stale revision has been accepted (%v)", err)
+	}
+	result, _ := HttpUpdateError(err)
+	if result.Code != http.StatusPreconditionFailed {
+		t.Errorf("unexpected status %d", result.Code)
+	}
+
+	// once pinned, resource's own revision is the one the update is conditioned by
+	rev = &requestRevision{ifMatch: ResourceETag(3)}
+	ctx = ctxSetRevision(context.Background(), rev)
+	err = ctxPinRevision(ctx, &r)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	r.setRevision(4)
+	if rev.resource.ETag() != ResourceETag(4) {
+		t.Errorf("unexpected ETag %s", rev.resource.ETag())
+	}
+}
+
+func TestRevisionConflict(t *testing.T) {
+	err := fmt.Errorf("update failed: %w", &KowabungaDbConflictError{Collection: MongoCollectionKawaiiName, ID: "abc"})
+	result, _ := HttpUpdateError(err)
+	if result.Code != http.StatusConflict {
+		t.Errorf("concurrent modification should be a conflict, got %d", result.Code)
+	}
+
+	result, _ = HttpUpdateError(fmt.Errorf("boom"))
+	if result.Code == http.StatusConflict {
+		t.Errorf("unexpected conflict")
+	}
+}
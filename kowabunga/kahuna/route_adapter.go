@@ -35,13 +35,7 @@ func (s *AdapterService) DeleteAdapter(ctx context.Context, adapterId string) (s
 }
 
 func (s *AdapterService) ListAdapters(ctx context.Context) (sdk.ImplResponse, error) {
-	adapters := FindAdapters()
-	var payload []string
-	for _, a := range adapters {
-		payload = append(payload, a.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[Adapter, sdk.Adapter](ctx, MongoCollectionAdapterName, nil)
 }
 
 func (s *AdapterService) ReadAdapter(ctx context.Context, adapterId string) (sdk.ImplResponse, error) {
@@ -49,6 +43,7 @@ func (s *AdapterService) ReadAdapter(ctx context.Context, adapterId string) (sdk
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &a.Resource)
 
 	payload := a.Model()
 	LogHttpResponse(payload)
@@ -69,8 +64,17 @@ func (s *AdapterService) UpdateAdapter(ctx context.Context, adapterId string, ad
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &a.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	// update adapter
-	a.Update(adapter.Name, adapter.Description, adapter.Mac, adapter.Addresses, adapter.Reserved)
+	err = a.Update(adapter.Name, adapter.Description, adapter.Mac, adapter.Addresses, adapter.Reserved)
+	if err != nil {
+		return HttpUpdateError(err)
+	}
 
 	payload := a.Model()
 	LogHttpResponse(payload)
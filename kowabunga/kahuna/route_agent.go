@@ -64,13 +64,7 @@ func (s *AgentService) DeleteAgent(ctx context.Context, agentId string) (sdk.Imp
 }
 
 func (s *AgentService) ListAgents(ctx context.Context) (sdk.ImplResponse, error) {
-	agents := FindAgents()
-	var payload []string
-	for _, a := range agents {
-		payload = append(payload, a.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[Agent, sdk.Agent](ctx, MongoCollectionAgentName, nil)
 }
 
 func (s *AgentService) ReadAgent(ctx context.Context, agentId string) (sdk.ImplResponse, error) {
@@ -78,6 +72,7 @@ func (s *AgentService) ReadAgent(ctx context.Context, agentId string) (sdk.ImplR
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &a.Resource)
 
 	payload := a.Model()
 	LogHttpResponse(payload)
@@ -135,8 +130,17 @@ func (s *AgentService) UpdateAgent(ctx context.Context, agentId string, agent sd
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &a.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	// update agent
-	a.Update(agent.Name, agent.Description)
+	err = a.Update(agent.Name, agent.Description)
+	if err != nil {
+		return HttpUpdateError(err)
+	}
 
 	payload := a.Model()
 	LogHttpResponse(payload)
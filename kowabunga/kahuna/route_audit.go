@@ -0,0 +1,116 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kowabunga-cloud/kowabunga/kowabunga/sdk"
+)
+
+// AuditAPIController binds http requests to the audit service and writes the service results to the http response
+type AuditAPIController struct {
+	service      *AuditService
+	errorHandler sdk.ErrorHandler
+}
+
+func NewAuditRouter() sdk.Router {
+	return &AuditAPIController{
+		service:      &AuditService{},
+		errorHandler: sdk.DefaultErrorHandler,
+	}
+}
+
+// Routes returns all the api routes for the AuditAPIController
+func (c *AuditAPIController) Routes() sdk.Routes {
+	routes := sdk.Routes{}
+	for _, r := range c.OrderedRoutes() {
+		routes[r.Name] = r
+	}
+	return routes
+}
+
+// OrderedRoutes returns all the api routes in a deterministic order for the AuditAPIController
+func (c *AuditAPIController) OrderedRoutes() []sdk.Route {
+	return []sdk.Route{
+		{
+			Name:        "ListAuditEntries",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + "/audit",
+			HandlerFunc: c.ListAuditEntries,
+		},
+	}
+}
+
+// ListAuditEntries -
+func (c *AuditAPIController) ListAuditEntries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := AuditFilter{
+		UserID:     query.Get("userId"),
+		ProjectID:  query.Get("projectId"),
+		ResourceID: query.Get("resourceId"),
+	}
+
+	var err error
+	if query.Has("from") {
+		filter.From, err = time.Parse(time.RFC3339, query.Get("from"))
+		if err != nil {
+			c.errorHandler(w, r, &sdk.ParsingError{Param: "from", Err: err}, nil)
+			return
+		}
+	}
+	if query.Has("to") {
+		filter.To, err = time.Parse(time.RFC3339, query.Get("to"))
+		if err != nil {
+			c.errorHandler(w, r, &sdk.ParsingError{Param: "to", Err: err}, nil)
+			return
+		}
+	}
+	if query.Has("limit") {
+		filter.Limit, err = strconv.ParseInt(query.Get("limit"), 10, 64)
+		if err != nil {
+			c.errorHandler(w, r, &sdk.ParsingError{Param: "limit", Err: err}, nil)
+			return
+		}
+	}
+
+	result, err := c.service.ListAuditEntries(r.Context(), filter)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+type AuditService struct{}
+
+func (s *AuditService) ListAuditEntries(ctx context.Context, filter AuditFilter) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("filter", filter))
+
+	// audit trail is restricted to super-administrators only
+	if !ctxGetSuperAdminRole(ctx) {
+		return HttpForbidden(nil)
+	}
+
+	entries, err := FindAuditEntries(filter)
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	payload := []AuditEntryModel{}
+	for _, e := range entries {
+		payload = append(payload, e.Model())
+	}
+
+	return HttpOK(payload)
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/kowabunga-cloud/kowabunga/kowabunga/sdk"
+)
+
+// CheckAPIController binds http requests to the consistency check service and writes the service results to the http response
+type CheckAPIController struct {
+	service      *CheckService
+	errorHandler sdk.ErrorHandler
+}
+
+func NewCheckRouter() sdk.Router {
+	return &CheckAPIController{
+		service:      &CheckService{},
+		errorHandler: sdk.DefaultErrorHandler,
+	}
+}
+
+// Routes returns all the api routes for the CheckAPIController
+func (c *CheckAPIController) Routes() sdk.Routes {
+	routes := sdk.Routes{}
+	for _, r := range c.OrderedRoutes() {
+		routes[r.Name] = r
+	}
+	return routes
+}
+
+// OrderedRoutes returns all the api routes in a deterministic order for the CheckAPIController
+func (c *CheckAPIController) OrderedRoutes() []sdk.Route {
+	return []sdk.Route{
+		{
+			Name:        "CheckConsistency",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + "/check",
+			HandlerFunc: c.CheckConsistency,
+		},
+		{
+			Name:        "RepairConsistency",
+			Method:      http.MethodPost,
+			Pattern:     SdkBaseRoute + "/check/repair",
+			HandlerFunc: c.RepairConsistency,
+		},
+	}
+}
+
+// CheckConsistency -
+func (c *CheckAPIController) CheckConsistency(w http.ResponseWriter, r *http.Request) {
+	result, err := c.service.CheckConsistency(r.Context(), false)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// RepairConsistency -
+func (c *CheckAPIController) RepairConsistency(w http.ResponseWriter, r *http.Request) {
+	result, err := c.service.CheckConsistency(r.Context(), true)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+type CheckService struct{}
+
+func (s *CheckService) CheckConsistency(ctx context.Context, repair bool) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("repair", repair))
+
+	// consistency checks are restricted to super-administrators only
+	if !ctxGetSuperAdminRole(ctx) {
+		return HttpForbidden(nil)
+	}
+
+	payload, err := CheckConsistency(repair, true)
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
@@ -39,6 +39,7 @@ func (s *DnsRecordService) ReadDnsRecord(ctx context.Context, recordId string) (
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &r.Resource)
 
 	payload := r.Model()
 	LogHttpResponse(payload)
@@ -59,8 +60,17 @@ func (s *DnsRecordService) UpdateDnsRecord(ctx context.Context, recordId string,
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &r.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	// update record
-	r.Update(dnsRecord.Name, dnsRecord.Description, dnsRecord.Addresses)
+	err = r.Update(dnsRecord.Name, dnsRecord.Description, dnsRecord.Addresses)
+	if err != nil {
+		return HttpUpdateError(err)
+	}
 
 	payload := r.Model()
 	LogHttpResponse(payload)
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kowabunga-cloud/common/klog"
+	"github.com/kowabunga-cloud/kowabunga/kowabunga/sdk"
+)
+
+const (
+	EventStreamContentType      = "text/event-stream"
+	EventStreamKeepAliveSeconds = 30
+)
+
+// EventAPIController binds http requests to the resource events stream (Server-Sent Events)
+type EventAPIController struct {
+	errorHandler sdk.ErrorHandler
+}
+
+func NewEventRouter() sdk.Router {
+	return &EventAPIController{
+		errorHandler: sdk.DefaultErrorHandler,
+	}
+}
+
+// Routes returns all the api routes for the EventAPIController
+func (c *EventAPIController) Routes() sdk.Routes {
+	routes := sdk.Routes{}
+	for _, r := range c.OrderedRoutes() {
+		routes[r.Name] = r
+	}
+	return routes
+}
+
+// OrderedRoutes returns all the api routes in a deterministic order for the EventAPIController
+func (c *EventAPIController) OrderedRoutes() []sdk.Route {
+	return []sdk.Route{
+		{
+			Name:        "StreamEvents",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + "/event",
+			HandlerFunc: c.StreamEvents,
+		},
+	}
+}
+
+// StreamEvents -
+func (c *EventAPIController) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	rc := http.NewResponseController(w)
+
+	// stream is long-lived, lift server-wide write timeout
+	err := rc.SetWriteDeadline(time.Time{})
+	if err != nil {
+		klog.Warningf("Unable to lift events stream write deadline: %v", err)
+	}
+
+	s := NewEventSubscriber(ctxGetUserId(ctx), ctxGetSuperAdminRole(ctx))
+	GetEventBus().Subscribe(s)
+	defer GetEventBus().Unsubscribe(s)
+
+	w.Header().Set("Content-Type", EventStreamContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	err = rc.Flush()
+	if err != nil {
+		klog.Errorf("events stream: %v", err)
+		return
+	}
+
+	keepAlive := time.NewTicker(EventStreamKeepAliveSeconds * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case e := <-s.Events:
+			if !s.IsAllowed(&e) {
+				continue
+			}
+			var data []byte
+			data, err = json.Marshal(e.Model())
+			if err != nil {
+				klog.Error(err)
+				continue
+			}
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+		}
+
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			// client is gone
+			klog.Debugf("events stream: %v", err)
+			return
+		}
+	}
+}
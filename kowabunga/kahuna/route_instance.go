@@ -30,8 +30,8 @@ func (s *InstanceService) DeleteInstance(ctx context.Context, instanceId string)
 		return HttpConflict(nil)
 	}
 
-	// remove instance
-	err = i.Delete()
+	// move instance to trash, it'll be purged later on
+	err = i.MoveToTrash("")
 	if err != nil {
 		return HttpServerError(err)
 	}
@@ -40,13 +40,7 @@ func (s *InstanceService) DeleteInstance(ctx context.Context, instanceId string)
 }
 
 func (s *InstanceService) ListInstances(ctx context.Context) (sdk.ImplResponse, error) {
-	instances := FindInstances()
-	var payload []string
-	for _, i := range instances {
-		payload = append(payload, i.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[Instance, sdk.Instance](ctx, MongoCollectionInstanceName, nil)
 }
 
 func (s *InstanceService) ReadInstance(ctx context.Context, instanceId string) (sdk.ImplResponse, error) {
@@ -54,6 +48,7 @@ func (s *InstanceService) ReadInstance(ctx context.Context, instanceId string) (
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &i.Resource)
 
 	payload := i.Model()
 	LogHttpResponse(payload)
@@ -65,6 +60,7 @@ func (s *InstanceService) ReadInstanceRemoteConnection(ctx context.Context, inst
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &i.Resource)
 
 	payload, err := i.GetRemoteConnectionURL()
 	if err != nil {
@@ -79,6 +75,7 @@ func (s *InstanceService) ReadInstanceState(ctx context.Context, instanceId stri
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &i.Resource)
 
 	payload, err := i.GetState()
 	if err != nil {
@@ -200,6 +197,12 @@ func (s *InstanceService) UpdateInstance(ctx context.Context, instanceId string,
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &i.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	// find associated project
 	prj, err := i.Project()
 	if err != nil {
@@ -218,7 +221,7 @@ func (s *InstanceService) UpdateInstance(ctx context.Context, instanceId string,
 	// update instance
 	err = i.Update(instance.Name, instance.Description, instance.Vcpus, instance.Memory, instance.Adapters, instance.Volumes)
 	if err != nil {
-		return HttpServerError(err)
+		return HttpUpdateError(err)
 	}
 
 	payload := i.Model()
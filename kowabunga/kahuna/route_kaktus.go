@@ -45,18 +45,11 @@ func (s *KaktusService) ListKaktusInstances(ctx context.Context, kaktusId string
 		return HttpNotFound(err)
 	}
 
-	payload := h.Instances()
-	return HttpOK(payload)
+	return HttpListResources[Instance, sdk.Instance](ctx, MongoCollectionInstanceName, listFilterByIDs(h.Instances()))
 }
 
 func (s *KaktusService) ListKaktuss(ctx context.Context) (sdk.ImplResponse, error) {
-	kaktuss := FindKaktuses()
-	var payload []string
-	for _, h := range kaktuss {
-		payload = append(payload, h.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[Kaktus, sdk.Kaktus](ctx, MongoCollectionKaktusName, nil)
 }
 
 func (s *KaktusService) ReadKaktus(ctx context.Context, kaktusId string) (sdk.ImplResponse, error) {
@@ -64,6 +57,7 @@ func (s *KaktusService) ReadKaktus(ctx context.Context, kaktusId string) (sdk.Im
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &h.Resource)
 
 	payload := h.Model()
 	LogHttpResponse(payload)
@@ -75,6 +69,7 @@ func (s *KaktusService) ReadKaktusCaps(ctx context.Context, kaktusId string) (sd
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &h.Resource)
 
 	payload := h.Capabilities.Model()
 	LogHttpResponse(payload)
@@ -95,6 +90,12 @@ func (s *KaktusService) UpdateKaktus(ctx context.Context, kaktusId string, kaktu
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &h.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	var cpu_price float32
 	cpu_currency := CostCurrencyDefault
 	if kaktus.CpuCost.Price != 0 {
@@ -118,7 +119,10 @@ func (s *KaktusService) UpdateKaktus(ctx context.Context, kaktusId string, kaktu
 		overcommit_memory = kaktus.OvercommitMemoryRatio
 	}
 
-	h.Update(kaktus.Name, kaktus.Description, cpu_price, cpu_currency, memory_price, memory_currency, overcommit_cpu, overcommit_memory, kaktus.Agents)
+	err = h.Update(kaktus.Name, kaktus.Description, cpu_price, cpu_currency, memory_price, memory_currency, overcommit_cpu, overcommit_memory, kaktus.Agents)
+	if err != nil {
+		return HttpUpdateError(err)
+	}
 
 	payload := h.Model()
 	LogHttpResponse(payload)
@@ -39,13 +39,7 @@ func (s *KawaiiService) DeleteKawaii(ctx context.Context, kawaiiId string) (sdk.
 }
 
 func (s *KawaiiService) ListKawaiis(ctx context.Context) (sdk.ImplResponse, error) {
-	kawaiis := FindKawaiis()
-	var payload []string
-	for _, k := range kawaiis {
-		payload = append(payload, k.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[Kawaii, sdk.Kawaii](ctx, MongoCollectionKawaiiName, nil)
 }
 
 func (s *KawaiiService) ReadKawaii(ctx context.Context, kawaiiId string) (sdk.ImplResponse, error) {
@@ -53,6 +47,7 @@ func (s *KawaiiService) ReadKawaii(ctx context.Context, kawaiiId string) (sdk.Im
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &k.Resource)
 
 	payload := k.Model()
 	LogHttpResponse(payload)
@@ -68,6 +63,12 @@ func (s *KawaiiService) UpdateKawaii(ctx context.Context, kawaiiId string, kawai
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &gw.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	// converts kawaii from model to object
 	fw := KawaiiFirewall{
 		EgressPolicy: kawaii.Firewall.EgressPolicy,
@@ -135,7 +136,7 @@ func (s *KawaiiService) UpdateKawaii(ctx context.Context, kawaiiId string, kawai
 	// update Kawaii
 	err = gw.Update(kawaii.Description, fw, natRules)
 	if err != nil {
-		return HttpServerError(err)
+		return HttpUpdateError(err)
 	}
 
 	payload := gw.Model()
@@ -212,16 +213,7 @@ func (s *KawaiiService) DeleteKawaiiIpSec(ctx context.Context, kawaiiId, ipsecId
 }
 
 func (s *KawaiiService) ListKawaiiIpSecs(ctx context.Context, kawaiiId string) (sdk.ImplResponse, error) {
-	ipsecs, err := FindIPsecByKawaii(kawaiiId)
-	if err != nil {
-		return HttpNotFound(err)
-	}
-	var payload []string
-	for _, k := range ipsecs {
-		payload = append(payload, k.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[KawaiiIPsec, sdk.KawaiiIpSec](ctx, MongoCollectionIPsecName, listFilterByKey("kawaii_id", kawaiiId))
 }
 
 func (s *KawaiiService) ReadKawaiiIpSec(ctx context.Context, kawaiiId, ipsecId string) (sdk.ImplResponse, error) {
@@ -230,6 +222,7 @@ func (s *KawaiiService) ReadKawaiiIpSec(ctx context.Context, kawaiiId, ipsecId s
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &ipsec.Resource)
 	payload := ipsec.Model()
 	LogHttpResponse(hideSecretsPayload(payload))
 	return HttpOK(payload)
@@ -243,6 +236,13 @@ func (s *KawaiiService) UpdateKawaiiIpSec(ctx context.Context, kawaiiId, ipsecId
 	if err != nil {
 		return HttpNotFound(err)
 	}
+
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &ipsecConn.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	fw := KawaiiFirewall{}
 	for _, in := range ipsecSpec.Firewall.Ingress {
 		err := IsValidPortListExpression(in.Ports)
@@ -270,7 +270,7 @@ func (s *KawaiiService) UpdateKawaiiIpSec(ctx context.Context, kawaiiId, ipsecId
 		ipsecSpec.Phase2Lifetime, ipsecSpec.Phase2DhGroupNumber, ipsecSpec.Phase2IntegrityAlgorithm, ipsecSpec.Phase2EncryptionAlgorithm,
 		fw)
 	if err != nil {
-		return HttpServerError(err)
+		return HttpUpdateError(err)
 	}
 	payload := ipsecConn.Model()
 	LogHttpResponse(hideSecretsPayload(payload))
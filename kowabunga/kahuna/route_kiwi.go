@@ -35,13 +35,7 @@ func (s *KiwiService) DeleteKiwi(ctx context.Context, kiwiId string) (sdk.ImplRe
 }
 
 func (s *KiwiService) ListKiwis(ctx context.Context) (sdk.ImplResponse, error) {
-	kiwis := FindKiwis()
-	var payload []string
-	for _, gw := range kiwis {
-		payload = append(payload, gw.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[Kiwi, sdk.Kiwi](ctx, MongoCollectionKiwiName, nil)
 }
 
 func (s *KiwiService) ReadKiwi(ctx context.Context, kiwiId string) (sdk.ImplResponse, error) {
@@ -49,6 +43,7 @@ func (s *KiwiService) ReadKiwi(ctx context.Context, kiwiId string) (sdk.ImplResp
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &gw.Resource)
 
 	payload := gw.Model()
 	LogHttpResponse(payload)
@@ -69,8 +64,17 @@ func (s *KiwiService) UpdateKiwi(ctx context.Context, kiwiId string, kiwi sdk.Ki
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &gw.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	// update kiwi
-	gw.Update(kiwi.Name, kiwi.Description, kiwi.Agents)
+	err = gw.Update(kiwi.Name, kiwi.Description, kiwi.Agents)
+	if err != nil {
+		return HttpUpdateError(err)
+	}
 
 	payload := gw.Model()
 	LogHttpResponse(payload)
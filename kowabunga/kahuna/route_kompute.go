@@ -25,8 +25,8 @@ func (s *KomputeService) DeleteKompute(ctx context.Context, komputeId string) (s
 		return HttpNotFound(err)
 	}
 
-	// remove Kompute
-	err = k.Delete()
+	// move Kompute to trash, it'll be purged later on
+	err = k.MoveToTrash()
 	if err != nil {
 		return HttpServerError(err)
 	}
@@ -35,13 +35,7 @@ func (s *KomputeService) DeleteKompute(ctx context.Context, komputeId string) (s
 }
 
 func (s *KomputeService) ListKomputes(ctx context.Context) (sdk.ImplResponse, error) {
-	komputes := FindKomputes()
-	var payload []string
-	for _, k := range komputes {
-		payload = append(payload, k.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[Kompute, sdk.Kompute](ctx, MongoCollectionKomputeName, nil)
 }
 
 func (s *KomputeService) ReadKompute(ctx context.Context, komputeId string) (sdk.ImplResponse, error) {
@@ -49,6 +43,7 @@ func (s *KomputeService) ReadKompute(ctx context.Context, komputeId string) (sdk
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &k.Resource)
 
 	payload := k.Model()
 	LogHttpResponse(payload)
@@ -60,6 +55,7 @@ func (s *KomputeService) ReadKomputeState(ctx context.Context, komputeId string)
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &k.Resource)
 
 	payload, err := k.GetState()
 	if err != nil {
@@ -181,6 +177,12 @@ func (s *KomputeService) UpdateKompute(ctx context.Context, komputeId string, ko
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &k.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	// find associated instance
 	i, err := k.Instance()
 	if err != nil {
@@ -219,7 +221,7 @@ func (s *KomputeService) UpdateKompute(ctx context.Context, komputeId string, ko
 	// update Kompute
 	err = k.Update(kompute.Name, kompute.Description, kompute.Vcpus, kompute.Memory, kompute.Disk, kompute.DataDisk)
 	if err != nil {
-		return HttpServerError(err)
+		return HttpUpdateError(err)
 	}
 
 	payload := k.Model()
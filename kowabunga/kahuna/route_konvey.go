@@ -36,13 +36,7 @@ func (s *KonveyService) DeleteKonvey(ctx context.Context, konveyId string) (sdk.
 }
 
 func (s *KonveyService) ListKonveys(ctx context.Context) (sdk.ImplResponse, error) {
-	konveys := FindKonveys()
-	var payload []string
-	for _, k := range konveys {
-		payload = append(payload, k.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[Konvey, sdk.Konvey](ctx, MongoCollectionKonveyName, nil)
 }
 
 func (s *KonveyService) ReadKonvey(ctx context.Context, konveyId string) (sdk.ImplResponse, error) {
@@ -50,6 +44,7 @@ func (s *KonveyService) ReadKonvey(ctx context.Context, konveyId string) (sdk.Im
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &k.Resource)
 
 	payload := k.Model()
 	LogHttpResponse(payload)
@@ -65,6 +60,12 @@ func (s *KonveyService) UpdateKonvey(ctx context.Context, konveyId string, konve
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &k.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	// converts from model to object
 	endpoints := []KonveyEndpoint{}
 	for _, ep := range konvey.Endpoints {
@@ -93,7 +94,7 @@ func (s *KonveyService) UpdateKonvey(ctx context.Context, konveyId string, konve
 	// update Konvey
 	err = k.Update(k.Description, endpoints)
 	if err != nil {
-		return HttpServerError(err)
+		return HttpUpdateError(err)
 	}
 
 	payload := k.Model()
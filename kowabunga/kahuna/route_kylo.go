@@ -35,13 +35,7 @@ func (s *KyloService) DeleteKylo(ctx context.Context, kyloId string) (sdk.ImplRe
 }
 
 func (s *KyloService) ListKylos(ctx context.Context) (sdk.ImplResponse, error) {
-	kylos := FindKylos()
-	var payload []string
-	for _, k := range kylos {
-		payload = append(payload, k.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[Kylo, sdk.Kylo](ctx, MongoCollectionKyloName, nil)
 }
 
 func (s *KyloService) ReadKylo(ctx context.Context, kyloId string) (sdk.ImplResponse, error) {
@@ -49,6 +43,7 @@ func (s *KyloService) ReadKylo(ctx context.Context, kyloId string) (sdk.ImplResp
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &k.Resource)
 
 	payload := k.Model()
 	LogHttpResponse(payload)
@@ -69,10 +64,16 @@ func (s *KyloService) UpdateKylo(ctx context.Context, kyloId string, kylo sdk.Ky
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &k.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	// update Kylo
 	err = k.Update(kylo.Name, kylo.Description, kylo.Access, kylo.Protocols)
 	if err != nil {
-		return HttpServerError(err)
+		return HttpUpdateError(err)
 	}
 
 	payload := k.Model()
@@ -0,0 +1,206 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/kowabunga-cloud/common/klog"
+	"github.com/kowabunga-cloud/kowabunga/kowabunga/sdk"
+)
+
+const (
+	ManifestContentTypeYAML = "application/yaml"
+)
+
+// ManifestAPIController binds http requests to the project manifests service and writes the service results to the http response
+type ManifestAPIController struct {
+	service      *ManifestService
+	errorHandler sdk.ErrorHandler
+}
+
+func NewManifestRouter() sdk.Router {
+	return &ManifestAPIController{
+		service:      &ManifestService{},
+		errorHandler: sdk.DefaultErrorHandler,
+	}
+}
+
+// Routes returns all the api routes for the ManifestAPIController
+func (c *ManifestAPIController) Routes() sdk.Routes {
+	routes := sdk.Routes{}
+	for _, r := range c.OrderedRoutes() {
+		routes[r.Name] = r
+	}
+	return routes
+}
+
+// OrderedRoutes returns all the api routes in a deterministic order for the ManifestAPIController
+func (c *ManifestAPIController) OrderedRoutes() []sdk.Route {
+	return []sdk.Route{
+		{
+			Name:        "ExportProjectManifest",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + "/project/{projectId}/manifest",
+			HandlerFunc: c.ExportProjectManifest,
+		},
+		{
+			Name:        "PlanProjectManifest",
+			Method:      http.MethodPost,
+			Pattern:     SdkBaseRoute + "/project/{projectId}/manifest/plan",
+			HandlerFunc: c.PlanProjectManifest,
+		},
+		{
+			Name:        "ApplyProjectManifest",
+			Method:      http.MethodPost,
+			Pattern:     SdkBaseRoute + "/project/{projectId}/manifest/apply",
+			HandlerFunc: c.ApplyProjectManifest,
+		},
+	}
+}
+
+// ExportProjectManifest -
+func (c *ManifestAPIController) ExportProjectManifest(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	projectIdParam := params["projectId"]
+	if projectIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "projectId"}, nil)
+		return
+	}
+	result, err := c.service.ExportProjectManifest(r.Context(), projectIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// Export as YAML if requested so
+	m, ok := result.Body.(*ProjectManifest)
+	if ok && strings.Contains(r.Header.Get("Accept"), "yaml") {
+		content, err := EncodeProjectManifestYAML(m)
+		if err != nil {
+			c.errorHandler(w, r, err, &sdk.ImplResponse{Code: http.StatusInternalServerError})
+			return
+		}
+		w.Header().Set("Content-Type", ManifestContentTypeYAML)
+		w.WriteHeader(result.Code)
+		_, err = w.Write(content)
+		if err != nil {
+			klog.Error(err)
+		}
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// PlanProjectManifest -
+func (c *ManifestAPIController) PlanProjectManifest(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	projectIdParam := params["projectId"]
+	if projectIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "projectId"}, nil)
+		return
+	}
+	manifestParam, err := DecodeProjectManifest(r.Body)
+	if err != nil {
+		c.errorHandler(w, r, &sdk.ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.PlanProjectManifest(r.Context(), projectIdParam, manifestParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ApplyProjectManifest -
+func (c *ManifestAPIController) ApplyProjectManifest(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	projectIdParam := params["projectId"]
+	if projectIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "projectId"}, nil)
+		return
+	}
+	manifestParam, err := DecodeProjectManifest(r.Body)
+	if err != nil {
+		c.errorHandler(w, r, &sdk.ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.ApplyProjectManifest(r.Context(), projectIdParam, manifestParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+type ManifestService struct{}
+
+func (s *ManifestService) ExportProjectManifest(ctx context.Context, projectId string) (sdk.ImplResponse, error) {
+	prj, err := FindProjectByID(projectId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	payload, err := NewProjectManifest(prj)
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
+
+func (s *ManifestService) PlanProjectManifest(ctx context.Context, projectId string, manifest *ProjectManifest) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("projectId", projectId), RA("manifest", manifest))
+
+	prj, err := FindProjectByID(projectId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	payload, err := NewManifestPlan(prj, manifest)
+	if err != nil {
+		return HttpBadParams(err)
+	}
+
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
+
+func (s *ManifestService) ApplyProjectManifest(ctx context.Context, projectId string, manifest *ProjectManifest) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("projectId", projectId), RA("manifest", manifest))
+
+	prj, err := FindProjectByID(projectId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	plan, err := NewManifestPlan(prj, manifest)
+	if err != nil {
+		return HttpBadParams(err)
+	}
+
+	// report actions outcome, even on failure
+	err = plan.Apply(ctx)
+	if err != nil {
+		klog.Errorf("Unable to apply manifest to project %s: %v", prj.Name, err)
+		return sdk.Response(http.StatusUnprocessableEntity, plan), nil
+	}
+
+	LogHttpResponse(plan)
+	return HttpOK(plan)
+}
@@ -45,18 +45,11 @@ func (s *NfsService) ListStorageNFSKylos(ctx context.Context, nfsId string) (sdk
 		return HttpNotFound(err)
 	}
 
-	payload := n.Kylos()
-	return HttpOK(payload)
+	return HttpListResources[Kylo, sdk.Kylo](ctx, MongoCollectionKyloName, listFilterByIDs(n.Kylos()))
 }
 
 func (s *NfsService) ListStorageNFSs(ctx context.Context) (sdk.ImplResponse, error) {
-	storages := FindNFSes()
-	var payload []string
-	for _, s := range storages {
-		payload = append(payload, s.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[NFS, sdk.StorageNfs](ctx, MongoCollectionNfsName, nil)
 }
 
 func (s *NfsService) ReadStorageNFS(ctx context.Context, nfsId string) (sdk.ImplResponse, error) {
@@ -64,6 +57,7 @@ func (s *NfsService) ReadStorageNFS(ctx context.Context, nfsId string) (sdk.Impl
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &n.Resource)
 
 	payload := n.Model()
 	LogHttpResponse(payload)
@@ -84,8 +78,17 @@ func (s *NfsService) UpdateStorageNFS(ctx context.Context, nfsId string, storage
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &n.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	// update NFS storage
-	n.Update(storageNfs.Name, storageNfs.Description, storageNfs.Endpoint, storageNfs.Fs, storageNfs.Backends, int(storageNfs.Port))
+	err = n.Update(storageNfs.Name, storageNfs.Description, storageNfs.Endpoint, storageNfs.Fs, storageNfs.Backends, int(storageNfs.Port))
+	if err != nil {
+		return HttpUpdateError(err)
+	}
 
 	payload := n.Model()
 	LogHttpResponse(payload)
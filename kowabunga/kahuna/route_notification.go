@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/kowabunga-cloud/kowabunga/kowabunga/sdk"
+)
+
+// NotificationAPIController binds http requests to the notification service and writes the service results to the http response
+type NotificationAPIController struct {
+	service      *NotificationService
+	errorHandler sdk.ErrorHandler
+}
+
+func NewNotificationRouter() sdk.Router {
+	return &NotificationAPIController{
+		service:      &NotificationService{},
+		errorHandler: sdk.DefaultErrorHandler,
+	}
+}
+
+// Routes returns all the api routes for the NotificationAPIController
+func (c *NotificationAPIController) Routes() sdk.Routes {
+	routes := sdk.Routes{}
+	for _, r := range c.OrderedRoutes() {
+		routes[r.Name] = r
+	}
+	return routes
+}
+
+// OrderedRoutes returns all the api routes in a deterministic order for the NotificationAPIController
+func (c *NotificationAPIController) OrderedRoutes() []sdk.Route {
+	return []sdk.Route{
+		{
+			Name:        "ListUserNotificationChannels",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + "/user/{userId}/notification",
+			HandlerFunc: c.ListUserNotificationChannels,
+		},
+		{
+			Name:        "SetUserNotificationChannels",
+			Method:      http.MethodPut,
+			Pattern:     SdkBaseRoute + "/user/{userId}/notification",
+			HandlerFunc: c.SetUserNotificationChannels,
+		},
+	}
+}
+
+// ListUserNotificationChannels -
+func (c *NotificationAPIController) ListUserNotificationChannels(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userIdParam := params["userId"]
+	if userIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "userId"}, nil)
+		return
+	}
+	result, err := c.service.ListUserNotificationChannels(r.Context(), userIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// SetUserNotificationChannels -
+func (c *NotificationAPIController) SetUserNotificationChannels(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userIdParam := params["userId"]
+	if userIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "userId"}, nil)
+		return
+	}
+	var channelsParam []UserNotificationChannelModel
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&channelsParam); err != nil {
+		c.errorHandler(w, r, &sdk.ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.SetUserNotificationChannels(r.Context(), userIdParam, channelsParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+type NotificationService struct{}
+
+func userNotificationChannelsModel(u *User) []UserNotificationChannelModel {
+	payload := []UserNotificationChannelModel{}
+	for _, c := range u.NotificationChannels {
+		payload = append(payload, c.Model())
+	}
+	return payload
+}
+
+func (s *NotificationService) ListUserNotificationChannels(ctx context.Context, userId string) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("userId", userId))
+
+	u, err := FindUserByID(userId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	payload := userNotificationChannelsModel(u)
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
+
+func (s *NotificationService) SetUserNotificationChannels(ctx context.Context, userId string, channels []UserNotificationChannelModel) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("userId", userId), RA("channels", channels))
+
+	u, err := FindUserByID(userId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	nc, err := NewUserNotificationChannels(channels)
+	if err != nil {
+		return HttpBadParams(err)
+	}
+	u.SetNotificationChannels(nc)
+
+	payload := userNotificationChannelsModel(u)
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/kowabunga-cloud/common/klog"
+	"github.com/kowabunga-cloud/kowabunga/kowabunga/sdk"
+)
+
+// OidcAPIController binds http requests to the OpenID Connect login flow and writes the results to the http response
+type OidcAPIController struct {
+	service      *OidcService
+	errorHandler sdk.ErrorHandler
+}
+
+func NewOidcRouter() sdk.Router {
+	return &OidcAPIController{
+		service:      &OidcService{},
+		errorHandler: sdk.DefaultErrorHandler,
+	}
+}
+
+// Routes returns all the api routes for the OidcAPIController
+func (c *OidcAPIController) Routes() sdk.Routes {
+	routes := sdk.Routes{}
+	for _, r := range c.OrderedRoutes() {
+		routes[r.Name] = r
+	}
+	return routes
+}
+
+// OrderedRoutes returns all the api routes in a deterministic order for the OidcAPIController
+func (c *OidcAPIController) OrderedRoutes() []sdk.Route {
+	return []sdk.Route{
+		{
+			Name:        "OidcLogin",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + "/auth/oidc/login",
+			HandlerFunc: c.OidcLogin,
+		},
+		{
+			Name:        "OidcCallback",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + OidcCallbackRoute,
+			HandlerFunc: c.OidcCallback,
+		},
+	}
+}
+
+// OidcLogin - redirects user-agent to IdP's authorization endpoint
+func (c *OidcAPIController) OidcLogin(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	organizationIdParam := query.Get("organization")
+	uri, result, err := c.service.OidcLogin(r.Context(), organizationIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	http.Redirect(w, r, uri, http.StatusFound)
+}
+
+// OidcCallback - completes authorization code flow and opens a Kowabunga session
+func (c *OidcAPIController) OidcCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Has("error") {
+		klog.Errorf("OpenID Connect authentication failure: %s (%s)", query.Get("error"), query.Get("error_description"))
+		result, _ := HttpUnauthorized(nil)
+		_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+		return
+	}
+	codeParam := query.Get("code")
+	if codeParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "code"}, nil)
+		return
+	}
+	stateParam := query.Get("state")
+	if stateParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "state"}, nil)
+		return
+	}
+	result, err := c.service.OidcCallback(r.Context(), codeParam, stateParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+type OidcService struct{}
+
+func (s *OidcService) OidcLogin(ctx context.Context, organizationId string) (string, sdk.ImplResponse, error) {
+	p, err := GetOidcProvider(organizationId)
+	if err != nil {
+		klog.Error(err)
+		result, err := HttpNotFound(err)
+		return "", result, err
+	}
+
+	l, err := NewOidcLogin(organizationId)
+	if err != nil {
+		result, err := HttpServerError(err)
+		return "", result, err
+	}
+
+	return p.AuthCodeURL(l.ID, l.Nonce, l.Verifier), sdk.ImplResponse{}, nil
+}
+
+func (s *OidcService) OidcCallback(ctx context.Context, code, state string) (sdk.ImplResponse, error) {
+	l, err := ConsumeOidcLogin(state)
+	if err != nil {
+		klog.Error(err)
+		return HttpUnauthorized(err)
+	}
+
+	p, err := GetOidcProvider(l.OrganizationID)
+	if err != nil {
+		klog.Error(err)
+		return HttpNotFound(err)
+	}
+
+	idToken, err := p.Exchange(code, l.Verifier)
+	if err != nil {
+		klog.Error(err)
+		return HttpUnauthorized(err)
+	}
+
+	claims, err := p.VerifyIdToken(idToken, l.Nonce)
+	if err != nil {
+		klog.Error(err)
+		return HttpUnauthorized(err)
+	}
+
+	u, err := p.ProvisionUser(claims)
+	if err != nil {
+		klog.Error(err)
+		return HttpForbidden(err)
+	}
+
+	// let's go with JWT
+	jwt, err := u.JwtSession()
+	if err != nil {
+		klog.Error(err)
+		return HttpServerError(err)
+	}
+
+	klog.Debugf("OpenID Connect based authentication for user %s", u.String())
+	return HttpCreated(sdk.UserCredentials{
+		Email: u.Email,
+		Jwt:   jwt,
+	})
+}
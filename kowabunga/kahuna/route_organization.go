@@ -0,0 +1,780 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gorilla/mux"
+
+	"github.com/kowabunga-cloud/kowabunga/kowabunga/sdk"
+)
+
+// OrganizationAPIController binds http requests to the organization service and writes the service results to the http response
+type OrganizationAPIController struct {
+	service      *OrganizationService
+	errorHandler sdk.ErrorHandler
+}
+
+func NewOrganizationRouter() sdk.Router {
+	return &OrganizationAPIController{
+		service:      &OrganizationService{},
+		errorHandler: sdk.DefaultErrorHandler,
+	}
+}
+
+// Routes returns all the api routes for the OrganizationAPIController
+func (c *OrganizationAPIController) Routes() sdk.Routes {
+	routes := sdk.Routes{}
+	for _, r := range c.OrderedRoutes() {
+		routes[r.Name] = r
+	}
+	return routes
+}
+
+// OrderedRoutes returns all the api routes in a deterministic order for the OrganizationAPIController
+func (c *OrganizationAPIController) OrderedRoutes() []sdk.Route {
+	return []sdk.Route{
+		{
+			Name:        "ListOrganizations",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + "/organization",
+			HandlerFunc: c.ListOrganizations,
+		},
+		{
+			Name:        "CreateOrganization",
+			Method:      http.MethodPost,
+			Pattern:     SdkBaseRoute + "/organization",
+			HandlerFunc: c.CreateOrganization,
+		},
+		{
+			Name:        "ReadOrganization",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + "/organization/{organizationId}",
+			HandlerFunc: c.ReadOrganization,
+		},
+		{
+			Name:        "UpdateOrganization",
+			Method:      http.MethodPut,
+			Pattern:     SdkBaseRoute + "/organization/{organizationId}",
+			HandlerFunc: c.UpdateOrganization,
+		},
+		{
+			Name:        "DeleteOrganization",
+			Method:      http.MethodDelete,
+			Pattern:     SdkBaseRoute + "/organization/{organizationId}",
+			HandlerFunc: c.DeleteOrganization,
+		},
+		{
+			Name:        "CreateOrganizationUser",
+			Method:      http.MethodPost,
+			Pattern:     SdkBaseRoute + "/organization/{organizationId}/user",
+			HandlerFunc: c.CreateOrganizationUser,
+		},
+		{
+			Name:        "AddOrganizationUser",
+			Method:      http.MethodPut,
+			Pattern:     SdkBaseRoute + "/organization/{organizationId}/user/{userId}",
+			HandlerFunc: c.AddOrganizationUser,
+		},
+		{
+			Name:        "RemoveOrganizationUser",
+			Method:      http.MethodDelete,
+			Pattern:     SdkBaseRoute + "/organization/{organizationId}/user/{userId}",
+			HandlerFunc: c.RemoveOrganizationUser,
+		},
+		{
+			Name:        "CreateOrganizationTeam",
+			Method:      http.MethodPost,
+			Pattern:     SdkBaseRoute + "/organization/{organizationId}/team",
+			HandlerFunc: c.CreateOrganizationTeam,
+		},
+		{
+			Name:        "AddOrganizationTeam",
+			Method:      http.MethodPut,
+			Pattern:     SdkBaseRoute + "/organization/{organizationId}/team/{teamId}",
+			HandlerFunc: c.AddOrganizationTeam,
+		},
+		{
+			Name:        "RemoveOrganizationTeam",
+			Method:      http.MethodDelete,
+			Pattern:     SdkBaseRoute + "/organization/{organizationId}/team/{teamId}",
+			HandlerFunc: c.RemoveOrganizationTeam,
+		},
+		{
+			Name:        "AddOrganizationProject",
+			Method:      http.MethodPut,
+			Pattern:     SdkBaseRoute + "/organization/{organizationId}/project/{projectId}",
+			HandlerFunc: c.AddOrganizationProject,
+		},
+		{
+			Name:        "RemoveOrganizationProject",
+			Method:      http.MethodDelete,
+			Pattern:     SdkBaseRoute + "/organization/{organizationId}/project/{projectId}",
+			HandlerFunc: c.RemoveOrganizationProject,
+		},
+	}
+}
+
+// ListOrganizations -
+func (c *OrganizationAPIController) ListOrganizations(w http.ResponseWriter, r *http.Request) {
+	result, err := c.service.ListOrganizations(r.Context())
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// CreateOrganization -
+func (c *OrganizationAPIController) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	var organizationParam OrganizationModel
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&organizationParam); err != nil {
+		c.errorHandler(w, r, &sdk.ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.CreateOrganization(r.Context(), organizationParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ReadOrganization -
+func (c *OrganizationAPIController) ReadOrganization(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	organizationIdParam := params["organizationId"]
+	if organizationIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "organizationId"}, nil)
+		return
+	}
+	result, err := c.service.ReadOrganization(r.Context(), organizationIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// UpdateOrganization -
+func (c *OrganizationAPIController) UpdateOrganization(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	organizationIdParam := params["organizationId"]
+	if organizationIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "organizationId"}, nil)
+		return
+	}
+	var organizationParam OrganizationModel
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&organizationParam); err != nil {
+		c.errorHandler(w, r, &sdk.ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.UpdateOrganization(r.Context(), organizationIdParam, organizationParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// DeleteOrganization -
+func (c *OrganizationAPIController) DeleteOrganization(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	organizationIdParam := params["organizationId"]
+	if organizationIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "organizationId"}, nil)
+		return
+	}
+	result, err := c.service.DeleteOrganization(r.Context(), organizationIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// CreateOrganizationUser -
+func (c *OrganizationAPIController) CreateOrganizationUser(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	organizationIdParam := params["organizationId"]
+	if organizationIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "organizationId"}, nil)
+		return
+	}
+	var userParam sdk.User
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&userParam); err != nil {
+		c.errorHandler(w, r, &sdk.ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.CreateOrganizationUser(r.Context(), organizationIdParam, userParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// AddOrganizationUser -
+func (c *OrganizationAPIController) AddOrganizationUser(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	organizationIdParam := params["organizationId"]
+	if organizationIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "organizationId"}, nil)
+		return
+	}
+	userIdParam := params["userId"]
+	if userIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "userId"}, nil)
+		return
+	}
+	result, err := c.service.AddOrganizationUser(r.Context(), organizationIdParam, userIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// RemoveOrganizationUser -
+func (c *OrganizationAPIController) RemoveOrganizationUser(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	organizationIdParam := params["organizationId"]
+	if organizationIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "organizationId"}, nil)
+		return
+	}
+	userIdParam := params["userId"]
+	if userIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "userId"}, nil)
+		return
+	}
+	result, err := c.service.RemoveOrganizationUser(r.Context(), organizationIdParam, userIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// CreateOrganizationTeam -
+func (c *OrganizationAPIController) CreateOrganizationTeam(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	organizationIdParam := params["organizationId"]
+	if organizationIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "organizationId"}, nil)
+		return
+	}
+	var teamParam sdk.Team
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&teamParam); err != nil {
+		c.errorHandler(w, r, &sdk.ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.CreateOrganizationTeam(r.Context(), organizationIdParam, teamParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// AddOrganizationTeam -
+func (c *OrganizationAPIController) AddOrganizationTeam(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	organizationIdParam := params["organizationId"]
+	if organizationIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "organizationId"}, nil)
+		return
+	}
+	teamIdParam := params["teamId"]
+	if teamIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "teamId"}, nil)
+		return
+	}
+	result, err := c.service.AddOrganizationTeam(r.Context(), organizationIdParam, teamIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// RemoveOrganizationTeam -
+func (c *OrganizationAPIController) RemoveOrganizationTeam(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	organizationIdParam := params["organizationId"]
+	if organizationIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "organizationId"}, nil)
+		return
+	}
+	teamIdParam := params["teamId"]
+	if teamIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "teamId"}, nil)
+		return
+	}
+	result, err := c.service.RemoveOrganizationTeam(r.Context(), organizationIdParam, teamIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// AddOrganizationProject -
+func (c *OrganizationAPIController) AddOrganizationProject(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	organizationIdParam := params["organizationId"]
+	if organizationIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "organizationId"}, nil)
+		return
+	}
+	projectIdParam := params["projectId"]
+	if projectIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "projectId"}, nil)
+		return
+	}
+	result, err := c.service.AddOrganizationProject(r.Context(), organizationIdParam, projectIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// RemoveOrganizationProject -
+func (c *OrganizationAPIController) RemoveOrganizationProject(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	organizationIdParam := params["organizationId"]
+	if organizationIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "organizationId"}, nil)
+		return
+	}
+	projectIdParam := params["projectId"]
+	if projectIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "projectId"}, nil)
+		return
+	}
+	result, err := c.service.RemoveOrganizationProject(r.Context(), organizationIdParam, projectIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+type OrganizationService struct{}
+
+// isOrganizationAdmin tells whether caller is allowed to manage organization's tenants
+func isOrganizationAdmin(ctx context.Context, o *Organization) bool {
+	return ctxGetSuperAdminRole(ctx) || o.IsAdmin(ctxGetUserId(ctx))
+}
+
+func (s *OrganizationService) ListOrganizations(ctx context.Context) (sdk.ImplResponse, error) {
+	// super-administrators can see everything, others only organizations they administrate
+	if ctxGetSuperAdminRole(ctx) {
+		return HttpListResources[Organization, OrganizationModel](ctx, MongoCollectionOrganizationName, nil)
+	}
+
+	return HttpListResources[Organization, OrganizationModel](ctx, MongoCollectionOrganizationName, listFilterByKey("admin_ids", ctxGetUserId(ctx)))
+}
+
+func (s *OrganizationService) CreateOrganization(ctx context.Context, organization OrganizationModel) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("organization", hideOrganizationSecrets(organization)))
+
+	if !ctxGetSuperAdminRole(ctx) {
+		return HttpForbidden(nil)
+	}
+
+	// check for params
+	if organization.Name == "" {
+		return HttpBadParams(nil)
+	}
+
+	// ensure organization does not already exists
+	_, err := FindOrganizationByName(organization.Name)
+	if err == nil {
+		return HttpConflict(err)
+	}
+
+	auth, err := NewOrganizationAuth(organization.Auth)
+	if err != nil {
+		return HttpBadParams(err)
+	}
+
+	// create organization
+	o, err := NewOrganization(organization.Name, organization.Description, organization.Admins, auth, organization.Quotas)
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	payload := o.Model()
+	LogHttpResponse(payload)
+	return HttpCreated(payload)
+}
+
+func (s *OrganizationService) ReadOrganization(ctx context.Context, organizationId string) (sdk.ImplResponse, error) {
+	o, err := FindOrganizationByID(organizationId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+	ctxTrackRevision(ctx, &o.Resource)
+
+	if !isOrganizationAdmin(ctx, o) {
+		return HttpForbidden(nil)
+	}
+
+	payload := o.Model()
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
+
+func (s *OrganizationService) UpdateOrganization(ctx context.Context, organizationId string, organization OrganizationModel) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("organizationId", organizationId), RA("organization", hideOrganizationSecrets(organization)))
+
+	o, err := FindOrganizationByID(organizationId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &o.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
+	if !isOrganizationAdmin(ctx, o) {
+		return HttpForbidden(nil)
+	}
+
+	auth, err := NewOrganizationAuth(organization.Auth)
+	if err != nil {
+		return HttpBadParams(err)
+	}
+	// organization administrators can't switch to another IdP, which could impersonate any user
+	if !ctxGetSuperAdminRole(ctx) && !auth.IsSameProvider(&o.Auth) {
+		return HttpForbidden(fmt.Errorf("%s", ErrOrganizationAuthDenied))
+	}
+	// IdP client secret is never exposed, keep it unless a new one is set
+	if auth.Oidc.ClientSecret == "" {
+		auth.Oidc.ClientSecret = o.Auth.Oidc.ClientSecret
+	}
+
+	// organization's administrators and quotas can only be set by super-administrators
+	var admins []string
+	var quotas *sdk.ProjectResources
+	if ctxGetSuperAdminRole(ctx) {
+		admins = organization.Admins
+		quotas = &organization.Quotas
+	}
+
+	err = o.Update(organization.Name, organization.Description, &auth, admins, quotas)
+	if err != nil {
+		return HttpUpdateError(err)
+	}
+
+	payload := o.Model()
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
+
+func (s *OrganizationService) DeleteOrganization(ctx context.Context, organizationId string) (sdk.ImplResponse, error) {
+	if !ctxGetSuperAdminRole(ctx) {
+		return HttpForbidden(nil)
+	}
+
+	o, err := FindOrganizationByID(organizationId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	if o.HasChildren() {
+		return HttpConflict(nil)
+	}
+
+	err = o.Delete()
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	return HttpOK(nil)
+}
+
+func (s *OrganizationService) CreateOrganizationUser(ctx context.Context, organizationId string, user sdk.User) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("organizationId", organizationId), RA("user", user))
+
+	o, err := FindOrganizationByID(organizationId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	if !isOrganizationAdmin(ctx, o) {
+		return HttpForbidden(nil)
+	}
+
+	// check for params
+	if user.Name == "" || user.Email == "" {
+		return HttpBadParams(nil)
+	}
+
+	// ensure user does not already exists
+	_, err = FindUserByName(user.Name)
+	if err == nil {
+		return HttpConflict(err)
+	}
+
+	// organization administrators can't grant platform-wide roles
+	role := user.Role
+	if !ctxGetSuperAdminRole(ctx) {
+		role = UserRoleStandard
+	}
+
+	// create user
+	u, err := NewUser(user.Name, user.Description, user.Email, role, user.Notifications)
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	err = o.AddUser(u)
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	payload := u.Model()
+	LogHttpResponse(payload)
+	return HttpCreated(payload)
+}
+
+func (s *OrganizationService) AddOrganizationUser(ctx context.Context, organizationId, userId string) (sdk.ImplResponse, error) {
+	if !ctxGetSuperAdminRole(ctx) {
+		return HttpForbidden(nil)
+	}
+
+	o, err := FindOrganizationByID(organizationId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	u, err := FindUserByID(userId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	err = o.AddUser(u)
+	if err != nil {
+		return HttpConflict(err)
+	}
+
+	return HttpOK(nil)
+}
+
+func (s *OrganizationService) RemoveOrganizationUser(ctx context.Context, organizationId, userId string) (sdk.ImplResponse, error) {
+	if !ctxGetSuperAdminRole(ctx) {
+		return HttpForbidden(nil)
+	}
+
+	o, err := FindOrganizationByID(organizationId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	u, err := FindUserByID(userId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	err = o.RemoveUser(u)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	return HttpOK(nil)
+}
+
+func (s *OrganizationService) CreateOrganizationTeam(ctx context.Context, organizationId string, team sdk.Team) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("organizationId", organizationId), RA("team", team))
+
+	o, err := FindOrganizationByID(organizationId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	if !isOrganizationAdmin(ctx, o) {
+		return HttpForbidden(nil)
+	}
+
+	// check for params
+	if team.Name == "" {
+		return HttpBadParams(nil)
+	}
+
+	// ensure team does not already exists
+	_, err = FindTeamByName(team.Name)
+	if err == nil {
+		return HttpConflict(err)
+	}
+
+	// teams can only gather organization's users
+	for _, userId := range team.Users {
+		if !slices.Contains(o.UserIDs, userId) {
+			return HttpBadParams(fmt.Errorf("%s: %s", ErrOrganizationNotMember, userId))
+		}
+	}
+
+	// create team
+	t, err := NewTeam(team.Name, team.Description, team.Users)
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	err = o.AddTeam(t)
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	payload := t.Model()
+	LogHttpResponse(payload)
+	return HttpCreated(payload)
+}
+
+func (s *OrganizationService) AddOrganizationTeam(ctx context.Context, organizationId, teamId string) (sdk.ImplResponse, error) {
+	if !ctxGetSuperAdminRole(ctx) {
+		return HttpForbidden(nil)
+	}
+
+	o, err := FindOrganizationByID(organizationId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	t, err := FindTeamByID(teamId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	err = o.AddTeam(t)
+	if err != nil {
+		return HttpConflict(err)
+	}
+
+	return HttpOK(nil)
+}
+
+func (s *OrganizationService) RemoveOrganizationTeam(ctx context.Context, organizationId, teamId string) (sdk.ImplResponse, error) {
+	if !ctxGetSuperAdminRole(ctx) {
+		return HttpForbidden(nil)
+	}
+
+	o, err := FindOrganizationByID(organizationId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	t, err := FindTeamByID(teamId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	err = o.RemoveTeam(t)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	return HttpOK(nil)
+}
+
+func (s *OrganizationService) AddOrganizationProject(ctx context.Context, organizationId, projectId string) (sdk.ImplResponse, error) {
+	if !ctxGetSuperAdminRole(ctx) {
+		return HttpForbidden(nil)
+	}
+
+	o, err := FindOrganizationByID(organizationId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	p, err := FindProjectByID(projectId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	err = o.AddProject(p)
+	if err != nil {
+		return HttpConflict(err)
+	}
+
+	return HttpOK(nil)
+}
+
+func (s *OrganizationService) RemoveOrganizationProject(ctx context.Context, organizationId, projectId string) (sdk.ImplResponse, error) {
+	if !ctxGetSuperAdminRole(ctx) {
+		return HttpForbidden(nil)
+	}
+
+	o, err := FindOrganizationByID(organizationId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	p, err := FindProjectByID(projectId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	err = o.RemoveProject(p)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	return HttpOK(nil)
+}
+
+func hideOrganizationSecrets(organization OrganizationModel) OrganizationModel {
+	if organization.Auth.Oidc != nil {
+		oidc := *organization.Auth.Oidc
+		oidc.ClientSecret = "<redacted>"
+		organization.Auth.Oidc = &oidc
+	}
+	return organization
+}
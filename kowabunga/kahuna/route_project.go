@@ -108,9 +108,9 @@ func (s *ProjectService) CreateProjectZoneInstance(ctx context.Context, projectI
 	}
 
 	// ensure instance does not already exists (globally, across all projects)
-	_, err = FindInstanceByName(instance.Name)
+	i, err := FindInstanceByName(instance.Name)
 	if err == nil {
-		return HttpConflict(err)
+		return HttpConflict(ErrNameInUse(MongoCollectionInstanceName, &i.Resource))
 	}
 
 	// now find the best-suited kaktus node
@@ -120,14 +120,13 @@ func (s *ProjectService) CreateProjectZoneInstance(ctx context.Context, projectI
 	}
 
 	// create instance
-	i, err := NewInstance(prj.String(), h.String(), instance.Name, instance.Description, "", "", instance.Vcpus, instance.Memory, instance.Adapters, instance.Volumes)
-	if err != nil {
-		return HttpServerError(err)
-	}
-
-	payload := i.Model()
-	LogHttpResponse(payload)
-	return HttpCreated(payload)
+	return HttpCreateMaybeAsync(ctx, "CreateProjectZoneInstance", func(t *Task) (string, any, error) {
+		i, err := NewInstance(prj.String(), h.String(), instance.Name, instance.Description, "", "", instance.Vcpus, instance.Memory, instance.Adapters, instance.Volumes)
+		if err != nil {
+			return "", nil, err
+		}
+		return i.String(), i.Model(), nil
+	})
 }
 
 func (s *ProjectService) CreateProjectZoneKompute(ctx context.Context, projectId string, zoneId string, kompute sdk.Kompute, poolId string, templateId string, public bool) (sdk.ImplResponse, error) {
@@ -190,9 +189,9 @@ func (s *ProjectService) CreateProjectZoneKompute(ctx context.Context, projectId
 	}
 
 	// ensure instance does not already exists (globally, across all projects), this would validate auto-named volumes as well
-	_, err = FindInstanceByName(kompute.Name)
+	i, err := FindInstanceByName(kompute.Name)
 	if err == nil {
-		return HttpConflict(err)
+		return HttpConflict(ErrNameInUse(MongoCollectionInstanceName, &i.Resource))
 	}
 
 	// now find the best-suited kaktus node
@@ -206,16 +205,16 @@ func (s *ProjectService) CreateProjectZoneKompute(ctx context.Context, projectId
 	//
 
 	// create Kompute
-	k, err := NewKompute(prj.String(), zone.String(), h.String(), p.String(), t.String(), kompute.Name, kompute.Description, "", "", kompute.Vcpus, kompute.Memory, kompute.Disk, kompute.DataDisk, public, []string{})
-	if err != nil {
-		return HttpServerError(err)
-	}
-	payload := k.Model()
-	LogHttpResponse(payload)
-	return HttpCreated(payload)
+	return HttpCreateMaybeAsync(ctx, "CreateProjectZoneKompute", func(task *Task) (string, any, error) {
+		k, err := NewKompute(prj.String(), zone.String(), h.String(), p.String(), t.String(), kompute.Name, kompute.Description, "", "", kompute.Vcpus, kompute.Memory, kompute.Disk, kompute.DataDisk, public, []string{})
+		if err != nil {
+			return "", nil, err
+		}
+		return k.String(), k.Model(), nil
+	})
 }
 
-func CreateProjectKonvey(projectId, regionId, name string, konvey sdk.Konvey, kaktusIds []string) (sdk.ImplResponse, error) {
+func CreateProjectKonvey(ctx context.Context, projectId, regionId, name string, konvey sdk.Konvey, kaktusIds []string) (sdk.ImplResponse, error) {
 	// converts from model to object
 	endpoints := []KonveyEndpoint{}
 	for _, ep := range konvey.Endpoints {
@@ -242,14 +241,13 @@ func CreateProjectKonvey(projectId, regionId, name string, konvey sdk.Konvey, ka
 	}
 
 	// Create Konvey
-	k, err := NewKonvey(projectId, regionId, name, konvey.Description, endpoints, kaktusIds)
-	if err != nil {
-		return HttpServerError(err)
-	}
-
-	payload := k.Model()
-	LogHttpResponse(payload)
-	return HttpCreated(payload)
+	return HttpCreateMaybeAsync(ctx, "CreateProjectKonvey", func(t *Task) (string, any, error) {
+		k, err := NewKonvey(projectId, regionId, name, konvey.Description, endpoints, kaktusIds)
+		if err != nil {
+			return "", nil, err
+		}
+		return k.String(), k.Model(), nil
+	})
 }
 
 func (s *ProjectService) CreateProjectZoneKonvey(ctx context.Context, projectId string, zoneId string, konvey sdk.Konvey) (sdk.ImplResponse, error) {
@@ -280,7 +278,7 @@ func (s *ProjectService) CreateProjectZoneKonvey(ctx context.Context, projectId
 	}
 	konveyName := KonveyDefaultNamePrefix + "-" + kName
 	_, err = FindKonveyByName(konveyName)
-	if err != nil {
+	if err == nil {
 		return HttpConflict(err)
 	}
 
@@ -301,7 +299,7 @@ func (s *ProjectService) CreateProjectZoneKonvey(ctx context.Context, projectId
 		kaktusIds = append(kaktusIds, h.String())
 	}
 
-	return CreateProjectKonvey(p.String(), r.String(), konveyName, konvey, kaktusIds)
+	return CreateProjectKonvey(ctx, p.String(), r.String(), konveyName, konvey, kaktusIds)
 }
 
 func (s *ProjectService) CreateProjectRegionKylo(ctx context.Context, projectId string, regionId string, kylo sdk.Kylo, nfsId string) (sdk.ImplResponse, error) {
@@ -504,14 +502,13 @@ func (s *ProjectService) CreateProjectRegionKawaii(ctx context.Context, projectI
 	}
 
 	// Create Kawaii
-	k, err := NewKawaii(p.String(), r.String(), kawaiiName, kawaii.Description, fw, natRules, vpcPeerings)
-	if err != nil {
-		return HttpServerError(err)
-	}
-
-	payload := k.Model()
-	LogHttpResponse(payload)
-	return HttpCreated(payload)
+	return HttpCreateMaybeAsync(ctx, "CreateProjectRegionKawaii", func(t *Task) (string, any, error) {
+		k, err := NewKawaii(p.String(), r.String(), kawaiiName, kawaii.Description, fw, natRules, vpcPeerings)
+		if err != nil {
+			return "", nil, err
+		}
+		return k.String(), k.Model(), nil
+	})
 }
 
 func (s *ProjectService) CreateProjectRegionKonvey(ctx context.Context, projectId string, regionId string, konvey sdk.Konvey) (sdk.ImplResponse, error) {
@@ -585,7 +582,7 @@ func (s *ProjectService) CreateProjectRegionKonvey(ctx context.Context, projectI
 		kaktusIds = append(kaktusIds, h.String())
 	}
 
-	return CreateProjectKonvey(p.String(), r.String(), konveyName, konvey, kaktusIds)
+	return CreateProjectKonvey(ctx, p.String(), r.String(), konveyName, konvey, kaktusIds)
 }
 
 func (s *ProjectService) CreateProjectRegionVolume(ctx context.Context, projectId string, regionId string, volume sdk.Volume, poolId string, templateId string) (sdk.ImplResponse, error) {
@@ -632,20 +629,19 @@ func (s *ProjectService) CreateProjectRegionVolume(ctx context.Context, projectI
 	}
 
 	// ensure volume does not already exists
-	_, err = FindVolumeByName(volume.Name)
+	v, err := FindVolumeByName(volume.Name)
 	if err == nil {
-		return HttpConflict(err)
+		return HttpConflict(ErrNameInUse(MongoCollectionVolumeName, &v.Resource))
 	}
 
 	// create volume
-	v, err := NewVolume(prj.String(), p.String(), tid, volume.Name, volume.Description, volume.Type, volume.Size)
-	if err != nil {
-		return HttpServerError(err)
-	}
-
-	payload := v.Model()
-	LogHttpResponse(payload)
-	return HttpCreated(payload)
+	return HttpCreateMaybeAsync(ctx, "CreateProjectRegionVolume", func(t *Task) (string, any, error) {
+		v, err := NewVolume(prj.String(), p.String(), tid, volume.Name, volume.Description, volume.Type, volume.Size)
+		if err != nil {
+			return "", nil, err
+		}
+		return v.String(), v.Model(), nil
+	})
 }
 
 func (s *ProjectService) DeleteProject(ctx context.Context, projectId string) (sdk.ImplResponse, error) {
@@ -675,8 +671,7 @@ func (s *ProjectService) ListProjectDnsRecords(ctx context.Context, projectId st
 		return HttpNotFound(err)
 	}
 
-	payload := p.DnsRecords()
-	return HttpOK(payload)
+	return HttpListResources[DnsRecord, sdk.DnsRecord](ctx, MongoCollectionDnsRecordName, listFilterByIDs(p.DnsRecords()))
 }
 
 func (s *ProjectService) ListProjectZoneInstances(ctx context.Context, projectId string, zoneId string) (sdk.ImplResponse, error) {
@@ -685,8 +680,7 @@ func (s *ProjectService) ListProjectZoneInstances(ctx context.Context, projectId
 		return HttpNotFound(err)
 	}
 
-	payload := p.Instances()
-	return HttpOK(payload)
+	return HttpListResources[Instance, sdk.Instance](ctx, MongoCollectionInstanceName, listFilterByIDs(p.Instances()))
 }
 
 func (s *ProjectService) ListProjectZoneKomputes(ctx context.Context, projectId string, zoneId string) (sdk.ImplResponse, error) {
@@ -695,8 +689,7 @@ func (s *ProjectService) ListProjectZoneKomputes(ctx context.Context, projectId
 		return HttpNotFound(err)
 	}
 
-	payload := p.Komputes()
-	return HttpOK(payload)
+	return HttpListResources[Kompute, sdk.Kompute](ctx, MongoCollectionKomputeName, listFilterByIDs(p.Komputes()))
 }
 
 func (s *ProjectService) ListProjectZoneKonveys(ctx context.Context, projectId string, zoneId string) (sdk.ImplResponse, error) {
@@ -705,8 +698,7 @@ func (s *ProjectService) ListProjectZoneKonveys(ctx context.Context, projectId s
 		return HttpNotFound(err)
 	}
 
-	payload := p.Konveys()
-	return HttpOK(payload)
+	return HttpListResources[Konvey, sdk.Konvey](ctx, MongoCollectionKonveyName, listFilterByIDs(p.Konveys()))
 }
 
 func (s *ProjectService) ListProjectRegionKylos(ctx context.Context, projectId string, regionId string, nfsId string) (sdk.ImplResponse, error) {
@@ -715,8 +707,7 @@ func (s *ProjectService) ListProjectRegionKylos(ctx context.Context, projectId s
 		return HttpNotFound(err)
 	}
 
-	payload := p.Kylos()
-	return HttpOK(payload)
+	return HttpListResources[Kylo, sdk.Kylo](ctx, MongoCollectionKyloName, listFilterByIDs(p.Kylos()))
 }
 
 func (s *ProjectService) ListProjectRegionKawaiis(ctx context.Context, projectId string, regionId string) (sdk.ImplResponse, error) {
@@ -725,8 +716,7 @@ func (s *ProjectService) ListProjectRegionKawaiis(ctx context.Context, projectId
 		return HttpNotFound(err)
 	}
 
-	payload := p.Kawaiis()
-	return HttpOK(payload)
+	return HttpListResources[Kawaii, sdk.Kawaii](ctx, MongoCollectionKawaiiName, listFilterByIDs(p.Kawaiis()))
 }
 
 func (s *ProjectService) ListProjectRegionKonveys(ctx context.Context, projectId string, regionId string) (sdk.ImplResponse, error) {
@@ -735,8 +725,7 @@ func (s *ProjectService) ListProjectRegionKonveys(ctx context.Context, projectId
 		return HttpNotFound(err)
 	}
 
-	payload := p.Konveys()
-	return HttpOK(payload)
+	return HttpListResources[Konvey, sdk.Konvey](ctx, MongoCollectionKonveyName, listFilterByIDs(p.Konveys()))
 }
 
 func (s *ProjectService) ListProjectRegionVolumes(ctx context.Context, projectId string, regionId string) (sdk.ImplResponse, error) {
@@ -745,18 +734,17 @@ func (s *ProjectService) ListProjectRegionVolumes(ctx context.Context, projectId
 		return HttpNotFound(err)
 	}
 
-	payload := p.Volumes()
-	return HttpOK(payload)
+	return HttpListResources[Volume, sdk.Volume](ctx, MongoCollectionVolumeName, listFilterByIDs(p.Volumes()))
 }
 
 func (s *ProjectService) ListProjects(ctx context.Context, subnetSize int32) (sdk.ImplResponse, error) {
-	projects := FindProjects()
-	var payload []string
-	for _, t := range projects {
-		payload = append(payload, t.String())
+	// users only get to see the projects they have access to
+	filter, err := rbacProjectsFilter(ctx)
+	if err != nil {
+		return HttpForbidden(err)
 	}
 
-	return HttpOK(payload)
+	return HttpListResources[Project, sdk.Project](ctx, MongoCollectionProjectName, filter)
 }
 
 func (s *ProjectService) ReadProject(ctx context.Context, projectId string) (sdk.ImplResponse, error) {
@@ -764,6 +752,7 @@ func (s *ProjectService) ReadProject(ctx context.Context, projectId string) (sdk
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &p.Resource)
 
 	payload := p.Model()
 	LogHttpResponse(payload)
@@ -776,6 +765,7 @@ func (s *ProjectService) ReadProjectCost(ctx context.Context, projectId string)
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &p.Resource)
 
 	// get project cost model
 	payload := p.GetCost()
@@ -788,6 +778,7 @@ func (s *ProjectService) ReadProjectUsage(ctx context.Context, projectId string)
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &p.Resource)
 
 	// get project usage model
 	payload := p.GetUsage()
@@ -809,12 +800,21 @@ func (s *ProjectService) UpdateProject(ctx context.Context, projectId string, pr
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &p.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	// update project
 	metas := map[string]string{}
 	for _, m := range project.Metadatas {
 		metas[m.Key] = m.Value
 	}
-	p.Update(project.Name, project.Description, project.RootPassword, project.BootstrapUser, project.BootstrapPubkey, project.Teams, project.Regions, project.Tags, metas, project.Quotas)
+	err = p.Update(project.Name, project.Description, project.RootPassword, project.BootstrapUser, project.BootstrapPubkey, project.Teams, project.Regions, project.Tags, metas, project.Quotas)
+	if err != nil {
+		return HttpUpdateError(err)
+	}
 
 	payload := p.Model()
 	LogHttpResponse(payload)
@@ -0,0 +1,188 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/kowabunga-cloud/kowabunga/kowabunga/sdk"
+)
+
+// RbacAPIController binds http requests to the role-based access control service and writes the service results to the http response
+type RbacAPIController struct {
+	service      *RbacService
+	errorHandler sdk.ErrorHandler
+}
+
+func NewRbacRouter() sdk.Router {
+	return &RbacAPIController{
+		service:      &RbacService{},
+		errorHandler: sdk.DefaultErrorHandler,
+	}
+}
+
+// Routes returns all the api routes for the RbacAPIController
+func (c *RbacAPIController) Routes() sdk.Routes {
+	routes := sdk.Routes{}
+	for _, r := range c.OrderedRoutes() {
+		routes[r.Name] = r
+	}
+	return routes
+}
+
+// OrderedRoutes returns all the api routes in a deterministic order for the RbacAPIController
+func (c *RbacAPIController) OrderedRoutes() []sdk.Route {
+	return []sdk.Route{
+		{
+			Name:        "ListProjectRoleBindings",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + "/project/{projectId}/binding",
+			HandlerFunc: c.ListProjectRoleBindings,
+		},
+		{
+			Name:        "SetProjectRoleBindings",
+			Method:      http.MethodPut,
+			Pattern:     SdkBaseRoute + "/project/{projectId}/binding",
+			HandlerFunc: c.SetProjectRoleBindings,
+		},
+		{
+			Name:        "ListUserPermissions",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + "/user/{userId}/permissions",
+			HandlerFunc: c.ListUserPermissions,
+		},
+	}
+}
+
+// ListProjectRoleBindings -
+func (c *RbacAPIController) ListProjectRoleBindings(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	projectIdParam := params["projectId"]
+	if projectIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "projectId"}, nil)
+		return
+	}
+	result, err := c.service.ListProjectRoleBindings(r.Context(), projectIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// SetProjectRoleBindings -
+func (c *RbacAPIController) SetProjectRoleBindings(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	projectIdParam := params["projectId"]
+	if projectIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "projectId"}, nil)
+		return
+	}
+	var bindingsParam []ProjectRoleBindingModel
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&bindingsParam); err != nil {
+		c.errorHandler(w, r, &sdk.ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.SetProjectRoleBindings(r.Context(), projectIdParam, bindingsParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ListUserPermissions -
+func (c *RbacAPIController) ListUserPermissions(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userIdParam := params["userId"]
+	if userIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "userId"}, nil)
+		return
+	}
+	result, err := c.service.ListUserPermissions(r.Context(), userIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+type RbacService struct{}
+
+// projectRoleBindings returns all of project's teams with their granted role
+func projectRoleBindings(p *Project) []ProjectRoleBindingModel {
+	payload := []ProjectRoleBindingModel{}
+	for _, teamId := range p.TeamIDs {
+		role, _ := p.TeamRole(teamId)
+		payload = append(payload, ProjectRoleBindingModel{
+			Team: teamId,
+			Role: role,
+		})
+	}
+	return payload
+}
+
+func (s *RbacService) ListProjectRoleBindings(ctx context.Context, projectId string) (sdk.ImplResponse, error) {
+	p, err := FindProjectByID(projectId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	payload := projectRoleBindings(p)
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
+
+func (s *RbacService) SetProjectRoleBindings(ctx context.Context, projectId string, bindings []ProjectRoleBindingModel) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("projectId", projectId), RA("bindings", bindings))
+
+	p, err := FindProjectByID(projectId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	rb := []ProjectRoleBinding{}
+	for _, m := range bindings {
+		b, err := NewProjectRoleBinding(m)
+		if err != nil {
+			return HttpBadParams(err)
+		}
+		rb = append(rb, b)
+	}
+
+	err = p.SetRoleBindings(rb)
+	if err != nil {
+		return HttpUpdateError(err)
+	}
+
+	payload := projectRoleBindings(p)
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
+
+func (s *RbacService) ListUserPermissions(ctx context.Context, userId string) (sdk.ImplResponse, error) {
+	u, err := FindUserByID(userId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	payload := UserPermissions(u)
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
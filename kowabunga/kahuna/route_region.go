@@ -101,18 +101,11 @@ func (s *RegionService) ListRegionZones(ctx context.Context, regionId string) (s
 		return HttpNotFound(err)
 	}
 
-	payload := r.Zones()
-	return HttpOK(payload)
+	return HttpListResources[Zone, sdk.Zone](ctx, MongoCollectionZoneName, listFilterByIDs(r.Zones()))
 }
 
 func (s *RegionService) ListRegions(ctx context.Context) (sdk.ImplResponse, error) {
-	regions := FindRegions()
-	var payload []string
-	for _, r := range regions {
-		payload = append(payload, r.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[Region, sdk.Region](ctx, MongoCollectionRegionName, nil)
 }
 
 func (s *RegionService) ReadRegion(ctx context.Context, regionId string) (sdk.ImplResponse, error) {
@@ -120,6 +113,7 @@ func (s *RegionService) ReadRegion(ctx context.Context, regionId string) (sdk.Im
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &r.Resource)
 
 	payload := r.Model()
 	LogHttpResponse(payload)
@@ -140,8 +134,17 @@ func (s *RegionService) UpdateRegion(ctx context.Context, regionId string, regio
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &r.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	// update region
-	r.Update(region.Name, region.Description, region.Domain)
+	err = r.Update(region.Name, region.Description, region.Domain)
+	if err != nil {
+		return HttpUpdateError(err)
+	}
 
 	payload := r.Model()
 	LogHttpResponse(payload)
@@ -336,8 +339,7 @@ func (s *RegionService) ListRegionKiwis(ctx context.Context, regionId string) (s
 		return HttpNotFound(err)
 	}
 
-	payload := r.Kiwis()
-	return HttpOK(payload)
+	return HttpListResources[Kiwi, sdk.Kiwi](ctx, MongoCollectionKiwiName, listFilterByIDs(r.Kiwis()))
 }
 
 func (s *RegionService) ListRegionVNets(ctx context.Context, regionId string) (sdk.ImplResponse, error) {
@@ -346,8 +348,7 @@ func (s *RegionService) ListRegionVNets(ctx context.Context, regionId string) (s
 		return HttpNotFound(err)
 	}
 
-	payload := r.VNets()
-	return HttpOK(payload)
+	return HttpListResources[VNet, sdk.VNet](ctx, MongoCollectionVNetName, listFilterByIDs(r.VNets()))
 }
 
 func (s *RegionService) ListRegionStorageNFSs(ctx context.Context, regionId, poolId string) (sdk.ImplResponse, error) {
@@ -356,8 +357,7 @@ func (s *RegionService) ListRegionStorageNFSs(ctx context.Context, regionId, poo
 		return HttpNotFound(err)
 	}
 
-	payload := r.Nfses()
-	return HttpOK(payload)
+	return HttpListResources[NFS, sdk.StorageNfs](ctx, MongoCollectionNfsName, listFilterByIDs(r.Nfses()))
 }
 
 func (s *RegionService) ListRegionStoragePools(ctx context.Context, regionId string) (sdk.ImplResponse, error) {
@@ -366,8 +366,7 @@ func (s *RegionService) ListRegionStoragePools(ctx context.Context, regionId str
 		return HttpNotFound(err)
 	}
 
-	payload := r.StoragePools()
-	return HttpOK(payload)
+	return HttpListResources[StoragePool, sdk.StoragePool](ctx, MongoCollectionStoragePoolName, listFilterByIDs(r.StoragePools()))
 }
 
 func (s *RegionService) SetRegionDefaultStorageNFS(ctx context.Context, regionId string, nfsId string) (sdk.ImplResponse, error) {
@@ -420,6 +419,5 @@ func (s *RegionService) ListRegionDnsRecords(ctx context.Context, regionId strin
 		return HttpNotFound(err)
 	}
 
-	payload := r.DnsRecords()
-	return HttpOK(payload)
+	return HttpListResources[DnsRecord, sdk.DnsRecord](ctx, MongoCollectionDnsRecordName, listFilterByIDs(r.DnsRecords()))
 }
@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/kowabunga-cloud/kowabunga/kowabunga/sdk"
+)
+
+// SearchAPIController binds http requests to the search service and writes the service results to the http response
+type SearchAPIController struct {
+	service      *SearchService
+	errorHandler sdk.ErrorHandler
+}
+
+func NewSearchRouter() sdk.Router {
+	return &SearchAPIController{
+		service:      &SearchService{},
+		errorHandler: sdk.DefaultErrorHandler,
+	}
+}
+
+// Routes returns all the api routes for the SearchAPIController
+func (c *SearchAPIController) Routes() sdk.Routes {
+	routes := sdk.Routes{}
+	for _, r := range c.OrderedRoutes() {
+		routes[r.Name] = r
+	}
+	return routes
+}
+
+// OrderedRoutes returns all the api routes in a deterministic order for the SearchAPIController
+func (c *SearchAPIController) OrderedRoutes() []sdk.Route {
+	return []sdk.Route{
+		{
+			Name:        "Search",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + "/search",
+			HandlerFunc: c.Search,
+		},
+	}
+}
+
+// Search -
+func (c *SearchAPIController) Search(w http.ResponseWriter, r *http.Request) {
+	queryParam := r.URL.Query().Get("q")
+	if queryParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "q"}, nil)
+		return
+	}
+	result, err := c.service.Search(r.Context(), queryParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+type SearchService struct{}
+
+func (s *SearchService) Search(ctx context.Context, query string) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("query", query))
+
+	q, err := ParseSearchQuery(query)
+	if err != nil {
+		return HttpBadParams(err)
+	}
+
+	payload, err := Search(q, ctxGetUserId(ctx), ctxGetSuperAdminRole(ctx), ctxGetTokenScope(ctx))
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
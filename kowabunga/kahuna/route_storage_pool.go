@@ -45,15 +45,14 @@ func (s *StoragePoolService) CreateTemplate(ctx context.Context, poolId string,
 		}
 	}
 
-	// create template
-	t, err := NewTemplate(p.String(), template.Name, template.Description, os, template.Source)
-	if err != nil {
-		return HttpServerError(err)
-	}
-
-	payload := t.Model()
-	LogHttpResponse(payload)
-	return HttpCreated(payload)
+	// create template, downloading source image can take a while
+	return HttpCreateMaybeAsync(ctx, "CreateTemplate", func(task *Task) (string, any, error) {
+		t, err := NewTemplate(p.String(), template.Name, template.Description, os, template.Source)
+		if err != nil {
+			return "", nil, err
+		}
+		return t.String(), t.Model(), nil
+	})
 }
 
 func (s *StoragePoolService) DeleteStoragePool(ctx context.Context, poolId string) (sdk.ImplResponse, error) {
@@ -83,8 +82,7 @@ func (s *StoragePoolService) ListStoragePoolTemplates(ctx context.Context, poolI
 		return HttpNotFound(err)
 	}
 
-	payload := p.Templates()
-	return HttpOK(payload)
+	return HttpListResources[Template, sdk.Template](ctx, MongoCollectionTemplateName, listFilterByIDs(p.Templates()))
 }
 
 func (s *StoragePoolService) ListStoragePoolVolumes(ctx context.Context, poolId string) (sdk.ImplResponse, error) {
@@ -93,18 +91,11 @@ func (s *StoragePoolService) ListStoragePoolVolumes(ctx context.Context, poolId
 		return HttpNotFound(err)
 	}
 
-	payload := p.Volumes()
-	return HttpOK(payload)
+	return HttpListResources[Volume, sdk.Volume](ctx, MongoCollectionVolumeName, listFilterByIDs(p.Volumes()))
 }
 
 func (s *StoragePoolService) ListStoragePools(ctx context.Context) (sdk.ImplResponse, error) {
-	pools := FindStoragePools()
-	var payload []string
-	for _, p := range pools {
-		payload = append(payload, p.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[StoragePool, sdk.StoragePool](ctx, MongoCollectionStoragePoolName, nil)
 }
 
 func (s *StoragePoolService) ReadStoragePool(ctx context.Context, poolId string) (sdk.ImplResponse, error) {
@@ -112,6 +103,7 @@ func (s *StoragePoolService) ReadStoragePool(ctx context.Context, poolId string)
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &p.Resource)
 
 	payload := p.Model()
 	LogHttpResponse(payload)
@@ -154,6 +146,12 @@ func (s *StoragePoolService) UpdateStoragePool(ctx context.Context, poolId strin
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &p.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	var price float32
 	currency := ""
 	if storagePool.Cost.Price != 0 {
@@ -162,7 +160,10 @@ func (s *StoragePoolService) UpdateStoragePool(ctx context.Context, poolId strin
 	}
 
 	// update pool
-	p.Update(storagePool.Name, storagePool.Description, storagePool.Pool, storagePool.CephAddress, int(storagePool.CephPort), storagePool.CephSecretUuid, price, currency, storagePool.Agents)
+	err = p.Update(storagePool.Name, storagePool.Description, storagePool.Pool, storagePool.CephAddress, int(storagePool.CephPort), storagePool.CephSecretUuid, price, currency, storagePool.Agents)
+	if err != nil {
+		return HttpUpdateError(err)
+	}
 
 	payload := p.Model()
 	LogHttpResponse(payload)
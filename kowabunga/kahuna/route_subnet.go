@@ -75,18 +75,11 @@ func (s *SubnetService) ListSubnetAdapters(ctx context.Context, subnetId string)
 		return HttpNotFound(err)
 	}
 
-	payload := sb.Adapters()
-	return HttpOK(payload)
+	return HttpListResources[Adapter, sdk.Adapter](ctx, MongoCollectionAdapterName, listFilterByIDs(sb.Adapters()))
 }
 
 func (s *SubnetService) ListSubnets(ctx context.Context) (sdk.ImplResponse, error) {
-	subnets := FindSubnets()
-	var payload []string
-	for _, s := range subnets {
-		payload = append(payload, s.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[Subnet, sdk.Subnet](ctx, MongoCollectionSubnetName, nil)
 }
 
 func (s *SubnetService) ReadSubnet(ctx context.Context, subnetId string) (sdk.ImplResponse, error) {
@@ -94,6 +87,7 @@ func (s *SubnetService) ReadSubnet(ctx context.Context, subnetId string) (sdk.Im
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &sb.Resource)
 
 	payload := sb.Model()
 	LogHttpResponse(payload)
@@ -114,10 +108,16 @@ func (s *SubnetService) UpdateSubnet(ctx context.Context, subnetId string, subne
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &sb.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	// update subnet
 	err = sb.Update(subnet.Name, subnet.Description, subnet.Gateway, subnet.Dns, subnet.Reserved, subnet.GwPool, subnet.ExtraRoutes, subnet.Application)
 	if err != nil {
-		return HttpServerError(err)
+		return HttpUpdateError(err)
 	}
 
 	payload := sb.Model()
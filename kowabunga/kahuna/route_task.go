@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/kowabunga-cloud/kowabunga/kowabunga/sdk"
+)
+
+// TaskAPIController binds http requests to the task service and writes the service results to the http response
+type TaskAPIController struct {
+	service      *TaskService
+	errorHandler sdk.ErrorHandler
+}
+
+func NewTaskRouter() sdk.Router {
+	return &TaskAPIController{
+		service:      &TaskService{},
+		errorHandler: sdk.DefaultErrorHandler,
+	}
+}
+
+// Routes returns all the api routes for the TaskAPIController
+func (c *TaskAPIController) Routes() sdk.Routes {
+	routes := sdk.Routes{}
+	for _, r := range c.OrderedRoutes() {
+		routes[r.Name] = r
+	}
+	return routes
+}
+
+// OrderedRoutes returns all the api routes in a deterministic order for the TaskAPIController
+func (c *TaskAPIController) OrderedRoutes() []sdk.Route {
+	return []sdk.Route{
+		{
+			Name:        "ListTasks",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + "/task",
+			HandlerFunc: c.ListTasks,
+		},
+		{
+			Name:        "ReadTask",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + "/task/{taskId}",
+			HandlerFunc: c.ReadTask,
+		},
+	}
+}
+
+// ListTasks -
+func (c *TaskAPIController) ListTasks(w http.ResponseWriter, r *http.Request) {
+	result, err := c.service.ListTasks(r.Context())
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ReadTask -
+func (c *TaskAPIController) ReadTask(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	taskIdParam := params["taskId"]
+	if taskIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "taskId"}, nil)
+		return
+	}
+	result, err := c.service.ReadTask(r.Context(), taskIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+type TaskService struct{}
+
+func (s *TaskService) ListTasks(ctx context.Context) (sdk.ImplResponse, error) {
+	// super-administrators can see everything, others only their own tasks
+	if ctxGetSuperAdminRole(ctx) {
+		return HttpListResources[Task, TaskModel](ctx, MongoCollectionTaskName, nil)
+	}
+
+	return HttpListResources[Task, TaskModel](ctx, MongoCollectionTaskName, listFilterByKey("user_id", ctxGetUserId(ctx)))
+}
+
+func (s *TaskService) ReadTask(ctx context.Context, taskId string) (sdk.ImplResponse, error) {
+	t, err := FindTaskByID(taskId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+	ctxTrackRevision(ctx, &t.Resource)
+
+	// one can only poll for its own tasks
+	if !ctxGetSuperAdminRole(ctx) && !t.IsOwnedBy(ctxGetUserId(ctx)) {
+		return HttpForbidden(nil)
+	}
+
+	payload := t.Model()
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
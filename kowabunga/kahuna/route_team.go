@@ -65,13 +65,7 @@ func (s *TeamService) DeleteTeam(ctx context.Context, teamId string) (sdk.ImplRe
 }
 
 func (s *TeamService) ListTeams(ctx context.Context) (sdk.ImplResponse, error) {
-	teams := FindTeams()
-	var payload []string
-	for _, g := range teams {
-		payload = append(payload, g.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[Team, sdk.Team](ctx, MongoCollectionTeamName, nil)
 }
 
 func (s *TeamService) ReadTeam(ctx context.Context, teamId string) (sdk.ImplResponse, error) {
@@ -79,6 +73,7 @@ func (s *TeamService) ReadTeam(ctx context.Context, teamId string) (sdk.ImplResp
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &g.Resource)
 
 	payload := g.Model()
 	LogHttpResponse(payload)
@@ -99,8 +94,17 @@ func (s *TeamService) UpdateTeam(ctx context.Context, teamId string, team sdk.Te
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &g.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	// update team
-	g.Update(team.Name, team.Description, team.Users)
+	err = g.Update(team.Name, team.Description, team.Users)
+	if err != nil {
+		return HttpUpdateError(err)
+	}
 
 	payload := g.Model()
 	LogHttpResponse(payload)
@@ -35,13 +35,7 @@ func (s *TemplateService) DeleteTemplate(ctx context.Context, templateId string)
 }
 
 func (s *TemplateService) ListTemplates(ctx context.Context) (sdk.ImplResponse, error) {
-	templates := FindTemplates()
-	var payload []string
-	for _, t := range templates {
-		payload = append(payload, t.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[Template, sdk.Template](ctx, MongoCollectionTemplateName, nil)
 }
 
 func (s *TemplateService) ReadTemplate(ctx context.Context, templateId string) (sdk.ImplResponse, error) {
@@ -49,6 +43,7 @@ func (s *TemplateService) ReadTemplate(ctx context.Context, templateId string) (
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &t.Resource)
 
 	payload := t.Model()
 	LogHttpResponse(payload)
@@ -69,8 +64,17 @@ func (s *TemplateService) UpdateTemplate(ctx context.Context, templateId string,
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &t.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	// update template
-	t.Update(template.Name, template.Description)
+	err = t.Update(template.Name, template.Description)
+	if err != nil {
+		return HttpUpdateError(err)
+	}
 
 	payload := t.Model()
 	LogHttpResponse(payload)
@@ -35,13 +35,7 @@ func (s *TokenService) DeleteApiToken(ctx context.Context, tokenId string) (sdk.
 }
 
 func (s *TokenService) ListApiTokens(ctx context.Context) (sdk.ImplResponse, error) {
-	tokens := FindTokens()
-	var payload []string
-	for _, t := range tokens {
-		payload = append(payload, t.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[Token, sdk.ApiToken](ctx, MongoCollectionTokenName, nil)
 }
 
 func (s *TokenService) ReadApiToken(ctx context.Context, tokenId string) (sdk.ImplResponse, error) {
@@ -49,6 +43,7 @@ func (s *TokenService) ReadApiToken(ctx context.Context, tokenId string) (sdk.Im
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &t.Resource)
 
 	payload := t.Model()
 	LogHttpResponse(payload)
@@ -69,10 +64,16 @@ func (s *TokenService) UpdateApiToken(ctx context.Context, tokenId string, apiTo
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &t.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	// update token
 	err = t.Update(apiToken.Name, apiToken.Description, apiToken.Expire, apiToken.ExpirationDate)
 	if err != nil {
-		return HttpServerError(err)
+		return HttpUpdateError(err)
 	}
 
 	payload := t.Model()
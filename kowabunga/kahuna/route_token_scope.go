@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/kowabunga-cloud/kowabunga/kowabunga/sdk"
+)
+
+// TokenScopeAPIController binds http requests to the user's API token scope service and writes the service results to the http response
+type TokenScopeAPIController struct {
+	service      *TokenScopeService
+	errorHandler sdk.ErrorHandler
+}
+
+func NewTokenScopeRouter() sdk.Router {
+	return &TokenScopeAPIController{
+		service:      &TokenScopeService{},
+		errorHandler: sdk.DefaultErrorHandler,
+	}
+}
+
+// Routes returns all the api routes for the TokenScopeAPIController
+func (c *TokenScopeAPIController) Routes() sdk.Routes {
+	routes := sdk.Routes{}
+	for _, r := range c.OrderedRoutes() {
+		routes[r.Name] = r
+	}
+	return routes
+}
+
+// OrderedRoutes returns all the api routes in a deterministic order for the TokenScopeAPIController
+func (c *TokenScopeAPIController) OrderedRoutes() []sdk.Route {
+	return []sdk.Route{
+		{
+			Name:        "GetUserApiTokenScope",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + "/user/{userId}/token/scope",
+			HandlerFunc: c.GetUserApiTokenScope,
+		},
+		{
+			Name:        "SetUserApiTokenScope",
+			Method:      http.MethodPut,
+			Pattern:     SdkBaseRoute + "/user/{userId}/token/scope",
+			HandlerFunc: c.SetUserApiTokenScope,
+		},
+	}
+}
+
+// GetUserApiTokenScope -
+func (c *TokenScopeAPIController) GetUserApiTokenScope(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userIdParam := params["userId"]
+	if userIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "userId"}, nil)
+		return
+	}
+	result, err := c.service.GetUserApiTokenScope(r.Context(), userIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// SetUserApiTokenScope -
+func (c *TokenScopeAPIController) SetUserApiTokenScope(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userIdParam := params["userId"]
+	if userIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "userId"}, nil)
+		return
+	}
+	var scopeParam TokenScopeModel
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&scopeParam); err != nil {
+		c.errorHandler(w, r, &sdk.ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.SetUserApiTokenScope(r.Context(), userIdParam, scopeParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+type TokenScopeService struct{}
+
+func (s *TokenScopeService) GetUserApiTokenScope(ctx context.Context, userId string) (sdk.ImplResponse, error) {
+	u, err := FindUserByID(userId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	t, err := FindUserApiToken(u)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	payload := t.Scope.Model()
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
+
+func (s *TokenScopeService) SetUserApiTokenScope(ctx context.Context, userId string, scope TokenScopeModel) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("userId", userId), RA("scope", scope))
+
+	u, err := FindUserByID(userId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	t, err := FindUserApiToken(u)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	ts, err := NewTokenScope(scope)
+	if err != nil {
+		return HttpBadParams(err)
+	}
+
+	err = t.SetScope(ts)
+	if err != nil {
+		return HttpUpdateError(err)
+	}
+
+	payload := t.Scope.Model()
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
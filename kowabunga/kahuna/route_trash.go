@@ -0,0 +1,396 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/kowabunga-cloud/kowabunga/kowabunga/sdk"
+)
+
+// TrashAPIController binds http requests to the trash service and writes the service results to the http response
+type TrashAPIController struct {
+	service      *TrashService
+	errorHandler sdk.ErrorHandler
+}
+
+func NewTrashRouter() sdk.Router {
+	return &TrashAPIController{
+		service:      &TrashService{},
+		errorHandler: sdk.DefaultErrorHandler,
+	}
+}
+
+// Routes returns all the api routes for the TrashAPIController
+func (c *TrashAPIController) Routes() sdk.Routes {
+	routes := sdk.Routes{}
+	for _, r := range c.OrderedRoutes() {
+		routes[r.Name] = r
+	}
+	return routes
+}
+
+// OrderedRoutes returns all the api routes in a deterministic order for the TrashAPIController
+func (c *TrashAPIController) OrderedRoutes() []sdk.Route {
+	return []sdk.Route{
+		{
+			Name:        "ListTrash",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + "/trash",
+			HandlerFunc: c.ListTrash,
+		},
+		{
+			Name:        "ListProjectTrash",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + "/project/{projectId}/trash",
+			HandlerFunc: c.ListProjectTrash,
+		},
+		{
+			Name:        "RestoreTrashedKompute",
+			Method:      http.MethodPost,
+			Pattern:     SdkBaseRoute + "/trash/kompute/{komputeId}/restore",
+			HandlerFunc: c.RestoreTrashedKompute,
+		},
+		{
+			Name:        "PurgeTrashedKompute",
+			Method:      http.MethodDelete,
+			Pattern:     SdkBaseRoute + "/trash/kompute/{komputeId}",
+			HandlerFunc: c.PurgeTrashedKompute,
+		},
+		{
+			Name:        "RestoreTrashedInstance",
+			Method:      http.MethodPost,
+			Pattern:     SdkBaseRoute + "/trash/instance/{instanceId}/restore",
+			HandlerFunc: c.RestoreTrashedInstance,
+		},
+		{
+			Name:        "PurgeTrashedInstance",
+			Method:      http.MethodDelete,
+			Pattern:     SdkBaseRoute + "/trash/instance/{instanceId}",
+			HandlerFunc: c.PurgeTrashedInstance,
+		},
+		{
+			Name:        "RestoreTrashedVolume",
+			Method:      http.MethodPost,
+			Pattern:     SdkBaseRoute + "/trash/volume/{volumeId}/restore",
+			HandlerFunc: c.RestoreTrashedVolume,
+		},
+		{
+			Name:        "PurgeTrashedVolume",
+			Method:      http.MethodDelete,
+			Pattern:     SdkBaseRoute + "/trash/volume/{volumeId}",
+			HandlerFunc: c.PurgeTrashedVolume,
+		},
+	}
+}
+
+// ListTrash -
+func (c *TrashAPIController) ListTrash(w http.ResponseWriter, r *http.Request) {
+	result, err := c.service.ListTrash(r.Context())
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ListProjectTrash -
+func (c *TrashAPIController) ListProjectTrash(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	projectIdParam := params["projectId"]
+	if projectIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "projectId"}, nil)
+		return
+	}
+	result, err := c.service.ListProjectTrash(r.Context(), projectIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// RestoreTrashedKompute -
+func (c *TrashAPIController) RestoreTrashedKompute(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	komputeIdParam := params["komputeId"]
+	if komputeIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "komputeId"}, nil)
+		return
+	}
+	result, err := c.service.RestoreTrashedKompute(r.Context(), komputeIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// PurgeTrashedKompute -
+func (c *TrashAPIController) PurgeTrashedKompute(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	komputeIdParam := params["komputeId"]
+	if komputeIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "komputeId"}, nil)
+		return
+	}
+	result, err := c.service.PurgeTrashedKompute(r.Context(), komputeIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// RestoreTrashedInstance -
+func (c *TrashAPIController) RestoreTrashedInstance(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	instanceIdParam := params["instanceId"]
+	if instanceIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "instanceId"}, nil)
+		return
+	}
+	result, err := c.service.RestoreTrashedInstance(r.Context(), instanceIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// PurgeTrashedInstance -
+func (c *TrashAPIController) PurgeTrashedInstance(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	instanceIdParam := params["instanceId"]
+	if instanceIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "instanceId"}, nil)
+		return
+	}
+	result, err := c.service.PurgeTrashedInstance(r.Context(), instanceIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// RestoreTrashedVolume -
+func (c *TrashAPIController) RestoreTrashedVolume(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	volumeIdParam := params["volumeId"]
+	if volumeIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "volumeId"}, nil)
+		return
+	}
+	result, err := c.service.RestoreTrashedVolume(r.Context(), volumeIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// PurgeTrashedVolume -
+func (c *TrashAPIController) PurgeTrashedVolume(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	volumeIdParam := params["volumeId"]
+	if volumeIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "volumeId"}, nil)
+		return
+	}
+	result, err := c.service.PurgeTrashedVolume(r.Context(), volumeIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+type TrashService struct{}
+
+func (s *TrashService) ListTrash(ctx context.Context) (sdk.ImplResponse, error) {
+	// platform-wide trash is restricted to super-administrators only
+	if !ctxGetSuperAdminRole(ctx) {
+		return HttpForbidden(nil)
+	}
+
+	payload, err := FindTrash("")
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
+
+func (s *TrashService) ListProjectTrash(ctx context.Context, projectId string) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("projectId", projectId))
+
+	p, err := FindProjectByID(projectId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	payload, err := FindTrash(p.String())
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
+
+func (s *TrashService) RestoreTrashedKompute(ctx context.Context, komputeId string) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("komputeId", komputeId))
+
+	k, err := FindTrashedKompute(komputeId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	// resources trashed along with their parent can only be restored with it
+	if k.Trashed.ParentID != "" {
+		return HttpConflict(nil)
+	}
+
+	err = k.RestoreFromTrash()
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	payload := k.Model()
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
+
+func (s *TrashService) PurgeTrashedKompute(ctx context.Context, komputeId string) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("komputeId", komputeId))
+
+	k, err := FindTrashedKompute(komputeId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	// resources trashed along with their parent can only be purged with it
+	if k.Trashed.ParentID != "" {
+		return HttpConflict(nil)
+	}
+
+	err = k.Delete()
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	return HttpOK(nil)
+}
+
+func (s *TrashService) RestoreTrashedInstance(ctx context.Context, instanceId string) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("instanceId", instanceId))
+
+	i, err := FindTrashedInstance(instanceId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	// resources trashed along with their parent can only be restored with it
+	if i.Trashed.ParentID != "" {
+		return HttpConflict(nil)
+	}
+
+	err = i.RestoreFromTrash()
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	payload := i.Model()
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
+
+func (s *TrashService) PurgeTrashedInstance(ctx context.Context, instanceId string) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("instanceId", instanceId))
+
+	i, err := FindTrashedInstance(instanceId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	// resources trashed along with their parent can only be purged with it
+	if i.Trashed.ParentID != "" {
+		return HttpConflict(nil)
+	}
+
+	err = i.Delete()
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	return HttpOK(nil)
+}
+
+func (s *TrashService) RestoreTrashedVolume(ctx context.Context, volumeId string) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("volumeId", volumeId))
+
+	v, err := FindTrashedVolume(volumeId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	// resources trashed along with their parent can only be restored with it
+	if v.Trashed.ParentID != "" {
+		return HttpConflict(nil)
+	}
+
+	err = v.RestoreFromTrash()
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	payload := v.Model()
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
+
+func (s *TrashService) PurgeTrashedVolume(ctx context.Context, volumeId string) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("volumeId", volumeId))
+
+	v, err := FindTrashedVolume(volumeId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	// resources trashed along with their parent can only be purged with it
+	if v.Trashed.ParentID != "" {
+		return HttpConflict(nil)
+	}
+
+	err = v.Delete()
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	return HttpOK(nil)
+}
@@ -39,6 +39,13 @@ func (s *UserService) Login(ctx context.Context, userCredentials sdk.UserCredent
 		return HttpUnauthorized(err)
 	}
 
+	// organization may enforce its own authentication scheme
+	o, err := u.Organization()
+	if err == nil && o.IsOidcEnabled() {
+		klog.Errorf("User %s must authenticate through organization's OpenID Connect provider", u.String())
+		return HttpUnauthorized(nil)
+	}
+
 	// check if password matches registered one
 	err = u.Verify(userCredentials.Password)
 	if err != nil {
@@ -120,13 +127,7 @@ func (s *UserService) DeleteUser(ctx context.Context, userId string) (sdk.ImplRe
 }
 
 func (s *UserService) ListUsers(ctx context.Context) (sdk.ImplResponse, error) {
-	users := FindUsers()
-	var payload []string
-	for _, u := range users {
-		payload = append(payload, u.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[User, sdk.User](ctx, MongoCollectionUserName, nil)
 }
 
 func (s *UserService) ReadUser(ctx context.Context, userId string) (sdk.ImplResponse, error) {
@@ -134,6 +135,7 @@ func (s *UserService) ReadUser(ctx context.Context, userId string) (sdk.ImplResp
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &u.Resource)
 
 	payload := u.Model()
 	LogHttpResponse(payload)
@@ -207,7 +209,7 @@ func (s *UserService) SetUserApiToken(ctx context.Context, userId string, expire
 	var t *Token
 
 	tokenName := fmt.Sprintf("%s-api-key", u.Name)
-	t, err = FindTokenByName(tokenName)
+	t, err = FindUserApiToken(u)
 	if err != nil {
 		// can't find any token, will create a new one
 		t, err = NewUserToken(userId, tokenName, "", expire, expirationDate)
@@ -245,8 +247,23 @@ func (s *UserService) UpdateUser(ctx context.Context, userId string, user sdk.Us
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &u.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
+	err = userUpdateAllowed(ctx, u, user)
+	if err != nil {
+		klog.Errorf("User %s is not allowed to update user %s: %v", ctxGetUserId(ctx), u.String(), err)
+		return HttpForbidden(err)
+	}
+
 	// update user
-	u.Update(user.Name, user.Description, user.Email, user.Role, user.Notifications)
+	err = u.Update(user.Name, user.Description, user.Email, user.Role, user.Notifications)
+	if err != nil {
+		return HttpUpdateError(err)
+	}
 
 	payload := u.Model()
 	LogHttpResponse(payload)
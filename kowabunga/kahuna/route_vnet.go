@@ -76,18 +76,11 @@ func (s *VNetService) ListVNetSubnets(ctx context.Context, vnetId string) (sdk.I
 		return HttpNotFound(err)
 	}
 
-	payload := v.Subnets()
-	return HttpOK(payload)
+	return HttpListResources[Subnet, sdk.Subnet](ctx, MongoCollectionSubnetName, listFilterByIDs(v.Subnets()))
 }
 
 func (s *VNetService) ListVNets(ctx context.Context) (sdk.ImplResponse, error) {
-	vnets := FindVNets()
-	var payload []string
-	for _, v := range vnets {
-		payload = append(payload, v.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[VNet, sdk.VNet](ctx, MongoCollectionVNetName, nil)
 }
 
 func (s *VNetService) ReadVNet(ctx context.Context, vnetId string) (sdk.ImplResponse, error) {
@@ -95,6 +88,7 @@ func (s *VNetService) ReadVNet(ctx context.Context, vnetId string) (sdk.ImplResp
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &v.Resource)
 
 	payload := v.Model()
 	LogHttpResponse(payload)
@@ -137,8 +131,17 @@ func (s *VNetService) UpdateVNet(ctx context.Context, vnetId string, vNet sdk.VN
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &v.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	// update vnet
-	v.Update(vNet.Name, vNet.Description, int(vNet.Vlan), vNet.Interface)
+	err = v.Update(vNet.Name, vNet.Description, int(vNet.Vlan), vNet.Interface)
+	if err != nil {
+		return HttpUpdateError(err)
+	}
 
 	payload := v.Model()
 	LogHttpResponse(payload)
@@ -25,8 +25,8 @@ func (s *VolumeService) DeleteVolume(ctx context.Context, volumeId string) (sdk.
 		return HttpNotFound(err)
 	}
 
-	// remove volume
-	err = v.Delete()
+	// move volume to trash, it'll be purged later on
+	err = v.MoveToTrash("")
 	if err != nil {
 		return HttpServerError(err)
 	}
@@ -35,13 +35,7 @@ func (s *VolumeService) DeleteVolume(ctx context.Context, volumeId string) (sdk.
 }
 
 func (s *VolumeService) ListVolumes(ctx context.Context) (sdk.ImplResponse, error) {
-	volumes := FindVolumes()
-	var payload []string
-	for _, v := range volumes {
-		payload = append(payload, v.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[Volume, sdk.Volume](ctx, MongoCollectionVolumeName, nil)
 }
 
 func (s *VolumeService) ReadVolume(ctx context.Context, volumeId string) (sdk.ImplResponse, error) {
@@ -49,6 +43,7 @@ func (s *VolumeService) ReadVolume(ctx context.Context, volumeId string) (sdk.Im
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &v.Resource)
 
 	payload := v.Model()
 	LogHttpResponse(payload)
@@ -70,6 +65,12 @@ func (s *VolumeService) UpdateVolume(ctx context.Context, volumeId string, volum
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &v.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	// find associated project
 	prj, err := v.Project()
 	if err != nil {
@@ -87,7 +88,7 @@ func (s *VolumeService) UpdateVolume(ctx context.Context, volumeId string, volum
 	// update volume
 	err = v.Update(volume.Name, volume.Description, volume.Size)
 	if err != nil {
-		return HttpServerError(err)
+		return HttpUpdateError(err)
 	}
 
 	payload := v.Model()
@@ -0,0 +1,447 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/kowabunga-cloud/kowabunga/kowabunga/sdk"
+)
+
+// WebhookAPIController binds http requests to the webhook service and writes the service results to the http response
+type WebhookAPIController struct {
+	service      *WebhookService
+	errorHandler sdk.ErrorHandler
+}
+
+func NewWebhookRouter() sdk.Router {
+	return &WebhookAPIController{
+		service:      &WebhookService{},
+		errorHandler: sdk.DefaultErrorHandler,
+	}
+}
+
+// Routes returns all the api routes for the WebhookAPIController
+func (c *WebhookAPIController) Routes() sdk.Routes {
+	routes := sdk.Routes{}
+	for _, r := range c.OrderedRoutes() {
+		routes[r.Name] = r
+	}
+	return routes
+}
+
+// OrderedRoutes returns all the api routes in a deterministic order for the WebhookAPIController
+func (c *WebhookAPIController) OrderedRoutes() []sdk.Route {
+	return []sdk.Route{
+		{
+			Name:        "ListWebhooks",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + "/webhook",
+			HandlerFunc: c.ListWebhooks,
+		},
+		{
+			Name:        "CreateWebhook",
+			Method:      http.MethodPost,
+			Pattern:     SdkBaseRoute + "/webhook",
+			HandlerFunc: c.CreateWebhook,
+		},
+		{
+			Name:        "ListProjectWebhooks",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + "/project/{projectId}/webhook",
+			HandlerFunc: c.ListProjectWebhooks,
+		},
+		{
+			Name:        "CreateProjectWebhook",
+			Method:      http.MethodPost,
+			Pattern:     SdkBaseRoute + "/project/{projectId}/webhook",
+			HandlerFunc: c.CreateProjectWebhook,
+		},
+		{
+			Name:        "ReadWebhook",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + "/webhook/{webhookId}",
+			HandlerFunc: c.ReadWebhook,
+		},
+		{
+			Name:        "UpdateWebhook",
+			Method:      http.MethodPut,
+			Pattern:     SdkBaseRoute + "/webhook/{webhookId}",
+			HandlerFunc: c.UpdateWebhook,
+		},
+		{
+			Name:        "DeleteWebhook",
+			Method:      http.MethodDelete,
+			Pattern:     SdkBaseRoute + "/webhook/{webhookId}",
+			HandlerFunc: c.DeleteWebhook,
+		},
+		{
+			Name:        "ListWebhookDeliveries",
+			Method:      http.MethodGet,
+			Pattern:     SdkBaseRoute + "/webhook/{webhookId}/delivery",
+			HandlerFunc: c.ListWebhookDeliveries,
+		},
+		{
+			Name:        "RetryWebhookDelivery",
+			Method:      http.MethodPost,
+			Pattern:     SdkBaseRoute + "/webhook/{webhookId}/delivery/{deliveryId}/retry",
+			HandlerFunc: c.RetryWebhookDelivery,
+		},
+	}
+}
+
+// ListWebhooks -
+func (c *WebhookAPIController) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	result, err := c.service.ListWebhooks(r.Context())
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// CreateWebhook -
+func (c *WebhookAPIController) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var webhookParam WebhookModel
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&webhookParam); err != nil {
+		c.errorHandler(w, r, &sdk.ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.CreateWebhook(r.Context(), webhookParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ListProjectWebhooks -
+func (c *WebhookAPIController) ListProjectWebhooks(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	projectIdParam := params["projectId"]
+	if projectIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "projectId"}, nil)
+		return
+	}
+	result, err := c.service.ListProjectWebhooks(r.Context(), projectIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// CreateProjectWebhook -
+func (c *WebhookAPIController) CreateProjectWebhook(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	projectIdParam := params["projectId"]
+	if projectIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "projectId"}, nil)
+		return
+	}
+	var webhookParam WebhookModel
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&webhookParam); err != nil {
+		c.errorHandler(w, r, &sdk.ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.CreateProjectWebhook(r.Context(), projectIdParam, webhookParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ReadWebhook -
+func (c *WebhookAPIController) ReadWebhook(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	webhookIdParam := params["webhookId"]
+	if webhookIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "webhookId"}, nil)
+		return
+	}
+	result, err := c.service.ReadWebhook(r.Context(), webhookIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// UpdateWebhook -
+func (c *WebhookAPIController) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	webhookIdParam := params["webhookId"]
+	if webhookIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "webhookId"}, nil)
+		return
+	}
+	var webhookParam WebhookModel
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&webhookParam); err != nil {
+		c.errorHandler(w, r, &sdk.ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.UpdateWebhook(r.Context(), webhookIdParam, webhookParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// DeleteWebhook -
+func (c *WebhookAPIController) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	webhookIdParam := params["webhookId"]
+	if webhookIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "webhookId"}, nil)
+		return
+	}
+	result, err := c.service.DeleteWebhook(r.Context(), webhookIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// ListWebhookDeliveries -
+func (c *WebhookAPIController) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	webhookIdParam := params["webhookId"]
+	if webhookIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "webhookId"}, nil)
+		return
+	}
+	result, err := c.service.ListWebhookDeliveries(r.Context(), webhookIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// RetryWebhookDelivery -
+func (c *WebhookAPIController) RetryWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	webhookIdParam := params["webhookId"]
+	if webhookIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "webhookId"}, nil)
+		return
+	}
+	deliveryIdParam := params["deliveryId"]
+	if deliveryIdParam == "" {
+		c.errorHandler(w, r, &sdk.RequiredError{Field: "deliveryId"}, nil)
+		return
+	}
+	result, err := c.service.RetryWebhookDelivery(r.Context(), webhookIdParam, deliveryIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = sdk.EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+type WebhookService struct{}
+
+func hideWebhookSecrets(webhook WebhookModel) WebhookModel {
+	if webhook.Secret != "" {
+		webhook.Secret = "<redacted>"
+	}
+	return webhook
+}
+
+func (s *WebhookService) createWebhook(projectId string, webhook WebhookModel) (sdk.ImplResponse, error) {
+	// check for params
+	if webhook.Name == "" || webhook.URL == "" {
+		return HttpBadParams(nil)
+	}
+
+	w, err := NewWebhook(projectId, webhook.Name, webhook.Description, webhook.URL, webhook.Secret, webhook.Events, webhook.Disabled)
+	if err != nil {
+		return HttpBadParams(err)
+	}
+
+	// signing secret is only exposed once, at creation
+	payload := w.Model()
+	LogHttpResponse(payload)
+	payload.Secret = w.Secret
+	return HttpCreated(payload)
+}
+
+func (s *WebhookService) ListWebhooks(ctx context.Context) (sdk.ImplResponse, error) {
+	// global webhooks are restricted to super-administrators only
+	if !ctxGetSuperAdminRole(ctx) {
+		return HttpForbidden(nil)
+	}
+
+	return HttpListResources[Webhook, WebhookModel](ctx, MongoCollectionWebhookName, nil)
+}
+
+func (s *WebhookService) CreateWebhook(ctx context.Context, webhook WebhookModel) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("webhook", hideWebhookSecrets(webhook)))
+
+	if !ctxGetSuperAdminRole(ctx) {
+		return HttpForbidden(nil)
+	}
+
+	// global subscription, unless project is specified
+	if webhook.ProjectId != "" {
+		_, err := FindProjectByID(webhook.ProjectId)
+		if err != nil {
+			return HttpNotFound(err)
+		}
+	}
+
+	return s.createWebhook(webhook.ProjectId, webhook)
+}
+
+func (s *WebhookService) ListProjectWebhooks(ctx context.Context, projectId string) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("projectId", projectId))
+
+	p, err := FindProjectByID(projectId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	return HttpListResources[Webhook, WebhookModel](ctx, MongoCollectionWebhookName, listFilterByKey("project_id", p.String()))
+}
+
+func (s *WebhookService) CreateProjectWebhook(ctx context.Context, projectId string, webhook WebhookModel) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("projectId", projectId), RA("webhook", hideWebhookSecrets(webhook)))
+
+	p, err := FindProjectByID(projectId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	return s.createWebhook(p.String(), webhook)
+}
+
+func (s *WebhookService) ReadWebhook(ctx context.Context, webhookId string) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("webhookId", webhookId))
+
+	w, err := FindWebhookByID(webhookId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+	ctxTrackRevision(ctx, &w.Resource)
+
+	payload := w.Model()
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
+
+func (s *WebhookService) UpdateWebhook(ctx context.Context, webhookId string, webhook WebhookModel) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("webhookId", webhookId), RA("webhook", hideWebhookSecrets(webhook)))
+
+	// check for params
+	if webhook.Name == "" || webhook.URL == "" {
+		return HttpBadParams(nil)
+	}
+
+	w, err := FindWebhookByID(webhookId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &w.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
+	err = w.Update(webhook.Name, webhook.Description, webhook.URL, webhook.Secret, webhook.Events, webhook.Disabled)
+	if IsDbConflictError(err) {
+		return HttpConflict(err)
+	}
+	if err != nil {
+		return HttpBadParams(err)
+	}
+
+	payload := w.Model()
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, webhookId string) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("webhookId", webhookId))
+
+	w, err := FindWebhookByID(webhookId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	err = w.Delete()
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	return HttpOK(nil)
+}
+
+func (s *WebhookService) ListWebhookDeliveries(ctx context.Context, webhookId string) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("webhookId", webhookId))
+
+	w, err := FindWebhookByID(webhookId)
+	if err != nil {
+		return HttpNotFound(err)
+	}
+
+	return HttpListResources[WebhookDelivery, WebhookDeliveryModel](ctx, MongoCollectionWebhookDeliveryName, listFilterByKey("webhook_id", w.String()))
+}
+
+func (s *WebhookService) RetryWebhookDelivery(ctx context.Context, webhookId, deliveryId string) (sdk.ImplResponse, error) {
+	LogHttpRequest(RA("webhookId", webhookId), RA("deliveryId", deliveryId))
+
+	d, err := FindWebhookDeliveryByID(deliveryId)
+	if err != nil || d.WebhookID != webhookId {
+		return HttpNotFound(err)
+	}
+
+	// only dead-lettered deliveries can be retried, pending ones will be anyway
+	if !d.IsDead() {
+		return HttpConflict(nil)
+	}
+
+	err = d.Retry()
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	payload := d.Model()
+	LogHttpResponse(payload)
+	return HttpOK(payload)
+}
@@ -99,18 +99,11 @@ func (s *ZoneService) ListZoneKaktuses(ctx context.Context, zoneId string) (sdk.
 		return HttpNotFound(err)
 	}
 
-	payload := z.Kaktuses()
-	return HttpOK(payload)
+	return HttpListResources[Kaktus, sdk.Kaktus](ctx, MongoCollectionKaktusName, listFilterByIDs(z.Kaktuses()))
 }
 
 func (s *ZoneService) ListZones(ctx context.Context) (sdk.ImplResponse, error) {
-	zones := FindZones()
-	var payload []string
-	for _, z := range zones {
-		payload = append(payload, z.String())
-	}
-
-	return HttpOK(payload)
+	return HttpListResources[Zone, sdk.Zone](ctx, MongoCollectionZoneName, nil)
 }
 
 func (s *ZoneService) ReadZone(ctx context.Context, zoneId string) (sdk.ImplResponse, error) {
@@ -118,6 +111,7 @@ func (s *ZoneService) ReadZone(ctx context.Context, zoneId string) (sdk.ImplResp
 	if err != nil {
 		return HttpNotFound(err)
 	}
+	ctxTrackRevision(ctx, &z.Resource)
 
 	payload := z.Model()
 	LogHttpResponse(payload)
@@ -138,8 +132,17 @@ func (s *ZoneService) UpdateZone(ctx context.Context, zoneId string, zone sdk.Zo
 		return HttpNotFound(err)
 	}
 
+	// ensure resource hasn't been modified since client read it
+	err = ctxPinRevision(ctx, &z.Resource)
+	if err != nil {
+		return HttpPreconditionFailed(err)
+	}
+
 	// update zone
-	z.Update(zone.Name, zone.Description)
+	err = z.Update(zone.Name, zone.Description)
+	if err != nil {
+		return HttpUpdateError(err)
+	}
 
 	payload := z.Model()
 	LogHttpResponse(payload)
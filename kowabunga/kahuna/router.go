@@ -28,6 +28,8 @@ const (
 	HttpHeaderAuthApiKey       = "X-API-Key"
 	HttpHeaderAuthorization    = "Authorization"
 	HttpHeaderAuthBearerPrefix = "Bearer "
+	HttpHeaderPrefer           = "Prefer"
+	HttpPreferRespondAsync     = "respond-async"
 	HttpApiMethodAll           = "*"
 )
 
@@ -38,6 +40,8 @@ type ApiOperation struct {
 
 var noAuthApiOperations = []string{
 	"Login",
+	"OidcCallback",
+	"OidcLogin",
 	"ResetPassword",
 }
 
@@ -70,6 +74,26 @@ var userAllowedRoutes = []ApiOperation{
 		Route:  "/zone/[A-Za-z0-9]*$",
 		Method: "GET",
 	},
+	{
+		Route:  "/organization$",
+		Method: "GET",
+	},
+	{
+		Route:  "/event$",
+		Method: "GET",
+	},
+	{
+		Route:  "/search(\\?.*)?$",
+		Method: "GET",
+	},
+	{
+		Route:  "/task$",
+		Method: "GET",
+	},
+	{
+		Route:  "/task/[A-Za-z0-9]*$",
+		Method: "GET",
+	},
 }
 
 var projectAdminAllowedRoutes = []ApiOperation{
@@ -171,6 +195,30 @@ func metadataHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+var routePathVarRegex = regexp.MustCompile(`{([A-Za-z0-9]+)}`)
+
+// routeTargetVar returns the name and value of the last path variable of the
+// request's route template (e.g. {instanceId}), i.e. the targeted resource
+func routeTargetVar(r *http.Request) (string, string) {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "", ""
+	}
+
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return "", ""
+	}
+
+	vars := routePathVarRegex.FindAllStringSubmatch(tpl, -1)
+	if len(vars) == 0 {
+		return "", ""
+	}
+
+	name := vars[len(vars)-1][1]
+	return name, mux.Vars(r)[name]
+}
+
 func loggingMiddleware(next http.Handler, name string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -192,6 +240,9 @@ const (
 	HttpRequestContextSuperAdminRole   = "superAdminRole"
 	HttpRequestContextProjectAdminRole = "projectAdminRole"
 	HttpRequestContextAuthMethod       = "authMethod"
+	HttpRequestContextAsync            = "async"
+	HttpRequestContextListQuery        = "listQuery"
+	HttpRequestContextTokenScope       = "tokenScope"
 )
 
 func ctxSetUserId(ctx context.Context, value string) context.Context {
@@ -218,6 +269,39 @@ func ctxGetAuthMethod(ctx context.Context) string {
 	return value.(string)
 }
 
+func ctxSetAsync(ctx context.Context) context.Context {
+	return ctxSet(ctx, HttpRequestContextAsync, true)
+}
+
+func ctxGetAsync(ctx context.Context) bool {
+	value := ctxGet(ctx, HttpRequestContextAsync)
+	return value != nil
+}
+
+func ctxSetListQuery(ctx context.Context, q *ListQuery) context.Context {
+	return ctxSet(ctx, HttpRequestContextListQuery, q)
+}
+
+func ctxGetListQuery(ctx context.Context) *ListQuery {
+	value := ctxGet(ctx, HttpRequestContextListQuery)
+	if value == nil {
+		return NewListQuery()
+	}
+	return value.(*ListQuery)
+}
+
+func ctxSetTokenScope(ctx context.Context, scope *TokenScope) context.Context {
+	return ctxSet(ctx, HttpRequestContextTokenScope, scope)
+}
+
+func ctxGetTokenScope(ctx context.Context) *TokenScope {
+	value := ctxGet(ctx, HttpRequestContextTokenScope)
+	if value == nil {
+		return nil
+	}
+	return value.(*TokenScope)
+}
+
 func ctxSetSuperAdminRole(ctx context.Context) context.Context {
 	return ctxSet(ctx, HttpRequestContextSuperAdminRole, true)
 }
@@ -250,41 +334,34 @@ func reqIsAuthenticated(r *http.Request) (*http.Request, bool) {
 		}
 
 		// check for regular user API key
-		tokens := FindTokens()
-		for _, t := range tokens {
-			// currently no better way than to verify all registered API keys ... ;-(
-			if t.ParentType != TokenParentTypeUser {
-				continue
-			}
-
-			err := t.Verify(apikey)
-			if err != nil {
-				continue
-			}
-
-			// we found the right token, let's verify validity
-			if t.HasExpired() {
-				return nil, false
-			}
+		t, err := FindTokenByApiKey(apikey)
+		if err != nil || t.ParentType != TokenParentTypeUser {
+			return nil, false
+		}
 
-			u, err := t.User()
-			if err != nil {
-				return nil, false
-			}
+		// we found the right token, let's verify validity
+		if t.HasExpired() {
+			return nil, false
+		}
 
-			ctx = ctxSetAuthMethod(ctx, HttpHeaderAuthApiKey)
-			ctx = ctxSetUserId(ctx, u.String())
-			if u.IsSuperAdmin() {
-				ctx = ctxSetSuperAdminRole(ctx)
-			}
-			if u.IsProjectAdmin() {
-				ctx = ctxSetProjectAdminRole(ctx)
-			}
-			klog.Debugf("API-key based authentication")
-			return r.WithContext(ctx), true
+		u, err := t.User()
+		if err != nil {
+			return nil, false
 		}
 
-		return nil, false
+		ctx = ctxSetAuthMethod(ctx, HttpHeaderAuthApiKey)
+		ctx = ctxSetUserId(ctx, u.String())
+		if !t.Scope.IsEmpty() {
+			ctx = ctxSetTokenScope(ctx, &t.Scope)
+		}
+		if u.IsSuperAdmin() {
+			ctx = ctxSetSuperAdminRole(ctx)
+		}
+		if u.IsProjectAdmin() {
+			ctx = ctxSetProjectAdminRole(ctx)
+		}
+		klog.Debugf("API-key based authentication")
+		return r.WithContext(ctx), true
 	}
 
 	// failover, JWT-based auth
@@ -332,6 +409,20 @@ func authenticationMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+func asyncMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Check if client requested asynchronous processing (RFC 7240)
+		for _, pref := range strings.Split(r.Header.Get(HttpHeaderPrefer), ",") {
+			if strings.EqualFold(strings.TrimSpace(pref), HttpPreferRespondAsync) {
+				r = r.WithContext(ctxSetAsync(r.Context()))
+				break
+			}
+		}
+		// Call the next middleware function or final handler
+		next.ServeHTTP(w, r)
+	})
+}
+
 func validMethod(method string, op ApiOperation) bool {
 	if op.Method == HttpApiMethodAll {
 		return true
@@ -339,7 +430,28 @@ func validMethod(method string, op ApiOperation) bool {
 	return strings.EqualFold(method, op.Method)
 }
 
-func reqIsAuthorized(r *http.Request) bool {
+// userAllowedProjects returns the list of projects user's teams are granted access to
+func userAllowedProjects(userId string) ([]Project, error) {
+	projects := []Project{}
+
+	u, err := FindUserByID(userId)
+	if err != nil {
+		return projects, err
+	}
+
+	for _, prj := range FindProjects() {
+		for _, teamId := range u.Teams() {
+			if slices.Contains(prj.TeamIDs, teamId) {
+				projects = append(projects, prj)
+				break
+			}
+		}
+	}
+
+	return projects, nil
+}
+
+func reqIsAuthorized(r *http.Request, name string) bool {
 	ctx := r.Context()
 
 	// check for almighty god rights
@@ -371,46 +483,20 @@ func reqIsAuthorized(r *http.Request) bool {
 		}
 	}
 
-	// checked for cached user resources mapping
-	userAllowedResources := []string{}
-	err := GetCache().Get(CacheNsUserResources, userId, &userAllowedResources)
-	if err != nil {
-		// cache miss: crawl over DB
-
-		// check for any other route: list all resources from projects where user's team is allowed to
-		// and check whether omne of the resource IDs match the request URI
-		u, err := FindUserByID(userId)
-		if err != nil {
-			return false
-		}
-		for _, prj := range FindProjects() {
-			for _, teamId := range u.Teams() {
-				if !slices.Contains(prj.TeamIDs, teamId) {
-					continue
-				}
-				userAllowedResources = append(userAllowedResources, prj.Resources()...)
-			}
-		}
-
-		// store in cache
-		GetCache().Set(CacheNsUserResources, userId, userAllowedResources)
-	}
-
-	// Check if request url contains one of the resource IDs from the projects user's part of
-	for _, res := range userAllowedResources {
-		if strings.Contains(r.RequestURI, res) {
-			return true
-		}
+	// check for any other route: resolve request's target to its owning project
+	// and check whether user's teams role bindings grant the required permission
+	if userId != "" && reqIsRbacAuthorized(r, name, userId) {
+		return true
 	}
 
 	// everything else is denied
 	return false
 }
 
-func authorizationMiddleware(next http.Handler) http.Handler {
+func authorizationMiddleware(next http.Handler, name string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Check if user is allowed to request
-		if !reqIsAuthorized(r) {
+		// Check if user is allowed to request, and within API token's scope, if any
+		if !reqIsAuthorized(r, name) || !reqIsTokenScopeAuthorized(r, name) {
 			http.Error(w, "Forbidden", http.StatusForbidden)
 			return
 		}
@@ -445,6 +531,15 @@ func verifyAgentHeaders(w http.ResponseWriter, r *http.Request) (string, string,
 		return agentType, agentId, fmt.Errorf("unsupported Kowabunga agent ID")
 	}
 
+	// check for agent client certificate, when mutual TLS is enabled
+	if GetCfg().Global.HTTP.TLS.AgentsMutualTLS() {
+		err = verifyAgentCertificate(r, agentId)
+		if err != nil {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return agentType, agentId, err
+		}
+	}
+
 	// check for agent API key authentication
 	apiKey := r.Header.Get(ws.WsHeaderAgentApiKey)
 	if apiKey == "" {
@@ -506,12 +601,36 @@ func NewRouter(ke *KahunaEngine) *mux.Router {
 			// WARNING: reverse-order logic in middlewares queueing
 			//
 
+			// list endpoints query middleware
+			handler = listQueryMiddleware(handler, name)
+
+			// resources labels middleware
+			handler = labelsMiddleware(handler, name)
+
+			// optimistic concurrency control middleware
+			handler = revisionMiddleware(handler, name)
+
+			// trashed resources hiding middleware
+			handler = trashMiddleware(handler, name)
+
 			if !slices.Contains(noAuthApiOperations, name) {
+				// asynchronous processing middleware
+				handler = asyncMiddleware(handler)
+
+				// idempotent resource creation middleware, needs authenticated caller
+				handler = idempotencyMiddleware(handler, name)
+
 				// authorization middelware
-				handler = authorizationMiddleware(handler)
+				handler = authorizationMiddleware(handler, name)
+
+				// audit middleware, needs authenticated caller
+				handler = auditMiddleware(handler, name)
 
 				// authentication middelware
 				handler = authenticationMiddleware(handler)
+			} else {
+				// audit middleware, anonymous caller
+				handler = auditMiddleware(handler, name)
 			}
 
 			// logging middleware
@@ -532,6 +651,8 @@ func NewRouter(ke *KahunaEngine) *mux.Router {
 	router.HandleFunc("/latest/meta-data", metadataHandler)
 	router.Handle("/metrics", ke.Exporter.HttpHandler())
 	router.HandleFunc(ws.WsRouterEndpoint, wsHandler)
+	router.HandleFunc(ClusterRpcRoute, clusterRpcHandler)
+	router.HandleFunc(ClusterDisconnectRoute, clusterDisconnectHandler)
 
 	return router
 }
@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * Provisioning sagas: multi-steps resources creation registers a compensation
+ * action for each successfully performed step, so that any later failure
+ * unwinds everything created so far, in reverse order. Compensations which
+ * fail themselves are reported as leftovers, for administrators to clean up.
+ */
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kowabunga-cloud/common/klog"
+)
+
+type SagaCompensation func() error
+
+type sagaStep struct {
+	name       string
+	compensate SagaCompensation
+}
+
+type Saga struct {
+	name  string
+	steps []sagaStep
+}
+
+// SagaLeftover references a step which could not be compensated
+type SagaLeftover struct {
+	Saga string
+	Step string
+	Err  error
+}
+
+func (l *SagaLeftover) String() string {
+	return fmt.Sprintf("%s/%s (%v)", l.Saga, l.Step, l.Err)
+}
+
+// SagaRollbackError is returned when a saga failed and could not be entirely rolled back
+type SagaRollbackError struct {
+	Cause     error
+	Leftovers []SagaLeftover
+}
+
+func (e *SagaRollbackError) Error() string {
+	leftovers := []string{}
+	for _, l := range e.Leftovers {
+		leftovers = append(leftovers, l.String())
+	}
+	return fmt.Sprintf("%v (rollback left over: %s)", e.Cause, strings.Join(leftovers, ", "))
+}
+
+func (e *SagaRollbackError) Unwrap() error {
+	return e.Cause
+}
+
+func NewSaga(name string) *Saga {
+	return &Saga{
+		name:  name,
+		steps: []sagaStep{},
+	}
+}
+
+// Step performs action and, if successful, registers its compensation
+func (s *Saga) Step(name string, action func() error, compensate SagaCompensation) error {
+	err := action()
+	if err != nil {
+		klog.Errorf("%s: %s step failed: %v", s.name, name, err)
+		return err
+	}
+	s.Compensate(name, compensate)
+	return nil
+}
+
+// Compensate registers the compensation of an already performed step
+func (s *Saga) Compensate(name string, compensate SagaCompensation) {
+	s.steps = append(s.steps, sagaStep{
+		name:       name,
+		compensate: compensate,
+	})
+}
+
+// Rollback unwinds all performed steps, in reverse order, following cause failure.
+// It returns cause, alongside with leftovers of any nested saga or failed compensations.
+func (s *Saga) Rollback(cause error) error {
+	klog.Warningf("%s: rolling back %d step(s) ...", s.name, len(s.steps))
+
+	leftovers := []SagaLeftover{}
+	var nested *SagaRollbackError
+	if errors.As(cause, &nested) {
+		leftovers = append(leftovers, nested.Leftovers...)
+		cause = nested.Cause
+	}
+
+	for i := len(s.steps) - 1; i >= 0; i-- {
+		step := s.steps[i]
+		err := step.compensate()
+		if err != nil {
+			klog.Errorf("%s: unable to compensate %s step: %v", s.name, step.name, err)
+			leftovers = append(leftovers, SagaLeftover{
+				Saga: s.name,
+				Step: step.name,
+				Err:  err,
+			})
+		}
+	}
+	s.steps = []sagaStep{}
+
+	if len(leftovers) == 0 {
+		return cause
+	}
+
+	return &SagaRollbackError{
+		Cause:     cause,
+		Leftovers: leftovers,
+	}
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSagaRollback(t *testing.T) {
+	undone := []string{}
+	undo := func(step string, err error) SagaCompensation {
+		return func() error {
+			undone = append(undone, step)
+			return err
+		}
+	}
+
+	// nested saga, partially rolled back
+	nested := NewSaga("nested")
+	nested.Compensate("volume", undo("volume", errors.New("volume busy")))
+	cause := errors.New("libvirt failure")
+	err := nested.Step("domain", func() error { return cause }, undo("domain", nil))
+	if err != cause {
+		t.Fatalf("unexpected step error: %v", err)
+	}
+	nestedErr := nested.Rollback(err)
+
+	s := NewSaga("parent")
+	err = s.Step("adapter", func() error { return nil }, undo("adapter", nil))
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	s.Compensate("dns", undo("dns", errors.New("gateway unreachable")))
+
+	err = s.Rollback(nestedErr)
+	if !slices.Equal(undone, []string{"volume", "dns", "adapter"}) {
+		t.Errorf("unexpected compensations order: %v", undone)
+	}
+	if !errors.Is(err, cause) {
+		t.Errorf("rollback error does not wrap original cause: %v", err)
+	}
+
+	var rbErr *SagaRollbackError
+	if !errors.As(err, &rbErr) || len(rbErr.Leftovers) != 2 {
+		t.Fatalf("unexpected rollback leftovers: %v", err)
+	}
+	if rbErr.Leftovers[0].Saga != "nested" || rbErr.Leftovers[1].Step != "dns" {
+		t.Errorf("unexpected rollback leftovers: %v", rbErr.Leftovers)
+	}
+
+	// successful rollback only returns original cause
+	s = NewSaga("clean")
+	s.Compensate("adapter", undo("adapter", nil))
+	err = s.Rollback(cause)
+	if err != cause {
+		t.Errorf("unexpected rollback error: %v", err)
+	}
+}
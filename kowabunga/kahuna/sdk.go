@@ -7,6 +7,7 @@
 package kahuna
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"runtime"
@@ -80,6 +81,10 @@ func HttpCreated(body interface{}) (sdk.ImplResponse, error) {
 	return sdk.Response(http.StatusCreated, body), nil
 }
 
+func HttpAccepted(body interface{}) (sdk.ImplResponse, error) {
+	return sdk.Response(http.StatusAccepted, body), nil
+}
+
 func HttpCreatedNoContent() (sdk.ImplResponse, error) {
 	return sdk.Response(http.StatusNoContent, nil), nil
 }
@@ -96,6 +101,10 @@ func HttpConflict(err error) (sdk.ImplResponse, error) {
 	return sdk.Response(http.StatusConflict, sdk.ApiErrorConflict{}), err
 }
 
+func HttpPreconditionFailed(err error) (sdk.ImplResponse, error) {
+	return sdk.Response(http.StatusPreconditionFailed, sdk.ApiErrorConflict{}), err
+}
+
 func HttpServerError(err error) (sdk.ImplResponse, error) {
 	return sdk.Response(http.StatusUnprocessableEntity, sdk.ApiErrorConflict{}), err
 }
@@ -107,3 +116,12 @@ func HttpQuota(err error) (sdk.ImplResponse, error) {
 func HttpNotImplemented(err error) (sdk.ImplResponse, error) {
 	return sdk.Response(http.StatusNotImplemented, nil), err
 }
+
+// HttpMiddlewareError rejects request before it reaches API services, reporting err the way services do
+func HttpMiddlewareError(w http.ResponseWriter, r *http.Request, status func(error) (sdk.ImplResponse, error), err error) {
+	result, err := status(err)
+	if err == nil {
+		err = errors.New(http.StatusText(result.Code))
+	}
+	sdk.DefaultErrorHandler(w, r, err, &result)
+}
@@ -0,0 +1,482 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * Global search looks up resources by name, IP or MAC address across
+ * adapters, instances, DNS records, subnets and MZR/HAR virtual IPs, and
+ * returns each hit along with its location (region, zone, kaktus, project, ...).
+ *
+ * Hits are restricted to the projects the caller has access to.
+ */
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/kowabunga-cloud/common/klog"
+)
+
+const (
+	SearchMinQueryLength = 2
+	SearchMaxHits        = 100
+
+	SearchKindAdapter   = "adapter"
+	SearchKindDnsRecord = "record"
+	SearchKindHar       = "har"
+	SearchKindInstance  = "instance"
+	SearchKindKaktus    = "kaktus"
+	SearchKindMzr       = "mzr"
+	SearchKindProject   = "project"
+	SearchKindRegion    = "region"
+	SearchKindSubnet    = "subnet"
+	SearchKindZone      = "zone"
+
+	SearchMatchAddress = "address"
+	SearchMatchCIDR    = "cidr"
+	SearchMatchLocalIP = "local_ip"
+	SearchMatchMAC     = "mac"
+	SearchMatchName    = "name"
+	SearchMatchVIP     = "vip"
+)
+
+type SearchPathElement struct {
+	Kind string `json:"kind"`
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type SearchHit struct {
+	Kind    string              `json:"kind"`
+	Id      string              `json:"id"`
+	Name    string              `json:"name"`
+	Match   string              `json:"match"`
+	Value   string              `json:"value"`
+	Trashed bool                `json:"trashed,omitempty"`
+	Path    []SearchPathElement `json:"path"`
+
+	projectId string
+}
+
+// SearchQuery describes what's being looked for
+type SearchQuery struct {
+	Text string
+	IP   net.IP
+	MAC  net.HardwareAddr
+}
+
+func ParseSearchQuery(q string) (*SearchQuery, error) {
+	q = strings.TrimSpace(q)
+	if len(q) < SearchMinQueryLength {
+		return nil, fmt.Errorf("search query must be at least %d characters long", SearchMinQueryLength)
+	}
+
+	sq := SearchQuery{
+		Text: q,
+		IP:   net.ParseIP(q),
+	}
+	if sq.IP == nil {
+		mac, err := net.ParseMAC(q)
+		if err == nil && len(mac) == 6 {
+			sq.MAC = mac
+		}
+	}
+
+	return &sq, nil
+}
+
+func (q *SearchQuery) IsName() bool {
+	return q.IP == nil && q.MAC == nil
+}
+
+// searchNameFilter matches resources whose name contains query text, case-insensitively
+func searchNameFilter(text string) bson.D {
+	return bson.D{bson.E{Key: "name", Value: bson.Regex{Pattern: regexp.QuoteMeta(text), Options: "i"}}}
+}
+
+// searchAccess restricts hits to caller's projects
+type searchAccess struct {
+	all      bool
+	projects []string
+}
+
+func (a *searchAccess) allows(projectId string) bool {
+	if a.all {
+		return true
+	}
+	return projectId != "" && slices.Contains(a.projects, projectId)
+}
+
+// filter restricts a query to caller's projects resources, for hits not to be crowded out by others
+func (a *searchAccess) filter() bson.D {
+	if a.all {
+		return bson.D{}
+	}
+	return bson.D{bson.E{Key: "project_id", Value: bson.D{bson.E{Key: "$in", Value: a.projects}}}}
+}
+
+func newSearchAccess(userId string, superAdmin bool, scope *TokenScope) (*searchAccess, error) {
+	access := searchAccess{
+		all:      superAdmin,
+		projects: []string{},
+	}
+
+	if !superAdmin {
+		u, err := FindUserByID(userId)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range UserPermissions(u).Projects {
+			if slices.Contains(p.Permissions, RbacPermissionRead) {
+				access.projects = append(access.projects, p.Project)
+			}
+		}
+	}
+
+	// API tokens may be restricted to a few projects
+	if scope != nil && len(scope.ProjectIDs) > 0 {
+		if access.all {
+			access.all = false
+			access.projects = scope.ProjectIDs
+		} else {
+			access.projects = slices.DeleteFunc(access.projects, func(id string) bool {
+				return !slices.Contains(scope.ProjectIDs, id)
+			})
+		}
+	}
+
+	return &access, nil
+}
+
+// searcher looks resources up, caching instances adapters lookup table
+type searcher struct {
+	q        *SearchQuery
+	access   *searchAccess
+	hits     []SearchHit
+	adapters map[string]*Instance
+}
+
+func (s *searcher) add(hit SearchHit) {
+	if len(s.hits) >= SearchMaxHits || !s.access.allows(hit.projectId) {
+		return
+	}
+	s.hits = append(s.hits, hit)
+}
+
+func projectPath(projectId string) []SearchPathElement {
+	prj, err := FindProjectByID(projectId)
+	if err != nil {
+		return []SearchPathElement{}
+	}
+	return []SearchPathElement{{Kind: SearchKindProject, Id: prj.String(), Name: prj.Name}}
+}
+
+func regionPath(regionId string) []SearchPathElement {
+	r, err := FindRegionByID(regionId)
+	if err != nil {
+		return []SearchPathElement{}
+	}
+	return []SearchPathElement{{Kind: SearchKindRegion, Id: r.String(), Name: r.Name}}
+}
+
+func subnetRegionPath(sn *Subnet) []SearchPathElement {
+	v, err := sn.VNet()
+	if err != nil {
+		return []SearchPathElement{}
+	}
+	return regionPath(v.RegionID)
+}
+
+// instancePath returns region -> zone -> kaktus -> project -> instance path
+func instancePath(i *Instance) []SearchPathElement {
+	path := []SearchPathElement{}
+
+	k, err := i.Kaktus()
+	if err == nil {
+		z, err := k.Zone()
+		if err == nil {
+			path = append(path, regionPath(z.RegionID)...)
+			path = append(path, SearchPathElement{Kind: SearchKindZone, Id: z.String(), Name: z.Name})
+		}
+		path = append(path, SearchPathElement{Kind: SearchKindKaktus, Id: k.String(), Name: k.Name})
+	}
+
+	path = append(path, projectPath(i.ProjectID)...)
+	return append(path, SearchPathElement{Kind: SearchKindInstance, Id: i.String(), Name: i.Name})
+}
+
+func (s *searcher) instanceHit(i *Instance, match, value string) SearchHit {
+	return SearchHit{
+		Kind:      SearchKindInstance,
+		Id:        i.String(),
+		Name:      i.Name,
+		Match:     match,
+		Value:     value,
+		Trashed:   i.IsTrashed(),
+		Path:      instancePath(i),
+		projectId: i.ProjectID,
+	}
+}
+
+func (s *searcher) searchInstances() error {
+	var filter bson.D
+	match := SearchMatchName
+	switch {
+	case s.q.IP != nil:
+		filter = listFilterByKey("local_ip", s.q.IP.String())
+		match = SearchMatchLocalIP
+	case s.q.IsName():
+		filter = searchNameFilter(s.q.Text)
+	default:
+		return nil
+	}
+
+	instances := []Instance{}
+	filter = append(filter, s.access.filter()...)
+	err := GetDB().FindAllByFilter(MongoCollectionInstanceName, filter, nil, SearchMaxHits, &instances)
+	if err != nil {
+		return err
+	}
+
+	for _, i := range instances {
+		value := i.Name
+		if match == SearchMatchLocalIP {
+			value = i.LocalIP
+		}
+		s.add(s.instanceHit(&i, match, value))
+	}
+
+	return nil
+}
+
+// adapterInstance returns the instance an adapter is plugged into, if any
+func (s *searcher) adapterInstance(adapterId string) *Instance {
+	if s.adapters == nil {
+		s.adapters = map[string]*Instance{}
+		for _, i := range FindInstances() {
+			for _, id := range i.Adapters() {
+				s.adapters[id] = &i
+			}
+		}
+	}
+	return s.adapters[adapterId]
+}
+
+func (s *searcher) searchAdapters() error {
+	var filter bson.D
+	match := SearchMatchAddress
+	switch {
+	case s.q.IP != nil:
+		filter = listFilterByKey("addresses", s.q.IP.String())
+	case s.q.MAC != nil:
+		filter = bson.D{bson.E{Key: "mac", Value: bson.Regex{Pattern: "^" + regexp.QuoteMeta(s.q.MAC.String()) + "$", Options: "i"}}}
+		match = SearchMatchMAC
+	default:
+		return nil
+	}
+
+	adapters := []Adapter{}
+	err := GetDB().FindAllByFilter(MongoCollectionAdapterName, filter, nil, SearchMaxHits, &adapters)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range adapters {
+		hit := SearchHit{
+			Kind:  SearchKindAdapter,
+			Id:    a.String(),
+			Name:  a.Name,
+			Match: match,
+			Value: s.q.IP.String(),
+			Path:  []SearchPathElement{},
+		}
+		if match == SearchMatchMAC {
+			hit.Value = a.MAC
+		}
+
+		sn, err := a.Subnet()
+		if err == nil {
+			hit.projectId = sn.ProjectID
+		}
+
+		i := s.adapterInstance(a.String())
+		switch {
+		case i != nil:
+			hit.projectId = i.ProjectID
+			hit.Trashed = i.IsTrashed()
+			hit.Path = instancePath(i)
+		case sn != nil:
+			hit.Path = append(subnetRegionPath(sn), projectPath(sn.ProjectID)...)
+		}
+		hit.Path = append(hit.Path, SearchPathElement{Kind: SearchKindAdapter, Id: a.String(), Name: a.Name})
+
+		s.add(hit)
+	}
+
+	return nil
+}
+
+func (s *searcher) searchDnsRecords() error {
+	var filter bson.D
+	match := SearchMatchName
+	switch {
+	case s.q.IP != nil:
+		filter = listFilterByKey("addresses", s.q.IP.String())
+		match = SearchMatchAddress
+	case s.q.IsName():
+		// either a record name or its fully qualified one
+		filter = bson.D{bson.E{Key: "$or", Value: bson.A{
+			searchNameFilter(s.q.Text),
+			searchNameFilter(strings.Split(s.q.Text, ".")[0]),
+		}}}
+	default:
+		return nil
+	}
+
+	records := []DnsRecord{}
+	filter = append(filter, s.access.filter()...)
+	err := GetDB().FindAllByFilter(MongoCollectionDnsRecordName, filter, nil, SearchMaxHits, &records)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		fqdn := r.Name
+		if r.Domain != "" {
+			fqdn = fmt.Sprintf("%s.%s", r.Name, r.Domain)
+		}
+
+		value := fqdn
+		if match == SearchMatchAddress {
+			value = s.q.IP.String()
+		} else if !strings.Contains(strings.ToLower(fqdn), strings.ToLower(s.q.Text)) {
+			// record name matched query's host part, but not its domain
+			continue
+		}
+
+		path := append(regionPath(r.RegionID), projectPath(r.ProjectID)...)
+		s.add(SearchHit{
+			Kind:      SearchKindDnsRecord,
+			Id:        r.String(),
+			Name:      fqdn,
+			Match:     match,
+			Value:     value,
+			Path:      append(path, SearchPathElement{Kind: SearchKindDnsRecord, Id: r.String(), Name: r.Name}),
+			projectId: r.ProjectID,
+		})
+	}
+
+	return nil
+}
+
+func (s *searcher) searchSubnets() error {
+	if s.q.IP == nil {
+		return nil
+	}
+
+	for _, sn := range FindSubnets() {
+		_, cidr, err := net.ParseCIDR(sn.CIDR)
+		if err != nil || !cidr.Contains(s.q.IP) {
+			continue
+		}
+
+		path := append(subnetRegionPath(&sn), projectPath(sn.ProjectID)...)
+		s.add(SearchHit{
+			Kind:      SearchKindSubnet,
+			Id:        sn.String(),
+			Name:      sn.Name,
+			Match:     SearchMatchCIDR,
+			Value:     sn.CIDR,
+			Path:      append(path, SearchPathElement{Kind: SearchKindSubnet, Id: sn.String(), Name: sn.Name}),
+			projectId: sn.ProjectID,
+		})
+	}
+
+	return nil
+}
+
+func (s *searcher) searchVirtualIPs() error {
+	if s.q.IP == nil {
+		return nil
+	}
+	ip := s.q.IP.String()
+
+	for _, mzr := range FindMZRs() {
+		vips := slices.Concat(mzr.PrivateVIPs, mzr.PublicVIPs)
+		for _, vip := range mzr.VirtualIPs {
+			vips = append(vips, vip.VIP)
+		}
+		if !slices.Contains(vips, ip) {
+			continue
+		}
+
+		path := append(regionPath(mzr.RegionID), projectPath(mzr.ProjectID)...)
+		s.add(SearchHit{
+			Kind:      SearchKindMzr,
+			Id:        mzr.String(),
+			Name:      mzr.Name,
+			Match:     SearchMatchVIP,
+			Value:     ip,
+			Path:      append(path, SearchPathElement{Kind: SearchKindMzr, Id: mzr.String(), Name: mzr.Name}),
+			projectId: mzr.ProjectID,
+		})
+	}
+
+	for _, har := range FindHARs() {
+		if har.PrivateVIP != ip && har.VirtualIP.VIP != ip {
+			continue
+		}
+
+		path := append(regionPath(har.RegionID), projectPath(har.ProjectID)...)
+		s.add(SearchHit{
+			Kind:      SearchKindHar,
+			Id:        har.String(),
+			Name:      har.Name,
+			Match:     SearchMatchVIP,
+			Value:     ip,
+			Path:      append(path, SearchPathElement{Kind: SearchKindHar, Id: har.String(), Name: har.Name}),
+			projectId: har.ProjectID,
+		})
+	}
+
+	return nil
+}
+
+// Search looks resources matching query up, amongst the ones caller has access to
+func Search(q *SearchQuery, userId string, superAdmin bool, scope *TokenScope) ([]SearchHit, error) {
+	access, err := newSearchAccess(userId, superAdmin, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	s := searcher{
+		q:      q,
+		access: access,
+		hits:   []SearchHit{},
+	}
+
+	for _, fn := range []func() error{
+		s.searchAdapters,
+		s.searchInstances,
+		s.searchDnsRecords,
+		s.searchSubnets,
+		s.searchVirtualIPs,
+	} {
+		err := fn()
+		if err != nil {
+			klog.Error(err)
+			return nil, err
+		}
+	}
+
+	return s.hits, nil
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"testing"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	q, err := ParseSearchQuery(" 10.0.0.12 ")
+	if err != nil || q.IP == nil || q.IP.String() != "10.0.0.12" || q.IsName() {
+		t.Fatalf("IP address query was not recognized: %+v", q)
+	}
+
+	q, err = ParseSearchQuery("52:54:00:AB:CD:EF")
+	if err != nil || q.MAC == nil || q.MAC.String() != "52:54:00:ab:cd:ef" || q.IsName() {
+		t.Fatalf("MAC address query was not recognized: %+v", q)
+	}
+
+	q, err = ParseSearchQuery("web.acme.com")
+	if err != nil || !q.IsName() {
+		t.Fatalf("name query was not recognized: %+v", q)
+	}
+
+	_, err = ParseSearchQuery("a")
+	if err == nil {
+		t.Fatalf("too short query was accepted")
+	}
+}
+
+func TestSearchAccess(t *testing.T) {
+	scope := &TokenScope{
+		ProjectIDs: []string{"a", "b"},
+	}
+
+	access, err := newSearchAccess("", true, scope)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if !access.allows("a") || access.allows("c") || access.allows("") {
+		t.Fatalf("unexpected access restriction: %+v", access)
+	}
+
+	access, err = newSearchAccess("", true, nil)
+	if err != nil || !access.allows("c") || !access.allows("") || len(access.filter()) != 0 {
+		t.Fatalf("unexpected access restriction: %+v", access)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * Sensitive settings stored in database (e.g. organizations IdP client
+ * secrets) are encrypted at rest, with a key derived from the configured
+ * database encryption key (db.encryptionKey).
+ */
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+const (
+	SecretEncryptedPrefix = "secret:v1:"
+
+	ErrSecretNoEncryptionKey = "database encryption key (db.encryptionKey) is not configured"
+	ErrSecretInvalid         = "unable to decrypt secret, database encryption key may have changed"
+)
+
+func secretCipher() (cipher.AEAD, error) {
+	passphrase := GetCfg().Global.DB.EncryptionKey
+	if passphrase == "" {
+		return nil, errors.New(ErrSecretNoEncryptionKey)
+	}
+
+	key := sha256.Sum256([]byte(passphrase))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func IsEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, SecretEncryptedPrefix)
+}
+
+// EncryptSecret encrypts value, unless empty or already encrypted
+func EncryptSecret(value string) (string, error) {
+	if value == "" || IsEncryptedSecret(value) {
+		return value, nil
+	}
+
+	aead, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), nil)
+	return SecretEncryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret decrypts value, unless stored in clear text
+func DecryptSecret(value string) (string, error) {
+	if !IsEncryptedSecret(value) {
+		return value, nil
+	}
+
+	aead, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, SecretEncryptedPrefix))
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New(ErrSecretInvalid)
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New(ErrSecretInvalid)
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"testing"
+)
+
+func TestSecret(t *testing.T) {
+	SetCfg(&KowabungaConfig{})
+	defer SetCfg(nil)
+
+	_, err := EncryptSecret("oidc-secret")
+	if err == nil {
+		t.Fatalf("secret has been encrypted with no encryption key")
+	}
+
+	SetCfg(&KowabungaConfig{
+		Global: KowabungaGlobalConfig{
+			DB: KowabungaDBConfig{
+				EncryptionKey: "s3cr3t",
+			},
+		},
+	})
+
+	enc, err := EncryptSecret("oidc-secret")
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if !IsEncryptedSecret(enc) || enc == "oidc-secret" {
+		t.Fatalf("secret has not been encrypted: %s", enc)
+	}
+
+	again, err := EncryptSecret(enc)
+	if err != nil || again != enc {
+		t.Fatalf("encrypted secret has been encrypted again")
+	}
+
+	plain, err := DecryptSecret(enc)
+	if err != nil || plain != "oidc-secret" {
+		t.Fatalf("unexpected decrypted secret: %s (%v)", plain, err)
+	}
+
+	// clear text secrets, from former schema
+	plain, err = DecryptSecret("oidc-secret")
+	if err != nil || plain != "oidc-secret" {
+		t.Fatalf("unexpected clear text secret: %s (%v)", plain, err)
+	}
+
+	SetCfg(&KowabungaConfig{
+		Global: KowabungaGlobalConfig{
+			DB: KowabungaDBConfig{
+				EncryptionKey: "guess",
+			},
+		},
+	})
+	_, err = DecryptSecret(enc)
+	if err == nil {
+		t.Fatalf("secret has been decrypted with an invalid key")
+	}
+}
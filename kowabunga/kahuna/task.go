@@ -0,0 +1,327 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * A Kowabunga task tracks a long-running operation (e.g. instance or Kawaii creation)
+ * executed in background, out of the HTTP request handler, and polled by API clients.
+ */
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/kowabunga-cloud/common/klog"
+	"github.com/kowabunga-cloud/kowabunga/kowabunga/sdk"
+)
+
+const (
+	MongoCollectionTaskSchemaVersion = 1
+	MongoCollectionTaskName          = "task"
+
+	TaskStatusPending   = "pending"
+	TaskStatusRunning   = "running"
+	TaskStatusSucceeded = "succeeded"
+	TaskStatusFailed    = "failed"
+
+	TaskHeartbeatIntervalSeconds = 30
+	TaskLeaseSeconds             = 4 * TaskHeartbeatIntervalSeconds // runner is considered gone past this delay
+
+	ErrTaskInterrupted = "task has been interrupted, its Kahuna runner stopped reporting"
+	ErrTaskPanicked    = "task has unexpectedly aborted"
+)
+
+type Task struct {
+	// anonymous field, inheritance
+	Resource `bson:"inline"`
+
+	// parents
+	UserID string `bson:"user_id"`
+
+	// properties
+	Owner       string    `bson:"owner"`
+	Operation   string    `bson:"operation"`
+	Status      string    `bson:"status"`
+	Error       string    `bson:"error"`
+	ResultID    string    `bson:"result_id"`
+	StartedAt   time.Time `bson:"started_at"`
+	HeartbeatAt time.Time `bson:"heartbeat_at"` // lease, renewed by runner until task completes
+	CompletedAt time.Time `bson:"completed_at"`
+
+	// runner and heartbeat both update task
+	lock *sync.Mutex `bson:"-"`
+}
+
+type TaskModel struct {
+	Id          string `json:"id"`
+	Operation   string `json:"operation"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+	ResultId    string `json:"result_id,omitempty"`
+	CreatedAt   string `json:"created_at"`
+	StartedAt   string `json:"started_at,omitempty"`
+	CompletedAt string `json:"completed_at,omitempty"`
+}
+
+// TaskFunc performs the actual task operation and returns the resulting resource ID.
+type TaskFunc func(t *Task) (string, error)
+
+func NewTask(operation, userId string) (*Task, error) {
+	t := Task{
+		Resource:    NewResource(operation, "", MongoCollectionTaskSchemaVersion),
+		UserID:      userId,
+		Owner:       taskOwner(),
+		Operation:   operation,
+		Status:      TaskStatusPending,
+		HeartbeatAt: time.Now(),
+		lock:        &sync.Mutex{},
+	}
+
+	_, err := GetDB().Insert(MongoCollectionTaskName, t)
+	if err != nil {
+		return nil, err
+	}
+
+	klog.Debugf("Created new task %s (%s)", t.String(), t.Operation)
+
+	return &t, nil
+}
+
+func FindTasks() []Task {
+	return FindResources[Task](MongoCollectionTaskName)
+}
+
+func FindTasksByUser(userId string) ([]Task, error) {
+	return FindResourcesByKey[Task](MongoCollectionTaskName, "user_id", userId)
+}
+
+func FindTaskByID(id string) (*Task, error) {
+	return FindResourceByID[Task](MongoCollectionTaskName, id)
+}
+
+// taskExpiredFilter matches unfinished tasks whose runner stopped renewing their lease, whichever replica it was
+func taskExpiredFilter(now time.Time) bson.D {
+	return bson.D{
+		bson.E{Key: "status", Value: bson.D{bson.E{Key: "$in", Value: []string{TaskStatusPending, TaskStatusRunning}}}},
+		bson.E{Key: "$or", Value: bson.A{
+			bson.D{bson.E{Key: "heartbeat_at", Value: bson.D{bson.E{Key: "$lt", Value: now.Add(-TaskLeaseSeconds * time.Second)}}}},
+			bson.D{bson.E{Key: "heartbeat_at", Value: bson.D{bson.E{Key: "$exists", Value: false}}}}, // created before leases
+		}},
+	}
+}
+
+// RecoverTasks flags all unfinished tasks whose lease has expired (i.e. Kahuna replica running them crashed or
+// has been restarted) as failed. Operations are not idempotent so we can't blindly replay them.
+func RecoverTasks() {
+	filter := taskExpiredFilter(time.Now())
+
+	tasks := []Task{}
+	err := GetDB().FindAllByFilter(MongoCollectionTaskName, filter, nil, 0, &tasks)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+
+	for _, t := range tasks {
+		klog.Warningf("Task %s (%s) was interrupted, flagging as failed", t.String(), t.Operation)
+		t.Fail(fmt.Errorf("%s", ErrTaskInterrupted))
+	}
+}
+
+// TaskReaper periodically recovers expired tasks, whichever replica was running them
+func TaskReaper(ctx context.Context) {
+	ticker := time.NewTicker(TaskHeartbeatIntervalSeconds * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			RecoverTasks()
+		}
+	}
+}
+
+// taskOwner identifies the Kahuna process in charge of running tasks, for information purpose only
+func taskOwner() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "kahuna"
+	}
+	return fmt.Sprintf("%s/%d", hostname, os.Getpid())
+}
+
+func (t *Task) IsCompleted() bool {
+	return t.Status == TaskStatusSucceeded || t.Status == TaskStatusFailed
+}
+
+func (t *Task) IsOwnedBy(userId string) bool {
+	return t.UserID != "" && t.UserID == userId
+}
+
+// update applies changes to task and saves it, runner and heartbeat being serialized
+func (t *Task) update(fn func()) {
+	if t.lock != nil {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+	}
+	fn()
+	t.Save()
+}
+
+func (t *Task) Start() {
+	t.update(func() {
+		t.Status = TaskStatusRunning
+		t.StartedAt = time.Now()
+		t.HeartbeatAt = t.StartedAt
+	})
+}
+
+// Heartbeat renews task's lease, for other replicas not to consider it interrupted
+func (t *Task) Heartbeat() {
+	t.update(func() {
+		t.HeartbeatAt = time.Now()
+	})
+}
+
+func (t *Task) Succeed(resultId string) {
+	klog.Infof("Task %s (%s) succeeded", t.String(), t.Operation)
+	t.update(func() {
+		t.Status = TaskStatusSucceeded
+		t.ResultID = resultId
+		t.CompletedAt = time.Now()
+	})
+}
+
+func (t *Task) Fail(err error) {
+	klog.Errorf("Task %s (%s) failed: %v", t.String(), t.Operation, err)
+	t.update(func() {
+		t.Status = TaskStatusFailed
+		t.Error = err.Error()
+		t.CompletedAt = time.Now()
+	})
+}
+
+// runTaskFunc executes task operation, turning any panic into a failure
+func runTaskFunc(t *Task, fn TaskFunc) (resultId string, err error) {
+	defer func() {
+		r := recover()
+		if r != nil {
+			klog.Errorf("Task panicked: %v", r)
+			err = fmt.Errorf("%s: %v", ErrTaskPanicked, r)
+		}
+	}()
+	return fn(t)
+}
+
+// Run executes task operation in background, renewing its lease until completion
+func (t *Task) Run(fn TaskFunc) {
+	go func() {
+		t.Start()
+
+		done := make(chan struct{})
+		go func() {
+			ticker := time.NewTicker(TaskHeartbeatIntervalSeconds * time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					t.Heartbeat()
+				}
+			}
+		}()
+
+		resultId, err := runTaskFunc(t, fn)
+		close(done)
+		if err != nil {
+			t.Fail(err)
+			return
+		}
+		t.Succeed(resultId)
+	}()
+}
+
+func (t *Task) Save() {
+	t.Updated()
+	_, err := GetDB().Update(MongoCollectionTaskName, t.ID, t)
+	if err != nil {
+		klog.Error(err)
+	}
+}
+
+func (t *Task) Delete() error {
+	klog.Debugf("Deleting task %s", t.String())
+
+	if t.String() == ResourceUnknown {
+		return nil
+	}
+
+	return GetDB().Delete(MongoCollectionTaskName, t.ID)
+}
+
+func (t *Task) Model() TaskModel {
+	tm := TaskModel{
+		Id:        t.String(),
+		Operation: t.Operation,
+		Status:    t.Status,
+		Error:     t.Error,
+		ResultId:  t.ResultID,
+		CreatedAt: t.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	if !t.StartedAt.IsZero() {
+		tm.StartedAt = t.StartedAt.UTC().Format(time.RFC3339)
+	}
+	if !t.CompletedAt.IsZero() {
+		tm.CompletedAt = t.CompletedAt.UTC().Format(time.RFC3339)
+	}
+	return tm
+}
+
+// TaskCreateFunc creates a resource and returns its ID along with its API model
+type TaskCreateFunc func(t *Task) (string, any, error)
+
+// HttpCreateMaybeAsync runs the resource creation operation synchronously,
+// or in background if API client requested so, returning a task to be polled.
+func HttpCreateMaybeAsync(ctx context.Context, operation string, fn TaskCreateFunc) (sdk.ImplResponse, error) {
+	if !ctxGetAsync(ctx) {
+		id, payload, err := fn(nil)
+		if err != nil {
+			return HttpServerError(err)
+		}
+		err = ctxApplyLabels(ctx, id)
+		if err != nil {
+			return HttpServerError(err)
+		}
+		LogHttpResponse(payload)
+		return HttpCreated(payload)
+	}
+
+	t, err := NewTask(operation, ctxGetUserId(ctx))
+	if err != nil {
+		return HttpServerError(err)
+	}
+
+	t.Run(func(t *Task) (string, error) {
+		id, _, err := fn(t)
+		if err != nil {
+			return id, err
+		}
+		return id, ctxApplyLabels(ctx, id)
+	})
+
+	payload := t.Model()
+	LogHttpResponse(payload)
+	return HttpAccepted(payload)
+}
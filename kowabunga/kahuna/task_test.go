@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestTaskRunPanic(t *testing.T) {
+	id, err := runTaskFunc(nil, func(t *Task) (string, error) {
+		return "abc", nil
+	})
+	if err != nil || id != "abc" {
+		t.Fatalf("unexpected result %s (%v)", id, err)
+	}
+
+	_, err = runTaskFunc(nil, func(t *Task) (string, error) {
+		return "", fmt.Errorf("libvirt failure")
+	})
+	if err == nil || err.Error() != "libvirt failure" {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	_, err = runTaskFunc(nil, func(t *Task) (string, error) {
+		var i *Instance
+		return i.Name, nil
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), ErrTaskPanicked) {
+		t.Fatalf("panic has not been turned into a failure (%v)", err)
+	}
+}
+
+func TestTaskExpiredFilter(t *testing.T) {
+	now := time.Now()
+	filter := taskExpiredFilter(now)
+
+	// whichever replica runs them
+	for _, e := range filter {
+		if e.Key == "owner" {
+			t.Fatalf("expired tasks should not be filtered by owner")
+		}
+	}
+
+	line, err := bson.MarshalExtJSON(filter, false, false)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	deadline := now.Add(-TaskLeaseSeconds * time.Second).UTC().Format("2006-01-02T15:04:05")
+	if !strings.Contains(string(line), deadline) || !strings.Contains(string(line), TaskStatusRunning) {
+		t.Fatalf("unexpected filter: %s", line)
+	}
+}
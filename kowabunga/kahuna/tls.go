@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * Native TLS serving: server certificate, key and agents certificate authority
+ * are periodically checked for changes and transparently reloaded, so that
+ * renewed certificates are served without restarting Kahuna.
+ */
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kowabunga-cloud/common/klog"
+)
+
+const (
+	TlsDefaultReloadIntervalSeconds = 60
+
+	ErrTlsInvalidAgentsCA          = "no valid certificate in agents certificate authority"
+	ErrTlsNoAgentCertificate       = "no verified agent client certificate"
+	ErrTlsAgentCertificateMismatch = "agent client certificate does not match agent ID"
+)
+
+func (cfg *KowabungaTLSConfig) AgentsMutualTLS() bool {
+	return cfg.Enabled && cfg.AgentsCA != ""
+}
+
+type TLSCertReloader struct {
+	mutex    sync.RWMutex
+	cfg      KowabungaTLSConfig
+	cert     *tls.Certificate
+	agentsCA *x509.CertPool
+	modTimes map[string]time.Time
+}
+
+func NewTLSCertReloader(cfg KowabungaTLSConfig) (*TLSCertReloader, error) {
+	cr := TLSCertReloader{
+		cfg:      cfg,
+		modTimes: map[string]time.Time{},
+	}
+
+	err := cr.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &cr, nil
+}
+
+func (cr *TLSCertReloader) files() []string {
+	files := []string{cr.cfg.Cert, cr.cfg.Key}
+	if cr.cfg.AgentsCA != "" {
+		files = append(files, cr.cfg.AgentsCA)
+	}
+	return files
+}
+
+// modified tells whether one of the certificate files has changed since last load
+func (cr *TLSCertReloader) modified() bool {
+	for _, f := range cr.files() {
+		fi, err := os.Stat(f)
+		if err != nil {
+			klog.Error(err)
+			continue
+		}
+		if !fi.ModTime().Equal(cr.modTimes[f]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cr *TLSCertReloader) load() error {
+	modTimes := map[string]time.Time{}
+	for _, f := range cr.files() {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return err
+		}
+		modTimes[f] = fi.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(cr.cfg.Cert, cr.cfg.Key)
+	if err != nil {
+		return err
+	}
+
+	var agentsCA *x509.CertPool
+	if cr.cfg.AgentsCA != "" {
+		pem, err := os.ReadFile(cr.cfg.AgentsCA)
+		if err != nil {
+			return err
+		}
+		agentsCA = x509.NewCertPool()
+		if !agentsCA.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("%s: %s", ErrTlsInvalidAgentsCA, cr.cfg.AgentsCA)
+		}
+	}
+
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+	cr.cert = &cert
+	cr.agentsCA = agentsCA
+	cr.modTimes = modTimes
+
+	return nil
+}
+
+// Watch periodically reloads certificates upon files change, until stop channel is closed
+func (cr *TLSCertReloader) Watch(stop <-chan struct{}) {
+	interval := cr.cfg.ReloadInterval
+	if interval <= 0 {
+		interval = TlsDefaultReloadIntervalSeconds
+	}
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if !cr.modified() {
+				continue
+			}
+			err := cr.load()
+			if err != nil {
+				// keep on serving previous certificate
+				klog.Errorf("Unable to reload TLS certificate: %v", err)
+				continue
+			}
+			klog.Infof("Reloaded TLS certificate from %s", cr.cfg.Cert)
+		}
+	}
+}
+
+func (cr *TLSCertReloader) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mutex.RLock()
+	defer cr.mutex.RUnlock()
+	return cr.cert, nil
+}
+
+func (cr *TLSCertReloader) getConfigForClient(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+	cr.mutex.RLock()
+	defer cr.mutex.RUnlock()
+
+	conf := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: cr.GetCertificate,
+		// agents WebSocket connections require HTTP/1.1
+		NextProtos: []string{"http/1.1"},
+	}
+
+	// agents may authenticate with client certificates, regular API clients don't
+	if cr.agentsCA != nil {
+		conf.ClientCAs = cr.agentsCA
+		conf.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return conf, nil
+}
+
+func (cr *TLSCertReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetConfigForClient: cr.getConfigForClient,
+	}
+}
+
+// verifyAgentCertificate ensures agent's verified client certificate CN or SAN matches its ID
+func verifyAgentCertificate(r *http.Request, agentId string) error {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return fmt.Errorf("%s", ErrTlsNoAgentCertificate)
+	}
+
+	cert := r.TLS.VerifiedChains[0][0]
+	if cert.Subject.CommonName == agentId || slices.Contains(cert.DNSNames, agentId) {
+		return nil
+	}
+
+	return fmt.Errorf("%s: %s", ErrTlsAgentCertificateMismatch, agentId)
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * API token scopes restrict what a user token is permitted to do, on top of
+ * its owner's role: a subset of projects, HTTP methods and API operations,
+ * or read-only access. Tokens with no scope inherit the user's full rights.
+ *
+ * Scoped tokens are not permitted to set users credentials (or the email
+ * address these are sent to) unless explicitly granted such routes, as
+ * logging in with these would provide them with an unscoped session.
+ */
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/kowabunga-cloud/common/klog"
+)
+
+const (
+	ErrTokenScopeInvalidMethod  = "unsupported HTTP method in token scope"
+	ErrTokenScopeInvalidProject = "unknown project in token scope"
+	ErrTokenScopeInvalidRoute   = "unknown API operation in token scope"
+)
+
+var tokenScopeReadOnlyMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+}
+
+var tokenScopeSupportedMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// scoped tokens can't be used to issue or widen API tokens
+var tokenScopeDeniedRoutes = []string{
+	"SetUserApiToken",
+	"SetUserApiTokenScope",
+}
+
+// scoped tokens must be explicitly granted these routes to be able to use them
+var tokenScopeCredentialsRoutes = []string{
+	"CreateUser",
+	"UpdateUser",
+	"SetUserPassword",
+	"ResetUserPassword",
+}
+
+type TokenScope struct {
+	ProjectIDs []string `bson:"project_ids"`
+	Methods    []string `bson:"methods"`
+	Routes     []string `bson:"routes"`
+	ReadOnly   bool     `bson:"read_only"`
+}
+
+type TokenScopeModel struct {
+	Projects []string `json:"projects,omitempty"`
+	Methods  []string `json:"methods,omitempty"`
+	Routes   []string `json:"routes,omitempty"`
+	ReadOnly bool     `json:"readOnly,omitempty"`
+}
+
+func NewTokenScope(m TokenScopeModel) (TokenScope, error) {
+	scope := TokenScope{
+		ProjectIDs: []string{},
+		Methods:    []string{},
+		Routes:     []string{},
+		ReadOnly:   m.ReadOnly,
+	}
+
+	for _, method := range m.Methods {
+		method = strings.ToUpper(method)
+		if !slices.Contains(tokenScopeSupportedMethods, method) {
+			return scope, fmt.Errorf("%s: %s", ErrTokenScopeInvalidMethod, method)
+		}
+		scope.Methods = append(scope.Methods, method)
+	}
+
+	for _, projectId := range m.Projects {
+		_, err := FindProjectByID(projectId)
+		if err != nil {
+			return scope, fmt.Errorf("%s: %s", ErrTokenScopeInvalidProject, projectId)
+		}
+		scope.ProjectIDs = append(scope.ProjectIDs, projectId)
+	}
+
+	for _, route := range m.Routes {
+		if !IsApiOperation(route) {
+			return scope, fmt.Errorf("%s: %s", ErrTokenScopeInvalidRoute, route)
+		}
+		scope.Routes = append(scope.Routes, route)
+	}
+
+	return scope, nil
+}
+
+func (s *TokenScope) IsEmpty() bool {
+	return len(s.ProjectIDs) == 0 && len(s.Methods) == 0 && len(s.Routes) == 0 && !s.ReadOnly
+}
+
+func (s *TokenScope) Model() TokenScopeModel {
+	return TokenScopeModel{
+		Projects: s.ProjectIDs,
+		Methods:  s.Methods,
+		Routes:   s.Routes,
+		ReadOnly: s.ReadOnly,
+	}
+}
+
+// allowsProjectResources tells whether request's targeted resources belong to scoped projects.
+// Resources which are not owned by any project (regions, zones ...) can only be read.
+func (s *TokenScope) allowsProjectResources(r *http.Request) bool {
+	target := rbacResolveTarget(r)
+	if target.Ambiguous {
+		return false
+	}
+
+	if target.ProjectID != "" {
+		return slices.Contains(s.ProjectIDs, target.ProjectID)
+	}
+
+	return slices.Contains(tokenScopeReadOnlyMethods, r.Method)
+}
+
+// Allows tells whether scope permits request to be processed
+func (s *TokenScope) Allows(r *http.Request, name string) bool {
+	if slices.Contains(tokenScopeDeniedRoutes, name) {
+		return false
+	}
+
+	if slices.Contains(tokenScopeCredentialsRoutes, name) && !slices.Contains(s.Routes, name) {
+		return false
+	}
+
+	if s.ReadOnly && !slices.Contains(tokenScopeReadOnlyMethods, r.Method) {
+		return false
+	}
+
+	if len(s.Methods) > 0 && !slices.Contains(s.Methods, r.Method) {
+		return false
+	}
+
+	if len(s.Routes) > 0 && !slices.Contains(s.Routes, name) {
+		return false
+	}
+
+	if len(s.ProjectIDs) > 0 && !s.allowsProjectResources(r) {
+		return false
+	}
+
+	return true
+}
+
+func reqIsTokenScopeAuthorized(r *http.Request, name string) bool {
+	scope := ctxGetTokenScope(r.Context())
+	if scope == nil {
+		return true
+	}
+
+	if !scope.Allows(r, name) {
+		klog.Debugf("Request %s %s is out of API token's scope", r.Method, name)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTokenScopeCredentialsRoutes(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPut, "/api/v1/user/0123456789abcdef01234567/password", nil)
+
+	scope := TokenScope{
+		Methods: []string{http.MethodPut},
+	}
+	if scope.Allows(r, "SetUserPassword") {
+		t.Fatalf("scoped token has been allowed to set user's password")
+	}
+	if scope.Allows(r, "UpdateUser") {
+		t.Fatalf("scoped token has been allowed to update user's email")
+	}
+
+	scope.Routes = []string{"SetUserPassword"}
+	if !scope.Allows(r, "SetUserPassword") {
+		t.Fatalf("scoped token has been denied explicitly granted route")
+	}
+
+	scope.Routes = []string{"SetUserApiToken"}
+	if scope.Allows(r, "SetUserApiToken") {
+		t.Fatalf("scoped token has been allowed to issue API tokens")
+	}
+}
+
+func TestTokenScopeRoutes(t *testing.T) {
+	scope, err := NewTokenScope(TokenScopeModel{
+		Methods: []string{"get"},
+		Routes:  []string{"ListProjects", "ReadInstance"},
+	})
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if len(scope.Routes) != 2 || scope.Methods[0] != http.MethodGet {
+		t.Fatalf("unexpected scope: %+v", scope)
+	}
+
+	_, err = NewTokenScope(TokenScopeModel{
+		Routes: []string{"ReadInstance", "ReadInstances"},
+	})
+	if err == nil {
+		t.Fatalf("unknown API operation has been accepted")
+	}
+}
@@ -0,0 +1,293 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * Deleted Komputes, instances and volumes are first moved to trash:
+ * instance is stopped but its disks, IP addresses and DNS records are held,
+ * so that resource can be restored, until retention period expires and
+ * resource finally gets purged.
+ *
+ * Trashed resources still account for project's quotas and costs. They
+ * also keep holding their name (as well as instance's libvirt domain and DNS
+ * records), which can't be reused until resource is either restored or
+ * purged: creation requests are rejected as conflicting meanwhile.
+ */
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/kowabunga-cloud/common/klog"
+)
+
+const (
+	TrashDefaultRetentionHours = 72
+	TrashPurgeIntervalSeconds  = 300
+	TrashPurgeRetryMinutes     = 60
+	TrashRouteName             = "Trash"
+
+	TrashKindKompute  = "kompute"
+	TrashKindInstance = "instance"
+	TrashKindVolume   = "volume"
+
+	ErrTrashNameInUse = "name is held by a trashed resource, restore or purge it first"
+)
+
+// ResourceTrash flags a deleted resource, pending purge
+type ResourceTrash struct {
+	TrashedAt time.Time `bson:"trashed_at"`
+	PurgeAt   time.Time `bson:"purge_at"`
+	ParentID  string    `bson:"parent_id,omitempty"` // resource whose deletion trashed this one, if any
+}
+
+type TrashModel struct {
+	Id        string `json:"id"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	ProjectId string `json:"project_id"`
+	TrashedAt string `json:"trashed_at"`
+	PurgeAt   string `json:"purge_at"`
+}
+
+// trashRouteVarCollections are routes variables targeting resources which can be trashed
+var trashRouteVarCollections = map[string]string{
+	"instanceId": MongoCollectionInstanceName,
+	"komputeId":  MongoCollectionKomputeName,
+	"volumeId":   MongoCollectionVolumeName,
+}
+
+func trashRetention() time.Duration {
+	retention := TrashDefaultRetentionHours
+	if GetCfg() != nil && GetCfg().Global.Trash.Retention > 0 {
+		retention = GetCfg().Global.Trash.Retention
+	}
+	return time.Duration(retention) * time.Hour
+}
+
+func NewResourceTrash(parentId string) *ResourceTrash {
+	now := time.Now()
+	return &ResourceTrash{
+		TrashedAt: now,
+		PurgeAt:   now.Add(trashRetention()),
+		ParentID:  parentId,
+	}
+}
+
+// listFilterNotTrashed hides trashed resources from list queries
+func listFilterNotTrashed() bson.D {
+	return bson.D{bson.E{Key: "trash", Value: bson.D{bson.E{Key: "$exists", Value: false}}}}
+}
+
+// listFilterTrashed restricts a query to trashed resources, ignoring the ones trashed along with their parent
+func listFilterTrashed() bson.D {
+	return bson.D{
+		bson.E{Key: "trash", Value: bson.D{bson.E{Key: "$exists", Value: true}}},
+		bson.E{Key: "trash.parent_id", Value: bson.D{bson.E{Key: "$exists", Value: false}}},
+	}
+}
+
+// IsTrashed tells whether resource from collection has been moved to trash
+func IsTrashed(collection, id string) bool {
+	oid, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return false
+	}
+
+	filter := bson.D{
+		bson.E{Key: "_id", Value: oid},
+		bson.E{Key: "trash", Value: bson.D{bson.E{Key: "$exists", Value: true}}},
+	}
+	count, err := GetDB().Count(collection, filter)
+	if err != nil {
+		klog.Error(err)
+		return false
+	}
+	return count > 0
+}
+
+// ErrNameInUse reports resource's name as already used, possibly by a trashed resource
+func ErrNameInUse(collection string, r *Resource) error {
+	if r.IsTrashed() {
+		return fmt.Errorf("%s %s: %s (%s)", collection, r.Name, ErrTrashNameInUse, r.String())
+	}
+	return fmt.Errorf("%s %s already exists", collection, r.Name)
+}
+
+func trashModel(kind string, r *Resource, projectId string) TrashModel {
+	return TrashModel{
+		Id:        r.String(),
+		Kind:      kind,
+		Name:      r.Name,
+		ProjectId: projectId,
+		TrashedAt: r.Trashed.TrashedAt.UTC().Format(time.RFC3339),
+		PurgeAt:   r.Trashed.PurgeAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// FindTrash returns the trashed resources, optionally restricted to a given project, most recent first
+func FindTrash(projectId string) ([]TrashModel, error) {
+	filter := listFilterTrashed()
+	if projectId != "" {
+		filter = append(filter, listFilterByKey("project_id", projectId)...)
+	}
+
+	trash := []TrashModel{}
+
+	komputes := []Kompute{}
+	err := GetDB().FindAllByFilter(MongoCollectionKomputeName, filter, nil, 0, &komputes)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range komputes {
+		trash = append(trash, trashModel(TrashKindKompute, &k.Resource, k.ProjectID))
+	}
+
+	instances := []Instance{}
+	err = GetDB().FindAllByFilter(MongoCollectionInstanceName, filter, nil, 0, &instances)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range instances {
+		trash = append(trash, trashModel(TrashKindInstance, &i.Resource, i.ProjectID))
+	}
+
+	volumes := []Volume{}
+	err = GetDB().FindAllByFilter(MongoCollectionVolumeName, filter, nil, 0, &volumes)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range volumes {
+		trash = append(trash, trashModel(TrashKindVolume, &v.Resource, v.ProjectID))
+	}
+
+	slices.SortStableFunc(trash, func(a, b TrashModel) int {
+		return strings.Compare(b.TrashedAt, a.TrashedAt)
+	})
+
+	return trash, nil
+}
+
+func findTrashedResource[T any, PT interface {
+	*T
+	IsTrashed() bool
+}](collection, id string) (*T, error) {
+	r, err := FindResourceByID[T](collection, id)
+	if err != nil {
+		return nil, err
+	}
+	if !PT(r).IsTrashed() {
+		return nil, fmt.Errorf("resource %s/%s is not in trash", collection, id)
+	}
+	return r, nil
+}
+
+func FindTrashedKompute(id string) (*Kompute, error) {
+	return findTrashedResource[Kompute](MongoCollectionKomputeName, id)
+}
+
+func FindTrashedInstance(id string) (*Instance, error) {
+	return findTrashedResource[Instance](MongoCollectionInstanceName, id)
+}
+
+func FindTrashedVolume(id string) (*Volume, error) {
+	return findTrashedResource[Volume](MongoCollectionVolumeName, id)
+}
+
+type trashableResource[T any] interface {
+	*T
+	String() string
+	Delete() error
+	trash() *ResourceTrash
+}
+
+// purgeTrashedResources permanently deletes collection's resources whose retention period has expired
+func purgeTrashedResources[T any, PT trashableResource[T]](collection string, now time.Time) {
+	filter := listFilterTrashed()
+	filter = append(filter, bson.E{Key: "trash.purge_at", Value: bson.D{bson.E{Key: "$lte", Value: now}}})
+
+	resources := []T{}
+	err := GetDB().FindAllByFilter(collection, filter, nil, 0, &resources)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+
+	for idx := range resources {
+		r := PT(&resources[idx])
+		id, err := bson.ObjectIDFromHex(r.String())
+		if err != nil {
+			continue
+		}
+
+		// claim resource first, so that other replicas don't purge it concurrently,
+		// postponed purge being retried later on, would this one fail
+		r.trash().PurgeAt = now.Add(TrashPurgeRetryMinutes * time.Minute)
+		_, err = GetDB().Update(collection, id, r)
+		if err != nil {
+			if !IsDbConflictError(err) {
+				klog.Error(err)
+			}
+			continue
+		}
+
+		klog.Infof("Purging trashed resource %s/%s", collection, r.String())
+		err = r.Delete()
+		if err != nil {
+			klog.Errorf("Unable to purge trashed resource %s/%s: %v", collection, r.String(), err)
+		}
+	}
+}
+
+// PurgeTrash permanently deletes resources whose trash retention period has expired
+func PurgeTrash() {
+	now := time.Now()
+
+	// Komputes first, as they take their instance and volumes along
+	purgeTrashedResources[Kompute](MongoCollectionKomputeName, now)
+	purgeTrashedResources[Instance](MongoCollectionInstanceName, now)
+	purgeTrashedResources[Volume](MongoCollectionVolumeName, now)
+}
+
+func TrashPurger(ctx context.Context) {
+	ticker := time.NewTicker(TrashPurgeIntervalSeconds * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			PurgeTrash()
+		}
+	}
+}
+
+// trashMiddleware hides trashed resources from all but trash routes
+func trashMiddleware(next http.Handler, name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(name, TrashRouteName) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		varName, id := routeTargetVar(r)
+		collection, ok := trashRouteVarCollections[varName]
+		if ok && id != "" && IsTrashed(collection, id) {
+			HttpMiddlewareError(w, r, HttpNotFound, fmt.Errorf("resource %s/%s is in trash", collection, id))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestResourceTrash(t *testing.T) {
+	v := Volume{
+		Resource: NewResource("data", "", MongoCollectionVolumeSchemaVersion),
+	}
+	if v.IsTrashed() {
+		t.Fatalf("new volume is trashed")
+	}
+
+	// restored resources must not match trash filters anymore
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if _, err := bson.Raw(raw).LookupErr("trash"); err == nil {
+		t.Fatalf("untrashed volume has a trash field")
+	}
+
+	v.Trashed = NewResourceTrash("")
+	if !v.IsTrashed() {
+		t.Fatalf("volume is not trashed")
+	}
+	if v.Trashed.PurgeAt.Sub(v.Trashed.TrashedAt) != TrashDefaultRetentionHours*time.Hour {
+		t.Fatalf("unexpected retention period: %s", v.Trashed.PurgeAt.Sub(v.Trashed.TrashedAt))
+	}
+
+	raw, err = bson.Marshal(v)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if _, err := bson.Raw(raw).LookupErr("trash", "purge_at"); err != nil {
+		t.Fatalf("trashed volume has no purge date: %s", err.Error())
+	}
+}
+
+func TestTrashNameInUse(t *testing.T) {
+	v := Volume{
+		Resource: NewResource("data", "", MongoCollectionVolumeSchemaVersion),
+	}
+	err := ErrNameInUse(MongoCollectionVolumeName, &v.Resource)
+	if strings.Contains(err.Error(), ErrTrashNameInUse) {
+		t.Fatalf("live resource reported as trashed: %v", err)
+	}
+
+	v.Trashed = NewResourceTrash("")
+	err = ErrNameInUse(MongoCollectionVolumeName, &v.Resource)
+	if !strings.Contains(err.Error(), ErrTrashNameInUse) {
+		t.Fatalf("trashed resource not reported as such: %v", err)
+	}
+}
@@ -0,0 +1,587 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+/*
+ * Webhooks notify external automation (e.g. CMDB, chat bots) of resources
+ * lifecycle events. Subscriptions are either global or restricted to a project.
+ *
+ * Each event is recorded as a delivery per matching subscription, sent in background
+ * as an HMAC-signed JSON payload, and retried with exponential backoff until it
+ * either succeeds or ends up dead-lettered.
+ */
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/kowabunga-cloud/common/klog"
+)
+
+const (
+	MongoCollectionWebhookSchemaVersion = 1
+	MongoCollectionWebhookName          = "webhook"
+
+	MongoCollectionWebhookDeliverySchemaVersion = 1
+	MongoCollectionWebhookDeliveryName          = "webhook_delivery"
+
+	WebhookEventProjectCreated   = "project.created"
+	WebhookEventProjectUpdated   = "project.updated"
+	WebhookEventProjectDeleted   = "project.deleted"
+	WebhookEventInstanceCreated  = "instance.created"
+	WebhookEventInstanceUpdated  = "instance.updated"
+	WebhookEventInstanceTrashed  = "instance.trashed"
+	WebhookEventInstanceRestored = "instance.restored"
+	WebhookEventInstanceDeleted  = "instance.deleted"
+	WebhookEventVolumeCreated    = "volume.created"
+	WebhookEventVolumeUpdated    = "volume.updated"
+	WebhookEventVolumeTrashed    = "volume.trashed"
+	WebhookEventVolumeRestored   = "volume.restored"
+	WebhookEventVolumeDeleted    = "volume.deleted"
+	WebhookEventUserCreated      = "user.created"
+
+	WebhookDeliveryStatusPending   = "pending"
+	WebhookDeliveryStatusSucceeded = "succeeded"
+	WebhookDeliveryStatusDead      = "dead"
+
+	WebhookSecretLength              = 32
+	WebhookDeliveryIntervalSeconds   = 10
+	WebhookDeliveryTimeoutSeconds    = 10
+	WebhookDeliveryMaxAttempts       = 8
+	WebhookDeliveryBackoffSeconds    = 30
+	WebhookDeliveryMaxBackoffSeconds = 3600
+	WebhookDeliveryRetentionDays     = 30
+	WebhookDeliveryBatchSize         = 100
+	WebhookDeliveryMaxErrorLength    = 512
+
+	HttpHeaderWebhookEvent     = "X-Kowabunga-Event"
+	HttpHeaderWebhookDelivery  = "X-Kowabunga-Delivery"
+	HttpHeaderWebhookSignature = "X-Kowabunga-Signature"
+	WebhookSignaturePrefix     = "sha256="
+
+	ErrWebhookInvalidURL   = "invalid webhook URL, only http(s) ones are supported"
+	ErrWebhookInvalidEvent = "unsupported webhook event type"
+)
+
+var webhookEvents = []string{
+	WebhookEventProjectCreated,
+	WebhookEventProjectUpdated,
+	WebhookEventProjectDeleted,
+	WebhookEventInstanceCreated,
+	WebhookEventInstanceUpdated,
+	WebhookEventInstanceTrashed,
+	WebhookEventInstanceRestored,
+	WebhookEventInstanceDeleted,
+	WebhookEventVolumeCreated,
+	WebhookEventVolumeUpdated,
+	WebhookEventVolumeTrashed,
+	WebhookEventVolumeRestored,
+	WebhookEventVolumeDeleted,
+	WebhookEventUserCreated,
+}
+
+var webhookSchemes = []string{"http", "https"}
+
+// webhookClient sends deliveries, never reaching control-plane's network
+var webhookClient = NewEgressHttpClient(WebhookDeliveryTimeoutSeconds * time.Second)
+
+// webhookDispatcherWakeUp triggers immediate delivery of freshly fired events
+var webhookDispatcherWakeUp = make(chan struct{}, 1)
+
+type Webhook struct {
+	// anonymous field, inheritance
+	Resource `bson:"inline"`
+
+	// parents
+	ProjectID string `bson:"project_id"` // global subscription if empty
+
+	// properties
+	URL      string   `bson:"url"`
+	Secret   string   `bson:"secret"`
+	Events   []string `bson:"events"` // all events if empty
+	Disabled bool     `bson:"disabled"`
+}
+
+type WebhookModel struct {
+	Id          string   `json:"id,omitempty"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	ProjectId   string   `json:"project_id,omitempty"`
+	URL         string   `json:"url"`
+	Secret      string   `json:"secret,omitempty"` // only exposed at creation
+	Events      []string `json:"events,omitempty"`
+	Disabled    bool     `json:"disabled"`
+}
+
+type WebhookDelivery struct {
+	// anonymous field, inheritance
+	Resource `bson:"inline"`
+
+	// parents
+	WebhookID string `bson:"webhook_id"`
+	ProjectID string `bson:"project_id"`
+
+	// properties
+	Event         string    `bson:"event"`
+	Payload       string    `bson:"payload"`
+	Status        string    `bson:"status"`
+	Attempts      int       `bson:"attempts"`
+	NextAttemptAt time.Time `bson:"next_attempt_at"`
+	LastAttemptAt time.Time `bson:"last_attempt_at"`
+	ResponseCode  int       `bson:"response_code"`
+	Error         string    `bson:"error"`
+}
+
+type WebhookDeliveryModel struct {
+	Id            string `json:"id"`
+	Event         string `json:"event"`
+	Status        string `json:"status"`
+	Attempts      int    `json:"attempts"`
+	Payload       string `json:"payload"`
+	ResponseCode  int    `json:"response_code,omitempty"`
+	Error         string `json:"error,omitempty"`
+	CreatedAt     string `json:"created_at"`
+	LastAttemptAt string `json:"last_attempt_at,omitempty"`
+	NextAttemptAt string `json:"next_attempt_at,omitempty"`
+}
+
+// WebhookPayload is the JSON document POSTed to subscribers
+type WebhookPayload struct {
+	Id        string `json:"id"`
+	Event     string `json:"event"`
+	Timestamp string `json:"timestamp"`
+	ProjectId string `json:"project_id,omitempty"`
+	Data      any    `json:"data"`
+}
+
+func IsValidWebhookEvent(event string) bool {
+	return slices.Contains(webhookEvents, event)
+}
+
+func validateWebhook(uri string, events []string) error {
+	err := validateEgressURL(uri, webhookSchemes)
+	if err != nil {
+		return fmt.Errorf("%s: %v", ErrWebhookInvalidURL, err)
+	}
+
+	for _, e := range events {
+		if !IsValidWebhookEvent(e) {
+			return fmt.Errorf("%s: %s", ErrWebhookInvalidEvent, e)
+		}
+	}
+
+	return nil
+}
+
+func webhookSecret() (string, error) {
+	b := make([]byte, WebhookSecretLength)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// WebhookSignature computes payload's HMAC-SHA256 signature, as sent in X-Kowabunga-Signature header
+func WebhookSignature(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(payload)
+	return WebhookSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff returns the delay before next delivery attempt, doubling at each failed one
+func webhookBackoff(attempts int) time.Duration {
+	delay := WebhookDeliveryBackoffSeconds * time.Second
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= WebhookDeliveryMaxBackoffSeconds*time.Second {
+			return WebhookDeliveryMaxBackoffSeconds * time.Second
+		}
+	}
+	return delay
+}
+
+func NewWebhook(projectId, name, desc, uri, secret string, events []string, disabled bool) (*Webhook, error) {
+	err := validateWebhook(uri, events)
+	if err != nil {
+		return nil, err
+	}
+
+	if secret == "" {
+		secret, err = webhookSecret()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if events == nil {
+		events = []string{}
+	}
+
+	w := Webhook{
+		Resource:  NewResource(name, desc, MongoCollectionWebhookSchemaVersion),
+		ProjectID: projectId,
+		URL:       uri,
+		Secret:    secret,
+		Events:    events,
+		Disabled:  disabled,
+	}
+
+	_, err = GetDB().Insert(MongoCollectionWebhookName, w)
+	if err != nil {
+		return nil, err
+	}
+
+	klog.Debugf("Created new webhook %s (%s)", w.String(), w.URL)
+
+	return &w, nil
+}
+
+func FindWebhookByID(id string) (*Webhook, error) {
+	return FindResourceByID[Webhook](MongoCollectionWebhookName, id)
+}
+
+func FindWebhooksByProject(projectId string) ([]Webhook, error) {
+	return FindResourcesByKey[Webhook](MongoCollectionWebhookName, "project_id", projectId)
+}
+
+// Subscribes tells whether webhook is to be notified of project's event
+func (w *Webhook) Subscribes(event, projectId string) bool {
+	if w.Disabled {
+		return false
+	}
+	if w.ProjectID != "" && w.ProjectID != projectId {
+		return false
+	}
+	return len(w.Events) == 0 || slices.Contains(w.Events, event)
+}
+
+func (w *Webhook) Update(name, desc, uri, secret string, events []string, disabled bool) error {
+	err := validateWebhook(uri, events)
+	if err != nil {
+		return err
+	}
+
+	if events == nil {
+		events = []string{}
+	}
+
+	w.UpdateResourceDefaults(name, desc)
+	w.URL = uri
+	// secret is never exposed, keep it unless a new one is set
+	if secret != "" {
+		w.Secret = secret
+	}
+	w.Events = events
+	w.Disabled = disabled
+	w.Updated()
+
+	_, err = GetDB().Update(MongoCollectionWebhookName, w.ID, w)
+	return err
+}
+
+func (w *Webhook) Delete() error {
+	klog.Debugf("Deleting webhook %s", w.String())
+
+	if w.String() == ResourceUnknown {
+		return nil
+	}
+
+	err := GetDB().DeleteAllByFilter(MongoCollectionWebhookDeliveryName, listFilterByKey("webhook_id", w.String()))
+	if err != nil {
+		return err
+	}
+
+	return GetDB().Delete(MongoCollectionWebhookName, w.ID)
+}
+
+func (w *Webhook) Model() WebhookModel {
+	return WebhookModel{
+		Id:          w.String(),
+		Name:        w.Name,
+		Description: w.Description,
+		ProjectId:   w.ProjectID,
+		URL:         w.URL,
+		Events:      w.Events,
+		Disabled:    w.Disabled,
+	}
+}
+
+// DeleteProjectWebhooks removes all of project's webhook subscriptions, along with their deliveries
+func DeleteProjectWebhooks(projectId string) error {
+	hooks, err := FindWebhooksByProject(projectId)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range hooks {
+		err := w.Delete()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FireWebhookEvent queues event's delivery to all subscribed webhooks
+func FireWebhookEvent(event, projectId string, data any) {
+	filter := bson.D{
+		bson.E{Key: "project_id", Value: bson.D{bson.E{Key: "$in", Value: []string{"", projectId}}}},
+	}
+	hooks := []Webhook{}
+	err := GetDB().FindAllByFilter(MongoCollectionWebhookName, filter, nil, 0, &hooks)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+
+	fired := false
+	now := time.Now()
+	for _, w := range hooks {
+		if !w.Subscribes(event, projectId) {
+			continue
+		}
+
+		d := WebhookDelivery{
+			Resource:      NewResource(event, "", MongoCollectionWebhookDeliverySchemaVersion),
+			WebhookID:     w.String(),
+			ProjectID:     projectId,
+			Event:         event,
+			Status:        WebhookDeliveryStatusPending,
+			NextAttemptAt: now,
+		}
+
+		payload, err := json.Marshal(WebhookPayload{
+			Id:        d.String(),
+			Event:     event,
+			Timestamp: now.UTC().Format(time.RFC3339),
+			ProjectId: projectId,
+			Data:      data,
+		})
+		if err != nil {
+			klog.Error(err)
+			continue
+		}
+		d.Payload = string(payload)
+
+		_, err = GetDB().Insert(MongoCollectionWebhookDeliveryName, d)
+		if err != nil {
+			klog.Error(err)
+			continue
+		}
+		fired = true
+	}
+
+	if fired {
+		select {
+		case webhookDispatcherWakeUp <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func FindWebhookDeliveryByID(id string) (*WebhookDelivery, error) {
+	return FindResourceByID[WebhookDelivery](MongoCollectionWebhookDeliveryName, id)
+}
+
+func (d *WebhookDelivery) IsDead() bool {
+	return d.Status == WebhookDeliveryStatusDead
+}
+
+// Failed records a failed delivery attempt, scheduling the next one or dead-lettering delivery
+func (d *WebhookDelivery) Failed(code int, err error, now time.Time) {
+	d.ResponseCode = code
+	d.Error = err.Error()
+	if len(d.Error) > WebhookDeliveryMaxErrorLength {
+		d.Error = d.Error[:WebhookDeliveryMaxErrorLength]
+	}
+
+	if d.Attempts >= WebhookDeliveryMaxAttempts {
+		d.Status = WebhookDeliveryStatusDead
+		return
+	}
+	d.NextAttemptAt = now.Add(webhookBackoff(d.Attempts))
+}
+
+// Retry re-schedules a dead-lettered delivery for immediate delivery
+func (d *WebhookDelivery) Retry() error {
+	err := UpdateResource(MongoCollectionWebhookDeliveryName, d, func(d *WebhookDelivery) {
+		d.Status = WebhookDeliveryStatusPending
+		d.Attempts = 0
+		d.NextAttemptAt = time.Now()
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case webhookDispatcherWakeUp <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (d *WebhookDelivery) Model() WebhookDeliveryModel {
+	m := WebhookDeliveryModel{
+		Id:           d.String(),
+		Event:        d.Event,
+		Status:       d.Status,
+		Attempts:     d.Attempts,
+		Payload:      d.Payload,
+		ResponseCode: d.ResponseCode,
+		Error:        d.Error,
+		CreatedAt:    d.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	if !d.LastAttemptAt.IsZero() {
+		m.LastAttemptAt = d.LastAttemptAt.UTC().Format(time.RFC3339)
+	}
+	if d.Status == WebhookDeliveryStatusPending {
+		m.NextAttemptAt = d.NextAttemptAt.UTC().Format(time.RFC3339)
+	}
+	return m
+}
+
+// post sends delivery's signed payload to webhook, returning the response status code
+func (d *WebhookDelivery) post(w *Webhook) (int, error) {
+	payload := []byte(d.Payload)
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HttpHeaderWebhookEvent, d.Event)
+	req.Header.Set(HttpHeaderWebhookDelivery, d.String())
+	req.Header.Set(HttpHeaderWebhookSignature, WebhookSignature(w.Secret, payload))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	// response body is never recorded, subscribers only get to tell about status
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// deliver performs a delivery attempt
+func (d *WebhookDelivery) deliver(now time.Time) {
+	id, err := bson.ObjectIDFromHex(d.String())
+	if err != nil {
+		return
+	}
+
+	w, err := FindWebhookByID(d.WebhookID)
+	if err != nil {
+		d.Status = WebhookDeliveryStatusDead
+		d.Error = "webhook no longer exists"
+		_, _ = GetDB().Update(MongoCollectionWebhookDeliveryName, id, d)
+		return
+	}
+
+	// claim delivery first, so that other replicas don't send it concurrently,
+	// postponed attempt being considered as failed if this one never completes
+	d.Attempts++
+	d.LastAttemptAt = now
+	d.NextAttemptAt = now.Add(webhookBackoff(d.Attempts))
+	_, err = GetDB().Update(MongoCollectionWebhookDeliveryName, id, d)
+	if err != nil {
+		if !IsDbConflictError(err) {
+			klog.Error(err)
+		}
+		return
+	}
+
+	code, err := d.post(w)
+	if err != nil {
+		klog.Warningf("Webhook %s delivery %s (%s) failed (attempt %d/%d): %v", w.String(), d.String(), d.Event, d.Attempts, WebhookDeliveryMaxAttempts, err)
+		d.Failed(code, err, now)
+	} else {
+		klog.Debugf("Webhook %s delivery %s (%s) succeeded", w.String(), d.String(), d.Event)
+		d.Status = WebhookDeliveryStatusSucceeded
+		d.ResponseCode = code
+		d.Error = ""
+	}
+
+	d.Updated()
+	_, err = GetDB().Update(MongoCollectionWebhookDeliveryName, id, d)
+	if err != nil {
+		klog.Error(err)
+	}
+}
+
+// DeliverWebhooks sends all pending deliveries which are due
+func DeliverWebhooks() {
+	now := time.Now()
+	filter := bson.D{
+		bson.E{Key: "status", Value: WebhookDeliveryStatusPending},
+		bson.E{Key: "next_attempt_at", Value: bson.D{bson.E{Key: "$lte", Value: now}}},
+	}
+	sort := bson.D{bson.E{Key: "next_attempt_at", Value: 1}}
+
+	deliveries := []WebhookDelivery{}
+	err := GetDB().FindAllByFilter(MongoCollectionWebhookDeliveryName, filter, sort, WebhookDeliveryBatchSize, &deliveries)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+
+	for idx := range deliveries {
+		deliveries[idx].deliver(now)
+	}
+}
+
+// PurgeWebhookDeliveries removes completed deliveries past retention period
+func PurgeWebhookDeliveries() {
+	expiry := time.Now().AddDate(0, 0, -WebhookDeliveryRetentionDays)
+	filter := bson.D{
+		bson.E{Key: "status", Value: bson.D{bson.E{Key: "$ne", Value: WebhookDeliveryStatusPending}}},
+		bson.E{Key: "created_at", Value: bson.D{bson.E{Key: "$lt", Value: expiry}}},
+	}
+	err := GetDB().DeleteAllByFilter(MongoCollectionWebhookDeliveryName, filter)
+	if err != nil {
+		klog.Error(err)
+	}
+}
+
+func WebhookDispatcher(ctx context.Context) {
+	ticker := time.NewTicker(WebhookDeliveryIntervalSeconds * time.Second)
+	defer ticker.Stop()
+
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-webhookDispatcherWakeUp:
+			DeliverWebhooks()
+		case <-ticker.C:
+			DeliverWebhooks()
+		case <-purge.C:
+			PurgeWebhookDeliveries()
+		}
+	}
+}
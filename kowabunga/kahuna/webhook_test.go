@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) The Kowabunga Project
+ * Apache License, Version 2.0 (see LICENSE or https://www.apache.org/licenses/LICENSE-2.0.txt)
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package kahuna
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	// RFC 4231 test case #2
+	sig := WebhookSignature("Jefe", []byte("what do ya want for nothing?"))
+	expected := "sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+	if sig != expected {
+		t.Fatalf("unexpected signature: %s", sig)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	if webhookBackoff(1) != 30*time.Second {
+		t.Fatalf("unexpected first backoff: %s", webhookBackoff(1))
+	}
+	if webhookBackoff(3) != 2*time.Minute {
+		t.Fatalf("unexpected third backoff: %s", webhookBackoff(3))
+	}
+	if webhookBackoff(20) != time.Hour {
+		t.Fatalf("backoff is not capped: %s", webhookBackoff(20))
+	}
+}
+
+func TestWebhookSubscribes(t *testing.T) {
+	global := Webhook{}
+	if !global.Subscribes(WebhookEventInstanceCreated, "p1") {
+		t.Fatalf("global webhook should subscribe to all events")
+	}
+
+	w := Webhook{ProjectID: "p1", Events: []string{WebhookEventInstanceCreated}}
+	if !w.Subscribes(WebhookEventInstanceCreated, "p1") {
+		t.Fatalf("project webhook should subscribe to its project's events")
+	}
+	if w.Subscribes(WebhookEventInstanceCreated, "p2") || w.Subscribes(WebhookEventVolumeCreated, "p1") {
+		t.Fatalf("project webhook should only subscribe to its project's filtered events")
+	}
+
+	w.Disabled = true
+	if w.Subscribes(WebhookEventInstanceCreated, "p1") {
+		t.Fatalf("disabled webhook should not subscribe to anything")
+	}
+}
+
+func TestWebhookValidation(t *testing.T) {
+	if validateWebhook("https://cmdb.acme.com/hooks", []string{WebhookEventProjectCreated}) != nil {
+		t.Fatalf("valid webhook has been rejected")
+	}
+	if validateWebhook("ftp://cmdb.acme.com/hooks", nil) == nil {
+		t.Fatalf("non-HTTP webhook has been accepted")
+	}
+	if validateWebhook("https://cmdb.acme.com/hooks", []string{"instance.exploded"}) == nil {
+		t.Fatalf("unknown event has been accepted")
+	}
+	if validateWebhook("http://169.254.169.254/latest/meta-data", nil) == nil {
+		t.Fatalf("link-local webhook has been accepted")
+	}
+}
+
+func TestWebhookDeliveryDeadLetter(t *testing.T) {
+	now := time.Now()
+	d := WebhookDelivery{Status: WebhookDeliveryStatusPending}
+
+	for d.Attempts = 1; d.Attempts < WebhookDeliveryMaxAttempts; d.Attempts++ {
+		d.Failed(500, fmt.Errorf("server error"), now)
+		if d.Status != WebhookDeliveryStatusPending || !d.NextAttemptAt.After(now) {
+			t.Fatalf("delivery should have been retried after attempt %d", d.Attempts)
+		}
+	}
+
+	d.Failed(500, fmt.Errorf("server error"), now)
+	if !d.IsDead() {
+		t.Fatalf("delivery should have been dead-lettered after %d attempts", d.Attempts)
+	}
+}
@@ -103,6 +103,23 @@ func (k *Kaktus) CreateInstance(args *proto.KaktusCreateInstanceArgs, reply *pro
 	return err
 }
 
+/*
+ * RPC ListInstances()
+ */
+
+type KaktusListInstancesArgs struct{}
+type KaktusListInstancesReply struct {
+	Instances []string
+}
+
+func (k *Kaktus) ListInstances(args *KaktusListInstancesArgs, reply *KaktusListInstancesReply) error {
+	instances, err := k.agent.lcs.ListInstances()
+	*reply = KaktusListInstancesReply{
+		Instances: instances,
+	}
+	return err
+}
+
 /*
  * RPC GetInstance()
  */
@@ -215,6 +232,25 @@ func (k *Kaktus) GetStoragePoolStats(args *proto.KaktusGetStoragePoolStatsArgs,
 	return err
 }
 
+/*
+ * RPC ListVolumes()
+ */
+
+type KaktusListVolumesArgs struct {
+	Pool string
+}
+type KaktusListVolumesReply struct {
+	Volumes []string
+}
+
+func (k *Kaktus) ListVolumes(args *KaktusListVolumesArgs, reply *KaktusListVolumesReply) error {
+	volumes, err := k.agent.ceph.ListRbdVolumes(args.Pool)
+	*reply = KaktusListVolumesReply{
+		Volumes: volumes,
+	}
+	return err
+}
+
 /*
  * RPC CreateRawVolume()
  */
@@ -339,6 +339,20 @@ func (lcs *LibvirtConnectionSettings) DeleteInstance(instanceName string) error
 	return nil
 }
 
+// List all defined instances, running or not
+func (lcs *LibvirtConnectionSettings) ListInstances() ([]string, error) {
+	domains, _, err := lcs.Conn.ConnectListAllDomains(1, virt.ConnectListDomainsActive|virt.ConnectListDomainsInactive)
+	if err != nil {
+		return nil, err
+	}
+
+	instances := []string{}
+	for _, d := range domains {
+		instances = append(instances, d.Name)
+	}
+	return instances, nil
+}
+
 func (lcs *LibvirtConnectionSettings) getInstance(instanceName string) (*virt.Domain, error) {
 	domain, err := lcs.Conn.DomainLookupByName(instanceName)
 	if err != nil {